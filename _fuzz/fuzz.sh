@@ -7,5 +7,19 @@ if [ `dirname $0` != "." ]; then
 	exit 1
 fi
 
-go-fuzz-build github.com/hirochachacha/plua
-go-fuzz -bin plua-fuzz.zip -workdir .
+case "$1" in
+"")
+	go-fuzz-build github.com/hirochachacha/plua
+	go-fuzz -bin plua-fuzz.zip -workdir .
+	;;
+undump)
+	cd undump
+	go run gen.go
+	go-fuzz-build -func FuzzUndump -o plua-fuzz.zip github.com/hirochachacha/plua
+	go-fuzz -bin plua-fuzz.zip -workdir .
+	;;
+*)
+	echo "usage: fuzz.sh [undump]"
+	exit 1
+	;;
+esac
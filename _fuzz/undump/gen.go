@@ -0,0 +1,53 @@
+// +build ignore
+
+// gen.go compiles the corpus of Fuzz, and writes binary chunks as the corpus of FuzzUndump.
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/dump"
+)
+
+func main() {
+	names, err := filepath.Glob(filepath.Join("..", "corpus", "*"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = os.MkdirAll("corpus", 0755)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := compiler.NewCompiler()
+
+	for _, name := range names {
+		proto, err := c.CompileFile(name, compiler.Text)
+		if err != nil {
+			continue
+		}
+
+		f, err := os.Create(filepath.Join("corpus", filepath.Base(name)))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		w := bufio.NewWriter(f)
+
+		err = dump.DumpTo(w, proto, dump.ColumnInfo)
+		if err == nil {
+			err = w.Flush()
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
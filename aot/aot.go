@@ -0,0 +1,322 @@
+// Package aot implements the runtime support of Go packages generated by lua2go.
+//
+// Generated functions are object.GoFunctions, and they call the helpers below
+// instead of executing instructions.
+// The helpers share arithmetic, comparison and table semantics with the VM,
+// including metamethods and error messages.
+//
+// pos arguments are positions prefixed to errors, such as "chunk:1:5: ".
+package aot
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/hirochachacha/plua/compiler/undump"
+	"github.com/hirochachacha/plua/internal/arith"
+	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/object"
+)
+
+// at locates err raised by an operation at pos, the same as the VM does.
+// errors raised by callees are located already.
+func at(err *object.RuntimeError, pos string) *object.RuntimeError {
+	if err == nil || len(err.Traceback) > 0 || err.Level != 1 {
+		return err
+	}
+
+	if msg, ok := err.RawValue.(object.String); ok {
+		err.RawValue = object.String(pos) + msg
+		err.Level = 0
+	}
+
+	return err
+}
+
+// Exec executes a dumped chunk with args.
+func Exec(th object.Thread, chunk string, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	p, err := undump.Undump(strings.NewReader(chunk), 0)
+	if err != nil {
+		return nil, object.NewRuntimeError(err.Error())
+	}
+
+	return th.Call(th.NewClosure(p), args...)
+}
+
+// Func is a Lua function embedded as a dumped chunk,
+// which takes values of the upvalues as varargs and returns the function.
+type Func struct {
+	Chunk string
+
+	once sync.Once
+	p    *object.Proto
+}
+
+// Closure returns a new closure of f, whose upvalues are initialized by upvals.
+// It panics if f.Chunk is broken.
+func (f *Func) Closure(th object.Thread, upvals ...object.Value) (object.Value, *object.RuntimeError) {
+	f.once.Do(func() {
+		p, err := undump.Undump(strings.NewReader(f.Chunk), 0)
+		if err != nil {
+			panic(err)
+		}
+
+		f.p = p
+	})
+
+	rets, err := th.Call(th.NewClosure(f.p), upvals...)
+	if err != nil {
+		return nil, err
+	}
+
+	return Arg(rets, 0), nil
+}
+
+// Truth reports whether val is neither nil nor false.
+func Truth(val object.Value) bool {
+	return val != nil && val != object.False
+}
+
+// Not returns the result of "not val".
+func Not(val object.Value) object.Value {
+	return object.Boolean(!Truth(val))
+}
+
+// Arg returns args[i], or nil if it doesn't exist.
+func Arg(args []object.Value, i int) object.Value {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+// Rest returns the arguments after n parameters.
+func Rest(args []object.Value, n int) []object.Value {
+	if n < len(args) {
+		return args[n:]
+	}
+	return nil
+}
+
+// Call calls fn with args, and returns its results.
+// calls nest the same as Go functions calling Lua functions, see runtime.Limits.
+func Call(th object.Thread, pos string, fn object.Value, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	if object.ToType(fn) != object.TFUNCTION {
+		mt := th.GetMetatable(fn)
+		if mt == nil || object.ToType(mt.Get(object.TM_CALL)) != object.TFUNCTION {
+			return nil, at(errors.CallError(th, fn), pos)
+		}
+	}
+
+	rets, err := th.Call(fn, args...)
+	if err != nil {
+		return nil, callerAt(th, err, pos)
+	}
+
+	return rets, nil
+}
+
+// callerAt locates err raised by a Go function at pos, if the function is called at pos.
+// the VM locates such errors at the Lua caller, but callers of translated code are Go functions.
+func callerAt(th object.Thread, err *object.RuntimeError, pos string) *object.RuntimeError {
+	if err.Level != 1 || len(err.Traceback) == 0 {
+		return at(err, pos)
+	}
+
+	// the traceback consists of the callee and frames from the current one.
+	if len(err.Traceback) != 1+len(th.Traceback(0)) || err.Traceback[1].Where() != "" {
+		return err
+	}
+
+	if msg, ok := err.RawValue.(object.String); ok {
+		err.RawValue = object.String(pos) + msg
+		err.Level = 0
+	}
+
+	return err
+}
+
+// Index returns t[key].
+func Index(th object.Thread, pos string, t, key object.Value) (object.Value, *object.RuntimeError) {
+	if t, ok := t.(object.Table); ok {
+		if val := t.Get(key); val != nil || t.Metatable() == nil {
+			return val, nil
+		}
+	}
+
+	val, err := arith.CallGettable(th, t, key)
+
+	return val, at(err, pos)
+}
+
+// SetIndex performs "t[key] = val".
+func SetIndex(th object.Thread, pos string, t, key, val object.Value) *object.RuntimeError {
+	return at(arith.CallSettable(th, t, key, val), pos)
+}
+
+// SetField stores a keyed field of a table constructor.
+func SetField(pos string, t object.Table, key, val object.Value) *object.RuntimeError {
+	if key == nil {
+		return at(errors.NilIndexError(), pos)
+	}
+
+	if object.IsNaN(key) {
+		return at(errors.NaNIndexError(), pos)
+	}
+
+	t.Set(key, val)
+
+	return nil
+}
+
+// SetList stores positional fields of a table constructor.
+func SetList(t object.Table, vals []object.Value) {
+	t.SetList(0, vals)
+}
+
+func Add(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	if x, ok := x.(object.Integer); ok {
+		if y, ok := y.(object.Integer); ok {
+			return x + y, nil
+		}
+	}
+
+	val, err := arith.CallAdd(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Sub(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	if x, ok := x.(object.Integer); ok {
+		if y, ok := y.(object.Integer); ok {
+			return x - y, nil
+		}
+	}
+
+	val, err := arith.CallSub(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Mul(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	if x, ok := x.(object.Integer); ok {
+		if y, ok := y.(object.Integer); ok {
+			return x * y, nil
+		}
+	}
+
+	val, err := arith.CallMul(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Div(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallDiv(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Mod(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallMod(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Pow(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallPow(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Idiv(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallIdiv(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Band(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallBand(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Bor(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallBor(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Bxor(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallBxor(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Shl(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallShl(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Shr(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallShr(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Concat(th object.Thread, pos string, x, y object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallConcat(th, x, y)
+
+	return val, at(err, pos)
+}
+
+func Unm(th object.Thread, pos string, x object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallUnm(th, x)
+
+	return val, at(err, pos)
+}
+
+func Bnot(th object.Thread, pos string, x object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallBnot(th, x)
+
+	return val, at(err, pos)
+}
+
+func Len(th object.Thread, pos string, x object.Value) (object.Value, *object.RuntimeError) {
+	val, err := arith.CallLen(th, x)
+
+	return val, at(err, pos)
+}
+
+// Equal reports whether x == y.
+func Equal(th object.Thread, pos string, x, y object.Value) (bool, *object.RuntimeError) {
+	b, err := arith.CallEqual(th, false, x, y)
+
+	return b, at(err, pos)
+}
+
+// LessThan reports whether x < y.
+func LessThan(th object.Thread, pos string, x, y object.Value) (bool, *object.RuntimeError) {
+	if x, ok := x.(object.Integer); ok {
+		if y, ok := y.(object.Integer); ok {
+			return x < y, nil
+		}
+	}
+
+	b, err := arith.CallLessThan(th, false, x, y)
+
+	return b, at(err, pos)
+}
+
+// LessEqual reports whether x <= y.
+func LessEqual(th object.Thread, pos string, x, y object.Value) (bool, *object.RuntimeError) {
+	if x, ok := x.(object.Integer); ok {
+		if y, ok := y.(object.Integer); ok {
+			return x <= y, nil
+		}
+	}
+
+	b, err := arith.CallLessThanOrEqualTo(th, false, x, y)
+
+	return b, at(err, pos)
+}
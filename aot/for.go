@@ -0,0 +1,101 @@
+package aot
+
+import (
+	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/object"
+)
+
+// ForLoop is the state of a numeric for statement.
+// it follows FORPREP and FORLOOP instructions of the VM.
+type ForLoop struct {
+	isInt bool
+
+	idx, limit, step    object.Integer
+	fidx, flimit, fstep object.Number
+}
+
+// NewForLoop prepares a loop "for v = init, limit, step".
+func NewForLoop(pos string, init, limit, step object.Value) (*ForLoop, *object.RuntimeError) {
+	if init, ok := init.(object.Integer); ok {
+		if step, ok := step.(object.Integer); ok {
+			ilimit, ok := object.ToInteger(limit)
+			if !ok {
+				nlimit, ok := object.ToNumber(limit)
+				if !ok {
+					return nil, at(errors.ForLoopError("limit"), pos)
+				}
+
+				switch {
+				case nlimit < object.Number(object.MinInteger):
+					ilimit = object.MinInteger
+					if step >= 0 {
+						init = 0
+					}
+				case nlimit > object.Number(object.MaxInteger):
+					ilimit = object.MaxInteger
+					if step < 0 {
+						init = 0
+					}
+				default:
+					if step < 0 {
+						if nlimit < 0 {
+							ilimit = object.Integer(nlimit)
+						} else {
+							ilimit = object.Integer(nlimit + 1)
+						}
+					} else {
+						if nlimit < 0 {
+							ilimit = object.Integer(nlimit - 1)
+						} else {
+							ilimit = object.Integer(nlimit)
+						}
+					}
+				}
+			}
+
+			return &ForLoop{isInt: true, idx: init - step, limit: ilimit, step: step}, nil
+		}
+	}
+
+	finit, ok := object.ToNumber(init)
+	if !ok {
+		return nil, at(errors.ForLoopError("initial"), pos)
+	}
+
+	flimit, ok := object.ToNumber(limit)
+	if !ok {
+		return nil, at(errors.ForLoopError("limit"), pos)
+	}
+
+	fstep, ok := object.ToNumber(step)
+	if !ok {
+		return nil, at(errors.ForLoopError("step"), pos)
+	}
+
+	return &ForLoop{fidx: finit - fstep, flimit: flimit, fstep: fstep}, nil
+}
+
+// Next advances the loop, and reports whether it continues.
+func (l *ForLoop) Next() bool {
+	if l.isInt {
+		l.idx += l.step
+		if 0 < l.step {
+			return l.idx <= l.limit
+		}
+		return l.idx >= l.limit
+	}
+
+	l.fidx += l.fstep
+	if 0 < l.fstep {
+		return l.fidx <= l.flimit
+	}
+	return l.fidx >= l.flimit
+}
+
+// Value returns the current value of the control variable.
+func (l *ForLoop) Value() object.Value {
+	if l.isInt {
+		return l.idx
+	}
+	return l.fidx
+}
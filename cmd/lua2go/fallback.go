@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/position"
+)
+
+// functions which the translator doesn't support are embedded as bytecode.
+// these are functions with goto statements or labels, and functions which yield,
+// since GoFunctions can't be bodies of coroutines which yield.
+//
+// embedded functions are compiled apart from the enclosing functions,
+// and local variables of the enclosing functions are passed to them by values.
+// so that the variables which are assigned after the declarations can't be shared,
+// the enclosing functions of the functions referring to them are embedded too.
+
+// functions of the coroutine library which GoFunctions can call.
+var goCoroutineFuncs = map[string]bool{
+	"create":      true,
+	"isyieldable": true,
+	"resume":      true,
+	"running":     true,
+	"status":      true,
+	"wrap":        true,
+}
+
+// findFallbacks finds functions of f which are embedded as bytecode.
+// the main chunk is represented by nil.
+func (t *translator) findFallbacks(f *ast.File) {
+	t.fallbacks = make(map[*ast.FuncBody]*unsupportedError)
+	t.localFuncs = make(map[*ast.FuncBody]*scope.Decl)
+
+	mark := func(body *ast.FuncBody, err *unsupportedError) {
+		if _, ok := t.fallbacks[body]; !ok {
+			t.fallbacks[body] = err
+		}
+	}
+
+	parents := make(map[*ast.FuncBody]*ast.FuncBody)
+	funcs := make(map[*scope.Decl]*ast.FuncBody) // bodies of local variables
+	goNames := make(map[*ast.Name]bool)          // "coroutine" of goCoroutineFuncs
+
+	var order []*ast.FuncBody // pre-order
+
+	stack := []*ast.FuncBody{nil}
+
+	ast.Apply(f, func(c *ast.Cursor) bool {
+		body := stack[len(stack)-1]
+
+		switch node := c.Node().(type) {
+		case *ast.FuncBody:
+			parents[node] = body
+			order = append(order, node)
+			stack = append(stack, node)
+		case *ast.GotoStmt, *ast.LabelStmt:
+			mark(body, &unsupportedError{node.Pos(), "goto"})
+		case *ast.LocalFuncStmt:
+			d := t.info.Decls[node.Name]
+			funcs[d] = node.Body
+			t.localFuncs[node.Body] = d
+		case *ast.LocalAssignStmt:
+			for i, name := range node.LHS {
+				if i < len(node.RHS) {
+					if fn, ok := node.RHS[i].(*ast.FuncLit); ok {
+						funcs[t.info.Decls[name]] = fn.Body
+					}
+				}
+			}
+		case *ast.SelectorExpr:
+			if t.isCoroutine(node.X) && goCoroutineFuncs[node.Sel.Name] {
+				goNames[node.X.(*ast.Name)] = true
+			}
+		case *ast.CallExpr:
+			// bodies of coroutines
+			if sel, ok := node.X.(*ast.SelectorExpr); ok && node.Name == nil && len(node.Args) > 0 && t.isCoroutine(sel.X) {
+				if sel.Sel.Name == "create" || sel.Sel.Name == "wrap" {
+					switch arg := node.Args[0].(type) {
+					case *ast.FuncLit:
+						mark(arg.Body, &unsupportedError{arg.Pos(), "coroutine"})
+					case *ast.Name:
+						if fn := funcs[t.info.DeclOf(arg)]; fn != nil {
+							mark(fn, &unsupportedError{arg.Pos(), "coroutine"})
+						}
+					}
+				}
+			}
+		case *ast.Name:
+			if t.isCoroutine(node) && !goNames[node] {
+				mark(body, &unsupportedError{node.Pos(), "coroutine"})
+			}
+		}
+
+		return true
+	}, func(c *ast.Cursor) bool {
+		if _, ok := c.Node().(*ast.FuncBody); ok {
+			stack = stack[:len(stack)-1]
+		}
+
+		return true
+	})
+
+	// inner functions first, so that embedding goes up to the outermost function at once
+	for i := len(order) - 1; i >= 0; i-- {
+		body := order[i]
+		if err, ok := t.fallbacks[body]; ok {
+			if _, ok := t.upvalues(body); !ok {
+				mark(parents[body], err)
+			}
+		}
+	}
+}
+
+// isCoroutine reports whether e is the global "coroutine".
+func (t *translator) isCoroutine(e ast.Expr) bool {
+	name, ok := e.(*ast.Name)
+	if !ok || name.Name != "coroutine" {
+		return false
+	}
+	b := t.info.Bindings[name]
+	return b != nil && b.Kind == scope.Global
+}
+
+// upvalues returns local variables of the enclosing functions which body refers to,
+// except the implicit _ENV of the main chunk and the local function of body itself.
+// ok is false if some of them are assigned after the declarations.
+func (t *translator) upvalues(body *ast.FuncBody) (decls []*scope.Decl, ok bool) {
+	s := t.scopes[body]
+
+	seen := make(map[*scope.Decl]bool)
+
+	for _, b := range t.info.Bindings {
+		d := b.Decl
+		if d == t.info.Env || d == t.localFuncs[body] || seen[d] {
+			continue
+		}
+		for _, up := range b.Upvals {
+			if up == s {
+				seen[d] = true
+				decls = append(decls, d)
+				break
+			}
+		}
+	}
+
+	for _, d := range decls {
+		for _, ref := range d.Refs {
+			if t.info.Bindings[ref].Write {
+				return nil, false
+			}
+		}
+	}
+
+	sort.Sort(byVisible(decls))
+
+	return decls, true
+}
+
+type byVisible []*scope.Decl
+
+func (s byVisible) Len() int           { return len(s) }
+func (s byVisible) Less(i, j int) bool { return s[i].Visible.LessThan(s[j].Visible) }
+func (s byVisible) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// isEmbedded reports whether body is embedded as bytecode.
+func (t *translator) isEmbedded(body *ast.FuncBody) bool {
+	_, ok := t.fallbacks[body]
+	return ok
+}
+
+// embedded evaluates a closure of the embedded function from pos to end,
+// and returns a Go expression of it.
+func (t *translator) embedded(body *ast.FuncBody, isMethod bool, pos, end position.Position) string {
+	t.warn(t.fallbacks[body])
+
+	decls, _ := t.upvalues(body)
+
+	args := ""
+	for _, d := range decls {
+		args += ", " + t.ident(d)
+	}
+
+	fn := t.temp()
+	t.printf("%s, err := %s.Closure(th%s)\n", fn, t.embed(body, isMethod, pos, end, decls), args)
+	t.check()
+
+	return fn
+}
+
+// embed declares an aot.Func of the function from pos to end, and returns the name of it.
+//
+// the chunk is
+//
+//	local <upvalues> = ...
+//	return function(...) end
+//
+// or for local functions referring to themselves,
+//
+//	local <upvalues> = ...
+//	local function f(...) end
+//	return f
+func (t *translator) embed(body *ast.FuncBody, isMethod bool, pos, end position.Position, upvals []*scope.Decl) string {
+	if isMethod {
+		params := *body.Params
+		params.List = append([]*ast.Name{{Name: "self"}}, params.List...)
+		body = &ast.FuncBody{Params: &params, Body: body.Body}
+	}
+
+	f := &ast.File{Filename: t.info.File.Filename}
+
+	if len(upvals) > 0 {
+		stmt := &ast.LocalAssignStmt{RHS: []ast.Expr{&ast.Vararg{}}}
+		for _, d := range upvals {
+			stmt.LHS = append(stmt.LHS, &ast.Name{Name: d.Name})
+		}
+		f.Chunk = append(f.Chunk, stmt)
+	}
+
+	if d, ok := t.localFuncs[body]; ok {
+		f.Chunk = append(f.Chunk,
+			&ast.LocalFuncStmt{Func: pos, Name: &ast.Name{Name: d.Name}, Body: body, EndPos: end},
+			&ast.ReturnStmt{Results: []ast.Expr{&ast.Name{Name: d.Name}}},
+		)
+	} else {
+		f.Chunk = append(f.Chunk,
+			&ast.ReturnStmt{Results: []ast.Expr{&ast.FuncLit{Func: pos, Body: body, EndPos: end}}},
+		)
+	}
+
+	p, err := codegen.Generate(f)
+	if err != nil {
+		t.error(err)
+		return "nil"
+	}
+
+	var buf bytes.Buffer
+
+	err = dump.DumpTo(&buf, p, dump.ColumnInfo)
+	if err != nil {
+		t.error(err)
+		return "nil"
+	}
+
+	t.nembeds++
+
+	name := fmt.Sprintf("embed%d", t.nembeds)
+
+	fmt.Fprintf(&t.embeds, "// %s is the function at line %d.\n", name, pos.Line)
+	fmt.Fprintf(&t.embeds, "var %s = &aot.Func{Chunk: %q}\n\n", name, buf.String())
+
+	return name
+}
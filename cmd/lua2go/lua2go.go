@@ -0,0 +1,165 @@
+// Lua2go translates a Lua chunk into a Go package.
+//
+// The package has a function
+//
+//	func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError)
+//
+// which executes the chunk, so that it can be used as an object.GoFunction,
+// e.g. th.Require("name", pkg.Open).
+// Lua functions are translated into object.GoFunctions calling helpers of the aot package,
+// which share arithmetic, comparison and table semantics with the VM.
+//
+// Functions which can't be translated are embedded as bytecode instead.
+// These are functions which contain goto statements or labels,
+// functions which use the coroutine library other than create, wrap, resume, status, running and isyieldable,
+// and functions passed to coroutine.create and coroutine.wrap.
+// Embedded functions are compiled apart, and local variables of the enclosing functions are passed to them by values.
+// So if they refer to local variables which are assigned after the declarations,
+// the enclosing functions are embedded too. If it's the main chunk, the whole chunk is embedded.
+//
+// Known differences from the VM:
+//
+//	errors raised with level 2 or more don't have positions of translated functions.
+//	the debug library and string.dump see translated functions as Go functions.
+//
+// Usage:
+//
+//	lua2go [flags] file.lua
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/internal/util"
+)
+
+var (
+	pkgName = flag.String("pkg", "", "package name (default the base name of the file)")
+	output  = flag.String("o", "", "output file (default stdout)")
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: lua2go [flags] file.lua\n")
+	flag.PrintDefaults()
+}
+
+// generate translates the Lua source src into a Go package named pkg.
+func generate(src []byte, filename, pkg string) ([]byte, error) {
+	srcname := "@" + filename
+
+	f, err := parser.Parse(scanner.Scan(bytes.NewReader(src), srcname, 0), 0)
+	if err != nil {
+		return nil, err
+	}
+
+	warn := func(err error) {
+		fmt.Fprintf(os.Stderr, "lua2go: %s:%v, falling back to bytecode\n", filename, err)
+	}
+
+	out, err := translate(f, pkg, filename, util.Shorten(srcname), warn)
+	if _, ok := err.(*unsupportedError); ok {
+		warn(err)
+
+		return bytecode(f, filename, pkg)
+	}
+
+	return out, err
+}
+
+// bytecode returns a Go package which embeds the compiled chunk of f.
+func bytecode(f *ast.File, filename, pkg string) ([]byte, error) {
+	p, err := codegen.Generate(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+
+	err = dump.DumpTo(&buf, p, dump.ColumnInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+
+	fmt.Fprintf(&body, "const chunk = %q\n\n", buf.String())
+	fmt.Fprintf(&body, "// Open executes the main chunk of %s.\n", filename)
+	fmt.Fprintf(&body, "func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {\n")
+	fmt.Fprintf(&body, "return aot.Exec(th, chunk, args...)\n")
+	fmt.Fprintf(&body, "}\n")
+
+	return source(pkg, filename, body.Bytes())
+}
+
+func packageName(filename string) string {
+	name := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+
+	if token.Lookup(name).IsKeyword() {
+		return name + "_"
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	filename := flag.Arg(0)
+
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	pkg := *pkgName
+	if pkg == "" {
+		pkg = packageName(filename)
+	}
+
+	out, err := generate(src, filename, pkg)
+	if err != nil {
+		if list, ok := err.(parser.ErrorList); ok {
+			for _, err := range list {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		} else {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(out)
+		return
+	}
+
+	err = ioutil.WriteFile(*output, out, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
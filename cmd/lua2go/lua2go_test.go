@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/cmd/lua2go/testdata/basic"
+	"github.com/hirochachacha/plua/cmd/lua2go/testdata/coro"
+	"github.com/hirochachacha/plua/cmd/lua2go/testdata/errors"
+	"github.com/hirochachacha/plua/cmd/lua2go/testdata/jump"
+	"github.com/hirochachacha/plua/cmd/lua2go/testdata/mixed"
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib"
+)
+
+// testdata/<name>.lua is translated into testdata/<name>/<name>.go.
+var testCases = []struct {
+	name string
+	open object.GoFunction
+}{
+	{"basic", basic.Open},
+	{"coro", coro.Open},
+	{"errors", errors.Open},
+	{"jump", jump.Open},
+	{"mixed", mixed.Open},
+}
+
+func TestGenerate(t *testing.T) {
+	for _, test := range testCases {
+		filename := "testdata/" + test.name + ".lua"
+
+		src, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := generate(src, filename, test.name)
+		if err != nil {
+			t.Fatalf("%s: %v", filename, err)
+		}
+
+		want, err := ioutil.ReadFile("testdata/" + test.name + "/" + test.name + ".go")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: generated code is outdated, run lua2go -o testdata/%s/%s.go %s", filename, test.name, test.name, filename)
+		}
+	}
+}
+
+var caller *object.Proto
+
+func init() {
+	var err error
+
+	caller, err = compiler.NewCompiler().Compile(strings.NewReader("return f(...)"), "=caller", compiler.Text)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// run calls the function returned by load in a new process.
+func run(load func(p object.Process) object.Value, args ...object.Value) string {
+	p := runtime.NewProcess()
+
+	p.Require("", stdlib.Open)
+
+	// a small stack makes stack overflows quick.
+	runtime.SetLimits(p, runtime.Limits{StackSize: 10000, GoDepth: runtime.DefaultLimits.GoDepth})
+
+	p.Globals().Set(object.String("f"), load(p))
+
+	rets, err := p.Exec(caller, args...)
+	if err != nil {
+		return fmt.Sprintf("error: %v", err.(*object.RuntimeError).Value())
+	}
+
+	return fmt.Sprint(rets)
+}
+
+func TestExec(t *testing.T) {
+	args := []object.Value{object.Integer(1), object.String("x")}
+
+	for _, test := range testCases {
+		filename := "testdata/" + test.name + ".lua"
+
+		proto, err := compiler.NewCompiler().CompileFile(filename, compiler.Text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := run(func(p object.Process) object.Value { return p.NewClosure(proto) }, args...)
+		got := run(func(p object.Process) object.Value { return test.open }, args...)
+
+		if got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", filename, got, want)
+		}
+	}
+}
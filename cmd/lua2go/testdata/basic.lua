@@ -0,0 +1,74 @@
+local results = {}
+
+local function fib(n)
+  if n < 2 then return n end
+  return fib(n - 1) + fib(n - 2)
+end
+results[#results + 1] = fib(20)
+
+local sum = 0
+for i = 1, 10, 2 do
+  sum = sum + i
+end
+for i = 1.5, 0, -0.5 do
+  sum = sum + i
+end
+results[#results + 1] = sum
+
+local t = {10, 20, 30, x = 1, ["y"] = 2}
+local keys = 0
+for k, v in pairs(t) do
+  keys = keys + 1
+end
+results[#results + 1] = keys
+
+local s = ""
+for i, v in ipairs(t) do
+  s = s .. i .. "=" .. v .. ";"
+end
+results[#results + 1] = s
+
+local a, b = 1, 2
+a, b = b, a
+results[#results + 1] = a * 10 + b
+
+local n = 0
+while n < 5 do n = n + 1 end
+repeat local m = n; n = n - 1 until m <= 3
+results[#results + 1] = n
+
+local Point = {}
+Point.__index = Point
+Point.__add = function(p, q) return setmetatable({x = p.x + q.x}, Point) end
+function Point.new(x) return setmetatable({x = x}, Point) end
+function Point:get() return self.x end
+results[#results + 1] = (Point.new(3) + Point.new(4)):get()
+
+local function va(...)
+  local x, y = ...
+  return select('#', ...), x, y, ...
+end
+results[#results + 1] = table.concat({va(1, 2, 3)}, ",")
+
+local c = 0
+local function counter() c = c + 1; return c end
+counter(); counter()
+results[#results + 1] = c
+
+results[#results + 1] = (1 and nil) or "or"
+results[#results + 1] = not (1 > 2) and 3 // 2 .. "" or false
+results[#results + 1] = 7 % 3 + 2 ^ 2 + 7 / 2 + (5 & 3) + (5 | 3) + (5 ~ 3) + (1 << 4) + (256 >> 4) + ~0 + -3
+results[#results + 1] = 1e300 * 1e10 == math.huge and 9223372036854775807 + 1 == math.mininteger
+
+if c == 1 then
+  results[#results + 1] = "one"
+elseif c == 2 then
+  results[#results + 1] = "two"
+else
+  results[#results + 1] = "other"
+end
+
+x = 5
+results[#results + 1] = x
+
+return table.unpack(results)
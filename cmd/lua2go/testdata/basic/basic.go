@@ -0,0 +1,704 @@
+// Code generated by lua2go from testdata/basic.lua. DO NOT EDIT.
+
+package basic
+
+import (
+	"github.com/hirochachacha/plua/aot"
+	"github.com/hirochachacha/plua/object"
+)
+
+// Open executes the main chunk of testdata/basic.lua.
+func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	var l__ENV object.Value = th.Globals()
+	varargs := args
+	_ = varargs
+	t1 := th.NewTableSize(0, 0)
+	var l_results object.Value = t1
+	var l_fib object.Value
+	l_fib = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_n object.Value = aot.Arg(args, 0)
+		t2, err := aot.LessThan(th, "testdata/basic.lua:4:8: ", l_n, object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		if t2 {
+			return []object.Value{l_n}, nil
+		}
+		var t3 object.Value = l_fib
+		t4, err := aot.Sub(th, "testdata/basic.lua:5:16: ", l_n, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		t5, err := aot.Call(th, "testdata/basic.lua:5:13: ", t3, t4)
+		if err != nil {
+			return nil, err
+		}
+		var t6 object.Value = l_fib
+		t7, err := aot.Sub(th, "testdata/basic.lua:5:29: ", l_n, object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		t8, err := aot.Call(th, "testdata/basic.lua:5:26: ", t6, t7)
+		if err != nil {
+			return nil, err
+		}
+		t9, err := aot.Add(th, "testdata/basic.lua:5:21: ", aot.Arg(t5, 0), aot.Arg(t8, 0))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t9}, nil
+	})
+	t10, err := aot.Len(th, "testdata/basic.lua:7:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t11, err := aot.Add(th, "testdata/basic.lua:7:18: ", t10, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var t12 object.Value = l_fib
+	t13, err := aot.Call(th, "testdata/basic.lua:7:28: ", t12, object.Integer(20))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:7:8: ", l_results, t11, aot.Arg(t13, 0)); err != nil {
+		return nil, err
+	}
+	var l_sum object.Value = object.Integer(0)
+	t14, err := aot.NewForLoop("testdata/basic.lua:10:1: ", object.Integer(1), object.Integer(10), object.Integer(2))
+	if err != nil {
+		return nil, err
+	}
+	for t14.Next() {
+		var l_i object.Value = t14.Value()
+		t15, err := aot.Add(th, "testdata/basic.lua:11:13: ", l_sum, l_i)
+		if err != nil {
+			return nil, err
+		}
+		l_sum = t15
+	}
+	t16, err := aot.NewForLoop("testdata/basic.lua:13:1: ", object.Number(1.5), object.Integer(0), object.Number(-0.5))
+	if err != nil {
+		return nil, err
+	}
+	for t16.Next() {
+		var l_i_2 object.Value = t16.Value()
+		t17, err := aot.Add(th, "testdata/basic.lua:14:13: ", l_sum, l_i_2)
+		if err != nil {
+			return nil, err
+		}
+		l_sum = t17
+	}
+	t18, err := aot.Len(th, "testdata/basic.lua:16:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t19, err := aot.Add(th, "testdata/basic.lua:16:18: ", t18, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:16:8: ", l_results, t19, l_sum); err != nil {
+		return nil, err
+	}
+	t20 := th.NewTableSize(3, 2)
+	if err := aot.SetField("testdata/basic.lua:18:28: ", t20, object.String("x"), object.Integer(1)); err != nil {
+		return nil, err
+	}
+	if err := aot.SetField("testdata/basic.lua:18:39: ", t20, object.String("y"), object.Integer(2)); err != nil {
+		return nil, err
+	}
+	aot.SetList(t20, []object.Value{object.Integer(10), object.Integer(20), object.Integer(30)})
+	var l_t object.Value = t20
+	var l_keys object.Value = object.Integer(0)
+	t21, err := aot.Index(th, "testdata/basic.lua:20:13: ", l__ENV, object.String("pairs"))
+	if err != nil {
+		return nil, err
+	}
+	t22, err := aot.Call(th, "testdata/basic.lua:20:18: ", t21, l_t)
+	if err != nil {
+		return nil, err
+	}
+	var t23, t24, t25 object.Value = aot.Arg(t22, 0), aot.Arg(t22, 1), aot.Arg(t22, 2)
+	for {
+		t26, err := aot.Call(th, "testdata/basic.lua:20:1: ", t23, t24, t25)
+		if err != nil {
+			return nil, err
+		}
+		var l_k object.Value = aot.Arg(t26, 0)
+		_ = l_k
+		if l_k == nil {
+			break
+		}
+		t25 = l_k
+		var l_v object.Value = aot.Arg(t26, 1)
+		_ = l_v
+		t27, err := aot.Add(th, "testdata/basic.lua:21:15: ", l_keys, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		l_keys = t27
+	}
+	t28, err := aot.Len(th, "testdata/basic.lua:23:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t29, err := aot.Add(th, "testdata/basic.lua:23:18: ", t28, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:23:8: ", l_results, t29, l_keys); err != nil {
+		return nil, err
+	}
+	var l_s object.Value = object.String("")
+	t30, err := aot.Index(th, "testdata/basic.lua:26:13: ", l__ENV, object.String("ipairs"))
+	if err != nil {
+		return nil, err
+	}
+	t31, err := aot.Call(th, "testdata/basic.lua:26:19: ", t30, l_t)
+	if err != nil {
+		return nil, err
+	}
+	var t32, t33, t34 object.Value = aot.Arg(t31, 0), aot.Arg(t31, 1), aot.Arg(t31, 2)
+	for {
+		t35, err := aot.Call(th, "testdata/basic.lua:26:1: ", t32, t33, t34)
+		if err != nil {
+			return nil, err
+		}
+		var l_i_3 object.Value = aot.Arg(t35, 0)
+		if l_i_3 == nil {
+			break
+		}
+		t34 = l_i_3
+		var l_v_2 object.Value = aot.Arg(t35, 1)
+		var t36 object.Value = l_s
+		var t37 object.Value = l_i_3
+		var t38 object.Value = l_v_2
+		t39, err := aot.Concat(th, "testdata/basic.lua:27:26: ", t38, object.String(";"))
+		if err != nil {
+			return nil, err
+		}
+		t40, err := aot.Concat(th, "testdata/basic.lua:27:21: ", object.String("="), t39)
+		if err != nil {
+			return nil, err
+		}
+		t41, err := aot.Concat(th, "testdata/basic.lua:27:14: ", t37, t40)
+		if err != nil {
+			return nil, err
+		}
+		t42, err := aot.Concat(th, "testdata/basic.lua:27:9: ", t36, t41)
+		if err != nil {
+			return nil, err
+		}
+		l_s = t42
+	}
+	t43, err := aot.Len(th, "testdata/basic.lua:29:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t44, err := aot.Add(th, "testdata/basic.lua:29:18: ", t43, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:29:8: ", l_results, t44, l_s); err != nil {
+		return nil, err
+	}
+	var l_a object.Value = object.Integer(1)
+	var l_b object.Value = object.Integer(2)
+	var t45 object.Value = l_b
+	var t46 object.Value = l_a
+	l_b = t46
+	l_a = t45
+	t47, err := aot.Len(th, "testdata/basic.lua:33:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t48, err := aot.Add(th, "testdata/basic.lua:33:18: ", t47, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t49, err := aot.Mul(th, "testdata/basic.lua:33:27: ", l_a, object.Integer(10))
+	if err != nil {
+		return nil, err
+	}
+	t50, err := aot.Add(th, "testdata/basic.lua:33:32: ", t49, l_b)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:33:8: ", l_results, t48, t50); err != nil {
+		return nil, err
+	}
+	var l_n_2 object.Value = object.Integer(0)
+	for {
+		t51, err := aot.LessThan(th, "testdata/basic.lua:36:9: ", l_n_2, object.Integer(5))
+		if err != nil {
+			return nil, err
+		}
+		if !(t51) {
+			break
+		}
+		t52, err := aot.Add(th, "testdata/basic.lua:36:22: ", l_n_2, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		l_n_2 = t52
+	}
+	for {
+		var l_m object.Value = l_n_2
+		t53, err := aot.Sub(th, "testdata/basic.lua:37:27: ", l_n_2, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		l_n_2 = t53
+		t54, err := aot.LessEqual(th, "testdata/basic.lua:37:39: ", l_m, object.Integer(3))
+		if err != nil {
+			return nil, err
+		}
+		if t54 {
+			break
+		}
+	}
+	t55, err := aot.Len(th, "testdata/basic.lua:38:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t56, err := aot.Add(th, "testdata/basic.lua:38:18: ", t55, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:38:8: ", l_results, t56, l_n_2); err != nil {
+		return nil, err
+	}
+	t57 := th.NewTableSize(0, 0)
+	var l_Point object.Value = t57
+	if err := aot.SetIndex(th, "testdata/basic.lua:41:6: ", l_Point, object.String("__index"), l_Point); err != nil {
+		return nil, err
+	}
+	var t58 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_p object.Value = aot.Arg(args, 0)
+		var l_q object.Value = aot.Arg(args, 1)
+		t59, err := aot.Index(th, "testdata/basic.lua:42:37: ", l__ENV, object.String("setmetatable"))
+		if err != nil {
+			return nil, err
+		}
+		t60 := th.NewTableSize(0, 1)
+		t61, err := aot.Index(th, "testdata/basic.lua:42:56: ", l_p, object.String("x"))
+		if err != nil {
+			return nil, err
+		}
+		t62, err := aot.Index(th, "testdata/basic.lua:42:62: ", l_q, object.String("x"))
+		if err != nil {
+			return nil, err
+		}
+		t63, err := aot.Add(th, "testdata/basic.lua:42:59: ", t61, t62)
+		if err != nil {
+			return nil, err
+		}
+		if err := aot.SetField("testdata/basic.lua:42:55: ", t60, object.String("x"), t63); err != nil {
+			return nil, err
+		}
+		var t64 object.Value = l_Point
+		return aot.Call(th, "testdata/basic.lua:42:49: ", t59, t60, t64)
+	})
+	if err := aot.SetIndex(th, "testdata/basic.lua:42:6: ", l_Point, object.String("__add"), t58); err != nil {
+		return nil, err
+	}
+	var t65 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_x object.Value = aot.Arg(args, 0)
+		t66, err := aot.Index(th, "testdata/basic.lua:43:30: ", l__ENV, object.String("setmetatable"))
+		if err != nil {
+			return nil, err
+		}
+		t67 := th.NewTableSize(0, 1)
+		if err := aot.SetField("testdata/basic.lua:43:48: ", t67, object.String("x"), l_x); err != nil {
+			return nil, err
+		}
+		var t68 object.Value = l_Point
+		return aot.Call(th, "testdata/basic.lua:43:42: ", t66, t67, t68)
+	})
+	if err := aot.SetIndex(th, "testdata/basic.lua:43:16: ", l_Point, object.String("new"), t65); err != nil {
+		return nil, err
+	}
+	var t69 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_self object.Value = aot.Arg(args, 0)
+		t70, err := aot.Index(th, "testdata/basic.lua:44:33: ", l_self, object.String("x"))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t70}, nil
+	})
+	if err := aot.SetIndex(th, "testdata/basic.lua:44:16: ", l_Point, object.String("get"), t69); err != nil {
+		return nil, err
+	}
+	t71, err := aot.Len(th, "testdata/basic.lua:45:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t72, err := aot.Add(th, "testdata/basic.lua:45:18: ", t71, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t73, err := aot.Index(th, "testdata/basic.lua:45:31: ", l_Point, object.String("new"))
+	if err != nil {
+		return nil, err
+	}
+	t74, err := aot.Call(th, "testdata/basic.lua:45:35: ", t73, object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	t75, err := aot.Index(th, "testdata/basic.lua:45:46: ", l_Point, object.String("new"))
+	if err != nil {
+		return nil, err
+	}
+	t76, err := aot.Call(th, "testdata/basic.lua:45:50: ", t75, object.Integer(4))
+	if err != nil {
+		return nil, err
+	}
+	t77, err := aot.Add(th, "testdata/basic.lua:45:39: ", aot.Arg(t74, 0), aot.Arg(t76, 0))
+	if err != nil {
+		return nil, err
+	}
+	t78, err := aot.Index(th, "testdata/basic.lua:45:54: ", t77, object.String("get"))
+	if err != nil {
+		return nil, err
+	}
+	t79, err := aot.Call(th, "testdata/basic.lua:45:58: ", t78, t77)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:45:8: ", l_results, t72, aot.Arg(t79, 0)); err != nil {
+		return nil, err
+	}
+	var l_va object.Value
+	l_va = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		varargs := aot.Rest(args, 0)
+		_ = varargs
+		var l_x_2 object.Value = aot.Arg(varargs, 0)
+		var l_y object.Value = aot.Arg(varargs, 1)
+		t80, err := aot.Index(th, "testdata/basic.lua:49:10: ", l__ENV, object.String("select"))
+		if err != nil {
+			return nil, err
+		}
+		t81, err := aot.Call(th, "testdata/basic.lua:49:16: ", t80, append([]object.Value{object.String("#")}, varargs...)...)
+		if err != nil {
+			return nil, err
+		}
+		var t82 object.Value = l_x_2
+		var t83 object.Value = l_y
+		return append([]object.Value{aot.Arg(t81, 0), t82, t83}, varargs...), nil
+	})
+	t84, err := aot.Len(th, "testdata/basic.lua:51:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t85, err := aot.Add(th, "testdata/basic.lua:51:18: ", t84, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t86, err := aot.Index(th, "testdata/basic.lua:51:25: ", l__ENV, object.String("table"))
+	if err != nil {
+		return nil, err
+	}
+	t87, err := aot.Index(th, "testdata/basic.lua:51:30: ", t86, object.String("concat"))
+	if err != nil {
+		return nil, err
+	}
+	t88 := th.NewTableSize(1, 0)
+	var t89 object.Value = l_va
+	t90, err := aot.Call(th, "testdata/basic.lua:51:41: ", t89, object.Integer(1), object.Integer(2), object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	aot.SetList(t88, t90)
+	t91, err := aot.Call(th, "testdata/basic.lua:51:37: ", t87, t88, object.String(","))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:51:8: ", l_results, t85, aot.Arg(t91, 0)); err != nil {
+		return nil, err
+	}
+	var l_c object.Value = object.Integer(0)
+	var l_counter object.Value
+	l_counter = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var t92 object.Value = l_c
+		t93, err := aot.Add(th, "testdata/basic.lua:54:32: ", t92, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		l_c = t93
+		var t94 object.Value = l_c
+		return []object.Value{t94}, nil
+	})
+	var t95 object.Value = l_counter
+	if _, err := aot.Call(th, "testdata/basic.lua:55:8: ", t95); err != nil {
+		return nil, err
+	}
+	var t96 object.Value = l_counter
+	if _, err := aot.Call(th, "testdata/basic.lua:55:19: ", t96); err != nil {
+		return nil, err
+	}
+	t97, err := aot.Len(th, "testdata/basic.lua:56:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t98, err := aot.Add(th, "testdata/basic.lua:56:18: ", t97, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:56:8: ", l_results, t98, l_c); err != nil {
+		return nil, err
+	}
+	t99, err := aot.Len(th, "testdata/basic.lua:58:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t100, err := aot.Add(th, "testdata/basic.lua:58:18: ", t99, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var t102 object.Value = object.Integer(1)
+	if aot.Truth(t102) {
+		t102 = nil
+	}
+	var t101 object.Value = t102
+	if !aot.Truth(t101) {
+		t101 = object.String("or")
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:58:8: ", l_results, t100, t101); err != nil {
+		return nil, err
+	}
+	t103, err := aot.Len(th, "testdata/basic.lua:59:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t104, err := aot.Add(th, "testdata/basic.lua:59:18: ", t103, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t107, err := aot.LessThan(th, "testdata/basic.lua:59:32: ", object.Integer(2), object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var t106 object.Value = object.Boolean(!(t107))
+	if aot.Truth(t106) {
+		t108, err := aot.Idiv(th, "testdata/basic.lua:59:43: ", object.Integer(3), object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		t109, err := aot.Concat(th, "testdata/basic.lua:59:48: ", t108, object.String(""))
+		if err != nil {
+			return nil, err
+		}
+		t106 = t109
+	}
+	var t105 object.Value = t106
+	if !aot.Truth(t105) {
+		t105 = object.False
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:59:8: ", l_results, t104, t105); err != nil {
+		return nil, err
+	}
+	t110, err := aot.Len(th, "testdata/basic.lua:60:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t111, err := aot.Add(th, "testdata/basic.lua:60:18: ", t110, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t112, err := aot.Mod(th, "testdata/basic.lua:60:27: ", object.Integer(7), object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	t113, err := aot.Pow(th, "testdata/basic.lua:60:35: ", object.Integer(2), object.Integer(2))
+	if err != nil {
+		return nil, err
+	}
+	t114, err := aot.Add(th, "testdata/basic.lua:60:31: ", t112, t113)
+	if err != nil {
+		return nil, err
+	}
+	t115, err := aot.Div(th, "testdata/basic.lua:60:43: ", object.Integer(7), object.Integer(2))
+	if err != nil {
+		return nil, err
+	}
+	t116, err := aot.Add(th, "testdata/basic.lua:60:39: ", t114, t115)
+	if err != nil {
+		return nil, err
+	}
+	t117, err := aot.Band(th, "testdata/basic.lua:60:52: ", object.Integer(5), object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	t118, err := aot.Add(th, "testdata/basic.lua:60:47: ", t116, t117)
+	if err != nil {
+		return nil, err
+	}
+	t119, err := aot.Bor(th, "testdata/basic.lua:60:62: ", object.Integer(5), object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	t120, err := aot.Add(th, "testdata/basic.lua:60:57: ", t118, t119)
+	if err != nil {
+		return nil, err
+	}
+	t121, err := aot.Bxor(th, "testdata/basic.lua:60:72: ", object.Integer(5), object.Integer(3))
+	if err != nil {
+		return nil, err
+	}
+	t122, err := aot.Add(th, "testdata/basic.lua:60:67: ", t120, t121)
+	if err != nil {
+		return nil, err
+	}
+	t123, err := aot.Shl(th, "testdata/basic.lua:60:82: ", object.Integer(1), object.Integer(4))
+	if err != nil {
+		return nil, err
+	}
+	t124, err := aot.Add(th, "testdata/basic.lua:60:77: ", t122, t123)
+	if err != nil {
+		return nil, err
+	}
+	t125, err := aot.Shr(th, "testdata/basic.lua:60:95: ", object.Integer(256), object.Integer(4))
+	if err != nil {
+		return nil, err
+	}
+	t126, err := aot.Add(th, "testdata/basic.lua:60:88: ", t124, t125)
+	if err != nil {
+		return nil, err
+	}
+	t127, err := aot.Bnot(th, "testdata/basic.lua:60:103: ", object.Integer(0))
+	if err != nil {
+		return nil, err
+	}
+	t128, err := aot.Add(th, "testdata/basic.lua:60:101: ", t126, t127)
+	if err != nil {
+		return nil, err
+	}
+	t129, err := aot.Add(th, "testdata/basic.lua:60:106: ", t128, object.Integer(-3))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:60:8: ", l_results, t111, t129); err != nil {
+		return nil, err
+	}
+	t130, err := aot.Len(th, "testdata/basic.lua:61:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t131, err := aot.Add(th, "testdata/basic.lua:61:18: ", t130, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t133, err := aot.Mul(th, "testdata/basic.lua:61:31: ", object.Number(1e+300), object.Number(1e+10))
+	if err != nil {
+		return nil, err
+	}
+	t134, err := aot.Index(th, "testdata/basic.lua:61:41: ", l__ENV, object.String("math"))
+	if err != nil {
+		return nil, err
+	}
+	t135, err := aot.Index(th, "testdata/basic.lua:61:45: ", t134, object.String("huge"))
+	if err != nil {
+		return nil, err
+	}
+	t136, err := aot.Equal(th, "testdata/basic.lua:61:38: ", t133, t135)
+	if err != nil {
+		return nil, err
+	}
+	var t132 object.Value = object.Boolean(t136)
+	if aot.Truth(t132) {
+		t137, err := aot.Add(th, "testdata/basic.lua:61:75: ", object.Integer(9223372036854775807), object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		t138, err := aot.Index(th, "testdata/basic.lua:61:82: ", l__ENV, object.String("math"))
+		if err != nil {
+			return nil, err
+		}
+		t139, err := aot.Index(th, "testdata/basic.lua:61:86: ", t138, object.String("mininteger"))
+		if err != nil {
+			return nil, err
+		}
+		t140, err := aot.Equal(th, "testdata/basic.lua:61:79: ", t137, t139)
+		if err != nil {
+			return nil, err
+		}
+		t132 = object.Boolean(t140)
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:61:8: ", l_results, t131, t132); err != nil {
+		return nil, err
+	}
+	t141, err := aot.Equal(th, "testdata/basic.lua:63:6: ", l_c, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	if t141 {
+		t142, err := aot.Len(th, "testdata/basic.lua:64:11: ", l_results)
+		if err != nil {
+			return nil, err
+		}
+		t143, err := aot.Add(th, "testdata/basic.lua:64:20: ", t142, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		if err := aot.SetIndex(th, "testdata/basic.lua:64:10: ", l_results, t143, object.String("one")); err != nil {
+			return nil, err
+		}
+	} else {
+		t144, err := aot.Equal(th, "testdata/basic.lua:65:10: ", l_c, object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		if t144 {
+			t145, err := aot.Len(th, "testdata/basic.lua:66:11: ", l_results)
+			if err != nil {
+				return nil, err
+			}
+			t146, err := aot.Add(th, "testdata/basic.lua:66:20: ", t145, object.Integer(1))
+			if err != nil {
+				return nil, err
+			}
+			if err := aot.SetIndex(th, "testdata/basic.lua:66:10: ", l_results, t146, object.String("two")); err != nil {
+				return nil, err
+			}
+		} else {
+			t147, err := aot.Len(th, "testdata/basic.lua:68:11: ", l_results)
+			if err != nil {
+				return nil, err
+			}
+			t148, err := aot.Add(th, "testdata/basic.lua:68:20: ", t147, object.Integer(1))
+			if err != nil {
+				return nil, err
+			}
+			if err := aot.SetIndex(th, "testdata/basic.lua:68:10: ", l_results, t148, object.String("other")); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:71:1: ", l__ENV, object.String("x"), object.Integer(5)); err != nil {
+		return nil, err
+	}
+	t149, err := aot.Len(th, "testdata/basic.lua:72:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t150, err := aot.Add(th, "testdata/basic.lua:72:18: ", t149, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t151, err := aot.Index(th, "testdata/basic.lua:72:25: ", l__ENV, object.String("x"))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/basic.lua:72:8: ", l_results, t150, t151); err != nil {
+		return nil, err
+	}
+	t152, err := aot.Index(th, "testdata/basic.lua:74:8: ", l__ENV, object.String("table"))
+	if err != nil {
+		return nil, err
+	}
+	t153, err := aot.Index(th, "testdata/basic.lua:74:13: ", t152, object.String("unpack"))
+	if err != nil {
+		return nil, err
+	}
+	return aot.Call(th, "testdata/basic.lua:74:20: ", t153, l_results)
+}
@@ -0,0 +1,5 @@
+local co = coroutine.wrap(function(x)
+  local y = coroutine.yield(x + 1)
+  return y * 2
+end)
+return co(1) + co(10)
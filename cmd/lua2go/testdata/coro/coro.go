@@ -0,0 +1,50 @@
+// Code generated by lua2go from testdata/coro.lua. DO NOT EDIT.
+
+package coro
+
+import (
+	"github.com/hirochachacha/plua/aot"
+	"github.com/hirochachacha/plua/object"
+)
+
+// Open executes the main chunk of testdata/coro.lua.
+func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	var l__ENV object.Value = th.Globals()
+	varargs := args
+	_ = varargs
+	t1, err := aot.Index(th, "testdata/coro.lua:1:12: ", l__ENV, object.String("coroutine"))
+	if err != nil {
+		return nil, err
+	}
+	t2, err := aot.Index(th, "testdata/coro.lua:1:21: ", t1, object.String("wrap"))
+	if err != nil {
+		return nil, err
+	}
+	t3, err := embed1.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	t4, err := aot.Call(th, "testdata/coro.lua:1:26: ", t2, t3)
+	if err != nil {
+		return nil, err
+	}
+	var l_co object.Value = aot.Arg(t4, 0)
+	var t5 object.Value = l_co
+	t6, err := aot.Call(th, "testdata/coro.lua:5:10: ", t5, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var t7 object.Value = l_co
+	t8, err := aot.Call(th, "testdata/coro.lua:5:18: ", t7, object.Integer(10))
+	if err != nil {
+		return nil, err
+	}
+	t9, err := aot.Add(th, "testdata/coro.lua:5:14: ", aot.Arg(t6, 0), aot.Arg(t8, 0))
+	if err != nil {
+		return nil, err
+	}
+	return []object.Value{t9}, nil
+}
+
+// embed1 is the function at line 1.
+var embed1 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x13@testdata/coro.lua\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x01\x01\x03\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00&\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x03\a\x00\x00\x00\x00\x00\x00\x00F\x00@\x00G@\xc0\x00\x8d\x80@\x00d\x80\x00\x01\x8f\xc0\xc0\x00\xa6\x00\x00\x01&\x00\x80\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\ncoroutine\x04\x06yield\x13\x01\x00\x00\x00\x00\x00\x00\x00\x13\x02\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x02x\x00\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x02y\x04\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\a\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\x16\x00\x00\x00\x00\x00\x00\x00\x1f\x00\x00\x00\x00\x00\x00\x00\x1c\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x03\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x1b\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"}
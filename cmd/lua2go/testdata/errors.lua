@@ -0,0 +1,45 @@
+local results = {}
+
+local function try(f, ...)
+  local ok, err = pcall(f, ...)
+  if type(err) == "table" then
+    err = "code " .. err.code
+  end
+  results[#results + 1] = tostring(ok) .. " " .. tostring(err)
+end
+
+try(function() return 1 + nil end)
+try(function(x) return x.y end)
+try(function(x) x.y = 1 end)
+try(function() return {} < 1 end)
+try(function() return 1 >= "x" end)
+try(function() return "a" .. {} end)
+try(function() return #5 end)
+try(function() return -{} end)
+try(function() return 1.5 | 1 end)
+try(function() undefined() end)
+try(function() local t = {} t:method() end)
+try(function() for i = 1, "x" do end end)
+try(function() for i = {}, 1 do end end)
+try(function() for k in nil do end end)
+try(function() return {[nil] = 1} end)
+try(function() return 1 // 0 end)
+try(function() error({code = 1}) end)
+try(function(...) return select(2, ...) end, 1, 2, 3)
+try(function() error("raised") end)
+try(function() return ("x"):rep() end)
+
+local function inf(n) return 1 + inf(n) end
+local ok, err = pcall(inf, 1)
+results[#results + 1] = tostring(ok) .. " " .. tostring(err:find("stack overflow") ~= nil)
+
+results[#results + 1] = setmetatable({}, {__call = function(self, x) return x end})("called")
+
+local mt = {__index = function(t, k) return k .. "!" end, __newindex = function(t, k, v) rawset(t, k, v * 2) end}
+local t = setmetatable({}, mt)
+t.a = 21
+results[#results + 1] = t.a .. " " .. t.b
+
+results[#results + 1] = #results
+
+return table.concat(results, "\n")
@@ -0,0 +1,569 @@
+// Code generated by lua2go from testdata/errors.lua. DO NOT EDIT.
+
+package errors
+
+import (
+	"github.com/hirochachacha/plua/aot"
+	"github.com/hirochachacha/plua/object"
+)
+
+// Open executes the main chunk of testdata/errors.lua.
+func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	var l__ENV object.Value = th.Globals()
+	varargs := args
+	_ = varargs
+	t1 := th.NewTableSize(0, 0)
+	var l_results object.Value = t1
+	var l_try object.Value
+	l_try = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_f object.Value = aot.Arg(args, 0)
+		varargs := aot.Rest(args, 1)
+		_ = varargs
+		t2, err := aot.Index(th, "testdata/errors.lua:4:19: ", l__ENV, object.String("pcall"))
+		if err != nil {
+			return nil, err
+		}
+		var t3 object.Value = l_f
+		t4, err := aot.Call(th, "testdata/errors.lua:4:24: ", t2, append([]object.Value{t3}, varargs...)...)
+		if err != nil {
+			return nil, err
+		}
+		var l_ok object.Value = aot.Arg(t4, 0)
+		var l_err object.Value = aot.Arg(t4, 1)
+		t5, err := aot.Index(th, "testdata/errors.lua:5:6: ", l__ENV, object.String("type"))
+		if err != nil {
+			return nil, err
+		}
+		t6, err := aot.Call(th, "testdata/errors.lua:5:10: ", t5, l_err)
+		if err != nil {
+			return nil, err
+		}
+		t7, err := aot.Equal(th, "testdata/errors.lua:5:16: ", aot.Arg(t6, 0), object.String("table"))
+		if err != nil {
+			return nil, err
+		}
+		if t7 {
+			t8, err := aot.Index(th, "testdata/errors.lua:6:25: ", l_err, object.String("code"))
+			if err != nil {
+				return nil, err
+			}
+			t9, err := aot.Concat(th, "testdata/errors.lua:6:19: ", object.String("code "), t8)
+			if err != nil {
+				return nil, err
+			}
+			l_err = t9
+		}
+		var t10 object.Value = l_results
+		var t11 object.Value = l_results
+		t12, err := aot.Len(th, "testdata/errors.lua:8:11: ", t11)
+		if err != nil {
+			return nil, err
+		}
+		t13, err := aot.Add(th, "testdata/errors.lua:8:20: ", t12, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		t14, err := aot.Index(th, "testdata/errors.lua:8:27: ", l__ENV, object.String("tostring"))
+		if err != nil {
+			return nil, err
+		}
+		t15, err := aot.Call(th, "testdata/errors.lua:8:35: ", t14, l_ok)
+		if err != nil {
+			return nil, err
+		}
+		t16, err := aot.Index(th, "testdata/errors.lua:8:50: ", l__ENV, object.String("tostring"))
+		if err != nil {
+			return nil, err
+		}
+		t17, err := aot.Call(th, "testdata/errors.lua:8:58: ", t16, l_err)
+		if err != nil {
+			return nil, err
+		}
+		t18, err := aot.Concat(th, "testdata/errors.lua:8:47: ", object.String(" "), aot.Arg(t17, 0))
+		if err != nil {
+			return nil, err
+		}
+		t19, err := aot.Concat(th, "testdata/errors.lua:8:40: ", aot.Arg(t15, 0), t18)
+		if err != nil {
+			return nil, err
+		}
+		if err := aot.SetIndex(th, "testdata/errors.lua:8:10: ", t10, t13, t19); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	var t20 object.Value = l_try
+	var t21 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t22, err := aot.Add(th, "testdata/errors.lua:11:25: ", object.Integer(1), nil)
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t22}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:11:4: ", t20, t21); err != nil {
+		return nil, err
+	}
+	var t23 object.Value = l_try
+	var t24 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_x object.Value = aot.Arg(args, 0)
+		t25, err := aot.Index(th, "testdata/errors.lua:12:25: ", l_x, object.String("y"))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t25}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:12:4: ", t23, t24); err != nil {
+		return nil, err
+	}
+	var t26 object.Value = l_try
+	var t27 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_x_2 object.Value = aot.Arg(args, 0)
+		if err := aot.SetIndex(th, "testdata/errors.lua:13:18: ", l_x_2, object.String("y"), object.Integer(1)); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:13:4: ", t26, t27); err != nil {
+		return nil, err
+	}
+	var t28 object.Value = l_try
+	var t29 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t30 := th.NewTableSize(0, 0)
+		t31, err := aot.LessThan(th, "testdata/errors.lua:14:26: ", t30, object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{object.Boolean(t31)}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:14:4: ", t28, t29); err != nil {
+		return nil, err
+	}
+	var t32 object.Value = l_try
+	var t33 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t34, err := aot.LessEqual(th, "testdata/errors.lua:15:25: ", object.String("x"), object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{object.Boolean(t34)}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:15:4: ", t32, t33); err != nil {
+		return nil, err
+	}
+	var t35 object.Value = l_try
+	var t36 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t37 := th.NewTableSize(0, 0)
+		t38, err := aot.Concat(th, "testdata/errors.lua:16:27: ", object.String("a"), t37)
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t38}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:16:4: ", t35, t36); err != nil {
+		return nil, err
+	}
+	var t39 object.Value = l_try
+	var t40 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t41, err := aot.Len(th, "testdata/errors.lua:17:23: ", object.Integer(5))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t41}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:17:4: ", t39, t40); err != nil {
+		return nil, err
+	}
+	var t42 object.Value = l_try
+	var t43 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t44 := th.NewTableSize(0, 0)
+		t45, err := aot.Unm(th, "testdata/errors.lua:18:23: ", t44)
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t45}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:18:4: ", t42, t43); err != nil {
+		return nil, err
+	}
+	var t46 object.Value = l_try
+	var t47 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t48, err := aot.Bor(th, "testdata/errors.lua:19:27: ", object.Number(1.5), object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t48}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:19:4: ", t46, t47); err != nil {
+		return nil, err
+	}
+	var t49 object.Value = l_try
+	var t50 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t51, err := aot.Index(th, "testdata/errors.lua:20:16: ", l__ENV, object.String("undefined"))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := aot.Call(th, "testdata/errors.lua:20:25: ", t51); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:20:4: ", t49, t50); err != nil {
+		return nil, err
+	}
+	var t52 object.Value = l_try
+	var t53 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t54 := th.NewTableSize(0, 0)
+		var l_t object.Value = t54
+		var t55 object.Value = l_t
+		t56, err := aot.Index(th, "testdata/errors.lua:21:30: ", t55, object.String("method"))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := aot.Call(th, "testdata/errors.lua:21:37: ", t56, t55); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:21:4: ", t52, t53); err != nil {
+		return nil, err
+	}
+	var t57 object.Value = l_try
+	var t58 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t59, err := aot.NewForLoop("testdata/errors.lua:22:16: ", object.Integer(1), object.String("x"), object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		for t59.Next() {
+			var l_i object.Value = t59.Value()
+			_ = l_i
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:22:4: ", t57, t58); err != nil {
+		return nil, err
+	}
+	var t60 object.Value = l_try
+	var t61 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t62 := th.NewTableSize(0, 0)
+		t63, err := aot.NewForLoop("testdata/errors.lua:23:16: ", t62, object.Integer(1), object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+		for t63.Next() {
+			var l_i_2 object.Value = t63.Value()
+			_ = l_i_2
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:23:4: ", t60, t61); err != nil {
+		return nil, err
+	}
+	var t64 object.Value = l_try
+	var t65 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var t66, t67, t68 object.Value = nil, nil, nil
+		for {
+			t69, err := aot.Call(th, "testdata/errors.lua:24:16: ", t66, t67, t68)
+			if err != nil {
+				return nil, err
+			}
+			var l_k object.Value = aot.Arg(t69, 0)
+			_ = l_k
+			if l_k == nil {
+				break
+			}
+			t68 = l_k
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:24:4: ", t64, t65); err != nil {
+		return nil, err
+	}
+	var t70 object.Value = l_try
+	var t71 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t72 := th.NewTableSize(0, 1)
+		if err := aot.SetField("testdata/errors.lua:25:32: ", t72, nil, object.Integer(1)); err != nil {
+			return nil, err
+		}
+		return []object.Value{t72}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:25:4: ", t70, t71); err != nil {
+		return nil, err
+	}
+	var t73 object.Value = l_try
+	var t74 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t75, err := aot.Idiv(th, "testdata/errors.lua:26:25: ", object.Integer(1), object.Integer(0))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t75}, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:26:4: ", t73, t74); err != nil {
+		return nil, err
+	}
+	var t76 object.Value = l_try
+	var t77 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t78, err := aot.Index(th, "testdata/errors.lua:27:16: ", l__ENV, object.String("error"))
+		if err != nil {
+			return nil, err
+		}
+		t79 := th.NewTableSize(0, 1)
+		if err := aot.SetField("testdata/errors.lua:27:30: ", t79, object.String("code"), object.Integer(1)); err != nil {
+			return nil, err
+		}
+		if _, err := aot.Call(th, "testdata/errors.lua:27:21: ", t78, t79); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:27:4: ", t76, t77); err != nil {
+		return nil, err
+	}
+	var t80 object.Value = l_try
+	var t81 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		varargs := aot.Rest(args, 0)
+		_ = varargs
+		t82, err := aot.Index(th, "testdata/errors.lua:28:26: ", l__ENV, object.String("select"))
+		if err != nil {
+			return nil, err
+		}
+		return aot.Call(th, "testdata/errors.lua:28:32: ", t82, append([]object.Value{object.Integer(2)}, varargs...)...)
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:28:4: ", t80, t81, object.Integer(1), object.Integer(2), object.Integer(3)); err != nil {
+		return nil, err
+	}
+	var t83 object.Value = l_try
+	var t84 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t85, err := aot.Index(th, "testdata/errors.lua:29:16: ", l__ENV, object.String("error"))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := aot.Call(th, "testdata/errors.lua:29:21: ", t85, object.String("raised")); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:29:4: ", t83, t84); err != nil {
+		return nil, err
+	}
+	var t86 object.Value = l_try
+	var t87 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		t88, err := aot.Index(th, "testdata/errors.lua:30:28: ", object.String("x"), object.String("rep"))
+		if err != nil {
+			return nil, err
+		}
+		return aot.Call(th, "testdata/errors.lua:30:32: ", t88, object.String("x"))
+	})
+	if _, err := aot.Call(th, "testdata/errors.lua:30:4: ", t86, t87); err != nil {
+		return nil, err
+	}
+	var l_inf object.Value
+	l_inf = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_n object.Value = aot.Arg(args, 0)
+		var t89 object.Value = l_inf
+		t90, err := aot.Call(th, "testdata/errors.lua:32:37: ", t89, l_n)
+		if err != nil {
+			return nil, err
+		}
+		t91, err := aot.Add(th, "testdata/errors.lua:32:32: ", object.Integer(1), aot.Arg(t90, 0))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t91}, nil
+	})
+	t92, err := aot.Index(th, "testdata/errors.lua:33:17: ", l__ENV, object.String("pcall"))
+	if err != nil {
+		return nil, err
+	}
+	var t93 object.Value = l_inf
+	t94, err := aot.Call(th, "testdata/errors.lua:33:22: ", t92, t93, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var l_ok_2 object.Value = aot.Arg(t94, 0)
+	var l_err_2 object.Value = aot.Arg(t94, 1)
+	t95, err := aot.Len(th, "testdata/errors.lua:34:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t96, err := aot.Add(th, "testdata/errors.lua:34:18: ", t95, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t97, err := aot.Index(th, "testdata/errors.lua:34:25: ", l__ENV, object.String("tostring"))
+	if err != nil {
+		return nil, err
+	}
+	t98, err := aot.Call(th, "testdata/errors.lua:34:33: ", t97, l_ok_2)
+	if err != nil {
+		return nil, err
+	}
+	t99, err := aot.Index(th, "testdata/errors.lua:34:48: ", l__ENV, object.String("tostring"))
+	if err != nil {
+		return nil, err
+	}
+	var t100 object.Value = l_err_2
+	t101, err := aot.Index(th, "testdata/errors.lua:34:60: ", t100, object.String("find"))
+	if err != nil {
+		return nil, err
+	}
+	t102, err := aot.Call(th, "testdata/errors.lua:34:65: ", t101, t100, object.String("stack overflow"))
+	if err != nil {
+		return nil, err
+	}
+	t103, err := aot.Equal(th, "testdata/errors.lua:34:84: ", aot.Arg(t102, 0), nil)
+	if err != nil {
+		return nil, err
+	}
+	t104, err := aot.Call(th, "testdata/errors.lua:34:56: ", t99, object.Boolean(!t103))
+	if err != nil {
+		return nil, err
+	}
+	t105, err := aot.Concat(th, "testdata/errors.lua:34:45: ", object.String(" "), aot.Arg(t104, 0))
+	if err != nil {
+		return nil, err
+	}
+	t106, err := aot.Concat(th, "testdata/errors.lua:34:38: ", aot.Arg(t98, 0), t105)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/errors.lua:34:8: ", l_results, t96, t106); err != nil {
+		return nil, err
+	}
+	t107, err := aot.Len(th, "testdata/errors.lua:36:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t108, err := aot.Add(th, "testdata/errors.lua:36:18: ", t107, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t109, err := aot.Index(th, "testdata/errors.lua:36:25: ", l__ENV, object.String("setmetatable"))
+	if err != nil {
+		return nil, err
+	}
+	t110 := th.NewTableSize(0, 0)
+	t111 := th.NewTableSize(0, 1)
+	var t112 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_self object.Value = aot.Arg(args, 0)
+		_ = l_self
+		var l_x_3 object.Value = aot.Arg(args, 1)
+		return []object.Value{l_x_3}, nil
+	})
+	if err := aot.SetField("testdata/errors.lua:36:52: ", t111, object.String("__call"), t112); err != nil {
+		return nil, err
+	}
+	t113, err := aot.Call(th, "testdata/errors.lua:36:37: ", t109, t110, t111)
+	if err != nil {
+		return nil, err
+	}
+	t114, err := aot.Call(th, "testdata/errors.lua:36:84: ", aot.Arg(t113, 0), object.String("called"))
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/errors.lua:36:8: ", l_results, t108, aot.Arg(t114, 0)); err != nil {
+		return nil, err
+	}
+	t115 := th.NewTableSize(0, 2)
+	var t116 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_t_2 object.Value = aot.Arg(args, 0)
+		_ = l_t_2
+		var l_k_2 object.Value = aot.Arg(args, 1)
+		var t117 object.Value = l_k_2
+		t118, err := aot.Concat(th, "testdata/errors.lua:38:47: ", t117, object.String("!"))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t118}, nil
+	})
+	if err := aot.SetField("testdata/errors.lua:38:23: ", t115, object.String("__index"), t116); err != nil {
+		return nil, err
+	}
+	var t119 object.Value = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_t_3 object.Value = aot.Arg(args, 0)
+		var l_k_3 object.Value = aot.Arg(args, 1)
+		var l_v object.Value = aot.Arg(args, 2)
+		t120, err := aot.Index(th, "testdata/errors.lua:38:90: ", l__ENV, object.String("rawset"))
+		if err != nil {
+			return nil, err
+		}
+		var t121 object.Value = l_t_3
+		var t122 object.Value = l_k_3
+		t123, err := aot.Mul(th, "testdata/errors.lua:38:105: ", l_v, object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := aot.Call(th, "testdata/errors.lua:38:96: ", t120, t121, t122, t123); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	if err := aot.SetField("testdata/errors.lua:38:72: ", t115, object.String("__newindex"), t119); err != nil {
+		return nil, err
+	}
+	var l_mt object.Value = t115
+	t124, err := aot.Index(th, "testdata/errors.lua:39:11: ", l__ENV, object.String("setmetatable"))
+	if err != nil {
+		return nil, err
+	}
+	t125 := th.NewTableSize(0, 0)
+	var t126 object.Value = l_mt
+	t127, err := aot.Call(th, "testdata/errors.lua:39:23: ", t124, t125, t126)
+	if err != nil {
+		return nil, err
+	}
+	var l_t_4 object.Value = aot.Arg(t127, 0)
+	if err := aot.SetIndex(th, "testdata/errors.lua:40:2: ", l_t_4, object.String("a"), object.Integer(21)); err != nil {
+		return nil, err
+	}
+	t128, err := aot.Len(th, "testdata/errors.lua:41:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t129, err := aot.Add(th, "testdata/errors.lua:41:18: ", t128, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t130, err := aot.Index(th, "testdata/errors.lua:41:26: ", l_t_4, object.String("a"))
+	if err != nil {
+		return nil, err
+	}
+	t131, err := aot.Index(th, "testdata/errors.lua:41:40: ", l_t_4, object.String("b"))
+	if err != nil {
+		return nil, err
+	}
+	t132, err := aot.Concat(th, "testdata/errors.lua:41:36: ", object.String(" "), t131)
+	if err != nil {
+		return nil, err
+	}
+	t133, err := aot.Concat(th, "testdata/errors.lua:41:29: ", t130, t132)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/errors.lua:41:8: ", l_results, t129, t133); err != nil {
+		return nil, err
+	}
+	t134, err := aot.Len(th, "testdata/errors.lua:43:9: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	t135, err := aot.Add(th, "testdata/errors.lua:43:18: ", t134, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	t136, err := aot.Len(th, "testdata/errors.lua:43:25: ", l_results)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/errors.lua:43:8: ", l_results, t135, t136); err != nil {
+		return nil, err
+	}
+	t137, err := aot.Index(th, "testdata/errors.lua:45:8: ", l__ENV, object.String("table"))
+	if err != nil {
+		return nil, err
+	}
+	t138, err := aot.Index(th, "testdata/errors.lua:45:13: ", t137, object.String("concat"))
+	if err != nil {
+		return nil, err
+	}
+	var t139 object.Value = l_results
+	return aot.Call(th, "testdata/errors.lua:45:20: ", t138, t139, object.String("\n"))
+}
@@ -0,0 +1,71 @@
+-- functions with goto statements are embedded as bytecode,
+-- and other functions are translated.
+local function odds(n)
+  local sum = 0
+  for i = 1, n do
+    if i % 2 == 0 then goto continue end
+    sum = sum + i
+    ::continue::
+  end
+  return sum
+end
+
+local function double(x)
+  return x * 2
+end
+
+local t = {}
+
+function t:find(x)
+  for i, v in ipairs(self) do
+    if v == x then goto found end
+  end
+  do return nil end
+  ::found::
+  return x
+end
+
+t[1], t[2], t[3] = "a", "b", "c"
+
+-- an embedded function referring to a local of the enclosing function
+-- embeds the enclosing one.
+local function counter()
+  local n = 0
+  return function()
+    n = n + 1
+    if n > 2 then goto done end
+    do return n end
+    ::done::
+    return -n
+  end
+end
+
+local c = counter()
+c()
+c()
+
+-- bodies of coroutines are embedded,
+-- and they can yield across translated functions.
+local function yield(v)
+  return coroutine.yield(v)
+end
+
+local function twice(v)
+  return yield(v) * 2
+end
+
+local gen = coroutine.wrap(function(a)
+  local b = twice(a + 1)
+  error("yielded " .. b)
+end)
+
+local function fail()
+  ::again::
+  error("goto")
+end
+
+local first = gen(1)
+local ok1, err1 = pcall(gen, 2)
+local ok2, err2 = pcall(fail)
+
+return double(odds(10)), t:find("b"), t:find("d"), c(), first, ok1, err1, ok2, err2, ...
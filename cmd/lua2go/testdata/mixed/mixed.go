@@ -0,0 +1,194 @@
+// Code generated by lua2go from testdata/mixed.lua. DO NOT EDIT.
+
+package mixed
+
+import (
+	"github.com/hirochachacha/plua/aot"
+	"github.com/hirochachacha/plua/object"
+)
+
+// Open executes the main chunk of testdata/mixed.lua.
+func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	var l__ENV object.Value = th.Globals()
+	varargs := args
+	_ = varargs
+	t1, err := embed1.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	var l_odds object.Value = t1
+	var l_double object.Value
+	l_double = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_x object.Value = aot.Arg(args, 0)
+		t2, err := aot.Mul(th, "testdata/mixed.lua:14:12: ", l_x, object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t2}, nil
+	})
+	t3 := th.NewTableSize(0, 0)
+	var l_t object.Value = t3
+	t4, err := embed2.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/mixed.lua:19:12: ", l_t, object.String("find"), t4); err != nil {
+		return nil, err
+	}
+	var t5 object.Value = l_t
+	var t6 object.Value = l_t
+	var t7 object.Value = l_t
+	if err := aot.SetIndex(th, "testdata/mixed.lua:28:14: ", t7, object.Integer(3), object.String("c")); err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/mixed.lua:28:8: ", t6, object.Integer(2), object.String("b")); err != nil {
+		return nil, err
+	}
+	if err := aot.SetIndex(th, "testdata/mixed.lua:28:2: ", t5, object.Integer(1), object.String("a")); err != nil {
+		return nil, err
+	}
+	t8, err := embed3.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	var l_counter object.Value = t8
+	var t9 object.Value = l_counter
+	t10, err := aot.Call(th, "testdata/mixed.lua:43:18: ", t9)
+	if err != nil {
+		return nil, err
+	}
+	var l_c object.Value = aot.Arg(t10, 0)
+	var t11 object.Value = l_c
+	if _, err := aot.Call(th, "testdata/mixed.lua:44:2: ", t11); err != nil {
+		return nil, err
+	}
+	var t12 object.Value = l_c
+	if _, err := aot.Call(th, "testdata/mixed.lua:45:2: ", t12); err != nil {
+		return nil, err
+	}
+	t13, err := embed4.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	var l_yield object.Value = t13
+	var l_twice object.Value
+	l_twice = object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		var l_v object.Value = aot.Arg(args, 0)
+		var t14 object.Value = l_yield
+		t15, err := aot.Call(th, "testdata/mixed.lua:54:15: ", t14, l_v)
+		if err != nil {
+			return nil, err
+		}
+		t16, err := aot.Mul(th, "testdata/mixed.lua:54:19: ", aot.Arg(t15, 0), object.Integer(2))
+		if err != nil {
+			return nil, err
+		}
+		return []object.Value{t16}, nil
+	})
+	t17, err := aot.Index(th, "testdata/mixed.lua:57:13: ", l__ENV, object.String("coroutine"))
+	if err != nil {
+		return nil, err
+	}
+	t18, err := aot.Index(th, "testdata/mixed.lua:57:22: ", t17, object.String("wrap"))
+	if err != nil {
+		return nil, err
+	}
+	t19, err := embed5.Closure(th, l_twice)
+	if err != nil {
+		return nil, err
+	}
+	t20, err := aot.Call(th, "testdata/mixed.lua:57:27: ", t18, t19)
+	if err != nil {
+		return nil, err
+	}
+	var l_gen object.Value = aot.Arg(t20, 0)
+	t21, err := embed6.Closure(th)
+	if err != nil {
+		return nil, err
+	}
+	var l_fail object.Value = t21
+	var t22 object.Value = l_gen
+	t23, err := aot.Call(th, "testdata/mixed.lua:67:18: ", t22, object.Integer(1))
+	if err != nil {
+		return nil, err
+	}
+	var l_first object.Value = aot.Arg(t23, 0)
+	t24, err := aot.Index(th, "testdata/mixed.lua:68:19: ", l__ENV, object.String("pcall"))
+	if err != nil {
+		return nil, err
+	}
+	var t25 object.Value = l_gen
+	t26, err := aot.Call(th, "testdata/mixed.lua:68:24: ", t24, t25, object.Integer(2))
+	if err != nil {
+		return nil, err
+	}
+	var l_ok1 object.Value = aot.Arg(t26, 0)
+	var l_err1 object.Value = aot.Arg(t26, 1)
+	t27, err := aot.Index(th, "testdata/mixed.lua:69:19: ", l__ENV, object.String("pcall"))
+	if err != nil {
+		return nil, err
+	}
+	t28, err := aot.Call(th, "testdata/mixed.lua:69:24: ", t27, l_fail)
+	if err != nil {
+		return nil, err
+	}
+	var l_ok2 object.Value = aot.Arg(t28, 0)
+	var l_err2 object.Value = aot.Arg(t28, 1)
+	var t29 object.Value = l_double
+	var t30 object.Value = l_odds
+	t31, err := aot.Call(th, "testdata/mixed.lua:71:19: ", t30, object.Integer(10))
+	if err != nil {
+		return nil, err
+	}
+	t32, err := aot.Call(th, "testdata/mixed.lua:71:14: ", t29, t31...)
+	if err != nil {
+		return nil, err
+	}
+	var t33 object.Value = l_t
+	t34, err := aot.Index(th, "testdata/mixed.lua:71:27: ", t33, object.String("find"))
+	if err != nil {
+		return nil, err
+	}
+	t35, err := aot.Call(th, "testdata/mixed.lua:71:32: ", t34, t33, object.String("b"))
+	if err != nil {
+		return nil, err
+	}
+	var t36 object.Value = l_t
+	t37, err := aot.Index(th, "testdata/mixed.lua:71:40: ", t36, object.String("find"))
+	if err != nil {
+		return nil, err
+	}
+	t38, err := aot.Call(th, "testdata/mixed.lua:71:45: ", t37, t36, object.String("d"))
+	if err != nil {
+		return nil, err
+	}
+	var t39 object.Value = l_c
+	t40, err := aot.Call(th, "testdata/mixed.lua:71:53: ", t39)
+	if err != nil {
+		return nil, err
+	}
+	var t41 object.Value = l_first
+	var t42 object.Value = l_ok1
+	var t43 object.Value = l_err1
+	var t44 object.Value = l_ok2
+	var t45 object.Value = l_err2
+	return append([]object.Value{aot.Arg(t32, 0), aot.Arg(t35, 0), aot.Arg(t38, 0), aot.Arg(t40, 0), t41, t42, t43, t44, t45}, varargs...), nil
+}
+
+// embed1 is the function at line 3.
+var embed1 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x02\x04\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00@\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x01\x00\a\x0e\x00\x00\x00\x00\x00\x00\x00A\x00\x00\x00\x81@\x00\x00\xc0\x00\x00\x00\x01A\x00\x00\xa8\x00\x01\x80\x90\x81\xc0\x02\x1f\x00@\x03\x1e\x00\x00\x80\x1e\x00\x00\x80M@\x81\x00\xa7@\xfe\x7f\x80\x00\x80\x00\xa6\x00\x00\x01&\x00\x80\x00\x03\x00\x00\x00\x00\x00\x00\x00\x13\x00\x00\x00\x00\x00\x00\x00\x00\x13\x01\x00\x00\x00\x00\x00\x00\x00\x13\x02\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x02n\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x04sum\x01\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\f(for index)\x04\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\f(for limit)\x04\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\v(for step)\x04\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x02i\x05\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x11\x00\x00\x00\x00\x00\x00\x00\x11\x00\x00\x00\x00\x00\x00\x00\x1d\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05odds\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"}
+
+// embed2 is the function at line 19.
+var embed2 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\x00\x01\x01\x03\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00&\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x13\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\x02\x00\a\x0e\x00\x00\x00\x00\x00\x00\x00\x86\x00@\x00\xc0\x00\x00\x00\xa4\x00\x01\x01\x1e\x80\x00\x80\x1f@\x00\x03\x1e\x00\x00\x80\x1e\xc0\x00\x80\xa9\x80\x00\x00*\x81\xfe\x7f\x84\x00\x00\x00\xa6\x00\x00\x01\x80\x00\x80\x00\xa6\x00\x00\x01&\x00\x80\x00\x01\x00\x00\x00\x00\x00\x00\x00\x04\aipairs\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\x17\x00\x00\x00\x00\x00\x00\x00\x17\x00\x00\x00\x00\x00\x00\x00\x19\x00\x00\x00\x00\x00\x00\x00\x19\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x05self\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x02x\x00\x00\x00\x00\x00\x00\x00\x00\x0e\x00\x00\x00\x00\x00\x00\x00\x10(for generator)\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x10(for generator)\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x0e(for control)\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x02i\x04\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x02v\x04\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x0e\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\x16\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\x19\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\x13\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x03\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"}
+
+// embed3 is the function at line 32.
+var embed3 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x02\x04\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00@\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00 \x00\x00\x00\x00\x00\x00\x00)\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00l\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x01\x00\x00\x00\x00\x00\x00\x00\x13\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\"\x00\x00\x00\x00\x00\x00\x00(\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\f\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\r\x00@\x00\t\x00\x00\x00 \x00\x80\x80\x1e\x00\x00\x80\x1e@\x00\x80\x05\x00\x00\x00&\x00\x00\x01\x05\x00\x00\x00\x19\x00\x00\x00&\x00\x00\x01&\x00\x80\x00\x02\x00\x00\x00\x00\x00\x00\x00\x13\x01\x00\x00\x00\x00\x00\x00\x00\x13\x02\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00#\x00\x00\x00\x00\x00\x00\x00#\x00\x00\x00\x00\x00\x00\x00#\x00\x00\x00\x00\x00\x00\x00$\x00\x00\x00\x00\x00\x00\x00$\x00\x00\x00\x00\x00\x00\x00$\x00\x00\x00\x00\x00\x00\x00%\x00\x00\x00\x00\x00\x00\x00%\x00\x00\x00\x00\x00\x00\x00'\x00\x00\x00\x00\x00\x00\x00'\x00\x00\x00\x00\x00\x00\x00'\x00\x00\x00\x00\x00\x00\x00(\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x02n\f\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\v\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\x18\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\x0f\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\f\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00!\x00\x00\x00\x00\x00\x00\x00(\x00\x00\x00\x00\x00\x00\x00\"\x00\x00\x00\x00\x00\x00\x00)\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x02n\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00)\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\bcounter\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\a\x00\x00\x00\x00\x00\x00\x00"}
+
+// embed4 is the function at line 49.
+var embed4 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x02\x04\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00@\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x001\x00\x00\x00\x00\x00\x00\x003\x00\x00\x00\x00\x00\x00\x00\x01\x00\x03\x06\x00\x00\x00\x00\x00\x00\x00F\x00@\x00G@\xc0\x00\x80\x00\x00\x00e\x00\x00\x01f\x00\x00\x00&\x00\x80\x00\x02\x00\x00\x00\x00\x00\x00\x00\x04\ncoroutine\x04\x06yield\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x002\x00\x00\x00\x00\x00\x00\x002\x00\x00\x00\x00\x00\x00\x002\x00\x00\x00\x00\x00\x00\x002\x00\x00\x00\x00\x00\x00\x002\x00\x00\x00\x00\x00\x00\x003\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x02v\x00\x00\x00\x00\x00\x00\x00\x00\x06\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x06\x00\x00\x00\x00\x00\x00\x00\n\x00\x00\x00\x00\x00\x00\x00\x13\x00\x00\x00\x00\x00\x00\x00\x1a\x00\x00\x00\x00\x00\x00\x00\x19\x00\x00\x00\x00\x00\x00\x00\x19\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x003\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x06yield\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x05\x00\x00\x00\x00\x00\x00\x00"}
+
+// embed5 is the function at line 57.
+var embed5 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00<\x00\x00\x00\x00\x00\x00\x00\x00\x01\x02\x04\x00\x00\x00\x00\x00\x00\x00-\x00\x00\x01l\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x009\x00\x00\x00\x00\x00\x00\x00<\x00\x00\x00\x00\x00\x00\x00\x01\x00\x05\t\x00\x00\x00\x00\x00\x00\x00E\x00\x00\x00\x8d\x00@\x00d\x80\x00\x01\x86@\xc0\x00\xc1\x80\x00\x00\x00\x01\x80\x00\xdd\x00\x81\x01\xa4@\x00\x01&\x00\x80\x00\x03\x00\x00\x00\x00\x00\x00\x00\x13\x01\x00\x00\x00\x00\x00\x00\x00\x04\x06error\x04\tyielded \x02\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00:\x00\x00\x00\x00\x00\x00\x00:\x00\x00\x00\x00\x00\x00\x00:\x00\x00\x00\x00\x00\x00\x00;\x00\x00\x00\x00\x00\x00\x00;\x00\x00\x00\x00\x00\x00\x00;\x00\x00\x00\x00\x00\x00\x00;\x00\x00\x00\x00\x00\x00\x00;\x00\x00\x00\x00\x00\x00\x00<\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x02a\x00\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x02b\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x02\x00\x00\x00\x00\x00\x00\x00\x06twice\x05_ENV\t\x00\x00\x00\x00\x00\x00\x00\r\x00\x00\x00\x00\x00\x00\x00\x15\x00\x00\x00\x00\x00\x00\x00\x12\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\x17\x00\x00\x00\x00\x00\x00\x00\x14\x00\x00\x00\x00\x00\x00\x00\b\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00<\x00\x00\x00\x00\x00\x00\x009\x00\x00\x00\x00\x00\x00\x00<\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x06twice\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x1c\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"}
+
+// embed6 is the function at line 62.
+var embed6 = &aot.Func{Chunk: "\x1bLuaSP\x19\x93\r\n\x1a\n\b\b\x04\b\bxV\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00(w@\x01\x14@testdata/mixed.lua\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x02\x04\x00\x00\x00\x00\x00\x00\x00,\x00\x00\x00@\x00\x00\x00f\x00\x00\x01&\x00\x80\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x01\x00\x01\x00\x00\x00\x00\x00\x00\x00\x00>\x00\x00\x00\x00\x00\x00\x00A\x00\x00\x00\x00\x00\x00\x00\x00\x00\x02\x04\x00\x00\x00\x00\x00\x00\x00\x06\x00@\x00A@\x00\x00$@\x00\x01&\x00\x80\x00\x02\x00\x00\x00\x00\x00\x00\x00\x04\x06error\x04\x05goto\x01\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00@\x00\x00\x00\x00\x00\x00\x00@\x00\x00\x00\x00\x00\x00\x00@\x00\x00\x00\x00\x00\x00\x00A\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x03\x00\x00\x00\x00\x00\x00\x00\t\x00\x00\x00\x00\x00\x00\x00\b\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00A\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05fail\x01\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00\x00\x00\x00\x05_ENV\x04\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x04\x00\x00\x00\x00\x00\x00\x00"}
@@ -0,0 +1,988 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	goast "go/ast"
+	"go/format"
+	goparser "go/parser"
+	gotoken "go/token"
+	"math"
+	gostrconv "strconv"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/internal/strconv"
+	"github.com/hirochachacha/plua/position"
+)
+
+type unsupportedError struct {
+	pos  position.Position
+	what string
+}
+
+func (e *unsupportedError) Error() string {
+	return fmt.Sprintf("%d:%d: %s is not supported", e.pos.Line, e.pos.Column, e.what)
+}
+
+// translator translates a chunk into Go code calling aot helpers.
+type translator struct {
+	info    *scope.Info
+	srcname string // chunk name used in error positions
+
+	scopes map[ast.Node]*scope.Scope
+	envs   map[*scope.Decl]bool // declarations of _ENV used by globals
+	idents map[*scope.Decl]string
+	used   map[string]bool
+
+	ntemps int
+
+	fallbacks  map[*ast.FuncBody]*unsupportedError // functions embedded as bytecode
+	localFuncs map[*ast.FuncBody]*scope.Decl       // declarations of local functions
+	embeds     bytes.Buffer                        // declarations of embedded functions
+	nembeds    int
+	warn       func(err error)
+
+	buf bytes.Buffer
+	err error
+}
+
+// translate translates f into a Go package named pkg.
+// functions which can't be translated are embedded as bytecode, and warn is called with the reasons.
+// it returns *unsupportedError if the main chunk can't be translated.
+func translate(f *ast.File, pkg, filename, srcname string, warn func(err error)) ([]byte, error) {
+	t := &translator{
+		info:    scope.Resolve(f),
+		srcname: srcname,
+		scopes:  make(map[ast.Node]*scope.Scope),
+		envs:    make(map[*scope.Decl]bool),
+		idents:  make(map[*scope.Decl]string),
+		used:    make(map[string]bool),
+		warn:    warn,
+	}
+
+	t.indexScopes(t.info.Root)
+
+	t.findFallbacks(f)
+
+	if err, ok := t.fallbacks[nil]; ok {
+		return nil, err
+	}
+
+	for _, b := range t.info.Bindings {
+		if b.Kind == scope.Global {
+			t.envs[b.Decl] = true
+		}
+	}
+
+	env := t.ident(t.info.Env)
+
+	t.printf("// Open executes the main chunk of %s.\n", filename)
+	t.printf("func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {\n")
+	t.printf("var %s object.Value = th.Globals()\n", env)
+	t.unused(t.info.Env)
+	t.printf("varargs := args\n")
+	t.printf("_ = varargs\n")
+	t.funcEnd(f.Chunk)
+	t.printf("}\n")
+
+	if t.err != nil {
+		return nil, t.err
+	}
+
+	if t.nembeds > 0 {
+		t.printf("\n")
+		t.buf.Write(t.embeds.Bytes())
+	}
+
+	return source(pkg, filename, t.buf.Bytes())
+}
+
+// source returns the formatted Go source of a package consisting of body.
+func source(pkg, filename string, body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by lua2go from %s. DO NOT EDIT.\n\n", filename)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+
+	used, err := usedPackages(body)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(&buf, "import (\n")
+	for _, imp := range []struct{ name, path string }{
+		{"aot", "github.com/hirochachacha/plua/aot"},
+		{"math", "math"},
+		{"object", "github.com/hirochachacha/plua/object"},
+	} {
+		if used[imp.name] {
+			fmt.Fprintf(&buf, "%q\n", imp.path)
+		}
+	}
+	fmt.Fprintf(&buf, ")\n\n")
+
+	buf.Write(body)
+
+	return format.Source(buf.Bytes())
+}
+
+// usedPackages returns names of packages referred by body.
+func usedPackages(body []byte) (map[string]bool, error) {
+	src := append([]byte("package p\n\n"), body...)
+
+	f, err := goparser.ParseFile(gotoken.NewFileSet(), "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	used := make(map[string]bool)
+
+	goast.Inspect(f, func(node goast.Node) bool {
+		if sel, ok := node.(*goast.SelectorExpr); ok {
+			if id, ok := sel.X.(*goast.Ident); ok {
+				used[id.Name] = true
+			}
+		}
+		return true
+	})
+
+	return used, nil
+}
+
+func (t *translator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&t.buf, format, args...)
+}
+
+func (t *translator) error(err error) {
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+func (t *translator) indexScopes(s *scope.Scope) {
+	t.scopes[s.Node] = s
+	for _, c := range s.Children {
+		t.indexScopes(c)
+	}
+}
+
+// ident returns the Go identifier of d, which is unique in the file.
+func (t *translator) ident(d *scope.Decl) string {
+	if id, ok := t.idents[d]; ok {
+		return id
+	}
+
+	id := "l_" + d.Name
+	for i := 2; t.used[id]; i++ {
+		id = fmt.Sprintf("l_%s_%d", d.Name, i)
+	}
+
+	t.idents[d] = id
+	t.used[id] = true
+
+	return id
+}
+
+// unused silences the Go compiler if d is never read.
+func (t *translator) unused(d *scope.Decl) {
+	if t.envs[d] {
+		return
+	}
+	for _, ref := range d.Refs {
+		if !t.info.Bindings[ref].Write {
+			return
+		}
+	}
+	t.printf("_ = %s\n", t.ident(d))
+}
+
+// declare declares the local variable name initialized by val.
+func (t *translator) declare(name *ast.Name, val string) {
+	d := t.info.Decls[name]
+	t.printf("var %s object.Value = %s\n", t.ident(d), val)
+	t.unused(d)
+}
+
+func (t *translator) temp() string {
+	t.ntemps++
+	return fmt.Sprintf("t%d", t.ntemps)
+}
+
+func (t *translator) pos(pos position.Position) string {
+	return gostrconv.Quote(fmt.Sprintf("%s:%d:%d: ", t.srcname, pos.Line, pos.Column))
+}
+
+func (t *translator) check() {
+	t.printf("if err != nil {\nreturn nil, err\n}\n")
+}
+
+// ----------------------------------------------------------------------------
+// Functions
+
+// closure evaluates the function from pos to end, and returns a Go expression of it.
+func (t *translator) closure(body *ast.FuncBody, isMethod bool, pos, end position.Position) string {
+	if t.isEmbedded(body) {
+		return t.embedded(body, isMethod, pos, end)
+	}
+
+	fn := t.temp()
+	t.printf("var %s object.Value = ", fn)
+	t.function(body, isMethod)
+	t.printf("\n")
+	return fn
+}
+
+// function writes a GoFunction literal of body.
+func (t *translator) function(body *ast.FuncBody, isMethod bool) {
+	t.printf("object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {\n")
+
+	n := 0
+	if isMethod {
+		self := t.scopes[body].Decls[0]
+		t.printf("var %s object.Value = aot.Arg(args, 0)\n", t.ident(self))
+		t.unused(self)
+		n++
+	}
+	for _, name := range body.Params.List {
+		t.declare(name, fmt.Sprintf("aot.Arg(args, %d)", n))
+		n++
+	}
+	if body.Params.Ellipsis.IsValid() {
+		t.printf("varargs := aot.Rest(args, %d)\n", n)
+		t.printf("_ = varargs\n")
+	}
+
+	t.funcEnd(body.Body.List)
+
+	t.printf("})")
+}
+
+// funcEnd writes the statements of a function body and closes it.
+func (t *translator) funcEnd(list []ast.Stmt) {
+	t.stmtList(list)
+
+	if !terminates(list) {
+		t.printf("return nil, nil\n")
+	}
+}
+
+// terminates reports whether the translation of list ends with a terminating statement of Go.
+func terminates(list []ast.Stmt) bool {
+	for len(list) > 0 {
+		if _, ok := list[len(list)-1].(*ast.EmptyStmt); !ok {
+			break
+		}
+		list = list[:len(list)-1]
+	}
+
+	if len(list) == 0 {
+		return false
+	}
+
+	switch stmt := list[len(list)-1].(type) {
+	case *ast.ReturnStmt:
+		return true
+	case *ast.DoStmt:
+		return terminates(stmt.Body.List)
+	case *ast.IfStmt:
+		if stmt.ElseBody == nil || !terminates(stmt.Body.List) || !terminates(stmt.ElseBody.List) {
+			return false
+		}
+		for _, e := range stmt.ElseIfList {
+			if !terminates(e.Body.List) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return false
+}
+
+// ----------------------------------------------------------------------------
+// Statements
+
+func (t *translator) stmtList(list []ast.Stmt) {
+	for i, stmt := range list {
+		t.stmt(stmt)
+
+		// following statements are unreachable
+		if _, ok := stmt.(*ast.BreakStmt); ok || terminates(list[:i+1]) {
+			return
+		}
+	}
+}
+
+func (t *translator) block(b *ast.Block) {
+	t.printf("{\n")
+	t.stmtList(b.List)
+	t.printf("}\n")
+}
+
+func (t *translator) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.EmptyStmt:
+		// nothing to do
+	case *ast.LocalAssignStmt:
+		vals := t.exprListN(stmt.RHS, len(stmt.LHS))
+		for i, name := range stmt.LHS {
+			t.declare(name, vals[i])
+		}
+	case *ast.LocalFuncStmt:
+		d := t.info.Decls[stmt.Name]
+		if t.isEmbedded(stmt.Body) {
+			t.declare(stmt.Name, t.embedded(stmt.Body, false, stmt.Func, stmt.EndPos))
+			break
+		}
+		t.printf("var %s object.Value\n", t.ident(d))
+		t.unused(d)
+		t.printf("%s = ", t.ident(d))
+		t.function(stmt.Body, false)
+		t.printf("\n")
+	case *ast.FuncStmt:
+		t.funcStmt(stmt)
+	case *ast.ExprStmt:
+		fn, args := t.callee(stmt.X)
+		t.printf("if _, err := aot.Call(th, %s, %s%s); err != nil {\nreturn nil, err\n}\n", t.callPos(stmt.X), fn, args)
+	case *ast.AssignStmt:
+		t.assign(stmt)
+	case *ast.BreakStmt:
+		t.printf("break\n")
+	case *ast.IfStmt:
+		t.ifStmt(stmt)
+	case *ast.DoStmt:
+		t.block(stmt.Body)
+	case *ast.WhileStmt:
+		t.printf("for {\n")
+		t.printf("if !(%s) {\nbreak\n}\n", t.cond(stmt.Cond))
+		t.stmtList(stmt.Body.List)
+		t.printf("}\n")
+	case *ast.RepeatStmt:
+		// the condition can refer to locals of the body
+		t.printf("for {\n")
+		t.stmtList(stmt.Body.List)
+		t.printf("if %s {\nbreak\n}\n", t.cond(stmt.Cond))
+		t.printf("}\n")
+	case *ast.ReturnStmt:
+		t.returnStmt(stmt)
+	case *ast.ForStmt:
+		t.forStmt(stmt)
+	case *ast.ForEachStmt:
+		t.forEachStmt(stmt)
+	default:
+		t.error(&unsupportedError{stmt.Pos(), fmt.Sprintf("%T", stmt)})
+	}
+}
+
+func (t *translator) funcStmt(stmt *ast.FuncStmt) {
+	if stmt.PathList == nil {
+		t.setName(stmt.Name, t.closure(stmt.Body, false, stmt.Func, stmt.EndPos))
+		return
+	}
+
+	obj := t.name(stmt.PathList[0])
+	for _, name := range stmt.PathList[1:] {
+		obj = t.index(name.Pos(), obj, t.key(name))
+	}
+
+	fn := t.closure(stmt.Body, stmt.AccessTok == token.COLON, stmt.Func, stmt.EndPos)
+	t.setIndex(stmt.Name.Pos(), obj, t.key(stmt.Name), fn)
+}
+
+func (t *translator) assign(stmt *ast.AssignStmt) {
+	type target struct {
+		name     *ast.Name
+		obj, key string
+		pos      position.Position
+	}
+
+	// operands of targets are evaluated before values,
+	// and they are copied if other assignments can change them.
+	eval := t.expr
+	if len(stmt.LHS) > 1 {
+		eval = t.value
+	}
+
+	targets := make([]target, len(stmt.LHS))
+	for i, lhs := range stmt.LHS {
+		switch lhs := lhs.(type) {
+		case *ast.Name:
+			targets[i] = target{name: lhs}
+		case *ast.SelectorExpr:
+			targets[i] = target{obj: eval(lhs.X), key: t.key(lhs.Sel), pos: lhs.Period}
+		case *ast.IndexExpr:
+			obj := eval(lhs.X)
+			targets[i] = target{obj: obj, key: eval(lhs.Index), pos: lhs.Lbrack}
+		default:
+			t.error(&unsupportedError{lhs.Pos(), fmt.Sprintf("assignment to %T", lhs)})
+			return
+		}
+	}
+
+	vals := t.exprListN(stmt.RHS, len(stmt.LHS))
+
+	// the VM assigns values from the last one
+	for i := len(targets) - 1; i >= 0; i-- {
+		if targets[i].name != nil {
+			t.setName(targets[i].name, vals[i])
+		} else {
+			t.setIndex(targets[i].pos, targets[i].obj, targets[i].key, vals[i])
+		}
+	}
+}
+
+func (t *translator) setName(name *ast.Name, val string) {
+	b := t.info.Bindings[name]
+	if b.Kind == scope.Global {
+		t.setIndex(name.Pos(), t.ident(b.Decl), t.key(name), val)
+		return
+	}
+	t.printf("%s = %s\n", t.ident(b.Decl), val)
+}
+
+func (t *translator) setIndex(pos position.Position, obj, key, val string) {
+	t.printf("if err := aot.SetIndex(th, %s, %s, %s, %s); err != nil {\nreturn nil, err\n}\n", t.pos(pos), obj, key, val)
+}
+
+func (t *translator) ifStmt(stmt *ast.IfStmt) {
+	t.printf("if %s {\n", t.cond(stmt.Cond))
+	t.stmtList(stmt.Body.List)
+
+	// conditions of elseif may need statements, so they are nested
+	for _, e := range stmt.ElseIfList {
+		t.printf("} else {\n")
+		t.printf("if %s {\n", t.cond(e.Cond))
+		t.stmtList(e.Body.List)
+	}
+
+	if stmt.ElseBody != nil {
+		t.printf("} else {\n")
+		t.stmtList(stmt.ElseBody.List)
+	}
+
+	t.printf("%s\n", strings.Repeat("}", len(stmt.ElseIfList)+1))
+}
+
+func (t *translator) returnStmt(stmt *ast.ReturnStmt) {
+	if len(stmt.Results) == 1 {
+		if call, ok := stmt.Results[0].(*ast.CallExpr); ok {
+			fn, args := t.callee(call)
+			t.printf("return aot.Call(th, %s, %s%s)\n", t.callPos(call), fn, args)
+			return
+		}
+	}
+
+	vals, rest := t.exprList(stmt.Results)
+
+	switch {
+	case len(vals) == 0 && rest == "":
+		t.printf("return nil, nil\n")
+	case len(vals) == 0:
+		t.printf("return %s, nil\n", rest)
+	case rest == "":
+		t.printf("return []object.Value{%s}, nil\n", strings.Join(vals, ", "))
+	default:
+		t.printf("return append([]object.Value{%s}, %s...), nil\n", strings.Join(vals, ", "), rest)
+	}
+}
+
+func (t *translator) forStmt(stmt *ast.ForStmt) {
+	init := t.value(stmt.Start)
+	limit := t.value(stmt.Finish)
+	step := "object.Integer(1)"
+	if stmt.Step != nil {
+		step = t.value(stmt.Step)
+	}
+
+	loop := t.temp()
+	t.printf("%s, err := aot.NewForLoop(%s, %s, %s, %s)\n", loop, t.pos(stmt.For), init, limit, step)
+	t.check()
+	t.printf("for %s.Next() {\n", loop)
+	t.declare(stmt.Name, loop+".Value()")
+	t.stmtList(stmt.Body.List)
+	t.printf("}\n")
+}
+
+func (t *translator) forEachStmt(stmt *ast.ForEachStmt) {
+	vals := t.exprListN(stmt.Exprs, 3)
+
+	f, s, ctl := t.temp(), t.temp(), t.temp()
+	t.printf("var %s, %s, %s object.Value = %s\n", f, s, ctl, strings.Join(vals, ", "))
+	t.printf("for {\n")
+	rets := t.temp()
+	t.printf("%s, err := aot.Call(th, %s, %s, %s, %s)\n", rets, t.pos(stmt.For), f, s, ctl)
+	t.check()
+	for i, name := range stmt.Names {
+		t.declare(name, fmt.Sprintf("aot.Arg(%s, %d)", rets, i))
+		if i == 0 {
+			id := t.ident(t.info.Decls[name])
+			t.printf("if %s == nil {\nbreak\n}\n", id)
+			t.printf("%s = %s\n", ctl, id)
+		}
+	}
+	t.stmtList(stmt.Body.List)
+	t.printf("}\n")
+}
+
+// ----------------------------------------------------------------------------
+// Expressions
+
+// isMulti reports whether e can have multiple values.
+func isMulti(e ast.Expr) bool {
+	switch e.(type) {
+	case *ast.CallExpr, *ast.Vararg:
+		return true
+	}
+	return false
+}
+
+// isLocal reports whether v is a Go identifier of a local variable.
+func isLocal(v string) bool {
+	return strings.HasPrefix(v, "l_")
+}
+
+// isTemp reports whether v is a temporary variable.
+func isTemp(v string) bool {
+	return strings.HasPrefix(v, "t")
+}
+
+// exprList evaluates list.
+// values of the last multi-valued expression are returned as rest.
+func (t *translator) exprList(list []ast.Expr) (vals []string, rest string) {
+	for i, e := range list {
+		if i == len(list)-1 && isMulti(e) {
+			return vals, t.multi(e)
+		}
+		if len(list) > 1 {
+			vals = append(vals, t.value(e))
+		} else {
+			vals = append(vals, t.expr(e))
+		}
+	}
+	return vals, ""
+}
+
+// exprListN evaluates list, and adjusts values to n.
+func (t *translator) exprListN(list []ast.Expr, n int) []string {
+	vals := make([]string, 0, n)
+
+	for i, e := range list {
+		switch {
+		case i == len(list)-1 && isMulti(e) && i < n:
+			rets := t.multi(e)
+			for j := i; j < n; j++ {
+				vals = append(vals, fmt.Sprintf("aot.Arg(%s, %d)", rets, j-i))
+			}
+		case i >= n:
+			t.discard(e)
+		case len(list) > 1 || n > 1:
+			vals = append(vals, t.value(e))
+		default:
+			vals = append(vals, t.expr(e))
+		}
+	}
+
+	for len(vals) < n {
+		vals = append(vals, "nil")
+	}
+
+	return vals
+}
+
+// discard evaluates e for its side effects.
+func (t *translator) discard(e ast.Expr) {
+	if call, ok := e.(*ast.CallExpr); ok {
+		t.stmt(&ast.ExprStmt{X: call})
+		return
+	}
+	if v := t.expr(e); isTemp(v) {
+		t.printf("_ = %s\n", v)
+	}
+}
+
+// multi evaluates e, and returns all values of it as a []object.Value.
+func (t *translator) multi(e ast.Expr) string {
+	if call, ok := e.(*ast.CallExpr); ok {
+		return t.call(call)
+	}
+	return "varargs"
+}
+
+// value is the same as expr, but copies local variables,
+// because following expressions can change them.
+func (t *translator) value(e ast.Expr) string {
+	v := t.expr(e)
+	if isLocal(v) {
+		tmp := t.temp()
+		t.printf("var %s object.Value = %s\n", tmp, v)
+		return tmp
+	}
+	return v
+}
+
+// expr evaluates e, and returns a Go expression of its first value.
+func (t *translator) expr(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		return t.literal(e.Token, false)
+	case *ast.Name:
+		return t.name(e)
+	case *ast.Vararg:
+		return "aot.Arg(varargs, 0)"
+	case *ast.FuncLit:
+		return t.closure(e.Body, false, e.Func, e.EndPos)
+	case *ast.TableLit:
+		return t.table(e)
+	case *ast.ParenExpr:
+		return t.expr(e.X)
+	case *ast.SelectorExpr:
+		return t.index(e.Period, t.expr(e.X), t.key(e.Sel))
+	case *ast.IndexExpr:
+		x := t.expr(e.X)
+		return t.index(e.Lbrack, x, t.expr(e.Index))
+	case *ast.CallExpr:
+		return fmt.Sprintf("aot.Arg(%s, 0)", t.call(e))
+	case *ast.UnaryExpr:
+		return t.unary(e)
+	case *ast.BinaryExpr:
+		return t.binary(e)
+	default:
+		t.error(&unsupportedError{e.Pos(), fmt.Sprintf("%T", e)})
+		return "nil"
+	}
+}
+
+func (t *translator) literal(tok token.Token, negate bool) string {
+	lit := tok.Lit
+	if negate {
+		lit = "-" + lit
+	}
+
+	switch tok.Type {
+	case token.NIL:
+		return "nil"
+	case token.TRUE:
+		return "object.True"
+	case token.FALSE:
+		return "object.False"
+	case token.INT:
+		i, err := strconv.ParseInt(lit)
+		if err == nil {
+			return fmt.Sprintf("object.Integer(%d)", i)
+		}
+		if err != strconv.ErrRange {
+			t.error(fmt.Errorf("%s: failed to parse int %s, err: %v", tok.Pos, lit, err))
+		}
+		fallthrough
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(lit)
+		if err != nil && err != strconv.ErrRange {
+			t.error(fmt.Errorf("%s: failed to parse float %s, err: %v", tok.Pos, lit, err))
+		}
+		return number(f)
+	case token.STRING:
+		s, err := strconv.Unquote(tok.Lit)
+		if err != nil {
+			t.error(fmt.Errorf("%s: failed to unquote %s, err: %v", tok.Pos, tok.Lit, err))
+		}
+		return fmt.Sprintf("object.String(%s)", gostrconv.Quote(s))
+	default:
+		panic("unreachable")
+	}
+}
+
+func number(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "object.Number(math.Inf(1))"
+	case math.IsInf(f, -1):
+		return "object.Number(math.Inf(-1))"
+	case f == 0 && math.Signbit(f):
+		return "object.Number(math.Copysign(0, -1))"
+	}
+
+	s := gostrconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+
+	return fmt.Sprintf("object.Number(%s)", s)
+}
+
+// key returns the string constant of a field name.
+func (t *translator) key(name *ast.Name) string {
+	return fmt.Sprintf("object.String(%s)", gostrconv.Quote(name.Name))
+}
+
+func (t *translator) name(name *ast.Name) string {
+	b := t.info.Bindings[name]
+
+	switch b.Kind {
+	case scope.Global:
+		return t.index(name.Pos(), t.ident(b.Decl), t.key(name))
+	case scope.Upvalue:
+		// upvalues are loaded before following expressions, as the VM does
+		v := t.temp()
+		t.printf("var %s object.Value = %s\n", v, t.ident(b.Decl))
+		return v
+	default:
+		return t.ident(b.Decl)
+	}
+}
+
+func (t *translator) index(pos position.Position, x, key string) string {
+	v := t.temp()
+	t.printf("%s, err := aot.Index(th, %s, %s, %s)\n", v, t.pos(pos), x, key)
+	t.check()
+	return v
+}
+
+func (t *translator) table(e *ast.TableLit) string {
+	var a []ast.Expr
+	var m []*ast.KeyValueExpr
+	for _, f := range e.Fields {
+		if kv, ok := f.(*ast.KeyValueExpr); ok {
+			m = append(m, kv)
+		} else {
+			a = append(a, f)
+		}
+	}
+
+	// trailing nils are dropped, and the last value is truncated then
+	var skipped bool
+	for len(a) > 0 {
+		if lit, ok := a[len(a)-1].(*ast.BasicLit); ok && lit.Token.Type == token.NIL {
+			a = a[:len(a)-1]
+			skipped = true
+			continue
+		}
+		break
+	}
+
+	tab := t.temp()
+	t.printf("%s := th.NewTableSize(%d, %d)\n", tab, len(a), len(m))
+
+	// the VM stores keyed fields first
+	for _, kv := range m {
+		var key string
+		if kv.Lbrack.IsValid() {
+			key = t.value(kv.Key)
+		} else {
+			key = t.key(kv.Key.(*ast.Name))
+		}
+		val := t.expr(kv.Value)
+		t.printf("if err := aot.SetField(%s, %s, %s, %s); err != nil {\nreturn nil, err\n}\n", t.pos(kv.Value.Pos()), tab, key, val)
+	}
+
+	if len(a) > 0 {
+		var vals []string
+		var rest string
+		if skipped {
+			for _, e := range a {
+				vals = append(vals, t.value(e))
+			}
+		} else {
+			vals, rest = t.exprList(a)
+		}
+
+		switch {
+		case len(vals) == 0:
+			t.printf("aot.SetList(%s, %s)\n", tab, rest)
+		case rest == "":
+			t.printf("aot.SetList(%s, []object.Value{%s})\n", tab, strings.Join(vals, ", "))
+		default:
+			t.printf("aot.SetList(%s, append([]object.Value{%s}, %s...))\n", tab, strings.Join(vals, ", "), rest)
+		}
+	}
+
+	return tab
+}
+
+func (t *translator) callPos(e *ast.CallExpr) string {
+	// f"str" and f{...} have no parentheses
+	if e.Lparen.IsValid() {
+		return t.pos(e.Lparen)
+	}
+	return t.pos(e.Args[0].Pos())
+}
+
+// callee evaluates the function and arguments of e.
+// args is a list of Go arguments following the function.
+func (t *translator) callee(e *ast.CallExpr) (fn, args string) {
+	var self string
+	if e.Name != nil {
+		self = t.value(e.X)
+		fn = t.index(e.Colon, self, t.key(e.Name))
+	} else {
+		fn = t.value(e.X)
+	}
+
+	vals, rest := t.exprList(e.Args)
+	if self != "" {
+		vals = append([]string{self}, vals...)
+	}
+
+	switch {
+	case rest == "" && len(vals) == 0:
+		return fn, ""
+	case rest == "":
+		return fn, ", " + strings.Join(vals, ", ")
+	case len(vals) == 0:
+		if rest == "varargs" {
+			// callees can keep arguments
+			rest = "append([]object.Value(nil), varargs...)"
+		}
+		return fn, ", " + rest + "..."
+	default:
+		return fn, fmt.Sprintf(", append([]object.Value{%s}, %s...)...", strings.Join(vals, ", "), rest)
+	}
+}
+
+// call evaluates e, and returns a []object.Value of the results.
+func (t *translator) call(e *ast.CallExpr) string {
+	fn, args := t.callee(e)
+	rets := t.temp()
+	t.printf("%s, err := aot.Call(th, %s, %s%s)\n", rets, t.callPos(e), fn, args)
+	t.check()
+	return rets
+}
+
+var unaryOps = map[token.Type]string{
+	token.UNM:  "Unm",
+	token.BNOT: "Bnot",
+	token.LEN:  "Len",
+}
+
+func (t *translator) unary(e *ast.UnaryExpr) string {
+	if e.Op == token.NOT {
+		return fmt.Sprintf("object.Boolean(!(%s))", t.cond(e.X))
+	}
+
+	if lit, ok := e.X.(*ast.BasicLit); ok && e.Op == token.UNM {
+		switch lit.Token.Type {
+		case token.INT, token.FLOAT:
+			return t.literal(lit.Token, true)
+		}
+	}
+
+	x := t.expr(e.X)
+	v := t.temp()
+	t.printf("%s, err := aot.%s(th, %s, %s)\n", v, unaryOps[e.Op], t.pos(e.OpPos), x)
+	t.check()
+	return v
+}
+
+var binaryOps = map[token.Type]string{
+	token.ADD:    "Add",
+	token.SUB:    "Sub",
+	token.MUL:    "Mul",
+	token.MOD:    "Mod",
+	token.POW:    "Pow",
+	token.DIV:    "Div",
+	token.IDIV:   "Idiv",
+	token.BAND:   "Band",
+	token.BOR:    "Bor",
+	token.BXOR:   "Bxor",
+	token.SHL:    "Shl",
+	token.SHR:    "Shr",
+	token.CONCAT: "Concat",
+}
+
+func (t *translator) binary(e *ast.BinaryExpr) string {
+	switch e.Op {
+	case token.EQ, token.NE, token.LT, token.LE, token.GT, token.GE:
+		return fmt.Sprintf("object.Boolean(%s)", t.compare(e))
+	case token.AND, token.OR:
+		v := t.temp()
+		t.printf("var %s object.Value = %s\n", v, t.expr(e.X))
+		if e.Op == token.AND {
+			t.printf("if aot.Truth(%s) {\n", v)
+		} else {
+			t.printf("if !aot.Truth(%s) {\n", v)
+		}
+		t.printf("%s = %s\n", v, t.expr(e.Y))
+		t.printf("}\n")
+		return v
+	}
+
+	var x string
+	if e.Op == token.CONCAT {
+		// operands of concatenation are loaded in order
+		x = t.value(e.X)
+	} else {
+		x = t.expr(e.X)
+	}
+	y := t.expr(e.Y)
+
+	v := t.temp()
+	t.printf("%s, err := aot.%s(th, %s, %s, %s)\n", v, binaryOps[e.Op], t.pos(e.OpPos), x, y)
+	t.check()
+	return v
+}
+
+// compare evaluates a comparison, and returns a Go bool expression.
+func (t *translator) compare(e *ast.BinaryExpr) string {
+	x := t.expr(e.X)
+	y := t.expr(e.Y)
+
+	var fn string
+	switch e.Op {
+	case token.EQ, token.NE:
+		fn = "Equal"
+	case token.LT:
+		fn = "LessThan"
+	case token.LE:
+		fn = "LessEqual"
+	case token.GT:
+		fn, x, y = "LessThan", y, x
+	case token.GE:
+		fn, x, y = "LessEqual", y, x
+	}
+
+	v := t.temp()
+	t.printf("%s, err := aot.%s(th, %s, %s, %s)\n", v, fn, t.pos(e.OpPos), x, y)
+	t.check()
+
+	if e.Op == token.NE {
+		return "!" + v
+	}
+	return v
+}
+
+// cond evaluates e as a condition, and returns a Go bool expression.
+func (t *translator) cond(e ast.Expr) string {
+	switch e := e.(type) {
+	case *ast.BasicLit:
+		switch e.Token.Type {
+		case token.NIL, token.FALSE:
+			return "false"
+		}
+		return "true"
+	case *ast.ParenExpr:
+		return t.cond(e.X)
+	case *ast.UnaryExpr:
+		if e.Op == token.NOT {
+			return fmt.Sprintf("!(%s)", t.cond(e.X))
+		}
+	case *ast.BinaryExpr:
+		switch e.Op {
+		case token.EQ, token.NE, token.LT, token.LE, token.GT, token.GE:
+			return t.compare(e)
+		case token.AND, token.OR:
+			v := t.temp()
+			t.printf("%s := %s\n", v, t.cond(e.X))
+			if e.Op == token.AND {
+				t.printf("if %s {\n", v)
+			} else {
+				t.printf("if !%s {\n", v)
+			}
+			t.printf("%s = %s\n", v, t.cond(e.Y))
+			t.printf("}\n")
+			return v
+		}
+	}
+	return fmt.Sprintf("aot.Truth(%s)", t.expr(e))
+}
@@ -8,7 +8,10 @@ import (
 	"strings"
 
 	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/cache"
+	"github.com/hirochachacha/plua/compiler/codegen"
 	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/cover"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/position"
 	"github.com/hirochachacha/plua/runtime"
@@ -17,19 +20,38 @@ import (
 	isatty "github.com/mattn/go-isatty"
 )
 
+var (
+	cpuprofile  = flag.String("cpuprofile", "", "write a profile of Lua functions to `file`")
+	profilemode = flag.String("profilemode", "sample", "profiling mode, \"sample\" or \"count\"")
+
+	coverage     = flag.Bool("cover", false, "report line coverage of Lua sources")
+	coverprofile = flag.String("coverprofile", "", "write a coverage profile to `file`, merged with the existing one (lcov format if the name ends with .info or .lcov)")
+	coverhtml    = flag.String("coverhtml", "", "write an HTML coverage report to `file`")
+
+	optimize = flag.Bool("O", false, "enable optimizations of the compiler")
+	backend  = flag.String("backend", "interpreter", "execution backend, \"interpreter\" or \"closure\"")
+	cachedir = flag.String("cachedir", "", "cache compiled chunks of required modules in `dir`")
+)
+
+var compileCache *cache.Cache
+
 func main() {
 	flag.Usage = func() {
-		fmt.Fprintln(os.Stderr, "usage: luaexec [file]")
+		fmt.Fprintln(os.Stderr, "usage: luaexec [flags] [file [args ...]]")
 		flag.PrintDefaults()
 	}
 
 	flag.Parse()
 
+	if *cachedir != "" {
+		compileCache = cache.New(*cachedir)
+	}
+
 	switch {
-	case len(os.Args) >= 2:
-		c := compiler.NewCompiler()
+	case flag.NArg() >= 1:
+		c := newCompiler()
 
-		proto, err := c.CompileFile(os.Args[1], compiler.Either)
+		proto, err := c.CompileFile(flag.Arg(0), compiler.Either)
 		if err != nil {
 			object.PrintError(err)
 			os.Exit(1)
@@ -37,7 +59,7 @@ func main() {
 
 		exec(proto)
 	case !isatty.IsTerminal(os.Stdin.Fd()):
-		c := compiler.NewCompiler()
+		c := newCompiler()
 
 		proto, err := c.Compile(os.Stdin, "=stdin", compiler.Either)
 		if err != nil {
@@ -52,15 +74,59 @@ func main() {
 
 }
 
+func newCompiler() *compiler.Compiler {
+	c := compiler.NewCompiler()
+
+	if *optimize {
+		c.Mode = codegen.Optimize
+	}
+
+	return c
+}
+
+func setBackend(p object.Process) {
+	var b runtime.Backend
+
+	switch *backend {
+	case "interpreter":
+		b = runtime.Interpreter
+	case "closure":
+		b = runtime.ClosureCompiler
+	default:
+		fmt.Fprintf(os.Stderr, "luaexec: unknown backend %q\n", *backend)
+		os.Exit(2)
+	}
+
+	if err := runtime.SetBackend(p, b); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func setCompileOptions(p object.Process) {
+	opts := runtime.CompileOptions{Cache: compileCache}
+
+	if *optimize {
+		opts.Mode = codegen.Optimize
+	}
+
+	if err := runtime.SetCompileOptions(p, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
 func exec(proto *object.Proto) {
 	p := runtime.NewProcess()
 
 	var a object.Table
 
-	if len(os.Args) >= 2 {
-		a = p.NewTableSize(len(os.Args)-2, 2)
+	if flag.NArg() >= 1 {
+		script := len(os.Args) - flag.NArg()
+
+		a = p.NewTableSize(flag.NArg()-1, script+1)
 		for i, arg := range os.Args {
-			a.Set(object.Integer(i-1), object.String(arg))
+			a.Set(object.Integer(i-script), object.String(arg))
 		}
 	} else {
 		a = p.NewTableSize(0, 1)
@@ -71,13 +137,71 @@ func exec(proto *object.Proto) {
 
 	p.Require("", stdlib.Open)
 
+	setBackend(p)
+	setCompileOptions(p)
+
+	var prof *runtime.Profiler
+
+	if *cpuprofile != "" {
+		switch *profilemode {
+		case "sample":
+			prof = runtime.NewProfiler(runtime.ProfileSample)
+		case "count":
+			prof = runtime.NewProfiler(runtime.ProfileCount)
+		default:
+			fmt.Fprintf(os.Stderr, "luaexec: unknown profiling mode %q\n", *profilemode)
+			os.Exit(2)
+		}
+
+		if err := prof.Start(p); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	var cov *runtime.Coverage
+
+	if *coverage || *coverprofile != "" || *coverhtml != "" {
+		cov = runtime.NewCoverage()
+
+		if err := cov.Start(p); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
 	_, err := p.Exec(proto)
+
+	if cov != nil {
+		cov.Stop()
+
+		if werr := writeCoverage(cov.Profile()); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			os.Exit(1)
+		}
+	}
+
+	if prof != nil {
+		prof.Stop()
+
+		if werr := writeProfile(prof, *cpuprofile); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			os.Exit(1)
+		}
+	}
+
 	if err != nil {
 		object.PrintError(err)
 		os.Exit(1)
 	}
 }
 
+func writeProfile(prof *runtime.Profiler, path string) error {
+	return writeFile(path, func(f *os.File) error {
+		return prof.WriteProfile(f)
+	})
+}
+
 func eof(s string) position.Position {
 	line := 0
 	column := 0
@@ -104,12 +228,15 @@ func isIncomplete(code string, err error) bool {
 }
 
 func interact() {
-	c := compiler.NewCompiler()
+	c := newCompiler()
 
 	p := runtime.NewProcess()
 
 	p.Require("", stdlib.Open)
 
+	setBackend(p)
+	setCompileOptions(p)
+
 	stdin := bufio.NewScanner(os.Stdin)
 
 	var code string
@@ -183,3 +310,68 @@ func interact() {
 		p = p.Fork()
 	}
 }
+
+func writeCoverage(prof *cover.Profile) error {
+	if *coverprofile != "" {
+		lcov := strings.HasSuffix(*coverprofile, ".info") || strings.HasSuffix(*coverprofile, ".lcov")
+
+		if f, err := os.Open(*coverprofile); err == nil {
+			var old *cover.Profile
+			if lcov {
+				old, err = cover.ReadLcov(f)
+			} else {
+				old, err = cover.ReadGoCover(f)
+			}
+			f.Close()
+			if err != nil {
+				return err
+			}
+
+			old.Merge(prof)
+
+			prof = old
+		}
+
+		err := writeFile(*coverprofile, func(f *os.File) error {
+			if lcov {
+				return prof.WriteLcov(f)
+			}
+			return prof.WriteGoCover(f)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if *coverhtml != "" {
+		err := writeFile(*coverhtml, func(f *os.File) error {
+			return prof.WriteHTML(f)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	covered, total := prof.Coverage()
+	if total > 0 {
+		fmt.Fprintf(os.Stderr, "coverage: %.1f%% of lines\n", 100*float64(covered)/float64(total))
+	} else {
+		fmt.Fprintln(os.Stderr, "coverage: [no lines]")
+	}
+
+	return nil
+}
+
+func writeFile(path string, write func(f *os.File) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
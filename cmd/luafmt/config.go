@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+)
+
+// configName is the name of configuration files.
+// luafmt uses the nearest one found by searching up the directory tree.
+//
+// each line of a configuration file has the form "name = value",
+// where name is one of styleFlags. blank lines and lines starting with '#' are ignored.
+// flags specified on the command line take precedence.
+const configName = ".luafmt"
+
+var styleFlags = []string{"indent", "tabs", "quote", "trailing-sep", "width", "op-space", "concat-space"}
+
+var configs = make(map[string]*printer.Config) // directory to config
+
+func printerConfig(dir string) (*printer.Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg, ok := configs[dir]; ok {
+		return cfg, nil
+	}
+
+	values := make(map[string]string)
+	for _, name := range styleFlags {
+		values[name] = flag.Lookup(name).DefValue
+	}
+
+	if path := findConfig(dir); path != "" {
+		if err := loadConfig(path, values); err != nil {
+			return nil, err
+		}
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		if _, ok := values[f.Name]; ok {
+			values[f.Name] = f.Value.String()
+		}
+	})
+
+	cfg, err := newConfig(values)
+	if err != nil {
+		return nil, err
+	}
+
+	configs[dir] = cfg
+
+	return cfg, nil
+}
+
+// findConfig returns the path of the nearest configuration file from dir, or "".
+func findConfig(dir string) string {
+	for {
+		path := filepath.Join(dir, configName)
+		if fi, err := os.Stat(path); err == nil && !fi.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+func loadConfig(path string, values map[string]string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" || s[0] == '#' {
+			continue
+		}
+
+		i := strings.IndexByte(s, '=')
+		if i == -1 {
+			return fmt.Errorf("%s:%d: expected name = value", path, line)
+		}
+
+		name := strings.TrimSpace(s[:i])
+		if _, ok := values[name]; !ok {
+			return fmt.Errorf("%s:%d: unknown option %q", path, line, name)
+		}
+
+		values[name] = strings.TrimSpace(s[i+1:])
+	}
+
+	return sc.Err()
+}
+
+func newConfig(values map[string]string) (*printer.Config, error) {
+	cfg := new(printer.Config)
+
+	var err error
+
+	cfg.Tabwidth, err = strconv.Atoi(values["indent"])
+	if err != nil || cfg.Tabwidth <= 0 {
+		return nil, fmt.Errorf("invalid indent %q", values["indent"])
+	}
+
+	tabs, err := strconv.ParseBool(values["tabs"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid tabs %q", values["tabs"])
+	}
+	if tabs {
+		cfg.Mode |= printer.TabIndent
+	}
+
+	switch values["quote"] {
+	case "keep":
+		cfg.Quote = printer.QuoteKeep
+	case "double":
+		cfg.Quote = printer.QuoteDouble
+	case "single":
+		cfg.Quote = printer.QuoteSingle
+	default:
+		return nil, fmt.Errorf("invalid quote %q", values["quote"])
+	}
+
+	switch values["trailing-sep"] {
+	case "multiline":
+		cfg.TrailingSep = printer.SepMultiline
+	case "always":
+		cfg.TrailingSep = printer.SepAlways
+	case "never":
+		cfg.TrailingSep = printer.SepNever
+	default:
+		return nil, fmt.Errorf("invalid trailing-sep %q", values["trailing-sep"])
+	}
+
+	cfg.MaxWidth, err = strconv.Atoi(values["width"])
+	if err != nil || cfg.MaxWidth < 0 {
+		return nil, fmt.Errorf("invalid width %q", values["width"])
+	}
+
+	cfg.OpSpacing, err = parseSpacing("op-space", values["op-space"])
+	if err != nil {
+		return nil, err
+	}
+
+	cfg.ConcatSpacing, err = parseSpacing("concat-space", values["concat-space"])
+	if err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func parseSpacing(name, value string) (printer.Spacing, error) {
+	switch value {
+	case "auto":
+		return printer.SpaceAuto, nil
+	case "always":
+		return printer.SpaceAlways, nil
+	case "never":
+		return printer.SpaceNever, nil
+	}
+	return 0, fmt.Errorf("invalid %s %q", name, value)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+)
+
+func TestPrinterConfig(t *testing.T) {
+	root, err := ioutil.TempDir("", "luafmt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	sub := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	data := "# comment\n\nindent = 4\nquote = single\nwidth=80\n"
+	if err := ioutil.WriteFile(filepath.Join(root, "a", configName), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := printerConfig(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := printer.Config{Tabwidth: 4, Quote: printer.QuoteSingle, MaxWidth: 80}
+	if *cfg != want {
+		t.Errorf("got %+v, want %+v", *cfg, want)
+	}
+
+	// flags take precedence
+	if err := flag.Set("tabs", "true"); err != nil {
+		t.Fatal(err)
+	}
+	defer flag.Set("tabs", "false")
+
+	cfg, err = printerConfig(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = printer.Config{Mode: printer.TabIndent, Tabwidth: 2}
+	if *cfg != want {
+		t.Errorf("got %+v, want %+v", *cfg, want)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(sub, configName), []byte("color = red\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	configs = make(map[string]*printer.Config)
+	if _, err := printerConfig(sub); err == nil {
+		t.Error("expected error for unknown option")
+	}
+}
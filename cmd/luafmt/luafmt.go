@@ -43,7 +43,6 @@ import (
 	"runtime"
 	"strings"
 
-	"github.com/hirochachacha/plua/compiler/ast/printer"
 	"github.com/hirochachacha/plua/compiler/parser"
 	"github.com/hirochachacha/plua/compiler/scanner"
 )
@@ -53,11 +52,17 @@ var (
 	list   = flag.Bool("l", false, "list files whose formatting differs from luafmt's")
 	write  = flag.Bool("w", false, "write result to (source) file instead of stdout")
 	doDiff = flag.Bool("d", false, "display diffs instead of rewriting files")
-)
 
-const (
-// tabWidth = 2
-// printerMode = printer.UseSpaces | printer.TabIndent
+	simplifyAST = flag.Bool("s", false, "simplify code")
+
+	// formatting style, these can be set by .luafmt files too
+	_ = flag.Int("indent", 2, "indentation width")
+	_ = flag.Bool("tabs", false, "indent with tabs")
+	_ = flag.String("quote", "keep", "quote style of strings: keep, double or single")
+	_ = flag.String("trailing-sep", "multiline", "trailing separator of tables: multiline, always or never")
+	_ = flag.Int("width", 0, "maximum line width, 0 means no limit")
+	_ = flag.String("op-space", "auto", "spacing around binary operators: auto, always or never")
+	_ = flag.String("concat-space", "auto", "spacing around '..': auto, always or never")
 )
 
 var (
@@ -87,12 +92,15 @@ func processFile(filename string) error {
 	var r io.Reader
 	var src []byte
 	var perm os.FileMode = 0644
+	var dir string
 
 	if filename == "" {
 		srcname = "=stdin"
 		r = os.Stdin
+		dir = "."
 	} else {
 		srcname = "@" + filename
+		dir = filepath.Dir(filename)
 		f, err := os.Open(filename)
 		if err != nil {
 			return err
@@ -115,10 +123,18 @@ func processFile(filename string) error {
 		return err
 	}
 
+	if *simplifyAST {
+		simplify(ast)
+	}
+
+	cfg, err := printerConfig(dir)
+	if err != nil {
+		return err
+	}
+
 	buf.Reset()
 
-	err = printer.Fprint(&buf, ast)
-	// err = (&printer.Config{Mode: printerMode, Tabwidth: tabWidth}).Fprint(&buf, ast)
+	err = cfg.Fprint(&buf, ast)
 	if err != nil {
 		return err
 	}
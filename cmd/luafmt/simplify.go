@@ -0,0 +1,408 @@
+package main
+
+import (
+	"reflect"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/internal/strconv"
+	"github.com/hirochachacha/plua/position"
+)
+
+// simplify rewrites f into a simpler form without changing its meaning:
+//
+//	(x) + 1                  => x + 1
+//	t["name"]                => t.name
+//	local f = function() end => local function f() end
+//	;;                       => (removed)
+//	x = x                    => (removed, if x is a local variable)
+func simplify(f *ast.File) {
+	f.Chunk = simplifyStmts(f.Chunk)
+
+	removeSelfAssigns(f)
+}
+
+func simplifyStmts(stmts []ast.Stmt) []ast.Stmt {
+	list := stmts[:0]
+	for _, stmt := range stmts {
+		if stmt = simplifyStmt(stmt); stmt != nil {
+			list = append(list, stmt)
+		}
+	}
+	return list
+}
+
+func simplifyBlock(block *ast.Block) {
+	if block != nil {
+		block.List = simplifyStmts(block.List)
+	}
+}
+
+// simplifyStmt returns the simplified stmt, or nil if stmt can be removed.
+func simplifyStmt(stmt ast.Stmt) ast.Stmt {
+	switch stmt := stmt.(type) {
+	case *ast.EmptyStmt:
+		return nil
+	case *ast.LocalAssignStmt:
+		simplifyExprs(stmt.RHS)
+		if fn := localFunc(stmt); fn != nil {
+			return fn
+		}
+	case *ast.LocalFuncStmt:
+		simplifyBlock(stmt.Body.Body)
+	case *ast.FuncStmt:
+		simplifyBlock(stmt.Body.Body)
+	case *ast.ExprStmt:
+		simplifyCall(stmt.X)
+	case *ast.AssignStmt:
+		simplifyExprs(stmt.LHS)
+		simplifyExprs(stmt.RHS)
+	case *ast.IfStmt:
+		stmt.Cond = unparen(simplifyExpr(stmt.Cond))
+		simplifyBlock(stmt.Body)
+		for i := range stmt.ElseIfList {
+			e := &stmt.ElseIfList[i]
+			e.Cond = unparen(simplifyExpr(e.Cond))
+			simplifyBlock(e.Body)
+		}
+		simplifyBlock(stmt.ElseBody)
+	case *ast.DoStmt:
+		simplifyBlock(stmt.Body)
+	case *ast.WhileStmt:
+		stmt.Cond = unparen(simplifyExpr(stmt.Cond))
+		simplifyBlock(stmt.Body)
+	case *ast.RepeatStmt:
+		simplifyBlock(stmt.Body)
+		stmt.Cond = unparen(simplifyExpr(stmt.Cond))
+	case *ast.ReturnStmt:
+		simplifyExprs(stmt.Results)
+	case *ast.ForStmt:
+		stmt.Start = unparen(simplifyExpr(stmt.Start))
+		stmt.Finish = unparen(simplifyExpr(stmt.Finish))
+		if stmt.Step != nil {
+			stmt.Step = unparen(simplifyExpr(stmt.Step))
+		}
+		simplifyBlock(stmt.Body)
+	case *ast.ForEachStmt:
+		simplifyExprs(stmt.Exprs)
+		simplifyBlock(stmt.Body)
+	}
+	return stmt
+}
+
+// localFunc returns the local function statement equivalent to stmt, or nil.
+// local f = function() end is equivalent to local function f() end,
+// only if the function doesn't refer f.
+func localFunc(stmt *ast.LocalAssignStmt) *ast.LocalFuncStmt {
+	if len(stmt.LHS) != 1 || len(stmt.RHS) != 1 {
+		return nil
+	}
+
+	fn, ok := stmt.RHS[0].(*ast.FuncLit)
+	if !ok || fn.Func.Line != stmt.Local.Line {
+		return nil
+	}
+
+	name := stmt.LHS[0]
+
+	var refer bool
+	ast.Walk(fn.Body, func(node ast.Node) bool {
+		if n, ok := node.(*ast.Name); ok && n.Name == name.Name {
+			refer = true
+			return true
+		}
+		return false
+	})
+	if refer {
+		return nil
+	}
+
+	return &ast.LocalFuncStmt{
+		Local:  stmt.Local,
+		Func:   fn.Func,
+		Name:   name,
+		Body:   fn.Body,
+		EndPos: fn.EndPos,
+	}
+}
+
+// removeSelfAssigns removes assignments of the form x, y = x, y.
+// assignments to globals are kept, because they may call metamethods of _ENV.
+func removeSelfAssigns(f *ast.File) {
+	info := scope.Resolve(f)
+
+	var lines []lineRange
+
+	filter := func(list []ast.Stmt, opening, closing position.Position) []ast.Stmt {
+		kept := list[:0]
+		for i, stmt := range list {
+			assign, ok := stmt.(*ast.AssignStmt)
+			if !ok || !isSelfAssign(info, assign) {
+				kept = append(kept, stmt)
+				continue
+			}
+
+			prev, next := opening, closing
+			if i > 0 {
+				prev = list[i-1].End()
+			}
+			if i+1 < len(list) {
+				next = list[i+1].Pos()
+			}
+
+			// drop the lines of stmt, if nothing else is on them.
+			r := lineRange{stmt.Pos().Line, stmt.End().Line}
+			if prev.Line < r.from && (next.Line > r.to || !next.IsValid()) && !hasComment(f, r) {
+				lines = append(lines, r)
+			}
+		}
+		return kept
+	}
+
+	f.Chunk = filter(f.Chunk, position.NoPos, position.NoPos)
+
+	ast.Walk(f, func(node ast.Node) bool {
+		if b, ok := node.(*ast.Block); ok {
+			b.List = filter(b.List, b.Opening, b.Closing)
+		}
+		return false
+	})
+
+	// from the bottom, so that ranges above aren't moved.
+	for i := len(lines) - 1; i >= 0; i-- {
+		r := lines[i]
+		shiftLines(reflect.ValueOf(f), r.to, r.to-r.from+1, make(map[uintptr]bool))
+	}
+}
+
+// isSelfAssign reports whether stmt has the form x, y = x, y, where x and y are local variables.
+func isSelfAssign(info *scope.Info, stmt *ast.AssignStmt) bool {
+	if len(stmt.LHS) != len(stmt.RHS) {
+		return false
+	}
+	for i, lhs := range stmt.LHS {
+		x, ok := lhs.(*ast.Name)
+		if !ok {
+			return false
+		}
+		y, ok := stmt.RHS[i].(*ast.Name)
+		if !ok {
+			return false
+		}
+		d := info.DeclOf(x)
+		if d == nil || d != info.DeclOf(y) {
+			return false
+		}
+	}
+	return true
+}
+
+type lineRange struct {
+	from, to int
+}
+
+func hasComment(f *ast.File, r lineRange) bool {
+	for _, g := range f.Comments {
+		for _, c := range g.List {
+			if c.Pos().Line <= r.to && c.Pos().Offset(c.Text).Line >= r.from {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+var positionType = reflect.TypeOf(position.Position{})
+
+// shiftLines moves positions below line after up by n lines.
+// the printer keeps blank lines by positions, so lines of removed statements must be deleted.
+func shiftLines(v reflect.Value, after, n int, visited map[uintptr]bool) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || visited[v.Pointer()] {
+			return
+		}
+		visited[v.Pointer()] = true
+		shiftLines(v.Elem(), after, n, visited)
+	case reflect.Interface:
+		if !v.IsNil() {
+			shiftLines(v.Elem(), after, n, visited)
+		}
+	case reflect.Struct:
+		if v.Type() == positionType {
+			if line := v.FieldByName("Line"); line.CanSet() && line.Int() > int64(after) {
+				line.SetInt(line.Int() - int64(n))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			shiftLines(v.Field(i), after, n, visited)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			shiftLines(v.Index(i), after, n, visited)
+		}
+	}
+}
+
+func simplifyExprs(exprs []ast.Expr) {
+	for i, e := range exprs {
+		exprs[i] = unparen(simplifyExpr(e))
+	}
+}
+
+func simplifyCall(call *ast.CallExpr) {
+	call.X = unparenPrefix(simplifyExpr(call.X))
+	simplifyExprs(call.Args)
+}
+
+func simplifyExpr(expr ast.Expr) ast.Expr {
+	switch expr := expr.(type) {
+	case *ast.FuncLit:
+		simplifyBlock(expr.Body.Body)
+	case *ast.TableLit:
+		simplifyExprs(expr.Fields)
+	case *ast.ParenExpr:
+		expr.X = simplifyExpr(expr.X)
+	case *ast.SelectorExpr:
+		expr.X = unparenPrefix(simplifyExpr(expr.X))
+	case *ast.IndexExpr:
+		expr.X = unparenPrefix(simplifyExpr(expr.X))
+		expr.Index = unparen(simplifyExpr(expr.Index))
+		if name := fieldName(expr.Index); name != "" {
+			// t["name"] => t.name
+			return &ast.SelectorExpr{
+				X:      expr.X,
+				Period: expr.Lbrack,
+				Sel:    &ast.Name{NamePos: expr.Index.Pos(), Name: name},
+			}
+		}
+	case *ast.CallExpr:
+		simplifyCall(expr)
+	case *ast.UnaryExpr:
+		expr.X = simplifyExpr(expr.X)
+		if x, ok := stripParens(expr.X).(*ast.BinaryExpr); ok {
+			if prec, _ := x.Op.Precedence(); prec > token.UnaryPrec {
+				expr.X = x // -(x^2) => -x^2
+			}
+		} else {
+			expr.X = unparenOperand(expr.X)
+		}
+	case *ast.BinaryExpr:
+		expr.X = simplifyExpr(expr.X)
+		expr.Y = simplifyExpr(expr.Y)
+
+		lprec, rprec := expr.Op.Precedence()
+
+		switch x := stripParens(expr.X).(type) {
+		case *ast.BinaryExpr:
+			if _, prec := x.Op.Precedence(); lprec <= prec {
+				expr.X = x // (a*b) + c => a*b + c
+			}
+		case *ast.UnaryExpr:
+			if lprec < token.UnaryPrec {
+				expr.X = x // (-a) + b => -a + b
+			}
+		default:
+			expr.X = unparenOperand(expr.X)
+		}
+
+		switch y := stripParens(expr.Y).(type) {
+		case *ast.BinaryExpr:
+			if prec, _ := y.Op.Precedence(); prec > rprec {
+				expr.Y = y // a + (b*c) => a + b*c
+			}
+		case *ast.UnaryExpr:
+			expr.Y = y // a ^ (-b) => a ^ -b
+		default:
+			expr.Y = unparenOperand(expr.Y)
+		}
+	case *ast.KeyValueExpr:
+		if expr.Lbrack.IsValid() {
+			expr.Key = unparen(simplifyExpr(expr.Key))
+		}
+		expr.Value = unparen(simplifyExpr(expr.Value))
+	}
+	return expr
+}
+
+// stripParens removes all parentheses around expr.
+func stripParens(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// unparen removes parentheses around expr in a value context.
+// parentheses around function calls and '...' are kept, because they truncate results.
+func unparen(expr ast.Expr) ast.Expr {
+	for {
+		paren, ok := expr.(*ast.ParenExpr)
+		if !ok {
+			return expr
+		}
+		switch paren.X.(type) {
+		case *ast.CallExpr, *ast.Vararg:
+			return expr
+		}
+		expr = paren.X
+	}
+}
+
+// unparenPrefix removes parentheses around expr used as a prefix expression,
+// e.g. (t).x => t.x, but ("s"):len() is kept.
+func unparenPrefix(expr ast.Expr) ast.Expr {
+	switch x := stripParens(expr).(type) {
+	case *ast.Name, *ast.SelectorExpr, *ast.IndexExpr, *ast.CallExpr:
+		return x
+	}
+	return expr
+}
+
+// unparenOperand removes parentheses around a primary expression used as an operand.
+func unparenOperand(expr ast.Expr) ast.Expr {
+	switch x := stripParens(expr).(type) {
+	case *ast.Name, *ast.BasicLit, *ast.Vararg, *ast.FuncLit, *ast.TableLit, *ast.SelectorExpr, *ast.IndexExpr, *ast.CallExpr:
+		return x
+	}
+	return expr
+}
+
+// fieldName returns the identifier which key represents, or "".
+func fieldName(key ast.Expr) string {
+	lit, ok := key.(*ast.BasicLit)
+	if !ok || lit.Token.Type != token.STRING {
+		return ""
+	}
+
+	s, err := strconv.Unquote(lit.Token.Lit)
+	if err != nil || !isName(s) {
+		return ""
+	}
+
+	return s
+}
+
+func isName(s string) bool {
+	if s == "" || token.Lookup(s) != token.NAME {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case '0' <= c && c <= '9':
+			if i == 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
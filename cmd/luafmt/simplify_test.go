@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+)
+
+var simplifyTests = []struct {
+	src  string
+	want string
+}{
+	// parentheses
+	{"x = (a) + (1)", "x = a + 1"},
+	{"x = (a * b) + c", "x = a*b + c"},
+	{"x = a * (b + c)", "x = a * (b+c)"},
+	{"x = a - (b - c)", "x = a - (b-c)"},
+	{"x = (a - b) - c", "x = a - b - c"},
+	{"x = (a .. b) .. c", "x = (a..b) .. c"},
+	{"x = (-a) ^ 2", "x = (-a) ^ 2"},
+	{"x = (-a) + 2", "x = -a + 2"},
+	{"x = -(a ^ 2)", "x = -a ^ 2"},
+	{"x = -(a + 2)", "x = -(a+2)"},
+	{"x = (t).y, (t)[1], (f)()", "x = t.y, t[1], f()"},
+	{`x = ("s"):rep(3), ({}).x`, `x = ("s"):rep(3), ({}).x`},
+	{"return (f())", "return (f())"},
+	{"return ((x))", "return x"},
+	{"if (a) then end", "if a then end"},
+	{"f((g()), ((h)))", "f((g()), h)"},
+
+	// selectors
+	{`x = t["name"]`, "x = t.name"},
+	{`t["a"]["b"] = 1`, "t.a.b = 1"},
+	{`x = t["end"], t["1a"], t["a b"]`, `x = t["end"], t["1a"], t["a b"]`},
+
+	// local functions
+	{"local f = function(x) return x end", "local function f(x) return x end"},
+	{"local f = function(n) return f(n) end", "local f = function(n) return f(n) end"},
+	{"local f, g = function() end", "local f, g = function() end"},
+
+	// no-ops
+	{"a = 1;; b = 2;", "a = 1; b = 2"},
+	{"local x = f\n;(a or b)()", "local x = f\n;(a or b)()"},
+	{"local x = f\n;((a))()", "local x = f\na()"},
+	{"local x, y\ny = x\nx = x", "local x, y\ny = x"},
+	{"local x, y\nx, y = x, y", "local x, y"},
+	{"local x, y\nx, y = y, x", "local x, y\nx, y = y, x"},
+	{"local x\nx = x\nprint(x)", "local x\nprint(x)"},
+	{"local x\n\nx = x\nprint(x)", "local x\n\nprint(x)"},
+	{"local x\ndo\n  x = x\n  print(x)\nend", "local x\ndo\n  print(x)\nend"},
+	{"local x\nfunction f()\n  x = x\nend", "local x\nfunction f()\nend"},
+	{"local x\nx = x -- c\nprint(x)", "local x\n-- c\nprint(x)"},
+	{"x = x", "x = x"},
+	{"local x\nx = y", "local x\nx = y"},
+	{"local t\nt.x = t.x", "local t\nt.x = t.x"},
+}
+
+func TestSimplify(t *testing.T) {
+	for _, test := range simplifyTests {
+		f, err := parser.Parse(scanner.Scan(strings.NewReader(test.src), "=test", scanner.ScanComments), parser.ParseComments)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		simplify(f)
+
+		var buf bytes.Buffer
+
+		if err := printer.Fprint(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != test.want {
+			t.Errorf("%q: got %q, want %q", test.src, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/position"
+)
+
+// available checks.
+var checks = []struct {
+	name string
+	doc  string
+}{
+	{"global", "assignment to a non-standard global variable"},
+	{"undefined", "access to an undefined global variable"},
+	{"unused", "unused local variable or function"},
+	{"unused-param", "unused function parameter"},
+	{"shadow", "local declaration which shadows another local"},
+	{"unreachable", "statement after return, goto or break"},
+	{"arity", "wrong number of arguments to a standard function"},
+	{"dupkey", "duplicate constant key in a table literal"},
+}
+
+func isCheck(name string) bool {
+	for _, c := range checks {
+		if c.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+type config struct {
+	checks  map[string]bool // enabled checks
+	globals map[string]bool // additional global variables
+}
+
+func newConfig() *config {
+	cfg := &config{
+		checks:  make(map[string]bool),
+		globals: make(map[string]bool),
+	}
+	for _, c := range checks {
+		cfg.checks[c.name] = true
+	}
+	return cfg
+}
+
+type Diagnostic struct {
+	Filename string `json:"file"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	Check    string `json:"check"`
+	Message  string `json:"message"`
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s (%s)", d.Filename, d.Line, d.Column, d.Message, d.Check)
+}
+
+type byPos []*Diagnostic
+
+func (ds byPos) Len() int      { return len(ds) }
+func (ds byPos) Swap(i, j int) { ds[i], ds[j] = ds[j], ds[i] }
+func (ds byPos) Less(i, j int) bool {
+	if ds[i].Line != ds[j].Line {
+		return ds[i].Line < ds[j].Line
+	}
+	return ds[i].Column < ds[j].Column
+}
+
+type linter struct {
+	cfg      *config
+	filename string
+	diags    []*Diagnostic
+}
+
+func (l *linter) report(pos position.Position, check, format string, args ...interface{}) {
+	if !l.cfg.checks[check] {
+		return
+	}
+
+	l.diags = append(l.diags, &Diagnostic{
+		Filename: l.filename,
+		Line:     pos.Line,
+		Column:   pos.Column,
+		Check:    check,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// lint runs enabled checks over f, src is used to locate suppression comments.
+func lint(filename string, f *ast.File, src []byte, cfg *config) []*Diagnostic {
+	l := &linter{cfg: cfg, filename: filename}
+
+	info := scope.Resolve(f)
+
+	l.checkGlobals(info)
+	l.checkDecls(info, info.Root)
+
+	l.checkUnreachable(f.Chunk)
+
+	for _, stmt := range f.Chunk {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Block:
+				l.checkUnreachable(node.List)
+			case *ast.ExprStmt:
+				// ast.Walk doesn't visit call expressions of statements
+				l.checkArity(info, node.X)
+			case *ast.CallExpr:
+				l.checkArity(info, node)
+			case *ast.TableLit:
+				l.checkTable(node)
+			}
+			return false
+		})
+	}
+
+	diags := suppress(l.diags, f, src)
+
+	sort.Stable(byPos(diags))
+
+	return diags
+}
+
+// suppression comments:
+//
+//   -- lualint:ignore [check ...]       suppresses diagnostics on the line,
+//                                        or on the next line if the comment stands alone.
+//   -- lualint:ignore-file [check ...]  suppresses diagnostics in the whole file.
+//
+// all checks are suppressed if no check is specified.
+func suppress(diags []*Diagnostic, f *ast.File, src []byte) []*Diagnostic {
+	type key struct {
+		line  int
+		check string
+	}
+
+	ignored := make(map[key]bool)
+
+	lines := strings.Split(string(src), "\n")
+
+	for _, cg := range f.Comments {
+		for _, c := range cg.List {
+			i := strings.Index(c.Text, "lualint:")
+			if i == -1 {
+				continue
+			}
+
+			text := c.Text[i+len("lualint:"):]
+			if j := strings.Index(text, "--"); j != -1 {
+				text = text[:j]
+			}
+
+			fields := strings.FieldsFunc(text, func(r rune) bool {
+				return r == ',' || r == ' ' || r == '\t' || r == ']'
+			})
+			if len(fields) == 0 {
+				continue
+			}
+
+			var lineNums []int
+
+			switch fields[0] {
+			case "ignore":
+				lineNums = append(lineNums, c.Hyphen.Line)
+				if line := c.Hyphen.Line - 1; line < len(lines) && c.Hyphen.Column-1 <= len(lines[line]) {
+					if strings.TrimSpace(lines[line][:c.Hyphen.Column-1]) == "" {
+						lineNums = append(lineNums, c.Hyphen.Line+1)
+					}
+				}
+			case "ignore-file":
+				lineNums = append(lineNums, 0)
+			default:
+				continue
+			}
+
+			names := fields[1:]
+			if len(names) == 0 {
+				names = []string{""}
+			}
+
+			for _, line := range lineNums {
+				for _, name := range names {
+					ignored[key{line, name}] = true
+				}
+			}
+		}
+	}
+
+	if len(ignored) == 0 {
+		return diags
+	}
+
+	var ds []*Diagnostic
+	for _, d := range diags {
+		if ignored[key{0, ""}] || ignored[key{0, d.Check}] || ignored[key{d.Line, ""}] || ignored[key{d.Line, d.Check}] {
+			continue
+		}
+		ds = append(ds, d)
+	}
+
+	return ds
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+)
+
+var (
+	enable  = flag.String("enable", "", "comma-separated list of checks to run (default all)")
+	disable = flag.String("disable", "", "comma-separated list of checks to skip")
+	globals = flag.String("globals", "", "comma-separated list of additional global variables")
+	asJSON  = flag.Bool("json", false, "print diagnostics in JSON")
+)
+
+var (
+	exitCode = 0
+
+	diags []*Diagnostic
+)
+
+func report(err error) {
+	if list, ok := err.(parser.ErrorList); ok {
+		for _, err := range list {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	} else {
+		fmt.Fprintln(os.Stderr, err)
+	}
+	exitCode = 2
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: lualint [flags] [path ...]\n")
+	flag.PrintDefaults()
+	fmt.Fprintf(os.Stderr, "\nchecks:\n")
+	for _, c := range checks {
+		fmt.Fprintf(os.Stderr, "  %-14s %s\n", c.name, c.doc)
+	}
+}
+
+func isLuaFile(f os.FileInfo) bool {
+	name := f.Name()
+	return !f.IsDir() && !strings.HasPrefix(name, ".") && strings.HasSuffix(name, ".lua")
+}
+
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func parseConfig() (*config, error) {
+	cfg := newConfig()
+
+	if *enable != "" {
+		for name := range cfg.checks {
+			cfg.checks[name] = false
+		}
+		for _, name := range splitList(*enable) {
+			if !isCheck(name) {
+				return nil, fmt.Errorf("unknown check: %s", name)
+			}
+			cfg.checks[name] = true
+		}
+	}
+
+	for _, name := range splitList(*disable) {
+		if !isCheck(name) {
+			return nil, fmt.Errorf("unknown check: %s", name)
+		}
+		cfg.checks[name] = false
+	}
+
+	for _, name := range splitList(*globals) {
+		cfg.globals[name] = true
+	}
+
+	return cfg, nil
+}
+
+func processFile(filename string, cfg *config) error {
+	var srcname string
+	var r io.Reader
+
+	if filename == "" {
+		srcname = "=stdin"
+		r = os.Stdin
+	} else {
+		srcname = "@" + filename
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	src, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	ast, err := parser.Parse(scanner.Scan(bytes.NewReader(src), srcname, scanner.ScanComments), parser.ParseComments|parser.AllErrors)
+	if err != nil {
+		return err
+	}
+
+	if filename == "" {
+		filename = "<stdin>"
+	}
+
+	diags = append(diags, lint(filename, ast, src, cfg)...)
+
+	return nil
+}
+
+func walkDir(path string, cfg *config) {
+	filepath.Walk(path, func(path string, f os.FileInfo, err error) error {
+		if err == nil && isLuaFile(f) {
+			err = processFile(path, cfg)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			report(err)
+		}
+		return nil
+	})
+}
+
+func main() {
+	lualintMain()
+	os.Exit(exitCode)
+}
+
+func lualintMain() {
+	flag.Usage = usage
+	flag.Parse()
+
+	cfg, err := parseConfig()
+	if err != nil {
+		report(err)
+		return
+	}
+
+	if flag.NArg() == 0 {
+		if err := processFile("", cfg); err != nil {
+			report(err)
+		}
+	}
+
+	for i := 0; i < flag.NArg(); i++ {
+		path := flag.Arg(i)
+		switch dir, err := os.Stat(path); {
+		case err != nil:
+			report(err)
+		case dir.IsDir():
+			walkDir(path, cfg)
+		default:
+			if err := processFile(path, cfg); err != nil {
+				report(err)
+			}
+		}
+	}
+
+	if *asJSON {
+		if diags == nil {
+			diags = []*Diagnostic{}
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(diags); err != nil {
+			report(err)
+		}
+	} else {
+		for _, d := range diags {
+			fmt.Println(d)
+		}
+	}
+
+	if len(diags) > 0 && exitCode == 0 {
+		exitCode = 1
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+)
+
+func lintFile(t *testing.T, filename string, cfg *config) []*Diagnostic {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := parser.Parse(scanner.Scan(bytes.NewReader(src), "@"+filename, scanner.ScanComments), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return lint(filename, f, src, cfg)
+}
+
+// wants collects expectations written as "-- want: check ..." comments.
+func wants(t *testing.T, filename string) map[int][]string {
+	src, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := make(map[int][]string)
+	for i, line := range strings.Split(string(src), "\n") {
+		if j := strings.Index(line, "-- want:"); j != -1 {
+			m[i+1] = strings.Fields(line[j+len("-- want:"):])
+		}
+	}
+	return m
+}
+
+func TestLint(t *testing.T) {
+	filename := "testdata/lint.lua"
+
+	got := make(map[int][]string)
+	for _, d := range lintFile(t, filename, newConfig()) {
+		got[d.Line] = append(got[d.Line], d.Check)
+	}
+	for _, checks := range got {
+		sort.Strings(checks)
+	}
+
+	want := wants(t, filename)
+
+	for line, checks := range want {
+		if !reflect.DeepEqual(got[line], checks) {
+			t.Errorf("line %d: got %v, want %v", line, got[line], checks)
+		}
+	}
+	for line, checks := range got {
+		if _, ok := want[line]; !ok {
+			t.Errorf("line %d: unexpected %v", line, checks)
+		}
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg := newConfig()
+	cfg.checks["global"] = false
+	cfg.globals["undefinedvar"] = true
+
+	for _, d := range lintFile(t, "testdata/lint.lua", cfg) {
+		switch d.Check {
+		case "global":
+			t.Errorf("unexpected diagnostic of disabled check: %v", d)
+		case "undefined":
+			t.Errorf("unexpected diagnostic of allowed global: %v", d)
+		}
+	}
+}
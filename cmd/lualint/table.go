@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/internal/strconv"
+	"github.com/hirochachacha/plua/position"
+)
+
+func (l *linter) checkTable(tab *ast.TableLit) {
+	seen := make(map[interface{}]position.Position)
+
+	add := func(key interface{}, pos position.Position, repr string) {
+		if prev, ok := seen[key]; ok {
+			l.report(pos, "dupkey", "duplicate key %s in table literal (previous at %d:%d)", repr, prev.Line, prev.Column)
+			return
+		}
+		seen[key] = pos
+	}
+
+	var n int64
+
+	for _, e := range tab.Fields {
+		kv, ok := e.(*ast.KeyValueExpr)
+		if !ok {
+			n++
+			add(n, e.Pos(), fmt.Sprint(n))
+			continue
+		}
+
+		if !kv.Lbrack.IsValid() {
+			if name, ok := kv.Key.(*ast.Name); ok {
+				add(name.Name, name.Pos(), fmt.Sprintf("%q", name.Name))
+			}
+			continue
+		}
+
+		if key, repr, ok := constKey(kv.Key); ok {
+			add(key, kv.Key.Pos(), repr)
+		}
+	}
+}
+
+// constKey returns the normalized table key of a literal.
+func constKey(e ast.Expr) (key interface{}, repr string, ok bool) {
+	lit, ok := e.(*ast.BasicLit)
+	if !ok {
+		return nil, "", false
+	}
+
+	switch lit.Token.Type {
+	case token.STRING:
+		s, err := strconv.Unquote(lit.Token.Lit)
+		if err != nil {
+			return nil, "", false
+		}
+		return s, fmt.Sprintf("%q", s), true
+	case token.INT:
+		i, err := strconv.ParseInt(lit.Token.Lit)
+		if err != nil {
+			return nil, "", false
+		}
+		return i, lit.Token.Lit, true
+	case token.FLOAT:
+		f, err := strconv.ParseFloat(lit.Token.Lit)
+		if err != nil || math.IsNaN(f) {
+			return nil, "", false
+		}
+		// float keys with integral values are normalized to integers.
+		if i := int64(f); float64(i) == f {
+			return i, lit.Token.Lit, true
+		}
+		return f, lit.Token.Lit, true
+	case token.TRUE:
+		return true, "true", true
+	case token.FALSE:
+		return false, "false", true
+	}
+
+	return nil, "", false
+}
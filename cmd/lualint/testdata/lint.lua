@@ -0,0 +1,100 @@
+local used = 1
+local unused = 2 -- want: unused
+local _ignored = 3
+
+counter = 0 -- want: global
+
+print(used, undefinedvar) -- want: undefined
+
+local function helper(a, b) -- want: unused-param
+  return a
+end
+
+helper(1)
+
+local function outer(x)
+  local x = x + 1 -- want: shadow
+  return x
+end
+
+outer(1)
+
+for i = 1, 10 do -- want: unused
+  print("loop")
+end
+
+for _, v in ipairs({}) do
+  print(v)
+end
+
+local function early(n)
+  if n then
+    return 1
+  else
+    error("no")
+  end
+  print("reachable")
+end
+
+early(1)
+
+local function early2()
+  do
+    return 2
+  end
+  print("never") -- want: unreachable
+end
+
+local function early3(n)
+  if n then return 1 elseif n == nil then return 2 else return 3 end
+  print("never") -- want: unreachable
+end
+
+early2()
+early3()
+
+while true do
+  break
+  print("after break") -- want: unreachable
+end
+
+do
+  goto skip
+  ::skip::
+  print("reachable")
+end
+
+print(type(1, 2)) -- want: arity
+print(string.rep("x")) -- want: arity
+print(string.rep("x", 3))
+rawset({}, 1, 2, 3) -- want: arity
+print(string.format("%d %d", 1, 2))
+print(tostring(table.unpack({1})))
+
+local t = {a = 1, a = 2} -- want: dupkey
+local u = {1, [1] = 2, ["b"] = 3, b = 4} -- want: dupkey dupkey
+local w = {[1.0] = 1, [1] = 2, [true] = 1, [false] = 2} -- want: dupkey
+print(t, u, w)
+
+local obj = {}
+
+function obj:method(arg1)
+  return self, arg1
+end
+
+function obj.func(self)
+  return self
+end
+
+function counter_inc() -- want: global
+  counter = counter + 1
+end
+
+repeat
+  local done = true
+until done
+
+local ignored = 1 -- lualint:ignore unused
+-- lualint:ignore
+local alsoignored = 2
+local notignored = 3 -- lualint:ignore shadow -- want: unused
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/hirochachacha/plua/compiler/ast"
+)
+
+func (l *linter) checkUnreachable(list []ast.Stmt) {
+	terminated := false
+
+	for _, stmt := range list {
+		if _, ok := stmt.(*ast.LabelStmt); ok {
+			terminated = false
+		}
+
+		if terminated {
+			if _, ok := stmt.(*ast.EmptyStmt); !ok {
+				l.report(stmt.Pos(), "unreachable", "unreachable code")
+				return
+			}
+		}
+
+		if isTerminating(stmt) {
+			terminated = true
+		}
+	}
+}
+
+// isTerminating reports whether the execution never proceeds to the next statement.
+func isTerminating(stmt ast.Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ReturnStmt, *ast.GotoStmt, *ast.BreakStmt:
+		return true
+	case *ast.DoStmt:
+		return isTerminatingList(stmt.Body.List)
+	case *ast.IfStmt:
+		if stmt.ElseBody == nil || !isTerminatingList(stmt.Body.List) || !isTerminatingList(stmt.ElseBody.List) {
+			return false
+		}
+		for _, e := range stmt.ElseIfList {
+			if !isTerminatingList(e.Body.List) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isTerminatingList(list []ast.Stmt) bool {
+	terminated := false
+	for _, stmt := range list {
+		if _, ok := stmt.(*ast.LabelStmt); ok {
+			terminated = false
+		}
+		if isTerminating(stmt) {
+			terminated = true
+		}
+	}
+	return terminated
+}
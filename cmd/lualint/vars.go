@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+)
+
+func isIgnored(name string) bool {
+	return strings.HasPrefix(name, "_")
+}
+
+func (l *linter) isGlobal(name string) bool {
+	return stdGlobals[name] || l.cfg.globals[name]
+}
+
+func (l *linter) checkGlobals(info *scope.Info) {
+	for name, refs := range info.Globals {
+		if l.isGlobal(name) {
+			continue
+		}
+
+		defined := false
+		for _, ref := range refs {
+			if info.Binding(ref).Write {
+				l.report(ref.Pos(), "global", "setting non-standard global variable %q", name)
+				defined = true
+				break
+			}
+		}
+
+		if defined {
+			continue
+		}
+
+		for _, ref := range refs {
+			l.report(ref.Pos(), "undefined", "accessing undefined variable %q", name)
+		}
+	}
+}
+
+func (l *linter) checkDecls(info *scope.Info, s *scope.Scope) {
+	for i, d := range s.Decls {
+		if d.IsImplicit() || isIgnored(d.Name) {
+			continue
+		}
+
+		l.checkShadow(s, i, d)
+		l.checkUnused(info, d)
+	}
+
+	for _, child := range s.Children {
+		l.checkDecls(info, child)
+	}
+}
+
+func (l *linter) checkShadow(s *scope.Scope, i int, d *scope.Decl) {
+	var prev *scope.Decl
+
+	for j := i - 1; j >= 0; j-- {
+		if s.Decls[j].Name == d.Name {
+			prev = s.Decls[j]
+			break
+		}
+	}
+
+	if prev == nil && s.Parent != nil {
+		prev = s.Parent.Lookup(d.Name, d.Pos())
+	}
+
+	if prev == nil || prev.Kind == scope.Self {
+		return
+	}
+
+	l.report(d.Pos(), "shadow", "declaration of %q shadows declaration at %d:%d", d.Name, prev.Pos().Line, prev.Pos().Column)
+}
+
+func (l *linter) checkUnused(info *scope.Info, d *scope.Decl) {
+	for _, ref := range d.Refs {
+		if !info.Binding(ref).Write {
+			return
+		}
+	}
+
+	switch d.Kind {
+	case scope.LocalVar:
+		l.report(d.Pos(), "unused", "unused local variable %q", d.Name)
+	case scope.LocalFunc:
+		l.report(d.Pos(), "unused", "unused local function %q", d.Name)
+	case scope.ForVar:
+		l.report(d.Pos(), "unused", "unused loop variable %q", d.Name)
+	case scope.Param:
+		l.report(d.Pos(), "unused-param", "unused parameter %q", d.Name)
+	}
+}
+
+func (l *linter) checkArity(info *scope.Info, call *ast.CallExpr) {
+	if call.Name != nil {
+		return // method call
+	}
+
+	isGlobal := func(name *ast.Name) bool {
+		b := info.Binding(name)
+		return b != nil && b.Kind == scope.Global
+	}
+
+	var fname string
+
+	switch fn := call.X.(type) {
+	case *ast.Name:
+		if !isGlobal(fn) {
+			return
+		}
+		fname = fn.Name
+	case *ast.SelectorExpr:
+		x, ok := fn.X.(*ast.Name)
+		if !ok || !isGlobal(x) {
+			return
+		}
+		fname = x.Name + "." + fn.Sel.Name
+	default:
+		return
+	}
+
+	a, ok := stdArities[fname]
+	if !ok {
+		return
+	}
+
+	nargs := len(call.Args)
+	variadic := false
+
+	// the last argument may expand to any number of values
+	if nargs > 0 {
+		switch call.Args[nargs-1].(type) {
+		case *ast.CallExpr, *ast.Vararg:
+			nargs--
+			variadic = true
+		}
+	}
+
+	switch {
+	case nargs < a.min && !variadic:
+		l.report(call.Pos(), "arity", "too few arguments in call to %s (want at least %d, have %d)", fname, a.min, nargs)
+	case a.max >= 0 && nargs > a.max:
+		l.report(call.Pos(), "arity", "too many arguments in call to %s (want at most %d, have %d)", fname, a.max, nargs)
+	}
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/position"
+)
+
+// lines converts positions between the compiler and LSP.
+// the compiler counts columns by bytes from 1, LSP counts characters by UTF-16 code units from 0.
+type lines []string
+
+func splitLines(text string) lines {
+	return lines(strings.Split(text, "\n"))
+}
+
+func (ls lines) toLSP(pos position.Position) Position {
+	line := pos.Line - 1
+	if line < 0 {
+		return Position{}
+	}
+	if line >= len(ls) {
+		return ls.end()
+	}
+
+	s := ls[line]
+
+	off := pos.Column - 1
+	if off < 0 {
+		off = 0
+	}
+	if off > len(s) {
+		off = len(s)
+	}
+
+	return Position{Line: line, Character: utf16Len(s[:off])}
+}
+
+func (ls lines) fromLSP(pos Position) position.Position {
+	if pos.Line < 0 || pos.Line >= len(ls) {
+		return position.NoPos
+	}
+
+	s := ls[pos.Line]
+
+	off := 0
+	for n := 0; off < len(s) && n < pos.Character; {
+		r, size := utf8.DecodeRuneInString(s[off:])
+		n += len(utf16.Encode([]rune{r}))
+		off += size
+	}
+
+	return position.Position{Line: pos.Line + 1, Column: off + 1}
+}
+
+func (ls lines) toRange(from, to position.Position) Range {
+	return Range{Start: ls.toLSP(from), End: ls.toLSP(to)}
+}
+
+func (ls lines) end() Position {
+	last := len(ls) - 1
+	return Position{Line: last, Character: utf16Len(ls[last])}
+}
+
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
+
+type document struct {
+	uri  string
+	text string
+
+	lines lines
+
+	err error // syntax errors of text
+
+	// the last successfully parsed version of text.
+	file      *ast.File
+	fileLines lines
+	info      *scope.Info
+
+	names     []*ast.Name             // all names in order of position
+	selectors map[*ast.Name]*ast.Name // selector name to the name of prefix expression, e.g. format to string
+	funcs     map[*ast.Name]string    // declaring name to signature of function
+}
+
+func newDocument(uri, text string) *document {
+	d := &document{uri: uri}
+	d.update(text)
+	return d
+}
+
+func (d *document) update(text string) {
+	d.text = text
+	d.lines = splitLines(text)
+
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(text), "@"+d.uri, scanner.ScanComments), parser.ParseComments|parser.AllErrors)
+	if err != nil {
+		d.err = err
+		return
+	}
+
+	d.err = nil
+	d.file = f
+	d.fileLines = d.lines
+	d.info = scope.Resolve(f)
+
+	d.index()
+}
+
+func (d *document) index() {
+	d.names = nil
+	d.selectors = make(map[*ast.Name]*ast.Name)
+	d.funcs = make(map[*ast.Name]string)
+
+	for _, stmt := range d.file.Chunk {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.Name:
+				d.names = append(d.names, node)
+			case *ast.SelectorExpr:
+				if x, ok := node.X.(*ast.Name); ok {
+					d.selectors[node.Sel] = x
+				}
+			case *ast.LocalFuncStmt:
+				d.funcs[node.Name] = "local function " + node.Name.Name + params(node.Body)
+			case *ast.FuncStmt:
+				d.funcs[node.Name] = "function " + funcName(node) + params(node.Body)
+			case *ast.LocalAssignStmt:
+				for i, name := range node.LHS {
+					if i < len(node.RHS) {
+						if fn, ok := node.RHS[i].(*ast.FuncLit); ok {
+							d.funcs[name] = "local function " + name.Name + params(fn.Body)
+						}
+					}
+				}
+			}
+			return false
+		})
+	}
+
+	sort.Sort(byPos(d.names))
+}
+
+type byPos []*ast.Name
+
+func (ns byPos) Len() int           { return len(ns) }
+func (ns byPos) Swap(i, j int)      { ns[i], ns[j] = ns[j], ns[i] }
+func (ns byPos) Less(i, j int) bool { return ns[i].Pos().LessThan(ns[j].Pos()) }
+
+func funcName(stmt *ast.FuncStmt) string {
+	var parts []string
+	for _, name := range stmt.PathList {
+		parts = append(parts, name.Name)
+	}
+	if len(parts) == 0 {
+		return stmt.Name.Name
+	}
+	sep := "."
+	if stmt.AccessTok == token.COLON {
+		sep = ":"
+	}
+	return strings.Join(parts, ".") + sep + stmt.Name.Name
+}
+
+func params(body *ast.FuncBody) string {
+	var ps []string
+	for _, name := range body.Params.List {
+		ps = append(ps, name.Name)
+	}
+	if body.Params.Ellipsis.IsValid() {
+		ps = append(ps, "...")
+	}
+	return "(" + strings.Join(ps, ", ") + ")"
+}
+
+// nameAt returns the name at pos, pos can be just after the name.
+func (d *document) nameAt(pos position.Position) *ast.Name {
+	i := sort.Search(len(d.names), func(i int) bool {
+		return pos.LessThan(d.names[i].Pos())
+	})
+	if i == 0 {
+		return nil
+	}
+
+	name := d.names[i-1]
+	if name.Pos().Line == pos.Line && pos.Column <= name.Pos().Column+len(name.Name) {
+		return name
+	}
+	return nil
+}
+
+func (d *document) location(node ast.Node) Location {
+	return Location{URI: d.uri, Range: d.fileLines.toRange(node.Pos(), node.End())}
+}
+
+// definition returns the declaring name of the variable which name refers to.
+// for globals, it returns the first assignment.
+func (d *document) definition(name *ast.Name) *ast.Name {
+	if decl := d.info.DeclOf(name); decl != nil {
+		return decl.Ident
+	}
+
+	if b := d.info.Binding(name); b != nil && b.Kind == scope.Global {
+		for _, ref := range d.info.Globals[name.Name] {
+			if d.info.Binding(ref).Write {
+				return ref
+			}
+		}
+	}
+
+	return nil
+}
+
+// references returns the references of the variable which name refers to.
+func (d *document) references(name *ast.Name, includeDecl bool) []*ast.Name {
+	if decl := d.info.DeclOf(name); decl != nil {
+		var refs []*ast.Name
+		if includeDecl && decl.Ident != nil {
+			refs = append(refs, decl.Ident)
+		}
+		return append(refs, decl.Refs...)
+	}
+
+	if b := d.info.Binding(name); b != nil && b.Kind == scope.Global {
+		def := d.definition(name)
+
+		var refs []*ast.Name
+		for _, ref := range d.info.Globals[name.Name] {
+			if ref != def || includeDecl {
+				refs = append(refs, ref)
+			}
+		}
+		return refs
+	}
+
+	return nil
+}
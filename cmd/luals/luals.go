@@ -0,0 +1,43 @@
+// Luals is a language server for Lua.
+//
+// It communicates with clients by the language server protocol over stdin and stdout.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+var logfile = flag.String("logfile", "", "write logs to file")
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "usage: luals [flags]\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	s := newServer(os.Stdin, os.Stdout)
+
+	if *logfile != "" {
+		f, err := os.Create(*logfile)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		defer f.Close()
+
+		s.logger = log.New(f, "luals: ", log.LstdFlags)
+	}
+
+	code, err := s.serve()
+	if err != nil {
+		s.logger.Println(err)
+	}
+
+	os.Exit(code)
+}
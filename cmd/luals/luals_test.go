@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// client is an in-process LSP client connected to a server.
+type client struct {
+	t *testing.T
+
+	conn *conn
+	id   int
+
+	responses     chan *message
+	notifications chan *message
+	exit          chan int
+}
+
+func newClient(t *testing.T) *client {
+	sr, cw := io.Pipe()
+	cr, sw := io.Pipe()
+
+	c := &client{
+		t:             t,
+		conn:          newConn(cr, cw),
+		responses:     make(chan *message, 16),
+		notifications: make(chan *message, 16),
+		exit:          make(chan int, 1),
+	}
+
+	go func() {
+		code, err := newServer(sr, sw).serve()
+		if err != nil {
+			t.Error(err)
+		}
+		c.exit <- code
+		sw.Close()
+	}()
+
+	go func() {
+		for {
+			msg, err := c.conn.read()
+			if err != nil {
+				close(c.responses)
+				close(c.notifications)
+				return
+			}
+			if msg.Method == "" {
+				c.responses <- msg
+			} else {
+				c.notifications <- msg
+			}
+		}
+	}()
+
+	return c
+}
+
+func (c *client) call(method string, params, result interface{}) *rpcError {
+	c.id++
+
+	id, err := rawJSON(c.id)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	raw, err := rawJSON(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+
+	if err := c.conn.write(&message{ID: id, Method: method, Params: raw}); err != nil {
+		c.t.Fatal(err)
+	}
+
+	select {
+	case msg := <-c.responses:
+		if string(*msg.ID) != string(*id) {
+			c.t.Fatalf("%s: unexpected response id %s", method, *msg.ID)
+		}
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil {
+			if err := json.Unmarshal(*msg.Result, result); err != nil {
+				c.t.Fatal(err)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		c.t.Fatalf("%s: timeout", method)
+	}
+
+	return nil
+}
+
+func (c *client) notify(method string, params interface{}) {
+	if err := c.conn.notify(method, params); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+func (c *client) diagnostics() *PublishDiagnosticsParams {
+	select {
+	case msg := <-c.notifications:
+		if msg.Method != "textDocument/publishDiagnostics" {
+			c.t.Fatalf("unexpected notification %s", msg.Method)
+		}
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(*msg.Params, &p); err != nil {
+			c.t.Fatal(err)
+		}
+		return &p
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("diagnostics: timeout")
+	}
+	return nil
+}
+
+const testURI = "file:///test.lua"
+
+var testCode = `local function add(a, b)
+  return a + b
+end
+
+counter = add(1, 2)
+
+function counter_inc()
+  counter = counter + 1
+end
+
+print(string.format("%d", counter))
+`
+
+func pos(line, char int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: testURI},
+		Position:     Position{Line: line, Character: char},
+	}
+}
+
+func TestServer(t *testing.T) {
+	c := newClient(t)
+
+	var init InitializeResult
+	if err := c.call("initialize", map[string]interface{}{}, &init); err != nil {
+		t.Fatal(err)
+	}
+	if !init.Capabilities.HoverProvider || init.Capabilities.TextDocumentSync != syncFull {
+		t.Errorf("unexpected capabilities: %+v", init.Capabilities)
+	}
+	c.notify("initialized", map[string]interface{}{})
+
+	// syntax error
+	c.notify("textDocument/didOpen", &DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: testURI, LanguageID: "lua", Version: 1, Text: "local x = )\nlocal y = ("},
+	})
+	diags := c.diagnostics()
+	if len(diags.Diagnostics) != 2 || diags.Diagnostics[0].Range.Start.Line != 0 || diags.Diagnostics[1].Range.Start.Line != 1 {
+		t.Errorf("unexpected diagnostics: %+v", diags)
+	}
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 2},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: testCode}},
+	})
+	if diags := c.diagnostics(); len(diags.Diagnostics) != 0 {
+		t.Errorf("unexpected diagnostics: %+v", diags)
+	}
+
+	// document symbols
+	var syms []SymbolInformation
+	if err := c.call("textDocument/documentSymbol", &DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &syms); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, sym := range syms {
+		names = append(names, sym.Name)
+	}
+	if strings.Join(names, " ") != "counter add counter_inc" {
+		t.Errorf("unexpected symbols: %v", names)
+	}
+
+	// definition of a local
+	var locs []Location
+	if err := c.call("textDocument/definition", pos(4, 11), &locs); err != nil {
+		t.Fatal(err)
+	}
+	if len(locs) != 1 || locs[0].Range.Start != (Position{0, 15}) || locs[0].Range.End != (Position{0, 18}) {
+		t.Errorf("unexpected definition: %+v", locs)
+	}
+
+	// definition of a global
+	locs = nil
+	if err := c.call("textDocument/definition", pos(10, 28), &locs); err != nil {
+		t.Fatal(err)
+	}
+	if len(locs) != 1 || locs[0].Range.Start != (Position{4, 0}) {
+		t.Errorf("unexpected definition: %+v", locs)
+	}
+
+	// references
+	refs := []Location{}
+	params := &ReferenceParams{TextDocumentPositionParams: pos(4, 0), Context: ReferenceContext{IncludeDeclaration: true}}
+	if err := c.call("textDocument/references", params, &refs); err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 4 {
+		t.Errorf("expected 4 references, got %+v", refs)
+	}
+	params.Position = Position{1, 9}
+	if err := c.call("textDocument/references", params, &refs); err != nil {
+		t.Fatal(err)
+	}
+	if len(refs) != 2 {
+		t.Errorf("expected 2 references, got %+v", refs)
+	}
+
+	// hover
+	for _, test := range []struct {
+		pos  TextDocumentPositionParams
+		want string
+	}{
+		{pos(10, 15), "function string.format(formatstring, ···)"},
+		{pos(10, 1), "function print(···)"},
+		{pos(4, 11), "local function add(a, b)"},
+		{pos(1, 9), "(parameter) a"},
+		{pos(7, 3), "global counter"},
+		{pos(10, 8), "module string"},
+	} {
+		var h Hover
+		if err := c.call("textDocument/hover", test.pos, &h); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(h.Contents.Value, test.want) {
+			t.Errorf("hover at %v: expected %q, got %q", test.pos.Position, test.want, h.Contents.Value)
+		}
+	}
+
+	// completion
+	var items []CompletionItem
+	if err := c.call("textDocument/completion", pos(2, 0), &items); err != nil {
+		t.Fatal(err)
+	}
+	labels := make(map[string]bool)
+	for _, item := range items {
+		labels[item.Label] = true
+	}
+	for _, want := range []string{"add", "counter", "counter_inc", "string", "print", "local"} {
+		if !labels[want] {
+			t.Errorf("expected %q in completion items", want)
+		}
+	}
+	if labels["a"] {
+		t.Error("unexpected parameter a in completion items")
+	}
+
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 3},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: testCode + "string.fo"}},
+	})
+	c.diagnostics()
+
+	items = nil
+	if err := c.call("textDocument/completion", pos(11, 9), &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Label != "format" {
+		t.Errorf("unexpected completion items: %+v", items)
+	}
+
+	// formatting
+	c.notify("textDocument/didChange", &DidChangeTextDocumentParams{
+		TextDocument:   VersionedTextDocumentIdentifier{URI: testURI, Version: 4},
+		ContentChanges: []TextDocumentContentChangeEvent{{Text: "local  x =   1\nprint( x )\n"}},
+	})
+	c.diagnostics()
+
+	var edits []TextEdit
+	if err := c.call("textDocument/formatting", &DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: testURI}}, &edits); err != nil {
+		t.Fatal(err)
+	}
+	if len(edits) != 1 || edits[0].NewText != "local x = 1\nprint(x)" {
+		t.Errorf("unexpected edits: %+v", edits)
+	}
+
+	if err := c.call("unknown/method", nil, nil); err == nil || err.Code != codeMethodNotFound {
+		t.Errorf("expected method not found error, got %v", err)
+	}
+
+	if err := c.call("shutdown", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	c.notify("exit", nil)
+
+	select {
+	case code := <-c.exit:
+		if code != 0 {
+			t.Errorf("expected exit code 0, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("exit: timeout")
+	}
+}
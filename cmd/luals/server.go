@@ -0,0 +1,582 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/position"
+)
+
+type handler func(s *server, params *json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*server).initialize,
+	"initialized":                 (*server).initialized,
+	"shutdown":                    (*server).shutdown,
+	"textDocument/didOpen":        (*server).didOpen,
+	"textDocument/didChange":      (*server).didChange,
+	"textDocument/didClose":       (*server).didClose,
+	"textDocument/documentSymbol": (*server).documentSymbol,
+	"textDocument/definition":     (*server).definition,
+	"textDocument/references":     (*server).references,
+	"textDocument/hover":          (*server).hover,
+	"textDocument/completion":     (*server).completion,
+	"textDocument/formatting":     (*server).formatting,
+}
+
+type server struct {
+	conn *conn
+
+	docs map[string]*document
+
+	isShutdown bool
+
+	logger *log.Logger
+}
+
+func newServer(r io.Reader, w io.Writer) *server {
+	return &server{
+		conn:   newConn(r, w),
+		docs:   make(map[string]*document),
+		logger: log.New(ioutil.Discard, "", 0),
+	}
+}
+
+// serve handles messages until the exit notification or EOF,
+// and returns the exit code.
+func (s *server) serve() (int, error) {
+	for {
+		msg, err := s.conn.read()
+		if err != nil {
+			if err == io.EOF {
+				return 1, nil
+			}
+			if rerr, ok := err.(*rpcError); ok {
+				if err := s.conn.reply(nil, nil, rerr); err != nil {
+					return 1, err
+				}
+				continue
+			}
+			return 1, err
+		}
+
+		if msg.Method == "exit" {
+			if s.isShutdown {
+				return 0, nil
+			}
+			return 1, nil
+		}
+
+		if err := s.handle(msg); err != nil {
+			return 1, err
+		}
+	}
+}
+
+func (s *server) handle(msg *message) error {
+	s.logger.Printf("<- %s", msg.Method)
+
+	h, ok := handlers[msg.Method]
+	if !ok {
+		if msg.ID == nil {
+			return nil // ignore unknown notifications
+		}
+		return s.conn.reply(msg.ID, nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method})
+	}
+
+	result, err := h(s, msg.Params)
+	if msg.ID == nil {
+		if err != nil {
+			s.logger.Printf("%s: %v", msg.Method, err)
+		}
+		return nil
+	}
+
+	return s.conn.reply(msg.ID, result, err)
+}
+
+func decode(params *json.RawMessage, v interface{}) error {
+	if params == nil {
+		return &rpcError{Code: codeInvalidParams, Message: "missing params"}
+	}
+	if err := json.Unmarshal(*params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *server) document(uri string) (*document, error) {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil, &rpcError{Code: codeInvalidParams, Message: "unknown document: " + uri}
+	}
+	return d, nil
+}
+
+// ----------------------------------------------------------------------------
+// Lifecycle
+
+func (s *server) initialize(params *json.RawMessage) (interface{}, error) {
+	return &InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:       syncFull,
+			DocumentSymbolProvider: true,
+			DefinitionProvider:     true,
+			ReferencesProvider:     true,
+			HoverProvider:          true,
+			CompletionProvider: &CompletionOptions{
+				TriggerCharacters: []string{".", ":"},
+			},
+			DocumentFormattingProvider: true,
+		},
+	}, nil
+}
+
+func (s *server) initialized(params *json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *server) shutdown(params *json.RawMessage) (interface{}, error) {
+	s.isShutdown = true
+	return nil, nil
+}
+
+// ----------------------------------------------------------------------------
+// Synchronization
+
+func (s *server) didOpen(params *json.RawMessage) (interface{}, error) {
+	var p DidOpenTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d := newDocument(p.TextDocument.URI, p.TextDocument.Text)
+
+	s.docs[d.uri] = d
+
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *server) didChange(params *json.RawMessage) (interface{}, error) {
+	var p DidChangeTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	// full synchronization, the last change is the whole text.
+	if n := len(p.ContentChanges); n > 0 {
+		d.update(p.ContentChanges[n-1].Text)
+	}
+
+	return nil, s.publishDiagnostics(d)
+}
+
+func (s *server) didClose(params *json.RawMessage) (interface{}, error) {
+	var p DidCloseTextDocumentParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	delete(s.docs, p.TextDocument.URI)
+
+	return nil, s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         p.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *server) publishDiagnostics(d *document) error {
+	diags := []Diagnostic{}
+
+	var errs []error
+
+	switch err := d.err.(type) {
+	case nil:
+	case parser.ErrorList:
+		errs = err
+	default:
+		errs = []error{err}
+	}
+
+	for _, err := range errs {
+		var pos position.Position
+		var msg string
+
+		switch err := err.(type) {
+		case *parser.Error:
+			pos, msg = err.Pos, err.Err.Error()
+		case *scanner.Error:
+			pos, msg = err.Pos, err.Err.Error()
+		default:
+			pos, msg = position.Position{Line: 1, Column: 1}, err.Error()
+		}
+
+		start := d.lines.toLSP(pos)
+		end := start
+		end.Character++
+
+		diags = append(diags, Diagnostic{
+			Range:    Range{Start: start, End: end},
+			Severity: severityError,
+			Source:   "luals",
+			Message:  msg,
+		})
+	}
+
+	return s.conn.notify("textDocument/publishDiagnostics", &PublishDiagnosticsParams{
+		URI:         d.uri,
+		Diagnostics: diags,
+	})
+}
+
+// ----------------------------------------------------------------------------
+// Language features
+
+func (s *server) documentSymbol(params *json.RawMessage) (interface{}, error) {
+	var p DocumentSymbolParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	syms := []SymbolInformation{}
+
+	if d.file == nil {
+		return syms, nil
+	}
+
+	add := func(name string, kind int, node ast.Node) {
+		syms = append(syms, SymbolInformation{Name: name, Kind: kind, Location: d.location(node)})
+	}
+
+	// top level variables
+	for _, stmt := range d.file.Chunk {
+		switch stmt := stmt.(type) {
+		case *ast.LocalAssignStmt:
+			for _, name := range stmt.LHS {
+				if _, ok := d.funcs[name]; !ok {
+					add(name.Name, symbolVariable, stmt)
+				}
+			}
+		case *ast.AssignStmt:
+			for _, e := range stmt.LHS {
+				if name, ok := e.(*ast.Name); ok && d.definition(name) == name {
+					add(name.Name, symbolVariable, stmt)
+				}
+			}
+		}
+	}
+
+	// functions
+	for _, stmt := range d.file.Chunk {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LocalFuncStmt:
+				add(node.Name.Name, symbolFunction, node)
+			case *ast.FuncStmt:
+				kind := symbolFunction
+				if node.PathList != nil {
+					kind = symbolMethod
+				}
+				add(funcName(node), kind, node)
+			case *ast.LocalAssignStmt:
+				for i, name := range node.LHS {
+					if _, ok := d.funcs[name]; ok {
+						add(name.Name, symbolFunction, node.RHS[i])
+					}
+				}
+			}
+			return false
+		})
+	}
+
+	return syms, nil
+}
+
+func (s *server) nameAt(p *TextDocumentPositionParams) (*document, *ast.Name, error) {
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if d.file == nil {
+		return d, nil, nil
+	}
+
+	return d, d.nameAt(d.fileLines.fromLSP(p.Position)), nil
+}
+
+func (s *server) definition(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, name, err := s.nameAt(&p)
+	if err != nil || name == nil {
+		return nil, err
+	}
+
+	def := d.definition(name)
+	if def == nil {
+		return nil, nil
+	}
+
+	return []Location{d.location(def)}, nil
+}
+
+func (s *server) references(params *json.RawMessage) (interface{}, error) {
+	var p ReferenceParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, name, err := s.nameAt(&p.TextDocumentPositionParams)
+	if err != nil || name == nil {
+		return nil, err
+	}
+
+	locs := []Location{}
+	for _, ref := range d.references(name, p.Context.IncludeDeclaration) {
+		locs = append(locs, d.location(ref))
+	}
+
+	return locs, nil
+}
+
+func (s *server) hover(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, name, err := s.nameAt(&p)
+	if err != nil || name == nil {
+		return nil, err
+	}
+
+	text := d.describe(name)
+	if text == "" {
+		return nil, nil
+	}
+
+	r := d.fileLines.toRange(name.Pos(), name.End())
+
+	return &Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: "```lua\n" + text + "\n```"},
+		Range:    &r,
+	}, nil
+}
+
+// describe returns a short description of name for hover.
+func (d *document) describe(name *ast.Name) string {
+	if x, ok := d.selectors[name]; ok {
+		if b := d.info.Binding(x); b != nil && b.Kind == scope.Global {
+			qname := x.Name + "." + name.Name
+			if sig, ok := stdFuncs[qname]; ok {
+				return "function " + sig
+			}
+			if v, ok := stdVars[qname]; ok {
+				return v
+			}
+		}
+		return ""
+	}
+
+	if decl := d.info.DeclOf(name); decl != nil {
+		if decl.Ident != nil {
+			if sig, ok := d.funcs[decl.Ident]; ok {
+				return sig
+			}
+		}
+		if decl.Kind == scope.LocalVar {
+			return "local " + decl.Name
+		}
+		return fmt.Sprintf("(%s) %s", decl.Kind, decl.Name)
+	}
+
+	b := d.info.Binding(name)
+	if b == nil {
+		return ""
+	}
+
+	if sig, ok := stdFuncs[name.Name]; ok {
+		return "function " + sig
+	}
+	if v, ok := stdVars[name.Name]; ok {
+		return v
+	}
+	if isStdModule(name.Name) {
+		return "module " + name.Name
+	}
+	if def := d.definition(name); def != nil {
+		if sig, ok := d.funcs[def]; ok {
+			return sig
+		}
+	}
+
+	return "global " + name.Name
+}
+
+func (s *server) completion(params *json.RawMessage) (interface{}, error) {
+	var p TextDocumentPositionParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := d.lines.fromLSP(p.Position)
+	if !pos.IsValid() {
+		return []CompletionItem{}, nil
+	}
+
+	line := d.lines[pos.Line-1][:pos.Column-1]
+
+	// identifier being typed
+	i := len(line)
+	for i > 0 && isIdent(line[i-1]) {
+		i--
+	}
+	prefix := line[i:]
+
+	// module member
+	if i > 0 && (line[i-1] == '.' || line[i-1] == ':') {
+		j := i - 1
+		for j > 0 && isIdent(line[j-1]) {
+			j--
+		}
+
+		items := []CompletionItem{}
+
+		if mod := line[j : i-1]; line[i-1] == '.' && isStdModule(mod) {
+			for _, member := range stdMembers(mod) {
+				if !strings.HasPrefix(member, prefix) {
+					continue
+				}
+				qname := mod + "." + member
+				if sig, ok := stdFuncs[qname]; ok {
+					items = append(items, CompletionItem{Label: member, Kind: completionFunction, Detail: sig})
+				} else {
+					items = append(items, CompletionItem{Label: member, Kind: completionField, Detail: qname})
+				}
+			}
+		}
+
+		return items, nil
+	}
+
+	items := []CompletionItem{}
+	seen := make(map[string]bool)
+
+	add := func(item CompletionItem) {
+		if seen[item.Label] || !strings.HasPrefix(item.Label, prefix) {
+			return
+		}
+		seen[item.Label] = true
+		items = append(items, item)
+	}
+
+	if d.info != nil {
+		// locals, innermost first
+		for sc := d.info.Root.Innermost(pos); sc != nil; sc = sc.Parent {
+			for k := len(sc.Decls) - 1; k >= 0; k-- {
+				decl := sc.Decls[k]
+				if pos.LessThan(decl.Visible) {
+					continue
+				}
+				kind := completionVariable
+				if decl.Kind == scope.LocalFunc {
+					kind = completionFunction
+				}
+				add(CompletionItem{Label: decl.Name, Kind: kind, Detail: decl.Kind.String()})
+			}
+		}
+
+		// globals defined in the document
+		for name, refs := range d.info.Globals {
+			for _, ref := range refs {
+				if d.info.Binding(ref).Write {
+					add(CompletionItem{Label: name, Kind: completionVariable, Detail: "global"})
+					break
+				}
+			}
+		}
+	}
+
+	for _, name := range stdGlobals() {
+		switch {
+		case isStdModule(name):
+			add(CompletionItem{Label: name, Kind: completionModule, Detail: "module"})
+		case stdFuncs[name] != "":
+			add(CompletionItem{Label: name, Kind: completionFunction, Detail: stdFuncs[name]})
+		default:
+			add(CompletionItem{Label: name, Kind: completionVariable, Detail: "global"})
+		}
+	}
+
+	for _, kw := range keywords {
+		add(CompletionItem{Label: kw, Kind: completionKeyword})
+	}
+
+	return items, nil
+}
+
+func isIdent(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9'
+}
+
+func (s *server) formatting(params *json.RawMessage) (interface{}, error) {
+	var p DocumentFormattingParams
+	if err := decode(params, &p); err != nil {
+		return nil, err
+	}
+
+	d, err := s.document(p.TextDocument.URI)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.err != nil {
+		return nil, nil // can't format broken sources
+	}
+
+	var buf bytes.Buffer
+
+	if err := printer.Fprint(&buf, d.file); err != nil {
+		return nil, err
+	}
+
+	if buf.String() == d.text {
+		return []TextEdit{}, nil
+	}
+
+	return []TextEdit{
+		{
+			Range:   Range{Start: Position{}, End: d.lines.end()},
+			NewText: buf.String(),
+		},
+	}, nil
+}
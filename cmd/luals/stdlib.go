@@ -0,0 +1,222 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// signatures of standard functions.
+var stdFuncs = map[string]string{
+	"assert":         "assert(v [, message])",
+	"collectgarbage": "collectgarbage([opt [, arg]])",
+	"dofile":         "dofile([filename])",
+	"error":          "error(message [, level])",
+	"getmetatable":   "getmetatable(object)",
+	"ipairs":         "ipairs(t)",
+	"load":           "load(chunk [, chunkname [, mode [, env]]])",
+	"loadfile":       "loadfile([filename [, mode [, env]]])",
+	"next":           "next(table [, index])",
+	"pairs":          "pairs(t)",
+	"pcall":          "pcall(f [, arg1, ···])",
+	"print":          "print(···)",
+	"rawequal":       "rawequal(v1, v2)",
+	"rawget":         "rawget(table, index)",
+	"rawlen":         "rawlen(v)",
+	"rawset":         "rawset(table, index, value)",
+	"require":        "require(modname)",
+	"select":         "select(index, ···)",
+	"setmetatable":   "setmetatable(table, metatable)",
+	"tonumber":       "tonumber(e [, base])",
+	"tostring":       "tostring(v)",
+	"type":           "type(v)",
+	"xpcall":         "xpcall(f, msgh [, arg1, ···])",
+
+	"coroutine.create":      "coroutine.create(f)",
+	"coroutine.isyieldable": "coroutine.isyieldable()",
+	"coroutine.resume":      "coroutine.resume(co [, val1, ···])",
+	"coroutine.running":     "coroutine.running()",
+	"coroutine.status":      "coroutine.status(co)",
+	"coroutine.wrap":        "coroutine.wrap(f)",
+	"coroutine.yield":       "coroutine.yield(···)",
+
+	"debug.debug":        "debug.debug()",
+	"debug.gethook":      "debug.gethook([thread])",
+	"debug.getinfo":      "debug.getinfo([thread,] f [, what])",
+	"debug.getlocal":     "debug.getlocal([thread,] f, local)",
+	"debug.getmetatable": "debug.getmetatable(value)",
+	"debug.getregistry":  "debug.getregistry()",
+	"debug.getupvalue":   "debug.getupvalue(f, up)",
+	"debug.getuservalue": "debug.getuservalue(u)",
+	"debug.sethook":      "debug.sethook([thread,] hook, mask [, count])",
+	"debug.setlocal":     "debug.setlocal([thread,] level, local, value)",
+	"debug.setmetatable": "debug.setmetatable(value, table)",
+	"debug.setupvalue":   "debug.setupvalue(f, up, value)",
+	"debug.setuservalue": "debug.setuservalue(udata, value)",
+	"debug.traceback":    "debug.traceback([thread,] [message [, level]])",
+	"debug.upvalueid":    "debug.upvalueid(f, n)",
+	"debug.upvaluejoin":  "debug.upvaluejoin(f1, n1, f2, n2)",
+
+	"io.close":   "io.close([file])",
+	"io.flush":   "io.flush()",
+	"io.input":   "io.input([file])",
+	"io.lines":   "io.lines([filename, ···])",
+	"io.open":    "io.open(filename [, mode])",
+	"io.output":  "io.output([file])",
+	"io.popen":   "io.popen(prog [, mode])",
+	"io.read":    "io.read(···)",
+	"io.tmpfile": "io.tmpfile()",
+	"io.type":    "io.type(obj)",
+	"io.write":   "io.write(···)",
+
+	"math.abs":        "math.abs(x)",
+	"math.acos":       "math.acos(x)",
+	"math.asin":       "math.asin(x)",
+	"math.atan":       "math.atan(y [, x])",
+	"math.ceil":       "math.ceil(x)",
+	"math.cos":        "math.cos(x)",
+	"math.deg":        "math.deg(x)",
+	"math.exp":        "math.exp(x)",
+	"math.floor":      "math.floor(x)",
+	"math.fmod":       "math.fmod(x, y)",
+	"math.log":        "math.log(x [, base])",
+	"math.max":        "math.max(x, ···)",
+	"math.min":        "math.min(x, ···)",
+	"math.modf":       "math.modf(x)",
+	"math.rad":        "math.rad(x)",
+	"math.random":     "math.random([m [, n]])",
+	"math.randomseed": "math.randomseed(x)",
+	"math.sin":        "math.sin(x)",
+	"math.sqrt":       "math.sqrt(x)",
+	"math.tan":        "math.tan(x)",
+	"math.tointeger":  "math.tointeger(x)",
+	"math.type":       "math.type(x)",
+	"math.ult":        "math.ult(m, n)",
+
+	"os.clock":     "os.clock()",
+	"os.date":      "os.date([format [, time]])",
+	"os.difftime":  "os.difftime(t2, t1)",
+	"os.execute":   "os.execute([command])",
+	"os.exit":      "os.exit([code [, close]])",
+	"os.getenv":    "os.getenv(varname)",
+	"os.remove":    "os.remove(filename)",
+	"os.rename":    "os.rename(oldname, newname)",
+	"os.setlocale": "os.setlocale(locale [, category])",
+	"os.time":      "os.time([table])",
+	"os.tmpname":   "os.tmpname()",
+
+	"string.byte":     "string.byte(s [, i [, j]])",
+	"string.char":     "string.char(···)",
+	"string.dump":     "string.dump(function [, strip])",
+	"string.find":     "string.find(s, pattern [, init [, plain]])",
+	"string.format":   "string.format(formatstring, ···)",
+	"string.gmatch":   "string.gmatch(s, pattern)",
+	"string.gsub":     "string.gsub(s, pattern, repl [, n])",
+	"string.len":      "string.len(s)",
+	"string.lower":    "string.lower(s)",
+	"string.match":    "string.match(s, pattern [, init])",
+	"string.pack":     "string.pack(fmt, v1, v2, ···)",
+	"string.packsize": "string.packsize(fmt)",
+	"string.rep":      "string.rep(s, n [, sep])",
+	"string.reverse":  "string.reverse(s)",
+	"string.sub":      "string.sub(s, i [, j])",
+	"string.unpack":   "string.unpack(fmt, s [, pos])",
+	"string.upper":    "string.upper(s)",
+
+	"table.concat": "table.concat(list [, sep [, i [, j]]])",
+	"table.insert": "table.insert(list, [pos,] value)",
+	"table.move":   "table.move(a1, f, e, t [, a2])",
+	"table.pack":   "table.pack(···)",
+	"table.remove": "table.remove(list [, pos])",
+	"table.sort":   "table.sort(list [, comp])",
+	"table.unpack": "table.unpack(list [, i [, j]])",
+
+	"utf8.char":      "utf8.char(···)",
+	"utf8.codepoint": "utf8.codepoint(s [, i [, j]])",
+	"utf8.codes":     "utf8.codes(s)",
+	"utf8.len":       "utf8.len(s [, i [, j]])",
+	"utf8.offset":    "utf8.offset(s, n [, i])",
+}
+
+// standard variables other than functions.
+var stdVars = map[string]string{
+	"_G":       "_G",
+	"_VERSION": "_VERSION",
+	"_ENV":     "_ENV",
+
+	"math.huge":       "math.huge",
+	"math.maxinteger": "math.maxinteger",
+	"math.mininteger": "math.mininteger",
+	"math.pi":         "math.pi",
+
+	"package.config":    "package.config",
+	"package.cpath":     "package.cpath",
+	"package.loaded":    "package.loaded",
+	"package.path":      "package.path",
+	"package.preload":   "package.preload",
+	"package.searchers": "package.searchers",
+
+	"utf8.charpattern": "utf8.charpattern",
+}
+
+var stdModules = []string{
+	"coroutine",
+	"debug",
+	"goroutine",
+	"io",
+	"math",
+	"os",
+	"package",
+	"string",
+	"table",
+	"utf8",
+}
+
+func isStdModule(name string) bool {
+	for _, mod := range stdModules {
+		if mod == name {
+			return true
+		}
+	}
+	return false
+}
+
+// stdMembers returns sorted member names of the module.
+func stdMembers(mod string) []string {
+	var names []string
+
+	prefix := mod + "."
+	for _, m := range []map[string]string{stdFuncs, stdVars} {
+		for name := range m {
+			if strings.HasPrefix(name, prefix) {
+				names = append(names, name[len(prefix):])
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// stdGlobals returns sorted names of standard global variables.
+func stdGlobals() []string {
+	names := append([]string(nil), stdModules...)
+
+	for _, m := range []map[string]string{stdFuncs, stdVars} {
+		for name := range m {
+			if !strings.Contains(name, ".") {
+				names = append(names, name)
+			}
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+var keywords = []string{
+	"and", "break", "do", "else", "elseif", "end", "false", "for", "function",
+	"goto", "if", "in", "local", "nil", "not", "or", "repeat", "return",
+	"then", "true", "until", "while",
+}
@@ -0,0 +1,567 @@
+// Package asm implements an assembler of Lua 5.3 byte code.
+//
+// The syntax is the listing printed by object.FprintProto,
+// so that a printed prototype can be assembled into an equivalent one.
+// Each function is written as:
+//
+//	main <"=chunk":0,0> (3 instructions)
+//	0+ params, 2 slots, 1 upvalues, 0 locals, 1 constants, 0 functions
+//		1	[1]	GETTABUP 	0 0 -1	; _ENV "x"
+//		2	[1]	RETURN   	0 2
+//		3	[1]	RETURN   	0 1
+//	constants (1):
+//		1	"x"
+//	locals (0):
+//	upvalues (1):
+//		0	_ENV	true	0
+//
+// The header consists of the keyword "main" or "function", the quoted source name,
+// the lines where the function is defined, and the number of instructions.
+// The next line lists the number of parameters, which is followed by '+'
+// if the function is vararg, the max stack size, and the number of upvalues,
+// local variables, constants and nested functions.
+// All counts must match the following sections.
+//
+// Each instruction consists of its index starting from 1, the line number in
+// brackets or [-] if the function has no line information, the opcode name and
+// the operands used by the opcode. Operands of RK mode and constant indices are
+// negative numbers; -1 refers to the first constant. The operand of EXTRAARG
+// is also written as a negative number.
+// The line number may be followed by ':' and the column number, if the function
+// has column information.
+//
+// Constants are nil, true, false, integers, floats, written with '.', an exponent,
+// inf, -inf, nan or -nan, and quoted strings with escape sequences of Lua.
+// Payloads of NaN other than the sign are not preserved.
+// Local variables are written with their names, start pc and end pc,
+// and upvalues are written with their names, whether they are in the stack of
+// the enclosing function and their indices.
+// Names which aren't identifiers are quoted.
+//
+// Nested functions follow the upvalues section in order, depth first.
+// Text after ';' is a comment, and blank lines are ignored.
+package asm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	gostrconv "strconv"
+	"strings"
+
+	"github.com/hirochachacha/plua/internal/strconv"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/position"
+)
+
+var opcodes = make(map[string]opcode.OpCode)
+
+func init() {
+	for op := opcode.MOVE; op < opcode.MaxOpcode; op++ {
+		opcodes[op.Name()] = op
+	}
+}
+
+// Assemble reads a listing from r and returns the main function.
+// srcname is the chunk name used for error positions, such as "@file.lasm".
+func Assemble(r io.Reader, srcname string) (p *object.Proto, err error) {
+	a := &assembler{
+		r:       bufio.NewReader(r),
+		srcname: srcname,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = r.(bailout)
+
+			p = nil
+			err = a.err
+		}
+	}()
+
+	a.next()
+
+	p = a.parseFunc()
+
+	if a.toks != nil {
+		a.errorf("unexpected %s after main function", a.tok().lit)
+	}
+
+	return p, nil
+}
+
+type bailout struct{}
+
+type tokenType int
+
+const (
+	word tokenType = iota
+	str
+	punct
+)
+
+type token struct {
+	typ tokenType
+	lit string
+	col int
+}
+
+type assembler struct {
+	r       *bufio.Reader
+	srcname string
+	err     error
+
+	line int     // current line
+	toks []token // remaining tokens in the current line, or nil at EOF
+}
+
+func (a *assembler) pos(col int) position.Position {
+	return position.Position{SourceName: a.srcname, Line: a.line, Column: col}
+}
+
+func (a *assembler) error(col int, err error) {
+	a.err = &Error{Pos: a.pos(col), Err: err}
+
+	panic(bailout{})
+}
+
+func (a *assembler) errorf(format string, args ...interface{}) {
+	col := 0
+	if len(a.toks) > 0 {
+		col = a.toks[0].col
+	}
+
+	a.error(col, fmt.Errorf(format, args...))
+}
+
+// next advances to the next line which has any tokens.
+func (a *assembler) next() {
+	if len(a.toks) > 0 {
+		a.errorf("unexpected %s", a.toks[0].lit)
+	}
+
+	for {
+		s, err := a.r.ReadString('\n')
+		if len(s) == 0 && err != nil {
+			if err != io.EOF {
+				a.error(0, err)
+			}
+
+			a.toks = nil
+
+			return
+		}
+
+		a.line++
+
+		a.toks = a.scanLine(s)
+		if len(a.toks) > 0 {
+			return
+		}
+	}
+}
+
+func (a *assembler) scanLine(s string) []token {
+	toks := []token{}
+
+	i := 0
+	for i < len(s) {
+		c := s[i]
+
+		switch {
+		case c == ';':
+			return toks
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				a.error(i+1, errors.New("unterminated string"))
+			}
+			toks = append(toks, token{typ: str, lit: s[i : j+1], col: i + 1})
+			i = j + 1
+		case strings.IndexByte("<>:,()[]", c) != -1:
+			toks = append(toks, token{typ: punct, lit: s[i : i+1], col: i + 1})
+			i++
+		default:
+			j := i + 1
+			for j < len(s) && strings.IndexByte(" \t\r\n;\"<>:,()[]", s[j]) == -1 {
+				j++
+			}
+			toks = append(toks, token{typ: word, lit: s[i:j], col: i + 1})
+			i = j
+		}
+	}
+
+	return toks
+}
+
+func (a *assembler) tok() token {
+	if len(a.toks) == 0 {
+		if a.toks == nil {
+			a.error(0, io.ErrUnexpectedEOF)
+		}
+		a.error(0, errors.New("unexpected end of line"))
+	}
+	return a.toks[0]
+}
+
+func (a *assembler) take() token {
+	tok := a.tok()
+	a.toks = a.toks[1:]
+	return tok
+}
+
+func (a *assembler) expect(lit string) {
+	if tok := a.tok(); tok.typ == str || tok.lit != lit {
+		a.errorf("expected %q, found %s", lit, tok.lit)
+	}
+	a.toks = a.toks[1:]
+}
+
+func (a *assembler) parseInt() int {
+	tok := a.tok()
+
+	i, err := gostrconv.Atoi(tok.lit)
+	if tok.typ != word || err != nil {
+		a.errorf("expected integer, found %s", tok.lit)
+	}
+
+	a.toks = a.toks[1:]
+
+	return i
+}
+
+// parseIndex parses an index of an element, which must be i.
+func (a *assembler) parseIndex(i int) {
+	col := a.tok().col
+
+	if a.parseInt() != i {
+		a.error(col, fmt.Errorf("expected index %d", i))
+	}
+}
+
+func (a *assembler) parseCount(section string) int {
+	a.expect(section)
+	a.expect("(")
+	n := a.parseInt()
+	a.expect(")")
+	a.expect(":")
+	a.next()
+	return n
+}
+
+func (a *assembler) parseString() string {
+	tok := a.tok()
+	if tok.typ != str {
+		a.errorf("expected string, found %s", tok.lit)
+	}
+
+	s, err := strconv.Unquote(tok.lit)
+	if err != nil {
+		a.error(tok.col, fmt.Errorf("invalid string %s", tok.lit))
+	}
+
+	a.toks = a.toks[1:]
+
+	return s
+}
+
+func (a *assembler) parseName() string {
+	tok := a.tok()
+	if tok.typ == str {
+		return a.parseString()
+	}
+	if tok.typ != word {
+		a.errorf("expected name, found %s", tok.lit)
+	}
+
+	a.toks = a.toks[1:]
+
+	return tok.lit
+}
+
+func (a *assembler) parseBool() bool {
+	switch a.tok().lit {
+	case "true":
+		a.take()
+		return true
+	case "false":
+		a.take()
+		return false
+	}
+
+	a.errorf("expected boolean, found %s", a.tok().lit)
+
+	panic("unreachable")
+}
+
+func (a *assembler) parseValue() object.Value {
+	tok := a.tok()
+
+	if tok.typ == str {
+		return object.String(a.parseString())
+	}
+
+	if tok.typ != word {
+		a.errorf("expected constant, found %s", tok.lit)
+	}
+
+	a.toks = a.toks[1:]
+
+	switch tok.lit {
+	case "nil":
+		return nil
+	case "true":
+		return object.True
+	case "false":
+		return object.False
+	case "inf":
+		return object.Number(math.Inf(1))
+	case "-inf":
+		return object.Number(math.Inf(-1))
+	case "nan":
+		return object.Number(math.Float64frombits(0x7FF8000000000000))
+	case "-nan":
+		return object.Number(math.Float64frombits(0xFFF8000000000000))
+	}
+
+	if strings.ContainsAny(tok.lit, ".eE") {
+		f, err := gostrconv.ParseFloat(tok.lit, 64)
+		if err != nil {
+			a.error(tok.col, fmt.Errorf("invalid float %s", tok.lit))
+		}
+		return object.Number(f)
+	}
+
+	i, err := gostrconv.ParseInt(tok.lit, 10, 64)
+	if err != nil {
+		a.error(tok.col, fmt.Errorf("invalid constant %s", tok.lit))
+	}
+	return object.Integer(i)
+}
+
+func (a *assembler) parseFunc() *object.Proto {
+	p := new(object.Proto)
+
+	// main <"source":linedefined,lastlinedefined> (n instructions)
+
+	if lit := a.tok().lit; lit != "main" && lit != "function" {
+		a.errorf("expected \"main\" or \"function\", found %s", lit)
+	}
+	a.take()
+	a.expect("<")
+	p.Source = a.parseString()
+	a.expect(":")
+	p.LineDefined = a.parseInt()
+	a.expect(",")
+	p.LastLineDefined = a.parseInt()
+	a.expect(">")
+	a.expect("(")
+	ncode := a.parseInt()
+	a.expect("instructions")
+	a.expect(")")
+	a.next()
+
+	// n+ params, n slots, n upvalues, n locals, n constants, n functions
+
+	tok := a.tok()
+	if strings.HasSuffix(tok.lit, "+") {
+		p.IsVararg = true
+		a.toks[0].lit = tok.lit[:len(tok.lit)-1]
+	}
+	p.NParams = a.parseInt()
+	a.expect("params")
+	a.expect(",")
+	p.MaxStackSize = a.parseInt()
+	a.expect("slots")
+	a.expect(",")
+	nupvals := a.parseInt()
+	a.expect("upvalues")
+	a.expect(",")
+	nlocvars := a.parseInt()
+	a.expect("locals")
+	a.expect(",")
+	nconsts := a.parseInt()
+	a.expect("constants")
+	a.expect(",")
+	nprotos := a.parseInt()
+	a.expect("functions")
+	a.next()
+
+	if p.NParams < 0 || p.NParams > math.MaxUint8 || p.MaxStackSize < 0 || p.MaxStackSize > math.MaxUint8 {
+		a.error(0, errors.New("number of parameters and slots must be in range [0, 255]"))
+	}
+
+	a.parseCode(p, ncode)
+
+	n := a.parseCount("constants")
+	if n != nconsts {
+		a.error(0, fmt.Errorf("expected %d constants, found %d", nconsts, n))
+	}
+	p.Constants = make([]object.Value, n)
+	for i := range p.Constants {
+		a.parseIndex(i + 1)
+		p.Constants[i] = a.parseValue()
+		a.next()
+	}
+
+	n = a.parseCount("locals")
+	if n != nlocvars {
+		a.error(0, fmt.Errorf("expected %d locals, found %d", nlocvars, n))
+	}
+	if n > 0 {
+		p.LocVars = make([]object.LocVar, n)
+	}
+	for i := range p.LocVars {
+		a.parseIndex(i)
+		p.LocVars[i].Name = a.parseName()
+		p.LocVars[i].StartPC = a.parseInt()
+		p.LocVars[i].EndPC = a.parseInt()
+		a.next()
+	}
+
+	n = a.parseCount("upvalues")
+	if n != nupvals {
+		a.error(0, fmt.Errorf("expected %d upvalues, found %d", nupvals, n))
+	}
+	p.Upvalues = make([]object.UpvalueDesc, n)
+	for i := range p.Upvalues {
+		a.parseIndex(i)
+		p.Upvalues[i].Name = a.parseName()
+		p.Upvalues[i].Instack = a.parseBool()
+		p.Upvalues[i].Index = a.parseInt()
+		if p.Upvalues[i].Index < 0 || p.Upvalues[i].Index > math.MaxUint8 {
+			a.error(0, errors.New("upvalue index must be in range [0, 255]"))
+		}
+		a.next()
+	}
+
+	p.Protos = make([]*object.Proto, nprotos)
+	for i := range p.Protos {
+		if a.toks == nil {
+			a.error(0, fmt.Errorf("expected %d functions, found %d", nprotos, i))
+		}
+		p.Protos[i] = a.parseFunc()
+	}
+
+	return p
+}
+
+func (a *assembler) parseCode(p *object.Proto, n int) {
+	p.Code = make([]opcode.Instruction, n)
+
+	var lineInfo []int
+	var columnInfo []int
+
+	for pc := range p.Code {
+		if a.toks == nil || a.tok().lit == "constants" {
+			a.error(0, fmt.Errorf("expected %d instructions, found %d", n, pc))
+		}
+
+		a.parseIndex(pc + 1)
+
+		// [line], [line:column] or [-]
+		a.expect("[")
+		if a.tok().lit == "-" {
+			if pc > 0 && lineInfo != nil {
+				a.errorf("missing line number")
+			}
+			a.take()
+		} else {
+			if pc > 0 && lineInfo == nil {
+				a.errorf("unexpected line number")
+			}
+			lineInfo = append(lineInfo, a.parseInt())
+			if a.tok().lit == ":" {
+				if pc > 0 && columnInfo == nil {
+					a.errorf("unexpected column number")
+				}
+				a.take()
+				columnInfo = append(columnInfo, a.parseInt())
+			} else if columnInfo != nil {
+				a.errorf("missing column number")
+			}
+		}
+		a.expect("]")
+
+		p.Code[pc] = a.parseInst()
+
+		a.next()
+	}
+
+	p.LineInfo = lineInfo
+	p.ColumnInfo = columnInfo
+}
+
+func (a *assembler) parseInst() opcode.Instruction {
+	tok := a.take()
+
+	op, ok := opcodes[tok.lit]
+	if !ok || tok.typ != word {
+		a.error(tok.col, fmt.Errorf("unknown opcode %s", tok.lit))
+	}
+
+	switch op.OpMode() {
+	case opcode.IABC:
+		a_ := a.parseArg(0, opcode.MaxA)
+		b := 0
+		if op.BMode() != opcode.OpArgN {
+			b = a.parseRK()
+		}
+		c := 0
+		if op.CMode() != opcode.OpArgN {
+			c = a.parseRK()
+		}
+		return opcode.ABC(op, a_, b, c)
+	case opcode.IABx:
+		a_ := a.parseArg(0, opcode.MaxA)
+		bx := 0
+		switch op.BMode() {
+		case opcode.OpArgK:
+			bx = -1 - a.parseArg(-1-opcode.MaxBx, -1)
+		case opcode.OpArgU:
+			bx = a.parseArg(0, opcode.MaxBx)
+		}
+		return opcode.ABx(op, a_, bx)
+	case opcode.IAsBx:
+		a_ := a.parseArg(0, opcode.MaxA)
+		sbx := a.parseArg(-opcode.MaxSBx, opcode.MaxBx-opcode.MaxSBx)
+		return opcode.AsBx(op, a_, sbx)
+	case opcode.IAx:
+		ax := -1 - a.parseArg(-1-opcode.MaxAx, -1)
+		return opcode.Ax(op, ax)
+	default:
+		panic("unreachable")
+	}
+}
+
+// parseArg parses an operand in range [min, max].
+func (a *assembler) parseArg(min, max int) int {
+	col := a.tok().col
+
+	x := a.parseInt()
+	if x < min || x > max {
+		a.error(col, fmt.Errorf("operand %d out of range [%d, %d]", x, min, max))
+	}
+
+	return x
+}
+
+// parseRK parses a register or an unsigned value,
+// or a constant index if it is negative.
+func (a *assembler) parseRK() int {
+	x := a.parseArg(-1-opcode.MaxRKIndex, opcode.MaxB)
+	if x < 0 {
+		return opcode.BitRK | (-1 - x)
+	}
+	return x
+}
@@ -0,0 +1,179 @@
+package asm_test
+
+import (
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/asm"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+const testListing = `
+; hand-written listing
+main <"=test":0,0> (6 instructions)
+0+ params, 3 slots, 1 upvalues, 1 locals, 4 constants, 1 functions
+	1	[1]	LOADKX   	0
+	2	[1]	EXTRAARG 	-4	; "long"
+	3	[2]	CLOSURE  	1 0
+	4	[2]	SETTABUP 	0 -1 1	; _ENV "f"
+	5	[3]	LOADK    	2 -2
+	6	[3]	RETURN   	0 1
+
+constants (4):
+	1	"f"
+	2	-1.5e-3
+	3	nil
+	4	"long"
+locals (1):
+	0	"(a name)"	1	6
+upvalues (1):
+	0	_ENV	true	0
+
+function <"=test":2,2> (1 instructions)
+2 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions
+	1	[-]	RETURN   	0 1
+constants (0):
+locals (0):
+upvalues (0):
+`
+
+func TestAssemble(t *testing.T) {
+	p, err := asm.Assemble(strings.NewReader(testListing), "@test.lasm")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []opcode.Instruction{
+		opcode.ABx(opcode.LOADKX, 0, 0),
+		opcode.Ax(opcode.EXTRAARG, 3),
+		opcode.ABx(opcode.CLOSURE, 1, 0),
+		opcode.ABC(opcode.SETTABUP, 0, opcode.BitRK|0, 1),
+		opcode.ABx(opcode.LOADK, 2, 1),
+		opcode.ABC(opcode.RETURN, 0, 1, 0),
+	}
+
+	for i, inst := range want {
+		if p.Code[i] != inst {
+			t.Errorf("pc %d: expected %v, got %v", i, inst, p.Code[i])
+		}
+	}
+
+	if p.Constants[1] != object.Number(-1.5e-3) || p.Constants[2] != nil {
+		t.Errorf("unexpected constants %v", p.Constants)
+	}
+	if p.LocVars[0].Name != "(a name)" {
+		t.Errorf("unexpected local variable %v", p.LocVars[0])
+	}
+	if len(p.Protos) != 1 || p.Protos[0].NParams != 2 || p.Protos[0].LineInfo != nil {
+		t.Errorf("unexpected nested function %v", p.Protos)
+	}
+}
+
+var errorTests = []struct {
+	src string
+	err string
+}{
+	{"main <\"=test\":0,0> (1 instructions)\n0 params", "test.lasm:2: unexpected end of line"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t1\t[1]\tMOV 0 1\n", "test.lasm:3: unknown opcode MOV"},
+	{"main <\"=test\":0,0> (2 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t1\t[1:1]\tMOVE 0 1\n\t2\t[1]\tRETURN 0 1\n", "test.lasm:4: missing column number"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t2\t[1]\tMOVE 0 1\n", "test.lasm:3: expected index 1"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t1\t[1]\tMOVE 0 1 2\n", "test.lasm:3: unexpected 2"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t1\t[1]\tMOVE 256 1\n", "test.lasm:3: operand 256 out of range [0, 255]"},
+	{"main <\"=test\":0,0> (2 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions\n\t1\t[1]\tRETURN 0 1\nconstants (0):\n", "test.lasm:4: expected 2 instructions, found 1"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 1 constants, 0 functions\n\t1\t[1]\tRETURN 0 1\nconstants (1):\n\t1\t\"x\n", "test.lasm:5: unterminated string"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 1 constants, 0 functions\n\t1\t[1]\tRETURN 0 1\nconstants (0):\n", "test.lasm:4: expected 1 constants, found 0"},
+	{"main <\"=test\":0,0> (1 instructions)\n0 params, 2 slots, 0 upvalues, 0 locals, 0 constants, 1 functions\n\t1\t[1]\tRETURN 0 1\nconstants (0):\nlocals (0):\nupvalues (0):\n", "test.lasm:6: expected 1 functions, found 0"},
+}
+
+func TestAssembleError(t *testing.T) {
+	for _, test := range errorTests {
+		_, err := asm.Assemble(strings.NewReader(test.src), "@test.lasm")
+		if err == nil {
+			t.Errorf("%q: expected error", test.src)
+		} else if !strings.HasSuffix(err.Error(), test.err) {
+			t.Errorf("%q: expected %q, got %q", test.src, test.err, err)
+		}
+	}
+}
+
+// TestAssembleCorpus checks that listings of compiled chunks are assembled
+// into the same prototypes.
+func TestAssembleCorpus(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("..", "..", "_fuzz", "corpus", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if testing.Short() && len(names) > 300 {
+		names = names[:300]
+	}
+
+	c := compiler.NewCompiler()
+
+	for _, name := range names {
+		p, err := c.CompileFile(name, compiler.Either)
+		if err != nil {
+			continue
+		}
+
+		var listing bytes.Buffer
+
+		err = object.FprintProto(&listing, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		q, err := asm.Assemble(bytes.NewReader(listing.Bytes()), name)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+
+			continue
+		}
+
+		canonicalizeNaN(p)
+
+		if !bytes.Equal(dumpProto(t, p), dumpProto(t, q)) {
+			t.Errorf("%s: prototype is changed", name)
+		}
+
+		var relisting bytes.Buffer
+
+		err = object.FprintProto(&relisting, q)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if listing.String() != relisting.String() {
+			t.Errorf("%s: listing is changed", name)
+		}
+	}
+}
+
+// canonicalizeNaN clears payloads of NaN constants, which are not printed.
+func canonicalizeNaN(p *object.Proto) {
+	for i, k := range p.Constants {
+		if n, ok := k.(object.Number); ok && n != n {
+			p.Constants[i] = object.Number(math.Copysign(math.Float64frombits(0x7FF8000000000000), float64(n)))
+		}
+	}
+	for _, q := range p.Protos {
+		canonicalizeNaN(q)
+	}
+}
+
+func dumpProto(t *testing.T, p *object.Proto) []byte {
+	var buf bytes.Buffer
+
+	err := dump.DumpTo(&buf, p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,369 @@
+// Original: golang.org/x/tools/go/ast/astutil/rewrite.go
+//
+// Copyright 2017 The Go Authors. All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are
+// met:
+//
+//    * Redistributions of source code must retain the above copyright
+// notice, this list of conditions and the following disclaimer.
+//    * Redistributions in binary form must reproduce the above
+// copyright notice, this list of conditions and the following disclaimer
+// in the documentation and/or other materials provided with the
+// distribution.
+//    * Neither the name of Google Inc. nor the names of its
+// contributors may be used to endorse or promote products derived from
+// this software without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS
+// "AS IS" AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT
+// LIMITED TO, THE IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR
+// A PARTICULAR PURPOSE ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT
+// OWNER OR CONTRIBUTORS BE LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL,
+// SPECIAL, EXEMPLARY, OR CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT
+// LIMITED TO, PROCUREMENT OF SUBSTITUTE GOODS OR SERVICES; LOSS OF USE,
+// DATA, OR PROFITS; OR BUSINESS INTERRUPTION) HOWEVER CAUSED AND ON ANY
+// THEORY OF LIABILITY, WHETHER IN CONTRACT, STRICT LIABILITY, OR TORT
+// (INCLUDING NEGLIGENCE OR OTHERWISE) ARISING IN ANY WAY OUT OF THE USE
+// OF THIS SOFTWARE, EVEN IF ADVISED OF THE POSSIBILITY OF SUCH DAMAGE.
+
+package ast
+
+import "fmt"
+
+// An ApplyFunc is invoked by Apply for each node n, even if n is nil,
+// before and/or after the node's children, using a Cursor describing
+// the current node and providing operations on it.
+//
+// The return value of ApplyFunc controls the syntax tree traversal.
+// See Apply for details.
+type ApplyFunc func(*Cursor) bool
+
+// Apply traverses a syntax tree recursively, starting with root,
+// and calling pre and post for each node as described below.
+// Apply returns the syntax tree, possibly modified.
+//
+// If pre is not nil, it is called for each node before the node's
+// children are traversed (pre-order). If pre returns false, no
+// children are traversed, and post is not called for that node.
+//
+// If post is not nil, and a prior call of pre didn't return false,
+// post is called for each node after its children are traversed
+// (post-order). If post returns false, traversal is terminated and
+// Apply returns immediately.
+//
+// Only fields that refer to AST nodes are considered children;
+// i.e., positions and comments are not traversed.
+// Children are traversed in source order.
+//
+// Unlike Walk, nil children are not visited.
+func Apply(root Node, pre, post ApplyFunc) (result Node) {
+	parent := &struct{ Node }{root}
+	defer func() {
+		if r := recover(); r != nil && r != abort {
+			panic(r)
+		}
+		result = parent.Node
+	}()
+	a := &application{pre: pre, post: post}
+	a.apply(nil, "Node", nil, root, func(n Node) { parent.Node = n })
+	return
+}
+
+var abort = new(int) // singleton, to signal termination of Apply
+
+// A Cursor describes a node encountered during Apply.
+// Information about the node and its parent is available
+// from the Node, Parent, Name, and Index methods.
+type Cursor struct {
+	parent Node
+	name   string
+	iter   *iterator // valid if non-nil
+	node   Node
+	set    func(Node) // replaces node, if iter is nil
+}
+
+// Node returns the current Node.
+func (c *Cursor) Node() Node { return c.node }
+
+// Parent returns the parent of the current Node.
+// For the root node, Parent returns nil.
+func (c *Cursor) Parent() Node { return c.parent }
+
+// Name returns the name of the parent Node field that contains the current Node.
+// If the parent is a *FuncStmt and the current Node is the function name,
+// Name returns "Name".
+func (c *Cursor) Name() string { return c.name }
+
+// Index reports the index >= 0 of the current Node in the slice of Nodes that
+// contains it, or a value < 0 if the current Node is not part of a slice.
+// The index of the current node changes if InsertBefore is called while
+// processing the current node.
+func (c *Cursor) Index() int {
+	if c.iter != nil {
+		return c.iter.index
+	}
+	return -1
+}
+
+// Replace replaces the current Node with n.
+// The replacement node is not walked by Apply.
+// Replace panics if n can't be stored in the parent field.
+func (c *Cursor) Replace(n Node) {
+	if c.iter != nil {
+		c.iter.list.set(c.iter.index, n)
+	} else {
+		c.set(n)
+	}
+	c.node = n
+}
+
+// Delete deletes the current Node from its containing slice.
+// If the current Node is not part of a slice, Delete panics.
+func (c *Cursor) Delete() {
+	i := c.Index()
+	if i < 0 {
+		panic("Delete node not contained in slice")
+	}
+	c.iter.list.delete(i)
+	c.iter.step--
+}
+
+// InsertAfter inserts n after the current Node in its containing slice.
+// If the current Node is not part of a slice, InsertAfter panics.
+// Apply does not walk n.
+func (c *Cursor) InsertAfter(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("InsertAfter node not contained in slice")
+	}
+	c.iter.list.insert(i+1, n)
+	c.iter.step++
+}
+
+// InsertBefore inserts n before the current Node in its containing slice.
+// If the current Node is not part of a slice, InsertBefore panics.
+// Apply will not walk n.
+func (c *Cursor) InsertBefore(n Node) {
+	i := c.Index()
+	if i < 0 {
+		panic("InsertBefore node not contained in slice")
+	}
+	c.iter.list.insert(i, n)
+	c.iter.index++
+}
+
+// list is a slice of nodes in a parent node.
+type list interface {
+	len() int
+	at(i int) Node
+	set(i int, n Node)
+	insert(i int, n Node)
+	delete(i int)
+}
+
+type stmtList struct{ p *[]Stmt }
+
+func (l stmtList) len() int          { return len(*l.p) }
+func (l stmtList) at(i int) Node     { return (*l.p)[i] }
+func (l stmtList) set(i int, n Node) { (*l.p)[i] = n.(Stmt) }
+func (l stmtList) delete(i int)      { *l.p = append((*l.p)[:i], (*l.p)[i+1:]...) }
+func (l stmtList) insert(i int, n Node) {
+	*l.p = append(*l.p, nil)
+	copy((*l.p)[i+1:], (*l.p)[i:])
+	(*l.p)[i] = n.(Stmt)
+}
+
+type exprList struct{ p *[]Expr }
+
+func (l exprList) len() int          { return len(*l.p) }
+func (l exprList) at(i int) Node     { return (*l.p)[i] }
+func (l exprList) set(i int, n Node) { (*l.p)[i] = n.(Expr) }
+func (l exprList) delete(i int)      { *l.p = append((*l.p)[:i], (*l.p)[i+1:]...) }
+func (l exprList) insert(i int, n Node) {
+	*l.p = append(*l.p, nil)
+	copy((*l.p)[i+1:], (*l.p)[i:])
+	(*l.p)[i] = n.(Expr)
+}
+
+type nameList struct{ p *[]*Name }
+
+func (l nameList) len() int          { return len(*l.p) }
+func (l nameList) at(i int) Node     { return (*l.p)[i] }
+func (l nameList) set(i int, n Node) { (*l.p)[i] = n.(*Name) }
+func (l nameList) delete(i int)      { *l.p = append((*l.p)[:i], (*l.p)[i+1:]...) }
+func (l nameList) insert(i int, n Node) {
+	*l.p = append(*l.p, nil)
+	copy((*l.p)[i+1:], (*l.p)[i:])
+	(*l.p)[i] = n.(*Name)
+}
+
+type application struct {
+	pre, post ApplyFunc
+	cursor    Cursor
+	iter      iterator
+}
+
+type iterator struct {
+	list        list
+	index, step int
+}
+
+func (a *application) apply(parent Node, name string, iter *iterator, n Node, set func(Node)) {
+	// avoid heap-allocating a new cursor for each apply call; reuse a.cursor instead
+	saved := a.cursor
+	a.cursor.parent = parent
+	a.cursor.name = name
+	a.cursor.iter = iter
+	a.cursor.node = n
+	a.cursor.set = set
+
+	if a.pre != nil && !a.pre(&a.cursor) {
+		a.cursor = saved
+		return
+	}
+
+	// walk children
+	// (the order of the cases matches the order of the corresponding node types in ast.go)
+	switch n := a.cursor.node.(type) {
+	case *BadExpr, *Name, *Vararg, *BasicLit:
+		// nothing to do
+	case *FuncLit:
+		a.applyFuncBody(n, "Body", &n.Body)
+	case *TableLit:
+		a.applyList(n, "Fields", exprList{&n.Fields})
+	case *ParenExpr:
+		a.applyExpr(n, "X", &n.X)
+	case *SelectorExpr:
+		a.applyExpr(n, "X", &n.X)
+		a.applyName(n, "Sel", &n.Sel)
+	case *IndexExpr:
+		a.applyExpr(n, "X", &n.X)
+		a.applyExpr(n, "Index", &n.Index)
+	case *CallExpr:
+		a.applyExpr(n, "X", &n.X)
+		a.applyName(n, "Name", &n.Name)
+		a.applyList(n, "Args", exprList{&n.Args})
+	case *UnaryExpr:
+		a.applyExpr(n, "X", &n.X)
+	case *BinaryExpr:
+		a.applyExpr(n, "X", &n.X)
+		a.applyExpr(n, "Y", &n.Y)
+	case *KeyValueExpr:
+		a.applyExpr(n, "Key", &n.Key)
+		a.applyExpr(n, "Value", &n.Value)
+
+	case *BadStmt, *EmptyStmt, *BreakStmt:
+		// nothing to do
+	case *LocalAssignStmt:
+		a.applyList(n, "LHS", nameList{&n.LHS})
+		a.applyList(n, "RHS", exprList{&n.RHS})
+	case *LocalFuncStmt:
+		a.applyName(n, "Name", &n.Name)
+		a.applyFuncBody(n, "Body", &n.Body)
+	case *FuncStmt:
+		a.applyList(n, "PathList", nameList{&n.PathList})
+		a.applyName(n, "Name", &n.Name)
+		a.applyFuncBody(n, "Body", &n.Body)
+	case *LabelStmt:
+		a.applyName(n, "Name", &n.Name)
+	case *ExprStmt:
+		if n.X != nil {
+			a.apply(n, "X", nil, n.X, func(x Node) { n.X = x.(*CallExpr) })
+		}
+	case *AssignStmt:
+		a.applyList(n, "LHS", exprList{&n.LHS})
+		a.applyList(n, "RHS", exprList{&n.RHS})
+	case *GotoStmt:
+		a.applyName(n, "Label", &n.Label)
+	case *IfStmt:
+		a.applyExpr(n, "Cond", &n.Cond)
+		a.applyBlock(n, "Body", &n.Body)
+		for i := range n.ElseIfList {
+			e := &n.ElseIfList[i]
+			a.applyExpr(n, "ElseIfList", &e.Cond)
+			a.applyBlock(n, "ElseIfList", &e.Body)
+		}
+		a.applyBlock(n, "ElseBody", &n.ElseBody)
+	case *DoStmt:
+		a.applyBlock(n, "Body", &n.Body)
+	case *WhileStmt:
+		a.applyExpr(n, "Cond", &n.Cond)
+		a.applyBlock(n, "Body", &n.Body)
+	case *RepeatStmt:
+		a.applyBlock(n, "Body", &n.Body)
+		a.applyExpr(n, "Cond", &n.Cond)
+	case *ReturnStmt:
+		a.applyList(n, "Results", exprList{&n.Results})
+	case *ForStmt:
+		a.applyName(n, "Name", &n.Name)
+		a.applyExpr(n, "Start", &n.Start)
+		a.applyExpr(n, "Finish", &n.Finish)
+		a.applyExpr(n, "Step", &n.Step)
+		a.applyBlock(n, "Body", &n.Body)
+	case *ForEachStmt:
+		a.applyList(n, "Names", nameList{&n.Names})
+		a.applyList(n, "Exprs", exprList{&n.Exprs})
+		a.applyBlock(n, "Body", &n.Body)
+
+	case *File:
+		a.applyList(n, "Chunk", stmtList{&n.Chunk})
+	case *Block:
+		a.applyList(n, "List", stmtList{&n.List})
+	case *FuncBody:
+		if n.Params != nil {
+			a.apply(n, "Params", nil, n.Params, func(x Node) { n.Params = x.(*ParamList) })
+		}
+		a.applyBlock(n, "Body", &n.Body)
+	case *ParamList:
+		a.applyList(n, "List", nameList{&n.List})
+	case *Comment, *CommentGroup:
+		// comments are not traversed
+	default:
+		panic(fmt.Sprintf("Apply: unexpected node type %T", n))
+	}
+
+	if a.post != nil && !a.post(&a.cursor) {
+		panic(abort)
+	}
+
+	a.cursor = saved
+}
+
+func (a *application) applyExpr(parent Node, name string, p *Expr) {
+	if *p != nil {
+		a.apply(parent, name, nil, *p, func(n Node) { *p = n.(Expr) })
+	}
+}
+
+func (a *application) applyName(parent Node, name string, p **Name) {
+	if *p != nil {
+		a.apply(parent, name, nil, *p, func(n Node) { *p = n.(*Name) })
+	}
+}
+
+func (a *application) applyBlock(parent Node, name string, p **Block) {
+	if *p != nil {
+		a.apply(parent, name, nil, *p, func(n Node) { *p = n.(*Block) })
+	}
+}
+
+func (a *application) applyFuncBody(parent Node, name string, p **FuncBody) {
+	if *p != nil {
+		a.apply(parent, name, nil, *p, func(n Node) { *p = n.(*FuncBody) })
+	}
+}
+
+func (a *application) applyList(parent Node, name string, l list) {
+	// avoid heap-allocating a new iterator for each applyList call; reuse a.iter instead
+	saved := a.iter
+	a.iter.list = l
+	a.iter.index = 0
+	for a.iter.index < l.len() {
+		a.iter.step = 1
+		a.apply(parent, name, &a.iter, l.at(a.iter.index), nil)
+		a.iter.index += a.iter.step
+	}
+	a.iter = saved
+}
@@ -0,0 +1,190 @@
+package ast_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/position"
+)
+
+var applyTests = []struct {
+	name string
+	src  string
+	pre  ast.ApplyFunc
+	want string
+}{
+	{
+		"replace name",
+		"x = a + b\nprint(a)\n",
+		func(c *ast.Cursor) bool {
+			if n, ok := c.Node().(*ast.Name); ok && n.Name == "a" {
+				c.Replace(ast.NewName(n.Pos(), "alpha"))
+			}
+			return true
+		},
+		"x = alpha + b\nprint(alpha)",
+	},
+	{
+		"replace expr",
+		"x = 1 + 2\n",
+		func(c *ast.Cursor) bool {
+			if n, ok := c.Node().(*ast.BinaryExpr); ok {
+				c.Replace(ast.NewCall(ast.NewName(n.Pos(), "add"), n.X, n.Y))
+			}
+			return true
+		},
+		"x = add(1, 2)",
+	},
+	{
+		"delete stmt",
+		"a = 1\nprint(a)\nb = 2\nprint(b)\n",
+		func(c *ast.Cursor) bool {
+			if _, ok := c.Node().(*ast.ExprStmt); ok {
+				c.Delete()
+			}
+			return true
+		},
+		"a = 1\n\nb = 2",
+	},
+	{
+		"insert stmt",
+		"local function f(x)\n  local y = x\n  return y\nend\n",
+		func(c *ast.Cursor) bool {
+			switch n := c.Node().(type) {
+			case *ast.LocalAssignStmt:
+				c.InsertAfter(ast.NewExprStmt(ast.NewCall(ast.NewName(position.NoPos, "print"), n.LHS[0])))
+			case *ast.ReturnStmt:
+				c.InsertBefore(ast.NewExprStmt(ast.NewCall(ast.NewName(position.NoPos, "print"), ast.NewString(position.NoPos, "leave"))))
+			}
+			return true
+		},
+		"local function f(x)\n  local y = x\n  print(y)\n  print(\"leave\")\n  return y\nend",
+	},
+	{
+		"delete arg",
+		"f(1, nil, 2, nil)\n",
+		func(c *ast.Cursor) bool {
+			if n, ok := c.Node().(*ast.BasicLit); ok && n.Token.Type == token.NIL {
+				c.Delete()
+			}
+			return true
+		},
+		"f(1, 2)",
+	},
+	{
+		"insert field",
+		"t = {1, 2}\n",
+		func(c *ast.Cursor) bool {
+			if n, ok := c.Node().(*ast.BasicLit); ok && n.Token.Lit == "1" {
+				c.InsertBefore(ast.NewInt(n.Pos(), -1))
+				c.InsertAfter(ast.NewField(ast.NewName(n.Pos(), "x"), ast.NewBool(n.Pos(), true)))
+				c.InsertAfter(ast.NewField(ast.NewString(n.Pos(), "y"), ast.NewNil(n.Pos())))
+			}
+			return true
+		},
+		"t = {-1, 1, [\"y\"] = nil, x = true, 2}",
+	},
+	{
+		"new stmts",
+		"print(1)\ndo end\n",
+		func(c *ast.Cursor) bool {
+			if n, ok := c.Node().(*ast.DoStmt); ok {
+				pos := n.Pos()
+				x := ast.NewName(position.NoPos, "x")
+				fn := ast.NewFuncLit(position.NoPos, []*ast.Name{x}, ast.NewReturn(position.NoPos, ast.NewBinary(x, token.MUL, ast.NewInt(position.NoPos, 2))))
+				c.InsertBefore(ast.NewLocalAssign(position.NoPos, []*ast.Name{ast.NewName(position.NoPos, "double")}, fn))
+				c.Replace(ast.NewAssign(
+					[]ast.Expr{ast.NewIndex(ast.NewName(pos, "t"), ast.NewString(pos, "k"))},
+					ast.NewMethodCall(ast.NewSelector(ast.NewName(pos, "a"), "b"), "c", ast.NewParen(ast.NewVararg(pos))),
+				))
+			}
+			return true
+		},
+		"print(1)\nlocal double = function(x) return x * 2 end\nt[\"k\"] = a.b:c((...))",
+	},
+}
+
+func TestApply(t *testing.T) {
+	for _, test := range applyTests {
+		f := parse(t, test.src)
+
+		ast.Apply(f, test.pre, nil)
+
+		got := format(t, f)
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+
+		if _, err := parser.Parse(scanner.Scan(strings.NewReader(got), "=test", 0), 0); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestApplyCursor(t *testing.T) {
+	f := parse(t, "a = 1\nif b then c() elseif d then e() end\n")
+
+	var names []string
+	ast.Apply(f, func(c *ast.Cursor) bool {
+		if n, ok := c.Node().(*ast.Name); ok {
+			names = append(names, n.Name+":"+c.Name())
+			if _, ok := c.Parent().(*ast.AssignStmt); ok && c.Index() != 0 {
+				t.Errorf("expected index 0, got %d", c.Index())
+			}
+		}
+		return true
+	}, nil)
+
+	want := "a:LHS b:Cond c:X d:ElseIfList e:X"
+	if got := strings.Join(names, " "); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	// post returning false stops the traversal
+	names = nil
+	root := ast.Apply(f, nil, func(c *ast.Cursor) bool {
+		if n, ok := c.Node().(*ast.Name); ok {
+			names = append(names, n.Name)
+			return n.Name != "b"
+		}
+		return true
+	})
+	if got := strings.Join(names, " "); got != "a b" {
+		t.Errorf("got %q, want %q", got, "a b")
+	}
+	if root != f {
+		t.Errorf("expected root to be returned")
+	}
+
+	// root can be replaced
+	x := ast.NewName(position.NoPos, "x")
+	root = ast.Apply(f, func(c *ast.Cursor) bool {
+		c.Replace(x)
+		return false
+	}, nil)
+	if root != x {
+		t.Errorf("expected replaced root, got %v", root)
+	}
+}
+
+func parse(t *testing.T, src string) *ast.File {
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(src), "=test", 0), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func format(t *testing.T, f *ast.File) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
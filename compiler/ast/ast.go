@@ -520,6 +520,7 @@ type File struct {
 	Shebang  string
 	Chunk    []Stmt
 	Comments []*CommentGroup // list of all comments in the source file
+	CST      *CST            // concrete syntax in parser.Lossless mode; or nil
 }
 
 func (f *File) Type() Type             { return FILE }
@@ -0,0 +1,180 @@
+package ast
+
+import (
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/internal/strconv"
+	"github.com/hirochachacha/plua/position"
+)
+
+// Constructors for nodes created by rewriters.
+//
+// Leaf nodes and nodes starting with a keyword take the position of their first token,
+// the other positions are derived from their children, so that the printer emits
+// a constructed node in a single line at the place of its first token.
+// Usually, pos is the position of the node to be replaced, or position.NoPos.
+// Nodes at position.NoPos are printed in line, after the preceding token.
+
+// NewName returns an identifier at pos.
+func NewName(pos position.Position, name string) *Name {
+	return &Name{NamePos: pos, Name: name}
+}
+
+// NewString returns a string literal representing s at pos.
+func NewString(pos position.Position, s string) *BasicLit {
+	return &BasicLit{token.Token{Type: token.STRING, Pos: pos, Lit: strconv.Quote(s)}}
+}
+
+// NewInt returns an integer literal representing i at pos.
+// If i is negative, the literal is wrapped by an unary minus expression.
+func NewInt(pos position.Position, i int64) Expr {
+	if i < 0 {
+		lit := &BasicLit{token.Token{Type: token.INT, Pos: pos.OffsetColumn(1), Lit: strconv.FormatUint(uint64(-i), 10)}}
+
+		return &UnaryExpr{OpPos: pos, Op: token.UNM, X: lit}
+	}
+	return &BasicLit{token.Token{Type: token.INT, Pos: pos, Lit: strconv.FormatInt(i, 10)}}
+}
+
+// NewNil returns a nil literal at pos.
+func NewNil(pos position.Position) *BasicLit {
+	return &BasicLit{token.Token{Type: token.NIL, Pos: pos, Lit: "nil"}}
+}
+
+// NewBool returns a true or false literal at pos.
+func NewBool(pos position.Position, b bool) *BasicLit {
+	if b {
+		return &BasicLit{token.Token{Type: token.TRUE, Pos: pos, Lit: "true"}}
+	}
+	return &BasicLit{token.Token{Type: token.FALSE, Pos: pos, Lit: "false"}}
+}
+
+// NewVararg returns '...' at pos.
+func NewVararg(pos position.Position) *Vararg {
+	return &Vararg{Ellipsis: pos}
+}
+
+// NewParen returns (x).
+func NewParen(x Expr) *ParenExpr {
+	return &ParenExpr{Lparen: mark(x.Pos()), X: x, Rparen: x.End()}
+}
+
+// NewSelector returns x.name.
+func NewSelector(x Expr, name string) *SelectorExpr {
+	period := x.End()
+
+	return &SelectorExpr{X: x, Period: period, Sel: NewName(period.OffsetColumn(1), name)}
+}
+
+// NewIndex returns x[index].
+func NewIndex(x, index Expr) *IndexExpr {
+	return &IndexExpr{X: x, Lbrack: mark(x.End()), Index: index, Rbrack: index.End()}
+}
+
+// NewCall returns fn(args...).
+func NewCall(fn Expr, args ...Expr) *CallExpr {
+	lparen := mark(fn.End())
+
+	return &CallExpr{X: fn, Lparen: lparen, Args: args, Rparen: endOf(lparen, args)}
+}
+
+// NewMethodCall returns x:name(args...).
+func NewMethodCall(x Expr, name string, args ...Expr) *CallExpr {
+	colon := mark(x.End())
+	sel := NewName(colon.OffsetColumn(1), name)
+	lparen := mark(sel.End())
+
+	return &CallExpr{X: x, Colon: colon, Name: sel, Lparen: lparen, Args: args, Rparen: endOf(lparen, args)}
+}
+
+// NewBinary returns x op y.
+// Operands are not parenthesized automatically.
+func NewBinary(x Expr, op token.Type, y Expr) *BinaryExpr {
+	return &BinaryExpr{X: x, OpPos: x.End().OffsetColumn(1), Op: op, Y: y}
+}
+
+// NewUnary returns op x, op is placed at pos.
+func NewUnary(pos position.Position, op token.Type, x Expr) *UnaryExpr {
+	return &UnaryExpr{OpPos: pos, Op: op, X: x}
+}
+
+// NewTable returns {fields...} at pos.
+// Use NewField for key value pairs.
+func NewTable(pos position.Position, fields ...Expr) *TableLit {
+	return &TableLit{Lbrace: pos, Fields: fields, Rbrace: endOf(pos, fields)}
+}
+
+// NewField returns key = value if key is *Name, [key] = value otherwise.
+func NewField(key, value Expr) *KeyValueExpr {
+	if key, ok := key.(*Name); ok {
+		return &KeyValueExpr{Key: key, Equal: key.End().OffsetColumn(1), Value: value}
+	}
+
+	rbrack := key.End()
+
+	return &KeyValueExpr{Lbrack: mark(key.Pos()), Key: key, Rbrack: rbrack, Equal: rbrack.OffsetColumn(2), Value: value}
+}
+
+// NewFuncLit returns function(params...) body end at pos.
+func NewFuncLit(pos position.Position, params []*Name, body ...Stmt) *FuncLit {
+	lparen := mark(pos.OffsetColumn(len("function")))
+	rparen := lparen
+	if n := len(params); n > 0 {
+		rparen = params[n-1].End()
+	}
+
+	end := rparen.OffsetColumn(2)
+	if n := len(body); n > 0 {
+		end = body[n-1].End().OffsetColumn(1)
+	}
+
+	return &FuncLit{
+		Func: pos,
+		Body: &FuncBody{
+			Params: &ParamList{Lparen: lparen, List: params, Rparen: rparen},
+			Body:   &Block{Opening: rparen.OffsetColumn(1), List: body, Closing: end},
+		},
+		EndPos: end,
+	}
+}
+
+// NewExprStmt returns a statement calling call.
+func NewExprStmt(call *CallExpr) *ExprStmt {
+	return &ExprStmt{X: call}
+}
+
+// NewAssign returns lhs... = rhs....
+func NewAssign(lhs []Expr, rhs ...Expr) *AssignStmt {
+	return &AssignStmt{LHS: lhs, Equal: lhs[len(lhs)-1].End().OffsetColumn(1), RHS: rhs}
+}
+
+// NewLocalAssign returns local names... = rhs... at pos.
+// If rhs is empty, the statement only declares names.
+func NewLocalAssign(pos position.Position, names []*Name, rhs ...Expr) *LocalAssignStmt {
+	stmt := &LocalAssignStmt{Local: pos, LHS: names, RHS: rhs}
+	if len(rhs) > 0 {
+		stmt.Equal = mark(names[len(names)-1].End().OffsetColumn(1))
+	}
+	return stmt
+}
+
+// NewReturn returns return results... at pos.
+func NewReturn(pos position.Position, results ...Expr) *ReturnStmt {
+	return &ReturnStmt{Return: pos, Results: results}
+}
+
+// mark returns pos, or a valid position in the first line if pos is not valid,
+// because some positions indicate presence of the token.
+func mark(pos position.Position) position.Position {
+	if !pos.IsValid() {
+		pos.Line = 1
+	}
+	return pos
+}
+
+// endOf returns the position of a closing token after exprs, which is opened at pos.
+func endOf(pos position.Position, exprs []Expr) position.Position {
+	if n := len(exprs); n > 0 {
+		return exprs[n-1].End()
+	}
+	return pos.OffsetColumn(1)
+}
@@ -0,0 +1,95 @@
+package ast
+
+import (
+	"sort"
+
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/position"
+)
+
+// A Token represents a token with the source text preceding it.
+type Token struct {
+	Type   token.Type
+	Pos    position.Position
+	Trivia string // spaces and comments before the token
+	Text   string // source text of the token
+}
+
+// A CST represents the concrete syntax of a file parsed in parser.Lossless mode.
+//
+// It holds all tokens of the file, and the tokens and children of each node
+// as they were parsed. Concatenating trivia and text of the tokens reproduces
+// the source byte for byte. Since the CST is not updated by modifications of the AST,
+// modified nodes can be detected by comparing them with the original children.
+type CST struct {
+	Tokens []Token // tokens in order of appearance, terminated by EOF
+
+	nodes map[Node]*cstNode
+}
+
+type cstNode struct {
+	start, end int // tokens of the node are Tokens[start:end]
+	children   []Node
+}
+
+// NewCST returns the CST of f, which consists of tokens.
+// f must not be modified yet.
+func NewCST(f *File, tokens []Token) *CST {
+	c := &CST{Tokens: tokens, nodes: make(map[Node]*cstNode)}
+
+	var stack []*cstNode
+
+	Apply(f, func(cur *Cursor) bool {
+		n := cur.Node()
+
+		var cn *cstNode
+		if n == f {
+			cn = &cstNode{start: 0, end: len(tokens)}
+		} else {
+			cn = &cstNode{start: c.search(n.Pos()), end: c.search(n.End())}
+			if cn.end < cn.start {
+				cn.end = cn.start
+			}
+
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, n)
+		}
+
+		c.nodes[n] = cn
+
+		stack = append(stack, cn)
+
+		return true
+	}, func(cur *Cursor) bool {
+		stack = stack[:len(stack)-1]
+
+		return true
+	})
+
+	return c
+}
+
+// search returns the index of the first token at pos or after pos.
+func (c *CST) search(pos position.Position) int {
+	return sort.Search(len(c.Tokens), func(i int) bool {
+		return !c.Tokens[i].Pos.LessThan(pos)
+	})
+}
+
+// Span returns the range of tokens of n, Tokens[start:end].
+// If n is not a node of the parsed file, ok is false.
+func (c *CST) Span(n Node) (start, end int, ok bool) {
+	cn, ok := c.nodes[n]
+	if !ok {
+		return 0, 0, false
+	}
+	return cn.start, cn.end, true
+}
+
+// Children returns the children of n in source order, when n was parsed.
+func (c *CST) Children(n Node) []Node {
+	if cn, ok := c.nodes[n]; ok {
+		return cn.children
+	}
+	return nil
+}
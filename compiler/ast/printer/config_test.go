@@ -0,0 +1,128 @@
+package printer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+)
+
+var configTests = []struct {
+	cfg  printer.Config
+	src  string
+	want string
+}{
+	{
+		printer.Config{},
+		"if x then\nf()\nend",
+		"if x then\n  f()\nend",
+	},
+	{
+		printer.Config{},
+		"x = [[a\n\tb]], \"a\\\nb\", '\\0\t'",
+		"x = [[a\n\tb]], \"a\\\nb\", '\\0\t'",
+	},
+	{
+		printer.Config{},
+		"x = f\n;(g)()\ny = 1; (t).x = 1",
+		"x = f\n;(g)()\ny = 1; (t).x = 1",
+	},
+	{
+		printer.Config{Tabwidth: 4},
+		"if x then\nf()\nend",
+		"if x then\n    f()\nend",
+	},
+	{
+		printer.Config{Mode: printer.TabIndent},
+		"if x then\nif y then\nf() -- c1\ng() -- comment2\nend\nend",
+		"if x then\n\tif y then\n\t\tf() -- c1\n\t\tg() -- comment2\n\tend\nend",
+	},
+	{
+		printer.Config{Quote: printer.QuoteDouble},
+		`x = 'a', 'b"c', 'd\'e', "f", [[g]]`,
+		`x = "a", 'b"c', "d'e", "f", [[g]]`,
+	},
+	{
+		printer.Config{Quote: printer.QuoteSingle},
+		`x = "a", "b'c", "d\"e", 'f', "\\"`,
+		`x = 'a', "b'c", 'd"e', 'f', '\\'`,
+	},
+	{
+		printer.Config{TrailingSep: printer.SepNever},
+		"t = {\n1,\n2,\n}",
+		"t = {\n  1,\n  2\n}",
+	},
+	{
+		printer.Config{TrailingSep: printer.SepAlways},
+		"t = {1, 2}",
+		"t = {1, 2,}",
+	},
+	{
+		printer.Config{OpSpacing: printer.SpaceAlways, ConcatSpacing: printer.SpaceAlways},
+		`x = f(1+2*3, a..b)`,
+		`x = f(1 + 2 * 3, a .. b)`,
+	},
+	{
+		printer.Config{OpSpacing: printer.SpaceNever},
+		`x = a + b * c == d and e .. f`,
+		`x = a+b*c==d and e..f`,
+	},
+	{
+		printer.Config{ConcatSpacing: printer.SpaceNever},
+		`x = a .. b .. .5, 1 .. 2`,
+		`x = a..b.. .5, 1 ..2`,
+	},
+	{
+		printer.Config{MaxWidth: 20},
+		`foo(aaaaaa, bbbbbb, cccccc)`,
+		"foo(\n  aaaaaa,\n  bbbbbb,\n  cccccc\n)",
+	},
+	{
+		printer.Config{MaxWidth: 20},
+		`t = {aaaaaa, b = {1, 2}, ccc}`,
+		"t = {\n  aaaaaa,\n  b = {1, 2},\n  ccc,\n}",
+	},
+	{
+		printer.Config{MaxWidth: 20},
+		`t = {aaaaaa, b = {1, 2, 3, 4, 5, 6, 7}}`,
+		"t = {\n  aaaaaa,\n  b = {\n    1,\n    2,\n    3,\n    4,\n    5,\n    6,\n    7,\n  },\n}",
+	},
+	{
+		printer.Config{MaxWidth: 20},
+		`foo(a, b)`,
+		`foo(a, b)`,
+	},
+}
+
+func format(t *testing.T, cfg *printer.Config, src string) string {
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(src), "=test", scanner.ScanComments), parser.ParseComments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := cfg.Fprint(&buf, f); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
+
+func TestConfig(t *testing.T) {
+	for i, test := range configTests {
+		got := format(t, &test.cfg, test.src)
+		if got != test.want {
+			t.Errorf("%d: got\n%s\nwant\n%s", i, got, test.want)
+			continue
+		}
+
+		// formatting should be stable
+		if again := format(t, &test.cfg, got); again != got {
+			t.Errorf("%d: not idempotent, got\n%s\nwant\n%s", i, again, got)
+		}
+	}
+}
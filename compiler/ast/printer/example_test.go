@@ -36,10 +36,13 @@ func ExampleFprint() {
 	//  man
 	//  ]]
 	//
-	// -- TODO
-	// -- x = 1 + -- bar
-	// -- 4 + 9 -- foo
-	// -- + 10 -- baz
+	// x = 1 + -- bar
+	//   4 + 9 -- foo
+	//   + 10  -- baz
+	//
+	// f(a, -- first
+	//   b, -- second
+	//   c)
 	//
 	// x = x + 5*9 - 10/5 - (-5)
 	// x = x + (5-1+9)*6
@@ -87,7 +90,7 @@ func ExampleFprint() {
 	//
 	// print('\'test')
 	//
-	// foo = "5"..1 > "21"
+	// foo = 5 ..1 > "21"
 	//
 	// fn({
 	//   1,
@@ -0,0 +1,87 @@
+package printer_test
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/compiler/token"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestGolden(t *testing.T) {
+	for _, name := range []string{"testdata/comments.lua"} {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := format(t, &printer.Config{}, string(src))
+
+		golden := strings.TrimSuffix(name, ".lua") + ".golden"
+		if *update {
+			if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != string(want) {
+			t.Errorf("%s: got\n%s\nwant\n%s", name, got, want)
+		}
+	}
+}
+
+// TestIdempotent formats real-world files twice, and checks that
+// formatting is stable and keeps all comments in order.
+func TestIdempotent(t *testing.T) {
+	names, err := filepath.Glob("../../../testdata/lua-5.3.3-tests/*.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = append(names, "testdata/example.lua", "testdata/comments.lua")
+
+	for _, name := range names {
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		res := format(t, &printer.Config{}, string(src))
+		if again := format(t, &printer.Config{}, res); again != res {
+			t.Errorf("%s: formatting is not idempotent", name)
+		}
+
+		if comments(t, string(src)) != comments(t, res) {
+			t.Errorf("%s: comments are not preserved", name)
+		}
+	}
+}
+
+// comments returns all comments in src joined by newlines.
+func comments(t *testing.T, src string) string {
+	var cs []string
+
+	s := scanner.Scan(strings.NewReader(src), "=test", scanner.ScanComments)
+	for {
+		tok, err := s.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch tok.Type {
+		case token.EOF:
+			return strings.Join(cs, "\n")
+		case token.COMMENT:
+			cs = append(cs, strings.TrimSpace(tok.Lit))
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package printer
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+)
+
+// lprinter prints files parsed in parser.Lossless mode.
+//
+// nodes recorded in the CST are printed from their original tokens,
+// so that unmodified parts of the file are reproduced byte for byte.
+// nodes created by rewriters and nodes whose children can't be matched
+// with the original ones are formatted by the normal printer.
+type lprinter struct {
+	cfg      *Config
+	cst      *ast.CST
+	w        io.Writer
+	skip     int    // index of the token whose trivia is already printed, or -1
+	indent   string // indentation of the current line
+	inIndent bool   // the current line has no text except indentation
+	err      error
+}
+
+func (cfg *Config) fprintLossless(w io.Writer, file *ast.File) error {
+	p := &lprinter{
+		cfg:      cfg,
+		cst:      file.CST,
+		w:        w,
+		skip:     -1,
+		inIndent: true,
+	}
+	p.printNode(file)
+	return p.err
+}
+
+func (p *lprinter) write(s string) {
+	if p.err != nil || s == "" {
+		return
+	}
+
+	if _, err := io.WriteString(p.w, s); err != nil {
+		p.err = err
+		return
+	}
+
+	if i := strings.LastIndexByte(s, '\n'); i != -1 {
+		s = s[i+1:]
+		p.indent = ""
+		p.inIndent = true
+	}
+	if p.inIndent {
+		t := strings.TrimLeft(s, " \t")
+		p.indent += s[:len(s)-len(t)]
+		if t != "" {
+			p.inIndent = false
+		}
+	}
+}
+
+func (p *lprinter) printTrivia(i int) {
+	if i < len(p.cst.Tokens) && i != p.skip {
+		p.write(p.cst.Tokens[i].Trivia)
+	}
+	p.skip = i
+}
+
+// printTokens prints Tokens[i:j] which belong to n itself.
+func (p *lprinter) printTokens(n ast.Node, i, j int) {
+	for ; i < j; i++ {
+		p.printTrivia(i)
+		p.write(p.text(n, i))
+	}
+}
+
+// text returns the text of Tokens[i], reflecting modified fields of n.
+func (p *lprinter) text(n ast.Node, i int) string {
+	tok := p.cst.Tokens[i]
+
+	switch n := n.(type) {
+	case *ast.Name:
+		return n.Name
+	case *ast.BasicLit:
+		if n.Lit != "" {
+			return n.Lit
+		}
+	case *ast.UnaryExpr:
+		if tok.Pos == n.OpPos && tok.Type != n.Op {
+			return n.Op.String()
+		}
+	case *ast.BinaryExpr:
+		if tok.Pos == n.OpPos && tok.Type != n.Op {
+			return n.Op.String()
+		}
+	case *ast.FuncStmt:
+		if tok.Pos == n.AccessPos && tok.Type != n.AccessTok {
+			return n.AccessTok.String()
+		}
+	}
+
+	return tok.Text
+}
+
+func (p *lprinter) printNode(n ast.Node) {
+	start, end, ok := p.cst.Span(n)
+	if !ok {
+		p.printFormatted(n)
+		return
+	}
+
+	edits, ok := diff(n, p.cst.Children(n), children(n))
+	if !ok {
+		p.printTrivia(start)
+		p.printFormatted(n)
+		return
+	}
+
+	i := start
+	for k, e := range edits {
+		if e.orig == nil {
+			p.printInsertion(n, edits, k, i)
+			continue
+		}
+
+		s, t, _ := p.cst.Span(e.orig)
+
+		p.printTokens(n, i, s)
+
+		switch e.node {
+		case e.orig:
+			p.printNode(e.orig)
+		case nil:
+			// deleted
+		default:
+			p.printTrivia(s)
+			p.printMoved(e.node)
+		}
+
+		i = t
+	}
+
+	p.printTokens(n, i, end)
+}
+
+// printInsertion prints the new statement edits[k].node before edits[k+1],
+// or after the last statement if there is no following one.
+// i is the index of the next token to be printed.
+func (p *lprinter) printInsertion(n ast.Node, edits []edit, k, i int) {
+	for _, e := range edits[k+1:] {
+		if e.orig != nil {
+			s, _, _ := p.cst.Span(e.orig)
+
+			p.printTokens(n, i, s)
+			p.printTrivia(s)
+
+			sep := " "
+			if trivia := p.cst.Tokens[s].Trivia; strings.LastIndexByte(trivia, '\n') != -1 || p.inIndent {
+				sep = "\n" + p.indent
+			}
+
+			p.printMoved(edits[k].node)
+			p.write(sep)
+
+			return
+		}
+	}
+
+	// append to the list
+	sep := "\n" + p.indent
+	if _, isFile := n.(*ast.File); !isFile {
+		sep += p.indentUnit()
+	}
+	for _, e := range edits {
+		if e.orig != nil {
+			s, _, _ := p.cst.Span(e.orig)
+			trivia := p.cst.Tokens[s].Trivia
+			if j := strings.LastIndexByte(trivia, '\n'); j != -1 {
+				sep = "\n" + trivia[j+1:]
+			} else {
+				sep = " "
+			}
+		}
+	}
+
+	if p.inIndent && p.indent == "" && strings.HasPrefix(sep, "\n") {
+		sep = sep[1:] // at the beginning of a line
+	}
+
+	p.write(sep)
+	p.printMoved(edits[k].node)
+}
+
+func (p *lprinter) indentUnit() string {
+	if p.cfg.Mode&TabIndent != 0 {
+		return "\t"
+	}
+	if p.cfg.Tabwidth <= 0 {
+		return "  "
+	}
+	return strings.Repeat(" ", p.cfg.Tabwidth)
+}
+
+// printMoved prints n in place of other tokens.
+func (p *lprinter) printMoved(n ast.Node) {
+	if s, _, ok := p.cst.Span(n); ok {
+		p.skip = s
+	}
+	p.printNode(n)
+}
+
+// printFormatted prints n by the normal printer,
+// indenting continuation lines as the current line.
+func (p *lprinter) printFormatted(n ast.Node) {
+	cfg := *p.cfg
+	cfg.Mode &^= Lossless
+
+	var buf bytes.Buffer
+	if err := cfg.Fprint(&buf, n); err != nil {
+		if p.err == nil {
+			p.err = err
+		}
+		return
+	}
+
+	text := strings.TrimSpace(buf.String())
+
+	p.write(strings.Replace(text, "\n", "\n"+p.indent, -1))
+}
+
+// An edit represents a difference of children.
+type edit struct {
+	orig ast.Node // original child; or nil
+	node ast.Node // current child; or nil
+}
+
+// diff returns edits which transform orig into cur.
+//
+// orig children kept in cur must be in the same order.
+// the others are replaced one by one, in order.
+// statements can be inserted or deleted freely.
+func diff(n ast.Node, orig, cur []ast.Node) (edits []edit, ok bool) {
+	index := make(map[ast.Node]int, len(orig))
+	for i, c := range orig {
+		index[c] = i
+	}
+
+	var isList bool
+	switch n.(type) {
+	case *ast.File, *ast.Block:
+		isList = true
+	}
+
+	i := 0 // index of the next orig
+	var fresh []ast.Node
+	flush := func(j int) bool {
+		removed := orig[i:j]
+		if len(removed) != len(fresh) && !isList {
+			return false
+		}
+		for k := 0; k < len(removed) || k < len(fresh); k++ {
+			var e edit
+			if k < len(removed) {
+				e.orig = removed[k]
+			}
+			if k < len(fresh) {
+				e.node = fresh[k]
+			}
+			edits = append(edits, e)
+		}
+		fresh = fresh[:0]
+		return true
+	}
+
+	for _, c := range cur {
+		j, kept := index[c]
+		if !kept || j < i {
+			fresh = append(fresh, c)
+			continue
+		}
+		if !flush(j) {
+			return nil, false
+		}
+		edits = append(edits, edit{orig: c, node: c})
+		i = j + 1
+	}
+
+	if !flush(len(orig)) {
+		return nil, false
+	}
+
+	return edits, true
+}
+
+// children returns the children of n in source order.
+func children(n ast.Node) []ast.Node {
+	var list []ast.Node
+	ast.Apply(n, func(c *ast.Cursor) bool {
+		if c.Parent() == nil {
+			return true
+		}
+		list = append(list, c.Node())
+		return false
+	}, nil)
+	return list
+}
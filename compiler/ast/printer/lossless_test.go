@@ -0,0 +1,128 @@
+package printer_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/compiler/token"
+)
+
+func parseStmt(t *testing.T, src string) ast.Stmt {
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(src), "=stmt", 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Chunk[0]
+}
+
+var losslessTests = []struct {
+	src  string
+	edit func(t *testing.T, c *ast.Cursor)
+	want string
+}{
+	{
+		"#!/usr/bin/env plua\n-- header\nlocal  x=1   -- one\n\n\nprint( x )\n",
+		nil,
+		"#!/usr/bin/env plua\n-- header\nlocal  x=1   -- one\n\n\nprint( x )\n",
+	},
+	{
+		"local  x = {1,2 , 3}  -- c\nprint( x )\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if lit, ok := c.Node().(*ast.BasicLit); ok && lit.Lit == "2" {
+				c.Replace(&ast.BinaryExpr{
+					X:  &ast.BasicLit{Token: token.Token{Type: token.INT, Lit: "4"}},
+					Op: token.SUB,
+					Y:  &ast.Name{Name: "y"},
+				})
+			}
+		},
+		"local  x = {1,4 - y , 3}  -- c\nprint( x )\n",
+	},
+	{
+		"local  x=1\nprint( x ) -- x\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if name, ok := c.Node().(*ast.Name); ok && name.Name == "x" {
+				name.Name = "alpha"
+			}
+		},
+		"local  alpha=1\nprint( alpha ) -- x\n",
+	},
+	{
+		"local x = a  ==  b\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if e, ok := c.Node().(*ast.BinaryExpr); ok {
+				e.Op = token.NE
+			}
+		},
+		"local x = a  ~=  b\n",
+	},
+	{
+		"do\n  a()\n  -- b\n  b( 1 )\n  c()\nend\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if s, ok := c.Node().(*ast.ExprStmt); ok && s.X.X.(*ast.Name).Name == "b" {
+				c.Delete()
+			}
+		},
+		"do\n  a()\n  c()\nend\n",
+	},
+	{
+		"do\n  a( 1 )\n\n  c( 3 )\nend\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if s, ok := c.Node().(*ast.ExprStmt); ok && s.X.X.(*ast.Name).Name == "c" {
+				c.InsertBefore(parseStmt(t, "b(2)"))
+				c.InsertAfter(parseStmt(t, "if x then\nd(4)\nend"))
+			}
+		},
+		"do\n  a( 1 )\n\n  b(2)\n  c( 3 )\n  if x then\n    d(4)\n  end\nend\n",
+	},
+	{
+		"-- header\nx = 1\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if _, ok := c.Node().(*ast.AssignStmt); ok {
+				c.InsertBefore(parseStmt(t, "local x"))
+			}
+		},
+		"-- header\nlocal x\nx = 1\n",
+	},
+	{
+		"f( a,  b )\n",
+		func(t *testing.T, c *ast.Cursor) {
+			if call, ok := c.Node().(*ast.CallExpr); ok {
+				call.Args = call.Args[:1]
+			}
+		},
+		"f(a)\n",
+	},
+}
+
+func TestLossless(t *testing.T) {
+	cfg := &printer.Config{Mode: printer.Lossless}
+
+	for i, test := range losslessTests {
+		f, err := parser.Parse(scanner.Scan(strings.NewReader(test.src), "=test", scanner.ScanTrivia), parser.Lossless)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if test.edit != nil {
+			ast.Apply(f, func(c *ast.Cursor) bool {
+				test.edit(t, c)
+				return true
+			}, nil)
+		}
+
+		var buf bytes.Buffer
+		if err := cfg.Fprint(&buf, f); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buf.String(); got != test.want {
+			t.Errorf("%d: got %q, want %q", i, got, test.want)
+		}
+	}
+}
@@ -6,14 +6,70 @@ import (
 	"github.com/hirochachacha/plua/compiler/ast"
 )
 
+// A Mode value is a set of flags (or 0). They control printing.
+type Mode uint
+
+const (
+	TabIndent Mode = 1 << iota // indent with tabs instead of spaces
+	Lossless                   // print files parsed in parser.Lossless mode as they are, except modified nodes
+)
+
+// QuoteStyle controls the delimiters of short strings.
+type QuoteStyle int
+
+const (
+	QuoteKeep   QuoteStyle = iota // leave strings as they are
+	QuoteDouble                   // prefer "..."
+	QuoteSingle                   // prefer '...'
+)
+
+// SepStyle controls the trailing separator of table constructors.
+type SepStyle int
+
+const (
+	SepMultiline SepStyle = iota // add a trailing separator if the closing brace is on its own line
+	SepAlways                    // always add a trailing separator
+	SepNever                     // never add a trailing separator
+)
+
+// Spacing controls blanks around binary operators.
+type Spacing int
+
+const (
+	SpaceAuto   Spacing = iota // omit blanks around operators of higher precedence, e.g. 1 + 2*3
+	SpaceAlways                // always put blanks around operators
+	SpaceNever                 // never put blanks around symbolic operators
+)
+
+// A Config node controls the output of Fprint.
+type Config struct {
+	Mode     Mode
+	Tabwidth int // width of an indentation, 2 if zero
+
+	Quote       QuoteStyle
+	TrailingSep SepStyle
+
+	// MaxWidth is the maximum line width.
+	// function calls and table constructors exceeding it are wrapped, one element per line.
+	// zero means no limit.
+	MaxWidth int
+
+	OpSpacing     Spacing // spacing around binary operators except for '..'
+	ConcatSpacing Spacing // spacing around '..'
+}
+
 func FprintTree(w io.Writer, node ast.Node) error {
 	p := treeprinter{w: w}
 	p.printNode(node, "", 0)
 	return p.err
 }
 
-func Fprint(w io.Writer, node ast.Node) error {
-	p := newPrinter(w)
+func (cfg *Config) Fprint(w io.Writer, node ast.Node) error {
+	if file, ok := node.(*ast.File); ok && file.CST != nil && cfg.Mode&Lossless != 0 {
+		return cfg.fprintLossless(w, file)
+	}
+
+	p := newPrinter(w, cfg)
 	p.printNode(node)
 	if p.err != nil {
 		return p.err
@@ -23,3 +79,7 @@ func Fprint(w io.Writer, node ast.Node) error {
 	}
 	return nil
 }
+
+func Fprint(w io.Writer, node ast.Node) error {
+	return (&Config{}).Fprint(w, node)
+}
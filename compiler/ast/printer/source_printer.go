@@ -1,20 +1,17 @@
 package printer
 
 import (
+	"bytes"
 	"io"
 	"strings"
 	"text/tabwriter"
+	"unicode/utf8"
 
 	"github.com/hirochachacha/plua/compiler/ast"
 	"github.com/hirochachacha/plua/compiler/token"
-	"github.com/hirochachacha/plua/internal/strconv"
 	"github.com/hirochachacha/plua/position"
 )
 
-const (
-	indent = "  "
-)
-
 type mode uint
 
 const (
@@ -23,12 +20,17 @@ const (
 	noParen
 	compact
 	insertSemi
+	newline // break the line regardless of the position
 )
 
 type printer struct {
+	cfg        *Config
+	indent     string
 	w          *tabwriter.Writer
+	col        int // current column, used for wrapping
 	depth      int // indent depth
 	doIndent   bool
+	indented   bool // continuation lines of the current list or binary expression are indented
 	formfeed   bool
 	stmtEnd    bool
 	lastPos    position.Position
@@ -41,11 +43,26 @@ type printer struct {
 
 var initPos = position.Position{Line: 1}
 
-func newPrinter(w io.Writer) *printer {
-	return &printer{
+func newPrinter(w io.Writer, cfg *Config) *printer {
+	p := &printer{
+		cfg:     cfg,
 		w:       tabwriter.NewWriter(w, 2, 2, 1, ' ', tabwriter.DiscardEmptyColumns|tabwriter.StripEscape),
 		lastPos: initPos,
 	}
+	if cfg.Mode&TabIndent != 0 {
+		p.indent = string([]byte{tabwriter.Escape, '\t', tabwriter.Escape})
+	} else {
+		p.indent = strings.Repeat(" ", p.tabwidth())
+	}
+	p.nextComment()
+	return p
+}
+
+func (p *printer) tabwidth() int {
+	if p.cfg.Tabwidth <= 0 {
+		return 2
+	}
+	return p.cfg.Tabwidth
 }
 
 // Nodes
@@ -324,6 +341,9 @@ func (p *printer) printExpr(expr ast.Expr, mode mode) {
 			if !ok {
 				break
 			}
+			if _, ok := paren.X.(*ast.ParenExpr); !ok && isMultiValue(paren.X) {
+				break // (f()) truncates results
+			}
 			expr = paren.X
 		}
 		mode &^= noParen
@@ -371,7 +391,14 @@ func (p *printer) printVararg(expr *ast.Vararg, mode mode) {
 
 func (p *printer) printBasicLit(expr *ast.BasicLit, mode mode) {
 	if expr.Token.Type == token.STRING {
-		p.print(expr.Token.Pos, expr.Token.Lit, mode|escape)
+		lit := expr.Token.Lit
+		switch p.cfg.Quote {
+		case QuoteDouble:
+			lit = requote(lit, '"')
+		case QuoteSingle:
+			lit = requote(lit, '\'')
+		}
+		p.print(expr.Token.Pos, lit, mode|escape)
 	} else {
 		p.print(expr.Token.Pos, expr.Token.Lit, mode)
 	}
@@ -384,11 +411,23 @@ func (p *printer) printFuncLit(expr *ast.FuncLit, mode mode) {
 }
 
 func (p *printer) printTableLit(expr *ast.TableLit, mode mode) {
+	if p.shouldWrap(expr.Lbrace, expr.Rbrace, expr.Fields, mode) {
+		p.print(expr.Lbrace, "{", mode)
+		p.wrap(expr.Fields, p.cfg.TrailingSep != SepNever)
+		p.print(expr.Rbrace, "}", noBlank|newline)
+		return
+	}
+
 	p.print(expr.Lbrace, "{", mode)
 	p.indentWith(expr.Lbrace, expr.Rbrace, func() {
 		p.printExprs(expr.Fields, noBlank|noParen)
 		if len(expr.Fields) > 0 {
-			if expr.Rbrace.Line-p.lastPos.Line > 0 {
+			switch p.cfg.TrailingSep {
+			case SepMultiline:
+				if expr.Rbrace.Line-p.lastPos.Line > 0 {
+					p.writeByte(',')
+				}
+			case SepAlways:
 				p.writeByte(',')
 			}
 		}
@@ -426,6 +465,13 @@ func (p *printer) printCallExpr(expr *ast.CallExpr, mode mode) {
 		p.printName(expr.Name, noBlank)
 	}
 	if expr.Lparen != position.NoPos {
+		if p.shouldWrap(expr.Lparen, expr.Rparen, expr.Args, noBlank) {
+			p.print(expr.Lparen, "(", noBlank)
+			p.wrap(expr.Args, false)
+			p.print(expr.Rparen, ")", noBlank|newline)
+			return
+		}
+
 		p.print(expr.Lparen, "(", noBlank)
 		p.indentWith(expr.Lparen, expr.Rparen, func() {
 			p.printExprs(expr.Args, noBlank|noParen)
@@ -465,24 +511,33 @@ func (p *printer) printUnaryExpr(expr *ast.UnaryExpr, mode mode) {
 }
 
 func (p *printer) printBinaryExpr(expr *ast.BinaryExpr, prec1 int, mode mode) {
+	depth, indented := p.depth, p.indented
+	p.indented = expr.Pos().Line > p.lastPos.Line
+	p.printBinary(expr, prec1, mode)
+	p.depth, p.indented = depth, indented
+}
+
+func (p *printer) printBinary(expr *ast.BinaryExpr, prec1 int, mode mode) {
 	prec, _ := expr.Op.Precedence()
 
-	if (mode&compact != 0 || prec > prec1) && prec > 3 { // should cutoff?
+	if p.cutoff(expr.Op, prec, prec1, mode) {
 		// (1 + 8 * 9) => (1+8*9)
 		// 1 + 2 * 3 +4/5 +6 ^ 7 +8 => 1 + 2*3 + 4/5 + 6^7 + 8
 
 		if x, ok := expr.X.(*ast.BinaryExpr); ok {
-			p.printBinaryExpr(x, prec1, mode)
+			p.printBinary(x, prec1, mode)
 		} else {
-			if x, ok := expr.X.(*ast.BasicLit); ok && (x.Token.Type == token.INT || x.Token.Type == token.FLOAT) && expr.Op == token.CONCAT {
-				// 2 .. 3 > "22" => "2"..3 > "22"
-				p.print(x.Token.Pos, strconv.Quote(x.Token.Lit), mode)
-			} else {
-				p.printExpr(expr.X, mode)
-			}
+			p.printExpr(expr.X, mode)
 		}
 
-		p.print(expr.OpPos, expr.Op.String(), noBlank)
+		p.continueAt(expr.OpPos)
+		if expr.Op == token.CONCAT && endsWithNumber(expr.X) {
+			// 2 .. 3 > "22" => 2 ..3 > "22"
+			p.print(expr.OpPos, expr.Op.String(), 0)
+		} else {
+			p.print(expr.OpPos, expr.Op.String(), noBlank)
+		}
+		p.continueAt(expr.Y.Pos())
 
 		switch y := expr.Y.(type) {
 		case *ast.UnaryExpr:
@@ -497,18 +552,27 @@ func (p *printer) printBinaryExpr(expr *ast.BinaryExpr, prec1 int, mode mode) {
 				p.printUnaryExpr(y, noBlank)
 			}
 		case *ast.BinaryExpr:
-			p.printBinaryExpr(y, prec1, noBlank)
+			p.printBinary(y, prec1, noBlank)
+		case *ast.BasicLit:
+			if y.Token.Type == token.FLOAT && strings.HasPrefix(y.Token.Lit, ".") && expr.Op == token.CONCAT {
+				// a .. .5 => a .. .5
+				p.printExpr(expr.Y, 0)
+			} else {
+				p.printExpr(expr.Y, noBlank)
+			}
 		default:
 			p.printExpr(expr.Y, noBlank)
 		}
 	} else {
 		if x, ok := expr.X.(*ast.BinaryExpr); ok {
-			p.printBinaryExpr(x, prec, mode)
+			p.printBinary(x, prec, mode)
 		} else {
 			p.printExpr(expr.X, mode)
 		}
 
+		p.continueAt(expr.OpPos)
 		p.print(expr.OpPos, expr.Op.String(), 0)
+		p.continueAt(expr.Y.Pos())
 
 		switch y := expr.Y.(type) {
 		case *ast.UnaryExpr:
@@ -523,13 +587,30 @@ func (p *printer) printBinaryExpr(expr *ast.BinaryExpr, prec1 int, mode mode) {
 				p.printUnaryExpr(y, 0)
 			}
 		case *ast.BinaryExpr:
-			p.printBinaryExpr(y, prec, 0)
+			p.printBinary(y, prec, 0)
 		default:
 			p.printExpr(expr.Y, 0)
 		}
 	}
 }
 
+// cutoff reports whether blanks around op should be omitted.
+func (p *printer) cutoff(op token.Type, prec, prec1 int, mode mode) bool {
+	spacing := p.cfg.OpSpacing
+	if op == token.CONCAT {
+		spacing = p.cfg.ConcatSpacing
+	}
+
+	switch spacing {
+	case SpaceAlways:
+		return false
+	case SpaceNever:
+		return op != token.AND && op != token.OR
+	}
+
+	return (mode&compact != 0 || prec > prec1) && prec > 3
+}
+
 func (p *printer) printKeyValueExpr(expr *ast.KeyValueExpr, mode mode) {
 	if expr.Lbrack != position.NoPos {
 		p.print(expr.Lbrack, "[", mode)
@@ -579,6 +660,14 @@ func replaceEscape(s string) string {
 	return s
 }
 
+// escapeLit makes the string literal s safe to be written between tabwriter.Escape.
+func escapeLit(s string) string {
+	if strings.HasPrefix(s, "[") {
+		return replaceEscape(s)
+	}
+	return strings.Replace(s, string([]byte{tabwriter.Escape}), `\255`, -1)
+}
+
 func (p *printer) printFile(file *ast.File) {
 	if file.Shebang != "" {
 		p.writeByte(tabwriter.Escape)
@@ -628,11 +717,18 @@ func (p *printer) printNames(names []*ast.Name, mode mode) {
 	if len(names) == 0 {
 		return
 	}
+
+	depth, indented := p.depth, p.indented
+	p.indented = names[0].Pos().Line > p.lastPos.Line
+
 	p.printName(names[0], mode)
 	for _, name := range names[1:] {
 		p.print(p.lastPos, ",", noBlank)
+		p.continueAt(name.Pos())
 		p.printName(name, 0)
 	}
+
+	p.depth, p.indented = depth, indented
 }
 
 func (p *printer) printExprs(exprs []ast.Expr, mode mode) {
@@ -642,14 +738,112 @@ func (p *printer) printExprs(exprs []ast.Expr, mode mode) {
 	case 1:
 		p.printExpr(exprs[0], mode)
 	default:
+		depth, indented := p.depth, p.indented
+		p.indented = exprs[0].Pos().Line > p.lastPos.Line
+
 		p.printExpr(exprs[0], mode|compact)
 		for _, expr := range exprs[1:] {
 			p.print(p.lastPos, ",", noBlank)
+			p.continueAt(expr.Pos())
 			p.printExpr(expr, noParen|compact)
 		}
+
+		p.depth, p.indented = depth, indented
 	}
 }
 
+// continueAt indents the rest of the current list or binary expression,
+// if pos is the beginning of its first continuation line.
+func (p *printer) continueAt(pos position.Position) {
+	if !p.indented && pos.Line > p.lastPos.Line {
+		p.depth++
+		p.indented = true
+	}
+}
+
+func isMultiValue(expr ast.Expr) bool {
+	switch expr.(type) {
+	case *ast.CallExpr, *ast.Vararg:
+		return true
+	}
+	return false
+}
+
+func endsWithNumber(expr ast.Expr) bool {
+	switch expr := expr.(type) {
+	case *ast.BasicLit:
+		return expr.Token.Type == token.INT || expr.Token.Type == token.FLOAT
+	case *ast.UnaryExpr:
+		return endsWithNumber(expr.X)
+	case *ast.BinaryExpr:
+		return endsWithNumber(expr.Y)
+	}
+	return false
+}
+
+// shouldWrap reports whether the list between open and close is too long to be on the current line.
+func (p *printer) shouldWrap(open, close position.Position, exprs []ast.Expr, mode mode) bool {
+	if p.cfg.MaxWidth <= 0 || len(exprs) == 0 || open.Line != close.Line {
+		return false
+	}
+
+	width := p.col + 2 // open and close
+	if mode&noBlank == 0 {
+		width++
+	}
+	for i, e := range exprs {
+		if i > 0 {
+			width += 2 // ", "
+		}
+		width += p.width(e)
+		if width > p.cfg.MaxWidth {
+			return true
+		}
+	}
+	return false
+}
+
+// width returns the width of the first line of expr printed without wrapping.
+func (p *printer) width(expr ast.Expr) int {
+	cfg := *p.cfg
+	cfg.MaxWidth = 0
+
+	var buf bytes.Buffer
+
+	sub := newPrinter(&buf, &cfg)
+	sub.lastPos = position.Position{Line: expr.Pos().Line}
+	sub.nextComment()
+	sub.printExpr(expr, noBlank|noParen)
+	sub.w.Flush()
+
+	s := buf.String()
+	if i := strings.IndexByte(s, '\n'); i != -1 {
+		s = s[:i]
+	}
+	return utf8.RuneCountInString(s)
+}
+
+// wrap prints exprs one per line.
+func (p *printer) wrap(exprs []ast.Expr, trailingSep bool) {
+	depth := p.depth
+
+	p.depth++
+	p.formfeed = true
+
+	for i, e := range exprs {
+		if i > 0 {
+			p.print(p.lastPos, ",", noBlank)
+		}
+		p.printExpr(e, noParen|newline)
+	}
+	if trailingSep {
+		p.writeByte(',')
+	}
+
+	p.depth = depth
+	p.formfeed = true
+}
+
 func (p *printer) indentWith(pos, end position.Position, fn func()) {
 	if pos.Line == end.Line {
 		fn()
@@ -695,9 +889,7 @@ func (p *printer) insertComment(pos position.Position) {
 				if d > 1 {
 					p.writeByte('\f')
 				}
-				for i := 0; i < p.depth; i++ {
-					p.writeString(indent)
-				}
+				p.writeIndent()
 				p.formfeed = false
 			default:
 				panic("unexpected")
@@ -740,6 +932,27 @@ func (p *printer) print(pos position.Position, s string, mode mode) {
 
 	d := pos.Line - p.lastPos.Line
 
+	// nodes which are created or moved by rewriters may have
+	// no position or a position before the last printed one.
+	// print them in line, except statements following another one,
+	// without moving lastPos back.
+	lastPos := pos.Offset(s)
+	if d < 0 || !pos.IsValid() {
+		if mode&insertSemi != 0 && p.stmtEnd {
+			d = 1
+		} else {
+			d = 0
+		}
+		lastPos = p.lastPos
+		if lastPos == initPos {
+			lastPos = lastPos.Offset(s)
+		}
+	}
+
+	if mode&newline != 0 && d == 0 {
+		d = 1
+	}
+
 	switch {
 	case d == 0:
 		if mode&noBlank == 0 {
@@ -759,32 +972,35 @@ func (p *printer) print(pos position.Position, s string, mode mode) {
 		if d > 1 {
 			p.writeByte('\f')
 		}
-		for i := 0; i < p.depth; i++ {
-			p.writeString(indent)
-		}
+		p.writeIndent()
 		p.formfeed = false
-	default:
-		panic("unexpected")
+		if s == "(" && p.stmtEnd && mode&insertSemi != 0 {
+			// f\n(g)() is a call of f, f\n;(g)() isn't
+			p.writeByte(';')
+		}
 	}
 
 	if mode&escape != 0 {
 		p.writeByte(tabwriter.Escape)
-		p.writeString(strconv.Escape(s))
+		p.writeString(escapeLit(s))
 		p.writeByte(tabwriter.Escape)
 	} else {
 		p.writeString(s)
 	}
 
-	p.lastPos = pos.Offset(s)
+	p.lastPos = lastPos
 
 	p.stmtEnd = false
 }
 
-func (p *printer) writeByte(c byte) {
-	if p.err != nil {
-		return
+func (p *printer) writeIndent() {
+	for i := 0; i < p.depth; i++ {
+		p.writeString(p.indent)
 	}
-	_, p.err = p.w.Write([]byte{c})
+}
+
+func (p *printer) writeByte(c byte) {
+	p.writeString(string([]byte{c}))
 }
 
 func (p *printer) writeString(s string) {
@@ -792,6 +1008,18 @@ func (p *printer) writeString(s string) {
 		return
 	}
 	_, p.err = p.w.Write([]byte(s))
+
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\n' || c == '\f':
+			p.col = 0
+		case c == '\t':
+			p.col += p.tabwidth()
+		case c == tabwriter.Escape || c&0xC0 == 0x80: // skip escapes and continuation bytes
+		default:
+			p.col++
+		}
+	}
 }
 
 func trimRightCR(s string) string {
@@ -800,3 +1028,39 @@ func trimRightCR(s string) string {
 	}
 	return s
 }
+
+// requote replaces the delimiters of the short string lit with q,
+// unless it requires more escapes than before.
+func requote(lit string, q byte) string {
+	if len(lit) < 2 || lit[0] == q || (lit[0] != '"' && lit[0] != '\'') {
+		return lit
+	}
+
+	old := lit[0]
+	body := lit[1 : len(lit)-1]
+
+	if strings.Count(body, string(q)) > strings.Count(body, string(old)) {
+		return lit
+	}
+
+	b := make([]byte, 0, len(lit)+2)
+	b = append(b, q)
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case c == '\\' && i+1 < len(body):
+			i++
+			if body[i] != old {
+				b = append(b, c)
+			}
+			b = append(b, body[i])
+		case c == q:
+			b = append(b, '\\', c)
+		default:
+			b = append(b, c)
+		}
+	}
+	b = append(b, q)
+
+	return string(b)
+}
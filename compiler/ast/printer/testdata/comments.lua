@@ -0,0 +1,34 @@
+-- comments inside expressions
+
+local total = base + -- the base value
+   extra * 2 -- doubled
+   - discount -- minus discount
+
+if ready and -- first condition
+   not busy or -- second condition
+   forced then
+  start()
+end
+
+call(first, -- the first argument
+     second, --[[ the second argument ]] third,
+     -- leading comment of the last argument
+     fourth)
+
+local config = {
+  name = "plua", -- name
+  -- leading comment
+  version = 1 + --[[ major ]] 0.3,
+  list = {1, 2, -- first two
+          3},
+  [key] = -- value on the next line
+    value,
+}
+
+local s = "a" .. -- first
+  "b" .. -- second
+  "c"
+
+return a, -- first
+  b, --[[ second ]]
+  c
@@ -18,10 +18,13 @@ end
  man
  ]]
 
--- TODO
--- x = 1 + -- bar
--- 4 + 9 -- foo
--- + 10 -- baz
+x = 1 + -- bar
+4 + 9 -- foo
++ 10 -- baz
+
+f(a, -- first
+b, -- second
+c)
 
 x = x + 5 * 9 - 10 / 5 - - 5
 x = x + (5 - 1+9) * 6
@@ -0,0 +1,280 @@
+package scope
+
+import (
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/token"
+	"github.com/hirochachacha/plua/position"
+)
+
+type resolver struct {
+	info *Info
+
+	scope *frame
+}
+
+// frame is a scope during resolution.
+type frame struct {
+	*Scope
+
+	outer *frame
+	names map[string]*Decl
+}
+
+// Resolve resolves all names in f.
+func Resolve(f *ast.File) *Info {
+	root := &Scope{
+		Node:  f,
+		Start: f.Pos(),
+		End:   f.End(),
+	}
+
+	info := &Info{
+		File:     f,
+		Root:     root,
+		Env:      &Decl{Name: "_ENV", Kind: Env, Scope: root, Visible: f.Pos()},
+		Bindings: make(map[*ast.Name]*Binding),
+		Decls:    make(map[*ast.Name]*Decl),
+		Globals:  make(map[string][]*ast.Name),
+	}
+
+	r := &resolver{
+		info:  info,
+		scope: &frame{Scope: root, names: make(map[string]*Decl)},
+	}
+
+	r.stmtList(f.Chunk)
+
+	return info
+}
+
+func (r *resolver) openScope(node ast.Node, start, end position.Position) {
+	s := &Scope{
+		Parent: r.scope.Scope,
+		Node:   node,
+		Start:  start,
+		End:    end,
+	}
+
+	r.scope.Children = append(r.scope.Children, s)
+
+	r.scope = &frame{Scope: s, outer: r.scope, names: make(map[string]*Decl)}
+}
+
+func (r *resolver) closeScope() {
+	r.scope = r.scope.outer
+}
+
+func (r *resolver) declare(name *ast.Name, kind Kind, visible position.Position) {
+	d := &Decl{
+		Name:    name.Name,
+		Ident:   name,
+		Kind:    kind,
+		Scope:   r.scope.Scope,
+		Visible: visible,
+	}
+
+	r.addDecl(d)
+
+	r.info.Decls[name] = d
+}
+
+func (r *resolver) addDecl(d *Decl) {
+	r.scope.Decls = append(r.scope.Decls, d)
+	r.scope.names[d.Name] = d
+}
+
+// lookup returns the declaration of name and function scopes between the current scope and the declaration.
+func (r *resolver) lookup(name string) (*Decl, []*Scope) {
+	var upvals []*Scope
+
+	for s := r.scope; s != nil; s = s.outer {
+		if d, ok := s.names[name]; ok {
+			return d, upvals
+		}
+		if s.IsFunc() {
+			upvals = append(upvals, s.Scope)
+		}
+	}
+
+	return nil, upvals
+}
+
+func (r *resolver) ref(name *ast.Name, write bool) {
+	d, upvals := r.lookup(name.Name)
+	if d != nil {
+		kind := Local
+		if len(upvals) > 0 {
+			kind = Upvalue
+		}
+
+		r.info.Bindings[name] = &Binding{Kind: kind, Decl: d, Upvals: upvals, Write: write}
+
+		d.Refs = append(d.Refs, name)
+
+		return
+	}
+
+	// the implicit _ENV is an upvalue of the main chunk
+	if name.Name == "_ENV" {
+		r.info.Bindings[name] = &Binding{Kind: Upvalue, Decl: r.info.Env, Upvals: upvals, Write: write}
+
+		r.info.Env.Refs = append(r.info.Env.Refs, name)
+
+		return
+	}
+
+	env, upvals := r.lookup("_ENV")
+	if env == nil {
+		env = r.info.Env
+	}
+
+	r.info.Bindings[name] = &Binding{Kind: Global, Decl: env, Upvals: upvals, Write: write}
+
+	r.info.Globals[name.Name] = append(r.info.Globals[name.Name], name)
+}
+
+func (r *resolver) block(b *ast.Block) {
+	r.openScope(b, b.Opening, b.Closing)
+	r.stmtList(b.List)
+	r.closeScope()
+}
+
+func (r *resolver) stmtList(list []ast.Stmt) {
+	for _, stmt := range list {
+		r.stmt(stmt)
+	}
+}
+
+func (r *resolver) stmt(stmt ast.Stmt) {
+	switch stmt := stmt.(type) {
+	case *ast.LocalAssignStmt:
+		r.exprList(stmt.RHS)
+		for _, name := range stmt.LHS {
+			r.declare(name, LocalVar, stmt.End())
+		}
+	case *ast.LocalFuncStmt:
+		r.declare(stmt.Name, LocalFunc, stmt.Name.Pos())
+		r.funcBody(stmt.Body, false)
+	case *ast.FuncStmt:
+		if stmt.PathList == nil {
+			r.ref(stmt.Name, true)
+		} else {
+			r.ref(stmt.PathList[0], false)
+		}
+		r.funcBody(stmt.Body, stmt.AccessTok == token.COLON)
+	case *ast.ExprStmt:
+		r.expr(stmt.X)
+	case *ast.AssignStmt:
+		for _, e := range stmt.LHS {
+			if name, ok := e.(*ast.Name); ok {
+				r.ref(name, true)
+			} else {
+				r.expr(e)
+			}
+		}
+		r.exprList(stmt.RHS)
+	case *ast.IfStmt:
+		r.expr(stmt.Cond)
+		r.block(stmt.Body)
+		for _, e := range stmt.ElseIfList {
+			r.expr(e.Cond)
+			r.block(e.Body)
+		}
+		if stmt.ElseBody != nil {
+			r.block(stmt.ElseBody)
+		}
+	case *ast.DoStmt:
+		r.block(stmt.Body)
+	case *ast.WhileStmt:
+		r.expr(stmt.Cond)
+		r.block(stmt.Body)
+	case *ast.RepeatStmt:
+		// the condition can refer to locals of the body
+		r.openScope(stmt, stmt.Body.Opening, stmt.Cond.End())
+		r.stmtList(stmt.Body.List)
+		r.expr(stmt.Cond)
+		r.closeScope()
+	case *ast.ReturnStmt:
+		r.exprList(stmt.Results)
+	case *ast.ForStmt:
+		r.expr(stmt.Start)
+		r.expr(stmt.Finish)
+		if stmt.Step != nil {
+			r.expr(stmt.Step)
+		}
+		r.openScope(stmt, stmt.Do, stmt.EndPos)
+		r.declare(stmt.Name, ForVar, stmt.Do)
+		r.block(stmt.Body)
+		r.closeScope()
+	case *ast.ForEachStmt:
+		r.exprList(stmt.Exprs)
+		r.openScope(stmt, stmt.Do, stmt.EndPos)
+		for _, name := range stmt.Names {
+			r.declare(name, ForVar, stmt.Do)
+		}
+		r.block(stmt.Body)
+		r.closeScope()
+	case *ast.BadStmt, *ast.EmptyStmt, *ast.LabelStmt, *ast.GotoStmt, *ast.BreakStmt:
+		// nothing to do
+	default:
+		panic("unreachable")
+	}
+}
+
+func (r *resolver) funcBody(body *ast.FuncBody, isMethod bool) {
+	r.openScope(body, body.Pos(), body.End())
+	if isMethod {
+		r.addDecl(&Decl{Name: "self", Kind: Self, Scope: r.scope.Scope, Visible: body.Pos()})
+	}
+	for _, name := range body.Params.List {
+		r.declare(name, Param, name.Pos())
+	}
+	// parameters and top level locals share the same scope
+	r.stmtList(body.Body.List)
+	r.closeScope()
+}
+
+func (r *resolver) exprList(list []ast.Expr) {
+	for _, e := range list {
+		r.expr(e)
+	}
+}
+
+func (r *resolver) expr(expr ast.Expr) {
+	switch expr := expr.(type) {
+	case *ast.Name:
+		r.ref(expr, false)
+	case *ast.FuncLit:
+		r.funcBody(expr.Body, false)
+	case *ast.TableLit:
+		for _, e := range expr.Fields {
+			if kv, ok := e.(*ast.KeyValueExpr); ok {
+				if kv.Lbrack.IsValid() {
+					r.expr(kv.Key)
+				}
+				r.expr(kv.Value)
+			} else {
+				r.expr(e)
+			}
+		}
+	case *ast.ParenExpr:
+		r.expr(expr.X)
+	case *ast.SelectorExpr:
+		r.expr(expr.X)
+	case *ast.IndexExpr:
+		r.expr(expr.X)
+		r.expr(expr.Index)
+	case *ast.CallExpr:
+		r.expr(expr.X)
+		r.exprList(expr.Args)
+	case *ast.UnaryExpr:
+		r.expr(expr.X)
+	case *ast.BinaryExpr:
+		r.expr(expr.X)
+		r.expr(expr.Y)
+	case *ast.BadExpr, *ast.Vararg, *ast.BasicLit:
+		// nothing to do
+	default:
+		panic("unreachable")
+	}
+}
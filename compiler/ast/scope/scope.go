@@ -0,0 +1,199 @@
+// Package scope resolves variable names of Lua sources.
+//
+// Resolve walks an *ast.File by the scoping rules of Lua 5.3 and returns
+// the binding of every name which denotes a variable, the declarations
+// with their references and the tree of scopes.
+//
+// Names which don't denote variables, such as field selectors, method names,
+// table keys and labels, have no bindings.
+package scope
+
+import (
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/position"
+)
+
+type Kind int
+
+const (
+	LocalVar  Kind = iota // local x
+	LocalFunc             // local function f
+	Param                 // function parameter
+	ForVar                // control variable of for statements
+	Self                  // implicit "self" parameter of methods
+	Env                   // implicit "_ENV" upvalue of the main chunk
+)
+
+var kindNames = [...]string{
+	LocalVar:  "local variable",
+	LocalFunc: "local function",
+	Param:     "parameter",
+	ForVar:    "loop variable",
+	Self:      "self parameter",
+	Env:       "environment",
+}
+
+func (k Kind) String() string {
+	return kindNames[k]
+}
+
+// Decl represents a declaration of a local variable.
+type Decl struct {
+	Name  string
+	Ident *ast.Name // declaring name; or nil for implicit declarations
+	Kind  Kind
+	Scope *Scope
+
+	// position where the variable becomes visible.
+	// e.g. in "local x = x", the right hand side x doesn't refer to the left hand side one.
+	Visible position.Position
+
+	Refs []*ast.Name // references in source order, excluding Ident
+}
+
+func (d *Decl) Pos() position.Position {
+	if d.Ident == nil {
+		return position.NoPos
+	}
+	return d.Ident.Pos()
+}
+
+// IsImplicit reports whether d is declared without names in the source.
+func (d *Decl) IsImplicit() bool {
+	return d.Ident == nil
+}
+
+type BindingKind int
+
+const (
+	Local   BindingKind = iota // local variable of the current function
+	Upvalue                    // local variable of an enclosing function
+	Global                     // field of _ENV
+)
+
+var bindingKindNames = [...]string{
+	Local:   "local",
+	Upvalue: "upvalue",
+	Global:  "global",
+}
+
+func (k BindingKind) String() string {
+	return bindingKindNames[k]
+}
+
+// Binding represents what a name refers to.
+type Binding struct {
+	Kind BindingKind
+
+	// declaration of the variable.
+	// for globals, it is the declaration of _ENV in effect.
+	Decl *Decl
+
+	// function scopes which capture Decl, innermost first.
+	// it is empty if Decl belongs to the current function.
+	// the implicit _ENV is captured by the main chunk too.
+	Upvals []*Scope
+
+	Write bool // assignment to the variable
+}
+
+// Scope represents a lexical scope.
+type Scope struct {
+	Parent   *Scope
+	Children []*Scope
+
+	// one of *ast.File, *ast.FuncBody, *ast.Block, *ast.ForStmt,
+	// *ast.ForEachStmt and *ast.RepeatStmt.
+	Node ast.Node
+
+	Start, End position.Position
+
+	Decls []*Decl // declarations in declaration order
+}
+
+// IsFunc reports whether s is the outermost scope of a function or the main chunk.
+func (s *Scope) IsFunc() bool {
+	switch s.Node.(type) {
+	case *ast.File, *ast.FuncBody:
+		return true
+	}
+	return false
+}
+
+// Func returns the function scope which s belongs to.
+func (s *Scope) Func() *Scope {
+	for !s.IsFunc() {
+		s = s.Parent
+	}
+	return s
+}
+
+// Contains reports whether pos is in the range of s.
+func (s *Scope) Contains(pos position.Position) bool {
+	if s.Parent == nil {
+		return true
+	}
+	return !pos.LessThan(s.Start) && pos.LessThan(s.End)
+}
+
+// Innermost returns the innermost scope containing pos.
+func (s *Scope) Innermost(pos position.Position) *Scope {
+	if !s.Contains(pos) {
+		return nil
+	}
+
+loop:
+	for {
+		for _, child := range s.Children {
+			if child.Contains(pos) {
+				s = child
+				continue loop
+			}
+		}
+		return s
+	}
+}
+
+// Lookup returns the declaration of name visible at pos in s,
+// searching the enclosing scopes too. Lookup returns nil for globals.
+func (s *Scope) Lookup(name string, pos position.Position) *Decl {
+	for ; s != nil; s = s.Parent {
+		for i := len(s.Decls) - 1; i >= 0; i-- {
+			d := s.Decls[i]
+			if d.Name == name && !pos.LessThan(d.Visible) {
+				return d
+			}
+		}
+	}
+	return nil
+}
+
+// Info is the result of Resolve.
+type Info struct {
+	File *ast.File
+
+	Root *Scope // scope of the main chunk
+	Env  *Decl  // implicit _ENV of the main chunk
+
+	Bindings map[*ast.Name]*Binding // references
+	Decls    map[*ast.Name]*Decl    // declaring names
+
+	Globals map[string][]*ast.Name // references of globals by name, in source order
+}
+
+// Binding returns the binding of name, or nil if name isn't a reference.
+func (info *Info) Binding(name *ast.Name) *Binding {
+	return info.Bindings[name]
+}
+
+// DeclOf returns the declaration which name declares or refers to.
+// DeclOf returns nil for globals and names which don't denote variables.
+func (info *Info) DeclOf(name *ast.Name) *Decl {
+	if d, ok := info.Decls[name]; ok {
+		return d
+	}
+	if b, ok := info.Bindings[name]; ok && b.Kind != Global {
+		return b.Decl
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package scope_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/ast"
+	"github.com/hirochachacha/plua/compiler/ast/scope"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/position"
+)
+
+var testCode = `local a = 1
+local function f(b)
+  local c = a + b
+  return function()
+    return c + g
+  end
+end
+local a = a
+for i = 1, 2 do
+  repeat local d = i until d
+end
+local t = {}
+function t:m(x)
+  return self, x, t.y
+end
+do
+  local _ENV = {}
+  h = a
+end
+`
+
+func resolve(t *testing.T, code string) *scope.Info {
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(code), "=test", 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return scope.Resolve(f)
+}
+
+// names returns all names in f by "name@line:column".
+func names(f *ast.File) map[string]*ast.Name {
+	m := make(map[string]*ast.Name)
+	for _, stmt := range f.Chunk {
+		ast.Walk(stmt, func(node ast.Node) bool {
+			if name, ok := node.(*ast.Name); ok {
+				m[fmt.Sprintf("%s@%d:%d", name.Name, name.Pos().Line, name.Pos().Column)] = name
+			}
+			return false
+		})
+	}
+	return m
+}
+
+func TestResolve(t *testing.T) {
+	info := resolve(t, testCode)
+
+	ns := names(info.File)
+
+	get := func(key string) *ast.Name {
+		name, ok := ns[key]
+		if !ok {
+			t.Fatalf("name %s is not found", key)
+		}
+		return name
+	}
+
+	decl := func(key string) *scope.Decl {
+		d := info.Decls[get(key)]
+		if d == nil {
+			t.Fatalf("%s is not a declaration", key)
+		}
+		return d
+	}
+
+	binding := func(key string) *scope.Binding {
+		b := info.Binding(get(key))
+		if b == nil {
+			t.Fatalf("%s is not a reference", key)
+		}
+		return b
+	}
+
+	testBinding := func(key string, kind scope.BindingKind, d *scope.Decl, nupvals int) {
+		b := binding(key)
+		if b.Kind != kind {
+			t.Errorf("%s: expected %v, got %v", key, kind, b.Kind)
+		}
+		if b.Decl != d {
+			t.Errorf("%s: expected %v, got %v", key, d, b.Decl)
+		}
+		if len(b.Upvals) != nupvals {
+			t.Errorf("%s: expected %d upvalues, got %d", key, nupvals, len(b.Upvals))
+		}
+	}
+
+	a1 := decl("a@1:7")
+	a8 := decl("a@8:7")
+
+	testBinding("a@3:13", scope.Upvalue, a1, 1)
+	testBinding("b@3:17", scope.Local, decl("b@2:18"), 0)
+	testBinding("c@5:12", scope.Upvalue, decl("c@3:9"), 1)
+	testBinding("g@5:16", scope.Global, info.Env, 3)
+	testBinding("a@8:11", scope.Local, a1, 0)
+	testBinding("i@10:20", scope.Local, decl("i@9:5"), 0)
+	testBinding("d@10:28", scope.Local, decl("d@10:16"), 0)
+	testBinding("t@13:10", scope.Local, decl("t@12:7"), 0)
+	testBinding("x@14:16", scope.Local, decl("x@13:14"), 0)
+	testBinding("t@14:19", scope.Upvalue, decl("t@12:7"), 1)
+	testBinding("h@18:3", scope.Global, decl("_ENV@17:9"), 0)
+	testBinding("a@18:7", scope.Local, a8, 0)
+
+	if b := binding("h@18:3"); !b.Write {
+		t.Error("expected write access")
+	}
+
+	self := binding("self@14:10")
+	if self.Kind != scope.Local || self.Decl.Kind != scope.Self || !self.Decl.IsImplicit() {
+		t.Errorf("unexpected binding of self: %+v", self)
+	}
+
+	for _, key := range []string{"y@14:21", "m@13:12"} {
+		if b := info.Binding(get(key)); b != nil {
+			t.Errorf("%s: expected no binding, got %+v", key, b)
+		}
+	}
+
+	if len(a1.Refs) != 2 {
+		t.Errorf("expected 2 references, got %d", len(a1.Refs))
+	}
+	if len(info.Globals["g"]) != 1 || len(info.Globals["h"]) != 1 {
+		t.Errorf("unexpected globals: %v", info.Globals)
+	}
+
+	inner := info.Root.Innermost(position.Position{Line: 10, Column: 20})
+	if _, ok := inner.Node.(*ast.RepeatStmt); !ok {
+		t.Errorf("expected repeat scope, got %T", inner.Node)
+	}
+	if inner.Func() != info.Root {
+		t.Error("expected main chunk")
+	}
+	if d := inner.Lookup("a", position.Position{Line: 10, Column: 20}); d != a8 {
+		t.Errorf("expected %v, got %v", a8, d)
+	}
+	if d := info.Root.Lookup("a", position.Position{Line: 8, Column: 11}); d != a1 {
+		t.Errorf("expected %v, got %v", a1, d)
+	}
+}
@@ -0,0 +1,321 @@
+// Package cache implements a cache of compiled chunks of files and strings.
+//
+// A Cache can be shared by processes and goroutines, see runtime.SetCompileOptions.
+// Chunks are kept in memory, and are compiled again if modification times or sizes of their files change.
+// If the cache has a directory, compiled chunks of files are also saved in it as binary chunks
+// named by hashes of their sources, so that other programs can skip compilation.
+// Chunks of strings are kept in memory only, by hashes of their contents.
+//
+// Prototypes in the cache are shared by all closures of them, so they must not be modified.
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/compiler/undump"
+	"github.com/hirochachacha/plua/internal/version"
+	"github.com/hirochachacha/plua/object"
+)
+
+// ext is the extension of files in cache directories.
+const ext = ".luac"
+
+// maxStrings is the maximum number of chunks of strings kept in memory.
+// all of them are dropped if it's exceeded, so that programs generating code don't leak.
+const maxStrings = 1024
+
+// Cache is a cache of compiled chunks of files and strings.
+type Cache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[key]*entry
+	strings map[string]*object.Proto // by hash
+}
+
+type key struct {
+	path string
+	mode codegen.Mode
+}
+
+type entry struct {
+	modTime time.Time
+	size    int64
+	binary  bool // source is a binary chunk
+	p       *object.Proto
+}
+
+// New returns a new cache.
+// If dir is not empty, compiled chunks are also saved in dir, which is created as needed.
+func New(dir string) *Cache {
+	return &Cache{
+		dir:     dir,
+		entries: make(map[key]*entry),
+		strings: make(map[string]*object.Proto),
+	}
+}
+
+// Dir returns the directory of c, or "" if c is in memory only.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+// CompileFile is the same as (*compiler.Compiler).CompileFile with mode,
+// but returns the cached prototype if the file isn't changed.
+func (c *Cache) CompileFile(path string, typ compiler.FormatType, mode codegen.Mode) (*object.Proto, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	k := key{path: path, mode: mode}
+
+	c.mu.Lock()
+	e := c.entries[k]
+	c.mu.Unlock()
+
+	if e != nil && e.modTime.Equal(fi.ModTime()) && e.size == fi.Size() && accepts(typ, e.binary) {
+		return e.p, nil
+	}
+
+	src, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	srcname := "@" + path
+
+	binary := len(src) > 0 && src[0] == version.LUA_SIGNATURE[0]
+	if !accepts(typ, binary) {
+		return compile(src, srcname, typ, mode) // reports the format mismatch
+	}
+
+	var p *object.Proto
+
+	var sum string
+
+	if c.dir != "" && !binary {
+		sum = hash(src, srcname, mode)
+
+		p = c.load(sum)
+	}
+
+	if p == nil {
+		p, err = compile(src, srcname, typ, mode)
+		if err != nil {
+			return nil, err
+		}
+
+		if sum != "" {
+			c.save(sum, p)
+		}
+	}
+
+	c.mu.Lock()
+	c.entries[k] = &entry{
+		modTime: fi.ModTime(),
+		size:    fi.Size(),
+		binary:  binary,
+		p:       p,
+	}
+	c.mu.Unlock()
+
+	return p, nil
+}
+
+// CompileString is the same as (*compiler.Compiler).Compile of s with mode,
+// but returns the cached prototype if s is compiled already with the same source name.
+func (c *Cache) CompileString(s, srcname string, typ compiler.FormatType, mode codegen.Mode) (*object.Proto, error) {
+	src := []byte(s)
+
+	binary := len(src) > 0 && src[0] == version.LUA_SIGNATURE[0]
+	if !accepts(typ, binary) {
+		return compile(src, srcname, typ, mode) // reports the format mismatch
+	}
+
+	sum := hash(src, srcname, mode)
+
+	c.mu.Lock()
+	p := c.strings[sum]
+	c.mu.Unlock()
+
+	if p != nil {
+		return p, nil
+	}
+
+	p, err := compile(src, srcname, typ, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if len(c.strings) >= maxStrings {
+		c.strings = make(map[string]*object.Proto)
+	}
+	c.strings[sum] = p
+	c.mu.Unlock()
+
+	return p, nil
+}
+
+// Warm compiles files of paths with mode in advance.
+// It returns the first error, but compiles all files.
+func (c *Cache) Warm(mode codegen.Mode, paths ...string) error {
+	var first error
+
+	for _, path := range paths {
+		_, err := c.CompileFile(path, compiler.Either, mode)
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// Invalidate removes the file of path from the memory,
+// so that it's loaded again by the next compilation.
+func (c *Cache) Invalidate(path string) {
+	c.mu.Lock()
+	for k := range c.entries {
+		if k.path == path {
+			delete(c.entries, k)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// Reset removes all files and strings from the memory, and compiled chunks from the directory.
+func (c *Cache) Reset() error {
+	c.mu.Lock()
+	c.entries = make(map[key]*entry)
+	c.strings = make(map[string]*object.Proto)
+	c.mu.Unlock()
+
+	if c.dir == "" {
+		return nil
+	}
+
+	names, err := filepath.Glob(filepath.Join(c.dir, "*"+ext))
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if isSum(strings.TrimSuffix(filepath.Base(name), ext)) {
+			err := os.Remove(name)
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// load returns the compiled chunk of sum in the directory, or nil.
+// broken chunks are ignored, they will be replaced.
+func (c *Cache) load(sum string) *object.Proto {
+	f, err := os.Open(filepath.Join(c.dir, sum+ext))
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	p, err := undump.Undump(bufio.NewReader(f), undump.Native)
+	if err != nil {
+		return nil
+	}
+
+	return p
+}
+
+// save writes p as the compiled chunk of sum.
+// the cache directory is an optimization, so errors are ignored.
+func (c *Cache) save(sum string, p *object.Proto) {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return
+	}
+
+	// write a temporary file, and rename it, so that readers never see partial chunks.
+	f, err := ioutil.TempFile(c.dir, sum)
+	if err != nil {
+		return
+	}
+
+	w := bufio.NewWriter(f)
+
+	err = dump.DumpTo(w, p, dump.ColumnInfo)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(c.dir, sum+ext))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+}
+
+func compile(src []byte, srcname string, typ compiler.FormatType, mode codegen.Mode) (*object.Proto, error) {
+	c := compiler.NewCompiler()
+
+	c.Mode = mode
+
+	return c.Compile(bytes.NewReader(src), srcname, typ)
+}
+
+func accepts(typ compiler.FormatType, binary bool) bool {
+	switch typ {
+	case compiler.Text:
+		return !binary
+	case compiler.Binary:
+		return binary
+	default:
+		return true
+	}
+}
+
+// hash returns the name of the compiled chunk of src.
+// chunks contain source names, and the format depends on the version of plua.
+func hash(src []byte, srcname string, mode codegen.Mode) string {
+	h := sha1.New()
+
+	fmt.Fprintf(h, "%s %c %d %d %q\n", version.LUA_VERSION, version.PLUAC_FORMAT, version.PLUA_CACHE_FORMAT, mode, srcname)
+
+	h.Write(src)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isSum(s string) bool {
+	if len(s) != 2*sha1.Size {
+		return false
+	}
+
+	_, err := hex.DecodeString(s)
+
+	return err == nil
+}
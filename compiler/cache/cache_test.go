@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/object"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "plua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeFile(t *testing.T, path, src string) {
+	err := ioutil.WriteFile(path, []byte(src), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// equal reports whether p1 and p2 are dumped to the same chunk.
+func equal(t *testing.T, p1, p2 *object.Proto) bool {
+	var b1, b2 bytes.Buffer
+
+	if err := dump.DumpTo(&b1, p1, dump.ColumnInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := dump.DumpTo(&b2, p2, dump.ColumnInfo); err != nil {
+		t.Fatal(err)
+	}
+
+	return bytes.Equal(b1.Bytes(), b2.Bytes())
+}
+
+func TestCompileFile(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.lua")
+
+	writeFile(t, path, "return 1")
+
+	c := New("")
+
+	p1, err := c.CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p2, err := c.CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p1 != p2 {
+		t.Error("expected the cached prototype")
+	}
+
+	p3, err := c.CompileFile(path, compiler.Either, codegen.Optimize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p3 == p1 {
+		t.Error("expected a prototype of another mode")
+	}
+
+	writeFile(t, path, "return 'a', 'b'")
+
+	p4, err := c.CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p4 == p1 || len(p4.Constants) == len(p1.Constants) {
+		t.Error("expected a recompiled prototype")
+	}
+
+	c.Invalidate(path)
+
+	p5, err := c.CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p5 == p4 {
+		t.Error("expected a recompiled prototype")
+	}
+
+	_, err = c.CompileFile(path, compiler.Binary, 0)
+	if err == nil {
+		t.Error("expected format mismatch, got nil")
+	}
+
+	writeFile(t, path, "return +")
+
+	_, err = c.CompileFile(path, compiler.Either, 0)
+	if err == nil {
+		t.Error("expected syntax error, got nil")
+	}
+
+	_, err = c.CompileFile(filepath.Join(dir, "none.lua"), compiler.Either, 0)
+	if !os.IsNotExist(err) {
+		t.Errorf("expected not exist error, got %v", err)
+	}
+}
+
+func TestCompileString(t *testing.T) {
+	c := New("")
+
+	p1, err := c.CompileString("return 1", "=a", compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p2, err := c.CompileString("return 1", "=a", compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p1 != p2 {
+		t.Error("expected the cached prototype")
+	}
+
+	p3, err := c.CompileString("return 1", "=b", compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p3 == p1 {
+		t.Error("expected a prototype of another source name")
+	}
+
+	_, err = c.CompileString("return 1", "=a", compiler.Binary, 0)
+	if err == nil {
+		t.Error("expected format mismatch, got nil")
+	}
+
+	_, err = c.CompileString("return +", "=a", compiler.Either, 0)
+	if err == nil {
+		t.Error("expected syntax error, got nil")
+	}
+
+	for i := 0; i < 2*maxStrings; i++ {
+		_, err := c.CompileString(fmt.Sprintf("return %d", i), "=a", compiler.Either, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(c.strings); n > maxStrings {
+		t.Errorf("expected at most %d strings, got %d", maxStrings, n)
+	}
+}
+
+func TestDir(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "a.lua")
+	cachedir := filepath.Join(dir, "cache")
+
+	writeFile(t, path, "local x = ...\nreturn function() return x + 1.5 end")
+
+	err := New(cachedir).Warm(0, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := filepath.Glob(filepath.Join(cachedir, "*"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(names) != 1 {
+		t.Fatalf("expected a compiled chunk, got %v", names)
+	}
+
+	want, err := compiler.NewCompiler().CompileFile(path, compiler.Either)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := New(cachedir)
+
+	got, err := c.CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(t, got, want) {
+		t.Error("expected the compiled prototype")
+	}
+
+	// broken chunks are replaced.
+	writeFile(t, names[0], "broken")
+
+	got, err = New(cachedir).CompileFile(path, compiler.Either, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !equal(t, got, want) {
+		t.Error("expected the compiled prototype")
+	}
+
+	err = c.Reset()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(names[0]); !os.IsNotExist(err) {
+		t.Errorf("expected removed chunk, got %v", err)
+	}
+}
@@ -19,6 +19,24 @@ const (
 	skipConstantFolding      = false
 )
 
+// A Mode value is a set of flags (or 0).
+// They enable optimizations in addition to the default ones.
+type Mode uint
+
+// PropagateConstants is unsafe if debug.setlocal assigns locals of the chunk,
+// since the replaced references don't see the assigned values.
+// The pass is skipped for chunks which refer to the name "debug",
+// but other ways to reach the library, e.g. local d = require "debug",
+// and hooks or functions of other chunks calling debug.setlocal aren't detected.
+const (
+	ThreadJumps        Mode = 1 << iota // redirect jumps to jumps to their final destinations
+	EliminateDeadCode                   // remove unreachable instructions, such as ones after return and break
+	PropagateConstants                  // replace locals which are never assigned with their constant values
+	ShareConstants                      // share equal string constants among functions
+
+	Optimize = ThreadJumps | EliminateDeadCode | PropagateConstants | ShareConstants
+)
+
 var tmp ast.Name
 
 func tmpName(name string) *ast.Name {
@@ -28,11 +46,21 @@ func tmpName(name string) *ast.Name {
 }
 
 func Generate(f *ast.File) (proto *object.Proto, err error) {
+	return GenerateMode(f, 0)
+}
+
+// GenerateMode is the same as Generate, but enables optimizations of mode.
+func GenerateMode(f *ast.File, mode Mode) (proto *object.Proto, err error) {
 	g := newGenerator(nil)
 
 	g.Source = f.Filename
+	g.mode = mode
 	g.cfolds = make(map[ast.Expr]object.Value) // cache for constant folding
 
+	if mode&ShareConstants != 0 {
+		g.strings = make(map[object.String]object.String)
+	}
+
 	defer func() {
 		if r := recover(); r != nil {
 			b := r.(bailout)
@@ -41,6 +69,10 @@ func Generate(f *ast.File) (proto *object.Proto, err error) {
 		}
 	}()
 
+	if mode&PropagateConstants != 0 {
+		g.declareConstLocals(f)
+	}
+
 	g.genFile(f)
 
 	proto = g.Proto
@@ -64,7 +96,11 @@ type generator struct {
 	*scope            // block scope
 	outer  *generator // function scope
 
-	cfolds map[ast.Expr]object.Value // cache for constant folding
+	mode Mode
+
+	cfolds  map[ast.Expr]object.Value       // cache for constant folding
+	clocals map[*ast.Name]*constLocal       // references of constant locals
+	strings map[object.String]object.String // string constants shared among functions
 
 	sp int
 
@@ -72,7 +108,7 @@ type generator struct {
 
 	rconstants map[object.Value]int // reverse map of Proto.constants
 
-	tokLine int
+	tokPos position.Position
 
 	locktmp  bool // don't remove tmp variable by peep hole optimization
 	lockpeep bool // don't do peep hole optimization, because here is jump destination
@@ -92,7 +128,10 @@ func newGenerator(outer *generator) *generator {
 
 	if outer != nil {
 		g.Source = outer.Source
+		g.mode = outer.mode
 		g.cfolds = outer.cfolds
+		g.clocals = outer.clocals
+		g.strings = outer.strings
 	}
 
 	return g
@@ -182,7 +221,7 @@ func (g *generator) declareLabelPos(name string, pos position.Position) {
 }
 
 func (g *generator) genSetJumpPoint(name string, pos position.Position) {
-	g.tokLine = pos.Line
+	g.tokPos = pos
 
 	jmp := g.genJumpPoint()
 
@@ -363,6 +402,15 @@ func (g *generator) constant(val object.Value) (k int) {
 
 	k = len(g.Constants)
 
+	// reuse the string of other functions, so that they have the same data pointer
+	if s, ok := val.(object.String); ok && g.strings != nil {
+		if s0, ok := g.strings[s]; ok {
+			val = s0
+		} else {
+			g.strings[s] = s
+		}
+	}
+
 	g.Constants = append(g.Constants, val)
 
 	g.rconstants[key] = k
@@ -370,10 +418,10 @@ func (g *generator) constant(val object.Value) (k int) {
 	return
 }
 
-func (g *generator) proto(f *ast.FuncBody, self bool, endLine int) (p int) {
+func (g *generator) proto(f *ast.FuncBody, self bool, endPos position.Position) (p int) {
 	generator := newGenerator(g)
 
-	generator.genFuncBody(f, self, endLine)
+	generator.genFuncBody(f, self, endPos)
 
 	g.Protos = append(g.Protos, generator.Proto)
 
@@ -464,35 +512,38 @@ func (g *generator) closeScope() {
 }
 
 func (g *generator) pushInst(inst opcode.Instruction) {
-	g.pushInstLine(inst, g.tokLine)
+	g.pushInstPos(inst, g.tokPos)
 }
 
-func (g *generator) pushInstLine(inst opcode.Instruction, line int) {
+func (g *generator) pushInstPos(inst opcode.Instruction, pos position.Position) {
 	if !skipPeepholeOptimization && !g.lockpeep {
-		g.peepLine(inst, line)
+		g.peep(inst, pos)
 	} else {
 		g.Code = append(g.Code, inst)
-		g.LineInfo = append(g.LineInfo, line)
+		g.LineInfo = append(g.LineInfo, pos.Line)
+		g.ColumnInfo = append(g.ColumnInfo, pos.Column)
 	}
 	g.lockpeep = false
 }
 
 func (g *generator) pushTemp() (pc int) {
-	return g.pushTempLine(g.tokLine)
+	return g.pushTempPos(g.tokPos)
 }
 
-func (g *generator) pushTempLine(line int) (pc int) {
+func (g *generator) pushTempPos(pos position.Position) (pc int) {
 	pc = g.pc()
 
 	g.Code = append(g.Code, opcode.AsBx(opcode.JMP, 0, 0))
-	g.LineInfo = append(g.LineInfo, line)
+	g.LineInfo = append(g.LineInfo, pos.Line)
+	g.ColumnInfo = append(g.ColumnInfo, pos.Column)
 
 	return
 }
 
-func (g *generator) pushReturn() {
+func (g *generator) pushReturn(endPos position.Position) {
 	g.Code = append(g.Code, opcode.AB(opcode.RETURN, 0, 1))
-	g.LineInfo = append(g.LineInfo, g.LastLineDefined)
+	g.LineInfo = append(g.LineInfo, endPos.Line)
+	g.ColumnInfo = append(g.ColumnInfo, endPos.Column)
 }
 
 func (g *generator) unquoteString(tok token.Token) string {
@@ -132,12 +132,17 @@ func (g *generator) genExprN(expr ast.Expr, nrets int) (isvar bool) {
 
 // resolve or move
 func (g *generator) genName(expr *ast.Name, typ genType) (rk int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	if typ&genKey != 0 {
 		return g.markRK(g.constant(object.String(expr.Name)), true)
 	}
 
+	// constant propagation
+	if val, ok := g.foldName(expr); ok {
+		return g.genConst(val, typ)
+	}
+
 	l, ok := g.resolveName(expr)
 	if !ok {
 		return g.genGetGlobal(expr)
@@ -168,7 +173,7 @@ func (g *generator) genName(expr *ast.Name, typ genType) (rk int) {
 }
 
 func (g *generator) genVarargN(expr *ast.Vararg, nrets int) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	sp := g.sp
 
@@ -191,7 +196,7 @@ func (g *generator) genVarargN(expr *ast.Vararg, nrets int) (r int) {
 }
 
 func (g *generator) genBasicLit(expr *ast.BasicLit, typ genType) (rk int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	var val object.Value
 
@@ -240,15 +245,15 @@ func (g *generator) genBasicLit(expr *ast.BasicLit, typ genType) (rk int) {
 }
 
 func (g *generator) genFuncLit(expr *ast.FuncLit) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	body := expr.Body
 
-	endLine := expr.End().Line
+	endPos := expr.End()
 
-	p := g.proto(body, false, endLine)
+	p := g.proto(body, false, endPos)
 
-	g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+	g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 	r = g.sp
 
@@ -258,7 +263,7 @@ func (g *generator) genFuncLit(expr *ast.FuncLit) (r int) {
 }
 
 func (g *generator) genTableLit(expr *ast.TableLit) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	var a []ast.Expr
 	var m []*ast.KeyValueExpr
@@ -378,13 +383,14 @@ func (g *generator) genTableLit(expr *ast.TableLit) (r int) {
 }
 
 func (g *generator) genSelectorExpr(expr *ast.SelectorExpr) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	sp := g.sp
 
 	x := g.genExpr(expr.X, genR)
 	y := g.genName(expr.Sel, genKey)
 
+	g.tokPos = expr.Period
 	g.pushInst(opcode.ABC(opcode.GETTABLE, sp, x, y))
 
 	r = sp
@@ -396,13 +402,14 @@ func (g *generator) genSelectorExpr(expr *ast.SelectorExpr) (r int) {
 }
 
 func (g *generator) genIndexExpr(expr *ast.IndexExpr) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	sp := g.sp
 
 	x := g.genExpr(expr.X, genR)
 	y := g.genExpr(expr.Index, genR|genK)
 
+	g.tokPos = expr.Lbrack
 	g.pushInst(opcode.ABC(opcode.GETTABLE, sp, x, y))
 
 	r = sp
@@ -414,7 +421,7 @@ func (g *generator) genIndexExpr(expr *ast.IndexExpr) (r int) {
 }
 
 func (g *generator) genCallExprN(expr *ast.CallExpr, nrets int, isTail bool) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	sp := g.sp
 
@@ -429,6 +436,7 @@ func (g *generator) genCallExprN(expr *ast.CallExpr, nrets int, isTail bool) (r
 
 		name := g.genName(expr.Name, genKey)
 
+		g.tokPos = expr.Colon
 		g.pushInst(opcode.ABC(opcode.SELF, sp, self, name))
 
 		nargs++
@@ -455,6 +463,13 @@ func (g *generator) genCallExprN(expr *ast.CallExpr, nrets int, isTail bool) (r
 
 	g.locktmp = locktmp
 
+	// f"str" and f{...} have no parentheses
+	if expr.Lparen != position.NoPos {
+		g.tokPos = expr.Lparen
+	} else {
+		g.tokPos = expr.Args[0].Pos()
+	}
+
 	if isTail {
 		if isVar {
 			g.pushInst(opcode.ABC(opcode.TAILCALL, fn, 0, 0))
@@ -494,7 +509,7 @@ func (g *generator) genCallExprN(expr *ast.CallExpr, nrets int, isTail bool) (r
 }
 
 func (g *generator) genUnaryExpr(expr *ast.UnaryExpr, typ genType) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	if expr.Op == token.UNM {
 		if x, ok := expr.X.(*ast.BasicLit); ok {
@@ -523,6 +538,8 @@ func (g *generator) genUnaryExpr(expr *ast.UnaryExpr, typ genType) (r int) {
 
 	x := g.genExpr(expr.X, genR)
 
+	g.tokPos = expr.OpPos
+
 	switch expr.Op {
 	case token.UNM:
 		g.pushInst(opcode.AB(opcode.UNM, sp, x))
@@ -545,7 +562,7 @@ func (g *generator) genUnaryExpr(expr *ast.UnaryExpr, typ genType) (r int) {
 }
 
 func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	sp := g.sp
 
@@ -554,6 +571,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.EQ, 1, x, y))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -564,6 +582,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.EQ, 0, x, y))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -574,6 +593,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.LT, 1, x, y))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -584,6 +604,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.LE, 1, x, y))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -594,6 +615,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.LT, 1, y, x))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -604,6 +626,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.LE, 1, y, x))
 		g.pushInst(opcode.AsBx(opcode.JMP, 0, 1))
 		g.pushInst(opcode.ABC(opcode.LOADBOOL, sp, 0, 1))
@@ -614,61 +637,73 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.ADD, sp, x, y))
 	case token.SUB:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.SUB, sp, x, y))
 	case token.MUL:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.MUL, sp, x, y))
 	case token.MOD:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.MOD, sp, x, y))
 	case token.POW:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.POW, sp, x, y))
 	case token.DIV:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.DIV, sp, x, y))
 	case token.IDIV:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.IDIV, sp, x, y))
 	case token.BAND:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.BAND, sp, x, y))
 	case token.BOR:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.BOR, sp, x, y))
 	case token.BXOR:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.BXOR, sp, x, y))
 	case token.SHL:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.SHL, sp, x, y))
 	case token.SHR:
 		x := g.genExpr(expr.X, genR|genK)
 		y := g.genExpr(expr.Y, genR|genK)
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.SHR, sp, x, y))
 	case token.CONCAT:
 		locktmp := g.locktmp
@@ -693,6 +728,7 @@ func (g *generator) genBinaryExpr(expr *ast.BinaryExpr, typ genType) (r int) {
 
 		g.locktmp = locktmp
 
+		g.tokPos = expr.OpPos
 		g.pushInst(opcode.ABC(opcode.CONCAT, sp, x, y))
 	case token.AND:
 		if _, ok := g.foldExpr(expr.X); ok {
@@ -6,13 +6,16 @@ import (
 	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/position"
 )
 
 // gen with global scope
 func (g *generator) genFile(f *ast.File) {
 	g.newScope()
 
-	g.LastLineDefined = f.End().Line
+	endPos := f.End()
+
+	g.LastLineDefined = endPos.Line
 
 	g.IsVararg = true
 
@@ -22,7 +25,7 @@ func (g *generator) genFile(f *ast.File) {
 		g.genStmt(e)
 	}
 
-	g.pushReturn()
+	g.pushReturn(endPos)
 
 	g.closeScope()
 
@@ -31,9 +34,11 @@ func (g *generator) genFile(f *ast.File) {
 	}
 
 	g.closeJumps()
+
+	g.optimize()
 }
 
-func (g *generator) genFuncBody(f *ast.FuncBody, self bool, endLine int) {
+func (g *generator) genFuncBody(f *ast.FuncBody, self bool, endPos position.Position) {
 	g.newScope()
 
 	g.LineDefined = f.Pos().Line
@@ -62,13 +67,15 @@ func (g *generator) genFuncBody(f *ast.FuncBody, self bool, endLine int) {
 		g.genBlock(f.Body)
 	}
 
-	g.LastLineDefined = endLine
+	g.LastLineDefined = endPos.Line
 
-	g.pushReturn()
+	g.pushReturn(endPos)
 
 	g.closeScope()
 
 	g.closeJumps()
+
+	g.optimize()
 }
 
 func (g *generator) genBlock(b *ast.Block) {
@@ -83,6 +90,29 @@ func (g *generator) genTest(cond ast.Expr, not bool) immBool {
 	case *ast.ParenExpr:
 		return g.genTest(cond.X, not)
 
+	case *ast.Name:
+		if !skipDeadCodeElimination {
+			if val, ok := g.foldName(cond); ok {
+				if object.ToGoBool(val) {
+					if not {
+						return immFalse
+					}
+					return immTrue
+				}
+
+				if not {
+					return immTrue
+				}
+				return immFalse
+			}
+		}
+
+		x := g.genExpr(cond, genR)
+		if not {
+			g.pushInst(opcode.AC(opcode.TEST, x, 1))
+		} else {
+			g.pushInst(opcode.AC(opcode.TEST, x, 0))
+		}
 	case *ast.BasicLit:
 		if !skipDeadCodeElimination {
 			switch cond.Token.Type {
@@ -267,6 +297,31 @@ func (g *generator) genTest(cond ast.Expr, not bool) immBool {
 }
 
 func (g *generator) genConst(val object.Value, typ genType) (rk int) {
+	if typ&genK == 0 {
+		switch val := val.(type) {
+		case nil:
+			g.pushInst(opcode.AB(opcode.LOADNIL, g.sp, 0))
+
+			rk = g.sp
+
+			g.nextSP()
+
+			return
+		case object.Boolean:
+			if val {
+				g.pushInst(opcode.ABC(opcode.LOADBOOL, g.sp, 1, 0))
+			} else {
+				g.pushInst(opcode.ABC(opcode.LOADBOOL, g.sp, 0, 0))
+			}
+
+			rk = g.sp
+
+			g.nextSP()
+
+			return
+		}
+	}
+
 	k := g.constant(val)
 
 	if typ&genK != 0 {
@@ -8,6 +8,7 @@ import (
 	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/position"
 )
 
 type immBool int
@@ -105,13 +106,13 @@ func (g *generator) genLocalFuncStmt(stmt *ast.LocalFuncStmt) {
 
 	body := stmt.Body
 
-	endLine := stmt.End().Line
+	endPos := stmt.End()
 
 	g.declareLocalName(name, g.sp) // declare before genFuncBody (for recursive function)
 
-	p := g.proto(body, false, endLine)
+	p := g.proto(body, false, endPos)
 
-	g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+	g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 	g.LocVars[len(g.LocVars)-1].StartPC++ // adjust StartPC (start from CLOSURE)
 
@@ -126,26 +127,26 @@ func (g *generator) genFuncStmt(stmt *ast.FuncStmt) {
 
 	body := stmt.Body
 
-	endLine := stmt.End().Line
+	endPos := stmt.End()
 
 	if prefix == nil {
 		l, ok := g.resolveName(name)
 
-		p := g.proto(body, false, endLine)
+		p := g.proto(body, false, endPos)
 
 		if ok {
 			switch l.kind {
 			case linkLocal:
-				g.pushInstLine(opcode.ABx(opcode.CLOSURE, l.index, p), endLine)
+				g.pushInstPos(opcode.ABx(opcode.CLOSURE, l.index, p), endPos)
 			case linkUpval:
-				g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+				g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 				g.pushInst(opcode.AB(opcode.SETUPVAL, g.sp, l.index))
 			default:
 				panic("unreachable")
 			}
 		} else {
-			g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+			g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 			g.genSetGlobal(name, g.sp)
 		}
@@ -156,15 +157,15 @@ func (g *generator) genFuncStmt(stmt *ast.FuncStmt) {
 
 		switch stmt.AccessTok {
 		case token.COLON:
-			p := g.proto(body, true, endLine)
+			p := g.proto(body, true, endPos)
 
-			g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+			g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 			g.pushInst(opcode.ABC(opcode.SETTABLE, r, rk, g.sp))
 		case token.PERIOD:
-			p := g.proto(body, false, endLine)
+			p := g.proto(body, false, endPos)
 
-			g.pushInstLine(opcode.ABx(opcode.CLOSURE, g.sp, p), endLine)
+			g.pushInstPos(opcode.ABx(opcode.CLOSURE, g.sp, p), endPos)
 
 			g.pushInst(opcode.ABC(opcode.SETTABLE, r, rk, g.sp))
 		default:
@@ -261,11 +262,13 @@ func (g *generator) genAssignSimple(lhs ast.Expr, r int) {
 		x := g.genExpr(lhs.X, genR|genK)
 		y := g.genName(lhs.Sel, genKey)
 
+		g.tokPos = lhs.Period
 		g.pushInst(opcode.ABC(opcode.SETTABLE, x, y, r))
 	case *ast.IndexExpr:
 		x := g.genExpr(lhs.X, genR|genK)
 		y := g.genExpr(lhs.Index, genR|genK)
 
+		g.tokPos = lhs.Lbrack
 		g.pushInst(opcode.ABC(opcode.SETTABLE, x, y, r))
 	default:
 		panic("unreachable")
@@ -274,6 +277,7 @@ func (g *generator) genAssignSimple(lhs ast.Expr, r int) {
 
 func (g *generator) genAssign(LHS []ast.Expr, base int) {
 	assigns := make([]opcode.Instruction, len(LHS))
+	poses := make([]position.Position, len(LHS))
 
 	g.locktmp = true
 
@@ -281,6 +285,8 @@ func (g *generator) genAssign(LHS []ast.Expr, base int) {
 	for i, lhs := range LHS {
 		r = base + i
 
+		poses[i] = lhs.Pos()
+
 		switch lhs := lhs.(type) {
 		case *ast.Name:
 			if l, ok := g.resolveName(lhs); ok {
@@ -304,23 +310,27 @@ func (g *generator) genAssign(LHS []ast.Expr, base int) {
 			y := g.genName(lhs.Sel, genKey)
 
 			assigns[i] = opcode.ABC(opcode.SETTABLE, x, y, r)
+			poses[i] = lhs.Period
 		case *ast.IndexExpr:
 			x := g.genExpr(lhs.X, genR|genK|genMove)
 			y := g.genExpr(lhs.Index, genR|genK|genMove)
 
 			assigns[i] = opcode.ABC(opcode.SETTABLE, x, y, r)
+			poses[i] = lhs.Lbrack
 		default:
 			panic("unreachable")
 		}
 	}
 
-	for _, assign := range assigns[:len(assigns)-1] {
-		g.pushInst(assign)
-	}
+	for i, assign := range assigns {
+		g.tokPos = poses[i]
 
-	g.locktmp = false
+		if i == len(assigns)-1 {
+			g.locktmp = false
+		}
 
-	g.pushInst(assigns[len(assigns)-1])
+		g.pushInst(assign)
+	}
 }
 
 func (g *generator) genGotoStmt(stmt *ast.GotoStmt) {
@@ -513,7 +523,7 @@ func (g *generator) genReturnStmt(stmt *ast.ReturnStmt) {
 func (g *generator) genForStmt(stmt *ast.ForStmt) {
 	g.openScope()
 
-	forLine := stmt.For.Line
+	forPos := stmt.For
 
 	sp := g.sp
 
@@ -533,7 +543,7 @@ func (g *generator) genForStmt(stmt *ast.ForStmt) {
 	g.declareLocal("(for limit)", sp+1)
 	g.declareLocal("(for step)", sp+2)
 
-	forprep := g.pushTempLine(forLine)
+	forprep := g.pushTempPos(forPos)
 
 	g.openScope()
 
@@ -549,7 +559,7 @@ func (g *generator) genForStmt(stmt *ast.ForStmt) {
 
 	g.Code[forprep] = opcode.AsBx(opcode.FORPREP, sp, g.pc()-forprep-1)
 
-	g.pushInstLine(opcode.AsBx(opcode.FORLOOP, sp, forprep-g.pc()), forLine)
+	g.pushInstPos(opcode.AsBx(opcode.FORLOOP, sp, forprep-g.pc()), forPos)
 
 	g.declareLabel("@break")
 
@@ -561,7 +571,7 @@ func (g *generator) genForStmt(stmt *ast.ForStmt) {
 func (g *generator) genForEachStmt(stmt *ast.ForEachStmt) {
 	g.openScope()
 
-	forLine := stmt.For.Line
+	forPos := stmt.For
 
 	sp := g.sp
 
@@ -621,9 +631,9 @@ func (g *generator) genForEachStmt(stmt *ast.ForEachStmt) {
 
 	g.closeScope()
 
-	g.pushInstLine(opcode.AC(opcode.TFORCALL, sp, len(stmt.Names)), forLine)
+	g.pushInstPos(opcode.AC(opcode.TFORCALL, sp, len(stmt.Names)), forPos)
 
-	g.pushInstLine(opcode.AsBx(opcode.TFORLOOP, sp+2, init-g.pc()-1), forLine)
+	g.pushInstPos(opcode.AsBx(opcode.TFORLOOP, sp+2, init-g.pc()-1), forPos)
 
 	g.declareLabel("@break")
 
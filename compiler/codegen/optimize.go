@@ -2,10 +2,12 @@ package codegen
 
 import (
 	"github.com/hirochachacha/plua/compiler/ast"
+	astscope "github.com/hirochachacha/plua/compiler/ast/scope"
 	"github.com/hirochachacha/plua/compiler/token"
 	"github.com/hirochachacha/plua/internal/arith"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/position"
 )
 
 // constant folding
@@ -18,6 +20,8 @@ func (g *generator) foldExpr(expr ast.Expr) (val object.Value, ok bool) {
 	switch expr := expr.(type) {
 	case *ast.ParenExpr:
 		return g.foldExpr(expr.X)
+	case *ast.Name:
+		return g.foldName(expr)
 	case *ast.BasicLit:
 		return g.foldBasic(expr)
 	case *ast.UnaryExpr:
@@ -34,7 +38,7 @@ func (g *generator) foldBasic(expr *ast.BasicLit) (val object.Value, ok bool) {
 		return nil, false
 	}
 
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	if c, ok := g.cfolds[expr]; ok {
 		return c, true
@@ -73,7 +77,7 @@ func (g *generator) foldUnary(expr *ast.UnaryExpr) (val object.Value, ok bool) {
 		return nil, false
 	}
 
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	if c, ok := g.cfolds[expr]; ok {
 		return c, c != nil
@@ -181,7 +185,7 @@ func (g *generator) foldBinary(expr *ast.BinaryExpr) (val object.Value, ok bool)
 		return nil, false
 	}
 
-	g.tokLine = expr.Pos().Line
+	g.tokPos = expr.Pos()
 
 	if c, ok := g.cfolds[expr]; ok {
 		return c, c != nil
@@ -366,6 +370,110 @@ func (g *generator) foldBinary(expr *ast.BinaryExpr) (val object.Value, ok bool)
 	return val, val != nil
 }
 
+// constant propagation
+
+// constLocal is a local variable which is never assigned after the declaration,
+// and never captured by closures.
+type constLocal struct {
+	init ast.Expr // initializer; or nil
+
+	folded bool
+	val    object.Value
+	ok     bool
+}
+
+// declareConstLocals finds constant locals in f.
+//
+// debug.setlocal can assign them anyway, so that
+// chunks which refer to the name "debug" are left as they are.
+// other ways to reach the library aren't detected, see PropagateConstants.
+func (g *generator) declareConstLocals(f *ast.File) {
+	info := astscope.Resolve(f)
+
+	if len(info.Globals["debug"]) > 0 {
+		return
+	}
+
+	for _, d := range info.Decls {
+		if d.Name == "debug" {
+			return
+		}
+	}
+
+	g.clocals = make(map[*ast.Name]*constLocal)
+
+	ast.Apply(f, func(c *ast.Cursor) bool {
+		stmt, ok := c.Node().(*ast.LocalAssignStmt)
+		if !ok {
+			return true
+		}
+
+	loop:
+		for i, name := range stmt.LHS {
+			d := info.Decls[name]
+			if d == nil {
+				continue
+			}
+
+			// upvalues are left as they are,
+			// so that debug.setupvalue and debug.upvaluejoin work as usual
+			for _, ref := range d.Refs {
+				if b := info.Bindings[ref]; b.Write || b.Kind != astscope.Local {
+					continue loop
+				}
+			}
+
+			var init ast.Expr
+
+			switch {
+			case i < len(stmt.RHS):
+				init = stmt.RHS[i]
+			case len(stmt.RHS) > 0:
+				switch stmt.RHS[len(stmt.RHS)-1].(type) {
+				case *ast.CallExpr, *ast.Vararg: // local x, y = f()
+					continue loop
+				}
+			}
+
+			cl := &constLocal{init: init}
+
+			for _, ref := range d.Refs {
+				g.clocals[ref] = cl
+			}
+		}
+
+		return true
+	}, nil)
+}
+
+// foldName returns the value of the constant local which name refers to.
+func (g *generator) foldName(name *ast.Name) (val object.Value, ok bool) {
+	cl, ok := g.clocals[name]
+	if !ok {
+		return nil, false
+	}
+
+	if !cl.folded {
+		cl.folded = true
+
+		if cl.init == nil {
+			cl.ok = true
+		} else {
+			tokPos := g.tokPos
+
+			cl.val, cl.ok = g.foldExpr(cl.init)
+
+			g.tokPos = tokPos
+		}
+	}
+
+	if cl.ok {
+		g.tokPos = name.Pos()
+	}
+
+	return cl.val, cl.ok
+}
+
 // peep hole optimization
 
 // optimize load to same address twice
@@ -402,7 +510,7 @@ func (g *generator) peepLoad(i0 opcode.Instruction, i opcode.Instruction) bool {
 	return false
 }
 
-func (g *generator) peepLine(i opcode.Instruction, line int) {
+func (g *generator) peep(i opcode.Instruction, pos position.Position) {
 	var offset int
 	var i0 opcode.Instruction
 
@@ -455,6 +563,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 
 							g.Code = g.Code[:len(g.Code)-offset]
 							g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+							g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 							return
 						}
@@ -513,6 +622,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 
 						g.Code = g.Code[:len(g.Code)-offset]
 						g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+						g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 						return
 					}
@@ -524,6 +634,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 				if a == b { // local x = local x => none
 					g.Code = g.Code[:len(g.Code)-offset]
 					g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+					g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 					return
 				}
@@ -543,6 +654,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 						if b0 == a { // local x = local y; y = x => local x = local y
 							g.Code = g.Code[:len(g.Code)-offset]
 							g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+							g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 							return
 						}
@@ -597,6 +709,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 						if a0 <= a && a <= a0+b0 {
 							g.Code = g.Code[:len(g.Code)-offset]
 							g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+							g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 							return
 						}
@@ -780,6 +893,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 					if a0 == a && b0 == b { // lexical x = local y; local y = lexical x => lexical x = local y
 						g.Code = g.Code[:len(g.Code)-offset]
 						g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+						g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 						return
 					}
@@ -892,6 +1006,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 					if a0 == a && b0 == b { // lexical x = local y; local y = lexical x => lexical x = local y
 						g.Code = g.Code[:len(g.Code)-offset]
 						g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+						g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 						return
 					}
@@ -985,6 +1100,7 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 				if a == 0 && b == 0 {
 					g.Code = g.Code[:len(g.Code)-offset]
 					g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset]
+					g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset]
 
 					return
 				}
@@ -992,13 +1108,16 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 
 			if offset == 0 {
 				g.Code = append(g.Code, i)
-				g.LineInfo = append(g.LineInfo, line)
+				g.LineInfo = append(g.LineInfo, pos.Line)
+				g.ColumnInfo = append(g.ColumnInfo, pos.Column)
 			} else {
 				g.Code[len(g.Code)-offset] = i
-				g.LineInfo[len(g.LineInfo)-offset] = line
+				g.LineInfo[len(g.LineInfo)-offset] = pos.Line
+				g.ColumnInfo[len(g.ColumnInfo)-offset] = pos.Column
 
 				g.Code = g.Code[:len(g.Code)-offset+1]
 				g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset+1]
+				g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset+1]
 			}
 
 			return
@@ -1007,13 +1126,16 @@ func (g *generator) peepLine(i opcode.Instruction, line int) {
 
 		if offset == 0 {
 			g.Code = append(g.Code, i)
-			g.LineInfo = append(g.LineInfo, line)
+			g.LineInfo = append(g.LineInfo, pos.Line)
+			g.ColumnInfo = append(g.ColumnInfo, pos.Column)
 		} else {
 			g.Code[len(g.Code)-offset] = i
-			g.LineInfo[len(g.LineInfo)-offset] = line
+			g.LineInfo[len(g.LineInfo)-offset] = pos.Line
+			g.ColumnInfo[len(g.ColumnInfo)-offset] = pos.Column
 
 			g.Code = g.Code[:len(g.Code)-offset+1]
 			g.LineInfo = g.LineInfo[:len(g.LineInfo)-offset+1]
+			g.ColumnInfo = g.ColumnInfo[:len(g.ColumnInfo)-offset+1]
 		}
 
 		return
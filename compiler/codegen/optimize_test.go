@@ -0,0 +1,339 @@
+package codegen_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib"
+)
+
+var modes = []codegen.Mode{
+	codegen.ThreadJumps,
+	codegen.EliminateDeadCode,
+	codegen.PropagateConstants,
+	codegen.ShareConstants,
+	codegen.Optimize,
+}
+
+func generate(t *testing.T, code string, mode codegen.Mode) *object.Proto {
+	f, err := parser.Parse(scanner.Scan(strings.NewReader(code), "=test", 0), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proto, err := codegen.GenerateMode(f, mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return proto
+}
+
+func count(p *object.Proto, op opcode.OpCode) (n int) {
+	for _, inst := range p.Code {
+		if inst.OpCode() == op {
+			n++
+		}
+	}
+	return n
+}
+
+// jumpsToJumps returns the number of jumps to other jumps.
+func jumpsToJumps(p *object.Proto) (n int) {
+	for pc, inst := range p.Code {
+		if inst.OpCode() != opcode.JMP {
+			continue
+		}
+
+		to := pc + 1 + inst.SBx()
+		if next := p.Code[to]; to != pc && next.OpCode() == opcode.JMP && next.A() == 0 {
+			n++
+		}
+	}
+
+	for _, p := range p.Protos {
+		n += jumpsToJumps(p)
+	}
+
+	return n
+}
+
+func TestThreadJumps(t *testing.T) {
+	code := `
+	local n = 0
+	for i = 1, 10 do
+	  if i % 2 == 0 then
+	    if i % 3 == 0 then
+	      n = n + 1
+	    else
+	      n = n + 2
+	    end
+	  elseif i > 5 then
+	    goto continue
+	  end
+	  n = n + 3
+	  ::continue::
+	end
+	while n > 0 do
+	  if n > 10 then
+	    n = n - 7
+	  else
+	    break
+	  end
+	end
+	return n
+	`
+
+	if jumpsToJumps(generate(t, code, 0)) == 0 {
+		t.Fatal("no jumps to jumps without ThreadJumps")
+	}
+
+	if n := jumpsToJumps(generate(t, code, codegen.ThreadJumps)); n != 0 {
+		t.Errorf("expected no jumps to jumps, got %d", n)
+	}
+}
+
+func TestEliminateDeadCode(t *testing.T) {
+	code := `
+	local function f(x)
+	  do return x end
+	  print("dead")
+	end
+	local function g(x)
+	  while true do
+	    if x then
+	      break
+	      print("dead")
+	    end
+	    x = not x
+	  end
+	  return x
+	end
+	return f, g
+	`
+
+	p := generate(t, code, 0)
+	q := generate(t, code, codegen.EliminateDeadCode)
+
+	for i := range p.Protos {
+		if count(p.Protos[i], opcode.CALL) == 0 {
+			t.Fatalf("function %d: no dead code without EliminateDeadCode", i)
+		}
+
+		if n := count(q.Protos[i], opcode.CALL); n != 0 {
+			t.Errorf("function %d: expected no calls, got %d", i, n)
+		}
+
+		if code, last := q.Protos[i].Code, len(q.Protos[i].Code)-1; code[last].OpCode() != opcode.RETURN {
+			t.Errorf("function %d: expected RETURN at the end, got %v", i, code[last])
+		}
+	}
+}
+
+func TestPropagateConstants(t *testing.T) {
+	code := `
+	local N = 10
+	local S = "a" .. "b"
+	local T = S .. N
+	local DEBUG = false
+	if DEBUG then
+	  print(T)
+	end
+	if N < 5 then
+	  return nil
+	end
+	local U = 1
+	return T, function() return U end
+	`
+
+	p := generate(t, code, codegen.PropagateConstants)
+
+	for _, op := range []opcode.OpCode{opcode.CONCAT, opcode.LT, opcode.TEST, opcode.CALL} {
+		if n := count(p, op); n != 0 {
+			t.Errorf("expected no %v, got %d", op, n)
+		}
+	}
+
+	found := false
+	for _, c := range p.Constants {
+		if c == object.String("ab10") {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("expected a constant \"ab10\", got %v", p.Constants)
+	}
+
+	// captured locals are left as they are
+	if n := count(p.Protos[0], opcode.GETUPVAL); n != 1 {
+		t.Errorf("expected GETUPVAL, got %d", n)
+	}
+
+	// the debug library can assign locals
+	code = "local x = 1; debug.setlocal(1, 1, 2); return x"
+
+	if p, q := generate(t, code, 0), generate(t, code, codegen.PropagateConstants); !reflect.DeepEqual(p.Code, q.Code) {
+		t.Errorf("expected the same code, got %v and %v", p.Code, q.Code)
+	}
+}
+
+func TestPropagateConstantsDebug(t *testing.T) {
+	// the debug library which isn't referred to by its name isn't detected,
+	// and the assignment is ignored by the optimized code.
+	code := `
+	local d = require "debug"
+	local x = 1
+	d.setlocal(1, 2, 2)
+	return x
+	`
+
+	if rets := exec(t, generate(t, code, 0)); len(rets) != 1 || rets[0] != object.Integer(2) {
+		t.Errorf("expected 2, got %v", rets)
+	}
+
+	if rets := exec(t, generate(t, code, codegen.PropagateConstants)); len(rets) != 1 || rets[0] != object.Integer(1) {
+		t.Errorf("expected 1, got %v", rets)
+	}
+}
+
+func stringData(s object.String) uintptr {
+	return (*reflect.StringHeader)(unsafe.Pointer(&s)).Data
+}
+
+func TestShareConstants(t *testing.T) {
+	code := `
+	local t = {}
+	t.name = "shared"
+	return function() return t.name, "shared" end
+	`
+
+	for _, mode := range []codegen.Mode{0, codegen.ShareConstants} {
+		p := generate(t, code, mode)
+
+		for _, k := range []object.String{"name", "shared"} {
+			var x, y uintptr
+
+			for _, c := range p.Constants {
+				if c == k {
+					x = stringData(c.(object.String))
+				}
+			}
+			for _, c := range p.Protos[0].Constants {
+				if c == k {
+					y = stringData(c.(object.String))
+				}
+			}
+
+			if shared := x == y; shared != (mode != 0) {
+				t.Errorf("mode %d: %q is shared: %v", mode, k, shared)
+			}
+		}
+	}
+}
+
+var optimizeExecTests = []string{
+	`
+	local a = 0
+	for i = 1, 100 do
+	  if i % 3 == 0 then
+	    goto continue
+	  elseif i % 5 == 0 then
+	    a = a + i
+	  else
+	    a = a - 1
+	  end
+	  ::continue::
+	end
+	return a
+	`,
+	`
+	local K = 3
+	local S = "x" .. K .. "y"
+	local t = {}
+	for i = 1, K do
+	  t[#t + 1] = S .. i
+	end
+	return table.concat(t, ","), S < "y", K == 3.0
+	`,
+	`
+	local function f(n)
+	  local r = {}
+	  repeat
+	    local m = n
+	    if m % 2 == 0 then
+	      r[#r + 1] = function() return m end
+	    end
+	    n = n - 1
+	  until n == 0
+	  local s = 0
+	  for _, g in ipairs(r) do
+	    s = s + g()
+	  end
+	  do return s end
+	  print("dead")
+	end
+	return f(10)
+	`,
+	`
+	local x, y = 1
+	local z = (string.rep("a", 2))
+	local w
+	if w then return 0 end
+	return x, y, z, w
+	`,
+	`
+	local function count(n)
+	  local c = 0
+	  while true do
+	    if n == 1 then break end
+	    if n % 2 == 0 then n = n // 2 else n = 3 * n + 1 end
+	    c = c + 1
+	  end
+	  return c
+	end
+	return count(27)
+	`,
+}
+
+func exec(t *testing.T, p *object.Proto) []object.Value {
+	proc := runtime.NewProcess()
+
+	proc.Require("", stdlib.Open)
+
+	rets, err := proc.Exec(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rets
+}
+
+func TestOptimizeExec(t *testing.T) {
+	for i, code := range optimizeExecTests {
+		want := exec(t, generate(t, code, 0))
+
+		for _, mode := range modes {
+			got := exec(t, generate(t, code, mode))
+
+			if len(got) != len(want) {
+				t.Errorf("%d: mode %d: expected %v, got %v", i, mode, want, got)
+				continue
+			}
+
+			for j := range got {
+				if !object.Equal(got[j], want[j]) {
+					t.Errorf("%d: mode %d: expected %v, got %v", i, mode, want, got)
+					break
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package codegen
+
+import (
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// optimization passes over the code of a function.
+// they run after all jumps are resolved.
+
+func (g *generator) optimize() {
+	if g.mode&ThreadJumps != 0 {
+		threadJumps(g.Proto)
+	}
+
+	if g.mode&EliminateDeadCode != 0 {
+		eliminateDeadCode(g.Proto)
+	}
+}
+
+// threadJumps redirects jumps to unconditional jumps to their final destinations.
+//
+// jumps which close upvalues aren't skipped.
+func threadJumps(p *object.Proto) {
+	for pc, inst := range p.Code {
+		if inst.OpCode() != opcode.JMP {
+			continue
+		}
+
+		to := pc + 1 + inst.SBx()
+
+		// the limit stops infinite loops, e.g. "::a:: goto a"
+		for n := 0; n < len(p.Code); n++ {
+			next := p.Code[to]
+			if next.OpCode() != opcode.JMP || next.A() != 0 {
+				break
+			}
+
+			to = to + 1 + next.SBx()
+		}
+
+		p.Code[pc] = opcode.AsBx(opcode.JMP, inst.A(), to-pc-1)
+	}
+}
+
+// eliminateDeadCode removes unreachable instructions,
+// and jumps to the next instruction.
+func eliminateDeadCode(p *object.Proto) {
+	code := p.Code
+
+	live := make([]bool, len(code))
+
+	// the final RETURN is always kept, since locals are active until there,
+	// and return hooks of the other RETURNs see them.
+	live[len(code)-1] = true
+
+	stack := []int{0}
+
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+
+		stack = stack[:len(stack)-1]
+
+		for pc < len(code)-1 && !live[pc] {
+			live[pc] = true
+
+			inst := code[pc]
+
+			switch op := inst.OpCode(); op {
+			case opcode.RETURN:
+				pc = len(code)
+			case opcode.JMP, opcode.FORPREP:
+				pc += 1 + inst.SBx()
+			case opcode.FORLOOP, opcode.TFORLOOP:
+				stack = append(stack, pc+1+inst.SBx())
+
+				pc++
+			case opcode.LOADBOOL:
+				if inst.C() != 0 {
+					// the skipped instruction is kept for the position of the next one
+					stack = append(stack, pc+2)
+				}
+
+				pc++
+			default:
+				if op.TestTMode() {
+					stack = append(stack, pc+2)
+				}
+
+				// EXTRAARG is executed as a no-op
+				pc++
+			}
+		}
+	}
+
+	for pc, inst := range code {
+		if !live[pc] || inst.OpCode() != opcode.JMP || inst.A() != 0 || inst.SBx() != 0 {
+			continue
+		}
+
+		if pc > 0 {
+			prev := code[pc-1]
+			if prev.OpCode().TestTMode() || prev.OpCode() == opcode.LOADBOOL && prev.C() != 0 {
+				continue
+			}
+		}
+
+		live[pc] = false
+	}
+
+	// newpc[pc] is the number of live instructions before pc
+	newpc := make([]int, len(code)+1)
+
+	n := 0
+	for pc := range code {
+		newpc[pc] = n
+		if live[pc] {
+			n++
+		}
+	}
+	newpc[len(code)] = n
+
+	if n == len(code) {
+		return
+	}
+
+	for pc, inst := range code {
+		if !live[pc] {
+			continue
+		}
+
+		switch op := inst.OpCode(); op {
+		case opcode.JMP, opcode.FORPREP, opcode.FORLOOP, opcode.TFORLOOP:
+			to := pc + 1 + inst.SBx()
+
+			inst = opcode.AsBx(op, inst.A(), newpc[to]-newpc[pc]-1)
+		}
+
+		code[newpc[pc]] = inst
+
+		p.LineInfo[newpc[pc]] = p.LineInfo[pc]
+
+		if len(p.ColumnInfo) > 0 {
+			p.ColumnInfo[newpc[pc]] = p.ColumnInfo[pc]
+		}
+	}
+
+	p.Code = code[:n]
+
+	p.LineInfo = p.LineInfo[:n]
+
+	if len(p.ColumnInfo) > 0 {
+		p.ColumnInfo = p.ColumnInfo[:n]
+	}
+
+	for i := range p.LocVars {
+		locVar := &p.LocVars[i]
+
+		locVar.StartPC = newpc[locVar.StartPC]
+		locVar.EndPC = newpc[locVar.EndPC]
+	}
+}
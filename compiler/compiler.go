@@ -36,6 +36,8 @@ func (typ FormatType) String() string {
 }
 
 type Compiler struct {
+	Mode codegen.Mode // optimizations of codegen
+
 	s *scanner.ScanState
 	r *bufio.Reader
 }
@@ -71,7 +73,7 @@ func (c *Compiler) Compile(r io.Reader, srcname string, typ FormatType) (*object
 			return nil, err
 		}
 
-		return codegen.Generate(ast)
+		return codegen.GenerateMode(ast, c.Mode)
 	case err == nil && b[0] == version.LUA_SIGNATURE[0]:
 		if typ != Either && typ != Binary {
 			return nil, &Error{fmt.Errorf("compiler: attempt to load a %s chunk (mode is '%s')", "binary", typ)}
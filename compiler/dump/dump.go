@@ -27,6 +27,10 @@ type Mode uint
 
 const (
 	StripDebugInfo Mode = 1 << iota
+
+	// ColumnInfo dumps column info in plua-specific format.
+	// It is ignored if StripDebugInfo is also specified.
+	ColumnInfo
 )
 
 func DumpTo(w io.Writer, p *object.Proto, mode Mode) (err error) {
@@ -354,9 +358,27 @@ func (d *dumper) dumpDebug(p *object.Proto) (err error) {
 		}
 	}
 
+	if d.plua() {
+		err = d.dumpInt(len(p.ColumnInfo))
+		if err != nil {
+			return err
+		}
+		for _, col := range p.ColumnInfo {
+			err = d.dumpInt(col)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
 	return
 }
 
+// plua reports whether chunks are dumped in plua-specific format.
+func (d *dumper) plua() bool {
+	return d.mode&ColumnInfo != 0 && d.mode&StripDebugInfo == 0
+}
+
 func (d *dumper) dumpFunction(p *object.Proto, psource string) (err error) {
 	if d.mode&StripDebugInfo != 0 || p.Source == psource {
 		err = d.dumpString("")
@@ -417,7 +439,11 @@ func (d *dumper) dumpHeader() (err error) {
 	if err != nil {
 		return err
 	}
-	err = d.dumpByte(version.LUAC_FORMAT)
+	if d.plua() {
+		err = d.dumpByte(version.PLUAC_FORMAT)
+	} else {
+		err = d.dumpByte(version.LUAC_FORMAT)
+	}
 	if err != nil {
 		return err
 	}
@@ -17,3 +17,16 @@ func (e Error) Error() string {
 	}
 	return fmt.Sprintf("compiler/parser: %v", e.Err)
 }
+
+// ErrorList is a list of *Error and *scanner.Error reported in AllErrors mode.
+type ErrorList []error
+
+func (list ErrorList) Error() string {
+	switch len(list) {
+	case 0:
+		return "no errors"
+	case 1:
+		return list[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", list[0], len(list)-1)
+}
@@ -1,10 +1,14 @@
 package parser_test
 
 import (
+	"bytes"
+	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/scanner"
 )
 
 func TestParseFile(t *testing.T) {
@@ -27,3 +31,127 @@ func TestParseFile(t *testing.T) {
 		// printer.PrintTree(ast)
 	}
 }
+
+var allErrorsTests = []struct {
+	src   string
+	errs  []string
+	stmts string // types of statements in the chunk
+}{
+	{
+		"x = \nlocal y = 2\nprint(y +)\nz = 3",
+		[]string{
+			"test:2: expected NAME or '(', found 'local' local",
+			"test:3: expected NAME or '(', found ')'",
+		},
+		"BadStmt LocalAssignStmt BadStmt",
+	},
+	{
+		"function f()\n  x = \nend\ny = 1",
+		[]string{
+			"test:3: expected NAME or '(', found 'end' end",
+		},
+		"FuncStmt AssignStmt",
+	},
+	{
+		"end\nx = 1\nuntil\ny = 2",
+		[]string{
+			"test:1: expected 'EOF', found 'end' end",
+			"test:3: expected 'EOF', found 'until' until",
+		},
+		"BadStmt AssignStmt BadStmt AssignStmt",
+	},
+	{
+		"local a = $\nb = \"abc\nc = 1",
+		[]string{
+			"test:1: illegal character $",
+			"test:2: unterminated string literal",
+		},
+		"BadStmt",
+	},
+	{
+		"local function f() return ... end\nwhile x do local y = ) break end\ndo",
+		[]string{
+			"test:1: cannot use '...' outside of vararg function",
+			"test:2: expected NAME or '(', found ')'",
+			"test:3: expected 'end', found 'EOF'",
+		},
+		"LocalFuncStmt WhileStmt BadStmt",
+	},
+}
+
+func TestParseAllErrors(t *testing.T) {
+	for _, test := range allErrorsTests {
+		f, err := parser.Parse(scanner.Scan(strings.NewReader(test.src), "=test", 0), parser.AllErrors)
+
+		list, ok := err.(parser.ErrorList)
+		if !ok {
+			t.Errorf("%q: expected ErrorList, got %v", test.src, err)
+
+			continue
+		}
+
+		if len(list) != len(test.errs) {
+			t.Errorf("%q: expected %d errors, got %v", test.src, len(test.errs), list)
+		} else {
+			for i, err := range list {
+				if !strings.HasSuffix(err.Error(), test.errs[i]) {
+					t.Errorf("%q: expected %q, got %q", test.src, test.errs[i], err)
+				}
+			}
+		}
+
+		var types []string
+		for _, stmt := range f.Chunk {
+			types = append(types, stmt.Type().String())
+		}
+
+		if stmts := strings.Join(types, " "); stmts != test.stmts {
+			t.Errorf("%q: expected statements %q, got %q", test.src, test.stmts, stmts)
+		}
+	}
+
+	// without AllErrors, the first error is returned
+	_, err := parser.Parse(scanner.Scan(strings.NewReader(allErrorsTests[0].src), "=test", 0), 0)
+	if _, ok := err.(*parser.Error); !ok {
+		t.Errorf("expected *parser.Error, got %v", err)
+	}
+}
+
+func TestParseLossless(t *testing.T) {
+	matches, err := filepath.Glob("testdata/*.lua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fname := range matches {
+		src, err := ioutil.ReadFile(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := parser.ParseFile(fname, parser.Lossless)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+
+		var text []byte
+		for _, tok := range f.CST.Tokens {
+			text = append(text, tok.Trivia...)
+			text = append(text, tok.Text...)
+		}
+
+		if !bytes.Equal(text, src) {
+			t.Errorf("%s: tokens don't reproduce the source", fname)
+		}
+
+		start, end, ok := f.CST.Span(f.Chunk[0])
+		if !ok || start != 0 || end <= start {
+			t.Errorf("%s: unexpected span of the first statement: %d, %d, %v", fname, start, end, ok)
+		}
+	}
+
+	_, err = parser.Parse(scanner.Scan(strings.NewReader("x = 1"), "=test", 0), parser.Lossless)
+	if err == nil {
+		t.Error("expected an error without scanner.ScanTrivia")
+	}
+}
@@ -45,12 +45,22 @@ import (
 var (
 	errIllegalVararg = errors.New("cannot use '...' outside of vararg function")
 	errIllegalBreak  = errors.New("cannot use 'break' outside of loop")
+	errNoTrivia      = errors.New("lossless mode requires scanner.ScanTrivia")
 )
 
 type Mode uint
 
 const (
 	ParseComments Mode = 1 << iota
+
+	// AllErrors reports all syntax errors as ErrorList, instead of the first one.
+	// The parser skips broken statements, and returns a partial AST with *ast.BadStmt.
+	AllErrors
+
+	// Lossless keeps all tokens and trivia in ast.File.CST,
+	// so that printer.Lossless mode can reproduce the source.
+	// The scanner must be in scanner.ScanTrivia mode.
+	Lossless
 )
 
 func ParseFile(filename string, mode Mode) (*ast.File, error) {
@@ -62,7 +72,10 @@ func ParseFile(filename string, mode Mode) (*ast.File, error) {
 
 	var m scanner.Mode
 	if mode&ParseComments != 0 {
-		m = scanner.ScanComments
+		m |= scanner.ScanComments
+	}
+	if mode&Lossless != 0 {
+		m |= scanner.ScanTrivia
 	}
 
 	s := scanner.Scan(f, "@"+filename, m)
@@ -73,20 +86,35 @@ func ParseFile(filename string, mode Mode) (*ast.File, error) {
 func Parse(s *scanner.ScanState, mode Mode) (f *ast.File, err error) {
 	p := &parser{
 		scanState: s,
+		mode:      mode,
 	}
 
 	defer func() {
 		if r := recover(); r != nil {
 			_ = r.(bailout)
 
-			err = p.err
+			if mode&AllErrors != 0 {
+				p.addError(p.err)
+
+				err = p.errors
+			} else {
+				err = p.err
+			}
 		}
 	}()
 
+	if mode&Lossless != 0 && s.Mode()&scanner.ScanTrivia == 0 {
+		return nil, errNoTrivia
+	}
+
 	p.next()
 
 	f = p.parseFile()
 
+	if len(p.errors) > 0 {
+		err = p.errors
+	}
+
 	return
 }
 
@@ -94,6 +122,8 @@ func Parse(s *scanner.ScanState, mode Mode) (f *ast.File, err error) {
 type parser struct {
 	scanState *scanner.ScanState
 
+	mode Mode
+
 	// Comments
 	comments    []*ast.CommentGroup
 	leadComment *ast.CommentGroup // last lead comment
@@ -109,6 +139,15 @@ type parser struct {
 	allowBreak bool
 
 	err error
+
+	// AllErrors mode
+	errors     ErrorList
+	lastErrPos position.Position // position of the last recorded error
+	fatal      bool              // err can't be recovered
+
+	// Lossless mode
+	tokens []ast.Token
+	trivia string // trivia and comments for the next token
 }
 
 type bailout struct{}
@@ -154,10 +193,31 @@ func (p *parser) markRHS(x ast.Expr) {
 func (p *parser) next0() {
 	tok, err := p.scanState.Token()
 	if err != nil {
-		p.err = err
-		panic(bailout{})
+		// the scanner can continue after errors, except I/O errors
+		if err, ok := err.(*scanner.Error); ok && p.mode&AllErrors != 0 && err.Pos.IsValid() {
+			p.addError(err)
+		} else {
+			p.err = err
+			p.fatal = true
+			panic(bailout{})
+		}
 	}
 	p.tok = tok
+
+	if p.mode&Lossless != 0 {
+		trivia, text := p.scanState.Trivia()
+
+		// comments are trivia of the next token
+		switch {
+		case tok.Type == token.COMMENT:
+			p.trivia += trivia + text
+		case tok.Type == token.EOF && len(p.tokens) > 0 && p.tokens[len(p.tokens)-1].Type == token.EOF:
+			// the parser may advance at EOF
+		default:
+			p.tokens = append(p.tokens, ast.Token{Type: tok.Type, Pos: tok.Pos, Trivia: p.trivia + trivia, Text: text})
+			p.trivia = ""
+		}
+	}
 }
 
 // Consume a comment and return it and the line on which it ends.
@@ -264,6 +324,27 @@ func (p *parser) error(pos position.Position, err error) {
 	panic(bailout{})
 }
 
+// addError records err in AllErrors mode.
+// errors at the same position are reported only once,
+// because a scanner error usually causes a parser error on the same token.
+func (p *parser) addError(err error) {
+	var pos position.Position
+
+	switch err := err.(type) {
+	case *Error:
+		pos = err.Pos
+	case *scanner.Error:
+		pos = err.Pos
+	}
+
+	if pos.IsValid() && pos == p.lastErrPos {
+		return
+	}
+
+	p.errors = append(p.errors, err)
+	p.lastErrPos = pos
+}
+
 func (p *parser) errorExpected(actual token.Token, expected string) {
 	found := "'" + actual.Type.String() + "'"
 	if len(actual.Lit) > 0 {
@@ -430,10 +511,10 @@ func (p *parser) parseStmtList() (list []ast.Stmt, closing position.Position) {
 			break
 		}
 		if p.tok.Type == token.RETURN {
-			list = append(list, p.parseReturnStmt())
+			list = append(list, p.recoverStmt(func() ast.Stmt { return p.parseReturnStmt() }))
 			break
 		}
-		list = append(list, p.parseStmt())
+		list = append(list, p.recoverStmt(p.parseStmt))
 	}
 
 	closing = p.tok.Pos
@@ -441,11 +522,84 @@ func (p *parser) parseStmtList() (list []ast.Stmt, closing position.Position) {
 	return list, closing
 }
 
+// recoverStmt returns the statement parsed by parse.
+// In AllErrors mode, it records a syntax error in the statement, then skips
+// to the next statement and returns *ast.BadStmt instead of bailing out.
+func (p *parser) recoverStmt(parse func() ast.Stmt) (stmt ast.Stmt) {
+	if p.mode&AllErrors == 0 {
+		return parse()
+	}
+
+	from := p.tok.Pos
+
+	allowEllipsis := p.allowEllipsis
+	allowBreak := p.allowBreak
+
+	defer func() {
+		if r := recover(); r != nil {
+			_ = r.(bailout)
+
+			if p.fatal {
+				panic(r)
+			}
+
+			p.addError(p.err)
+
+			p.allowEllipsis = allowEllipsis
+			p.allowBreak = allowBreak
+
+			stmt = &ast.BadStmt{From: from, To: p.sync(from)}
+		}
+	}()
+
+	return parse()
+}
+
+// sync skips tokens until a keyword which starts a statement or ends a block,
+// and returns its position.
+func (p *parser) sync(from position.Position) position.Position {
+	if p.tok.Pos == from && p.tok.Type != token.EOF {
+		unbalanced := p.isEndOfBlock()
+
+		p.next() // make progress
+
+		if unbalanced {
+			return p.tok.Pos
+		}
+	}
+
+	for {
+		switch p.tok.Type {
+		case token.EOF, token.END, token.ELSE, token.ELSEIF, token.UNTIL,
+			token.IF, token.WHILE, token.DO, token.FOR, token.REPEAT, token.FUNCTION, token.LOCAL,
+			token.LABEL, token.RETURN, token.GOTO, token.BREAK:
+			return p.tok.Pos
+		}
+
+		p.next()
+	}
+}
+
 func (p *parser) parseChunk() []ast.Stmt {
 	p.allowEllipsis = true
 
 	list, _ := p.parseStmtList()
 
+	if p.mode&AllErrors != 0 {
+		// skip unbalanced 'end', 'else', 'elseif' and 'until'
+		for p.tok.Type != token.EOF {
+			list = append(list, p.recoverStmt(func() ast.Stmt {
+				p.errorExpected(p.tok, "'"+token.EOF.String()+"'")
+
+				return nil
+			}))
+
+			more, _ := p.parseStmtList()
+
+			list = append(list, more...)
+		}
+	}
+
 	p.expect(token.EOF)
 
 	return list
@@ -1325,10 +1479,16 @@ func (p *parser) parseStmt() (s ast.Stmt) {
 func (p *parser) parseFile() *ast.File {
 	chunk := p.parseChunk()
 
-	return &ast.File{
+	f := &ast.File{
 		Filename: p.scanState.SourceName(),
 		Shebang:  p.scanState.Shebang(),
 		Chunk:    chunk,
 		Comments: p.comments,
 	}
+
+	if p.mode&Lossless != 0 {
+		f.CST = ast.NewCST(f, p.tokens)
+	}
+
+	return f
 }
@@ -66,6 +66,7 @@ type Mode uint
 
 const (
 	ScanComments = 1 << iota
+	ScanTrivia   // keep the source text between tokens, see Trivia
 )
 
 type ScanState struct {
@@ -90,6 +91,12 @@ type ScanState struct {
 	lineOffset int
 	line       int
 
+	// ScanTrivia mode
+	src         []byte // source text read so far
+	triviaStart int    // offset of the end of the token before the last token
+	tokStart    int    // offset of the last token
+	tokEnd      int    // offset of the end of the last token
+
 	err error
 }
 
@@ -127,6 +134,11 @@ func (s *ScanState) Reset(r io.Reader, srcname string, mode Mode) {
 	s.lineOffset = -1
 	s.line = 1
 
+	s.src = s.src[:0]
+	s.triviaStart = 0
+	s.tokStart = 0
+	s.tokEnd = 0
+
 	s.err = nil
 }
 
@@ -134,6 +146,22 @@ func (s *ScanState) SourceName() string {
 	return s.sourceName
 }
 
+func (s *ScanState) Mode() Mode {
+	return s.mode
+}
+
+// Trivia returns the source text between the last two tokens, such as spaces and comments,
+// and the source text of the last token.
+// The source text before the first token includes BOM and shebang.
+// In other words, concatenating them reproduces the source.
+// Unless ScanTrivia is specified, both are empty.
+func (s *ScanState) Trivia() (trivia, text string) {
+	if s.mode&ScanTrivia == 0 {
+		return "", ""
+	}
+	return string(s.src[s.triviaStart:s.tokStart]), string(s.src[s.tokStart:s.tokEnd])
+}
+
 func (s *ScanState) Shebang() string {
 	return s.shebang
 }
@@ -142,6 +170,7 @@ func (s *ScanState) Token() (tok token.Token, err error) {
 	var typ token.Type
 	var pos position.Position
 	var lit string
+	var start int
 
 	defer func() {
 		if r := recover(); r != nil {
@@ -152,6 +181,8 @@ func (s *ScanState) Token() (tok token.Token, err error) {
 			s.err = nil
 			s._mark = -1
 		}
+
+		s.triviaStart, s.tokStart, s.tokEnd = s.tokEnd, start, s.offset
 	}()
 
 	if s.offset == 0 {
@@ -170,6 +201,7 @@ scanAgain:
 	s.skipSpace()
 
 	pos = s.pos()
+	start = s.offset
 
 	switch ch := s.ch; {
 	case isLetter(ch):
@@ -917,6 +949,9 @@ func (s *ScanState) fill() {
 		if n < 0 {
 			panic("reader returned negative count from Read")
 		}
+		if s.mode&ScanTrivia != 0 {
+			s.src = append(s.src, s.buf[s.end:s.end+n]...)
+		}
 		s.end += n
 		if err != nil {
 			s.error(position.NoPos, err)
@@ -1,8 +1,10 @@
 package undump
 
 import (
+	"bytes"
 	"encoding/binary"
 	"errors"
+	"fmt"
 	"io"
 
 	"github.com/hirochachacha/plua/internal/limits"
@@ -12,17 +14,12 @@ import (
 )
 
 var (
-	errIntegerOverflow        = &Error{errors.New("integer overflow")}
 	errShortHeader            = &Error{errors.New("header is too short")}
 	errSignatureMismatch      = &Error{errors.New("signature mismatch")}
 	errVersionMismatch        = &Error{errors.New("version mismatch")}
 	errFormatMismatch         = &Error{errors.New("format mismatch")}
 	errDataMismatch           = &Error{errors.New("data mismatch")}
-	errInvalidIntSize         = &Error{errors.New("int size is invalid")}
-	errInvalidIntegerSize     = &Error{errors.New("integer size is invalid")}
-	errInvalidNumberSize      = &Error{errors.New("number size is invalid")}
 	errInvalidInstructionSize = &Error{errors.New("instruction size is invalid")}
-	errEndiannessMismatch     = &Error{errors.New("endianness mismatch")}
 	errNumberFormatMismatch   = &Error{errors.New("number format mismatch")}
 	errMalformedByteCode      = &Error{errors.New("malformed byte code detected")}
 	errTruncatedChunk         = &Error{errors.New("truncated precompiled chunk")}
@@ -30,7 +27,21 @@ var (
 
 const bufferSize = 20
 
-type Mode uint // currently, no mode are defined
+// maxIntSize is the maximum size of integers in chunks.
+const maxIntSize = 16
+
+// maxPrealloc is the maximum number of elements allocated before reading them,
+// because the number of elements read from untrusted chunk can't be trusted.
+const maxPrealloc = 1024
+
+type Mode uint
+
+const (
+	// Native rejects chunks which are not in the native format,
+	// that is the format written by dump.DumpTo.
+	// By default, chunks built for other platforms are accepted and converted.
+	Native Mode = 1 << iota
+)
 
 func Undump(r io.Reader, mode Mode) (*object.Proto, error) {
 	u := &undumper{
@@ -50,7 +61,7 @@ func Undump(r io.Reader, mode Mode) (*object.Proto, error) {
 		}
 	}
 
-	err := u.loadHeader()
+	err := u.loadHeader(mode)
 	if err != nil {
 		if err == io.EOF || err == io.ErrUnexpectedEOF {
 			return nil, errShortHeader
@@ -81,12 +92,18 @@ func Undump(r io.Reader, mode Mode) (*object.Proto, error) {
 		return nil, errMalformedByteCode
 	}
 
+	err = verify(p, nil)
+	if err != nil {
+		return nil, err
+	}
+
 	return p, nil
 }
 
 type undumper struct {
 	r     io.Reader
 	order binary.ByteOrder
+	plua  bool // chunk has plua extensions
 
 	bs [bufferSize]byte // buffer for short string
 
@@ -111,6 +128,18 @@ func (u *undumper) loadInt() (int, error) {
 	return u.int(u)
 }
 
+// loadCount loads the number of following elements.
+func (u *undumper) loadCount() (int, error) {
+	n, err := u.int(u)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, errMalformedByteCode
+	}
+	return n, nil
+}
+
 func (u *undumper) loadSizeT() (int, error) {
 	return u.sizeT(u)
 }
@@ -143,47 +172,70 @@ func (u *undumper) loadString() (string, error) {
 
 	if length == 0xFF {
 		length, err = u.loadSizeT()
+		if err != nil {
+			return "", err
+		}
 	}
 
 	if length == 0 {
 		return "", nil
 	}
 
-	length--
+	if length < 0 {
+		return "", errMalformedByteCode
+	}
 
-	var bs []byte
+	length--
 
 	if length <= len(u.bs) {
-		bs = u.bs[:length]
-	} else {
-		bs = make([]byte, length)
+		bs := u.bs[:length]
+
+		_, err = io.ReadFull(u.r, bs)
+
+		return string(bs), err
 	}
 
-	_, err = io.ReadFull(u.r, bs)
+	// don't trust length, the chunk may be truncated
+	var buf bytes.Buffer
+
+	m, err := io.CopyN(&buf, u.r, int64(length))
+	if err == io.EOF && m < int64(length) {
+		err = io.ErrUnexpectedEOF
+	}
 
-	return string(bs), err
+	return buf.String(), err
 }
 
 func (u *undumper) loadCode() ([]opcode.Instruction, error) {
-	n, err := u.loadInt()
+	n, err := u.loadCount()
 	if err != nil {
 		return nil, err
 	}
 
-	code := make([]opcode.Instruction, n)
+	// don't trust n, the chunk may be truncated
+	code := make([]opcode.Instruction, 0, min(n, maxPrealloc))
 
-	err = binary.Read(u.r, u.order, code)
+	for len(code) < n {
+		chunk := make([]opcode.Instruction, min(n-len(code), maxPrealloc))
 
-	return code, err
+		err = binary.Read(u.r, u.order, chunk)
+		if err != nil {
+			return nil, err
+		}
+
+		code = append(code, chunk...)
+	}
+
+	return code, nil
 }
 
 func (u *undumper) loadConstants() ([]object.Value, error) {
-	n, err := u.loadInt()
+	n, err := u.loadCount()
 	if err != nil {
 		return nil, err
 	}
 
-	constants := make([]object.Value, n)
+	constants := make([]object.Value, 0, min(n, maxPrealloc))
 
 	var v object.Value
 	var t int
@@ -207,26 +259,26 @@ func (u *undumper) loadConstants() ([]object.Value, error) {
 			v = object.String(s)
 			err = _err
 		default:
-			panic("unexpected")
+			return nil, errMalformedByteCode
 		}
 
 		if err != nil {
 			return nil, err
 		}
 
-		constants[i] = v
+		constants = append(constants, v)
 	}
 
 	return constants, nil
 }
 
 func (u *undumper) loadUpvalues() ([]object.UpvalueDesc, error) {
-	n, err := u.loadInt()
+	n, err := u.loadCount()
 	if err != nil {
 		return nil, err
 	}
 
-	upvalues := make([]object.UpvalueDesc, n)
+	upvalues := make([]object.UpvalueDesc, 0, min(n, maxPrealloc))
 
 	var instack bool
 	var idx int
@@ -241,27 +293,28 @@ func (u *undumper) loadUpvalues() ([]object.UpvalueDesc, error) {
 			return nil, err
 		}
 
-		upvalues[i].Instack = instack
-		upvalues[i].Index = idx
+		upvalues = append(upvalues, object.UpvalueDesc{Instack: instack, Index: idx})
 	}
 
 	return upvalues, nil
 }
 
 func (u *undumper) loadProtos(source string) ([]*object.Proto, error) {
-	n, err := u.loadInt()
+	n, err := u.loadCount()
 	if err != nil {
 		return nil, err
 	}
 
-	protos := make([]*object.Proto, n)
+	protos := make([]*object.Proto, 0, min(n, maxPrealloc))
 
+	var p *object.Proto
 	for i := 0; i < n; i++ {
-		protos[i], err = u.loadFunction(source)
-
+		p, err = u.loadFunction(source)
 		if err != nil {
 			return nil, err
 		}
+
+		protos = append(protos, p)
 	}
 
 	return protos, nil
@@ -270,27 +323,29 @@ func (u *undumper) loadProtos(source string) ([]*object.Proto, error) {
 func (u *undumper) loadDebug() (lineInfo []int, locVars []object.LocVar, upvalueNames []string, err error) {
 	var n int
 
-	n, err = u.loadInt()
+	n, err = u.loadCount()
 	if err != nil {
 		return
 	}
 
-	lineInfo = make([]int, n)
+	lineInfo = make([]int, 0, min(n, maxPrealloc))
 
+	var line int
 	for i := 0; i < n; i++ {
-		lineInfo[i], err = u.loadInt()
-
+		line, err = u.loadInt()
 		if err != nil {
 			return
 		}
+
+		lineInfo = append(lineInfo, line)
 	}
 
-	n, err = u.loadInt()
+	n, err = u.loadCount()
 	if err != nil {
 		return
 	}
 
-	locVars = make([]object.LocVar, n)
+	locVars = make([]object.LocVar, 0, min(n, maxPrealloc))
 
 	var name string
 	var startPC int
@@ -311,30 +366,54 @@ func (u *undumper) loadDebug() (lineInfo []int, locVars []object.LocVar, upvalue
 			return
 		}
 
-		locVars[i].Name = name
-		locVars[i].StartPC = startPC
-		locVars[i].EndPC = endPC
+		locVars = append(locVars, object.LocVar{Name: name, StartPC: startPC, EndPC: endPC})
 	}
 
-	n, err = u.loadInt()
+	n, err = u.loadCount()
 	if err != nil {
 		return
 	}
 
-	upvalueNames = make([]string, n)
-	for i := 0; i < n; i++ {
-		upvalueNames[i], err = u.loadString()
+	upvalueNames = make([]string, 0, min(n, maxPrealloc))
 
+	for i := 0; i < n; i++ {
+		name, err = u.loadString()
 		if err != nil {
 			return
 		}
+
+		upvalueNames = append(upvalueNames, name)
 	}
 
 	return
 }
 
+func (u *undumper) loadColumnInfo() ([]int, error) {
+	n, err := u.loadCount()
+	if err != nil {
+		return nil, err
+	}
+
+	columnInfo := make([]int, 0, min(n, maxPrealloc))
+
+	var col int
+	for i := 0; i < n; i++ {
+		col, err = u.loadInt()
+		if err != nil {
+			return nil, err
+		}
+
+		columnInfo = append(columnInfo, col)
+	}
+
+	return columnInfo, nil
+}
+
 func (u *undumper) loadFunction(psource string) (*object.Proto, error) {
 	source, err := u.loadString()
+	if err != nil {
+		return nil, err
+	}
 	if len(source) == 0 {
 		source = psource
 	}
@@ -378,9 +457,19 @@ func (u *undumper) loadFunction(psource string) (*object.Proto, error) {
 	if err != nil {
 		return nil, err
 	}
+	if len(upvalueNames) > len(upvalues) {
+		return nil, errMalformedByteCode
+	}
 	for i, name := range upvalueNames {
 		upvalues[i].Name = name
 	}
+	var columnInfo []int
+	if u.plua {
+		columnInfo, err = u.loadColumnInfo()
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	p := &object.Proto{
 		Code:            code,
@@ -394,13 +483,14 @@ func (u *undumper) loadFunction(psource string) (*object.Proto, error) {
 		IsVararg:        isVararg,
 		MaxStackSize:    maxStackSize,
 		LineInfo:        lineInfo,
+		ColumnInfo:      columnInfo,
 		LocVars:         locVars,
 	}
 
 	return p, nil
 }
 
-func (u *undumper) loadHeader() error {
+func (u *undumper) loadHeader(mode Mode) error {
 	header := make([]byte, 12)
 
 	_, err := io.ReadFull(u.r, header)
@@ -420,7 +510,11 @@ func (u *undumper) loadHeader() error {
 		return errVersionMismatch
 	}
 
-	if header[5] != version.LUAC_FORMAT {
+	switch header[5] {
+	case version.LUAC_FORMAT:
+	case version.PLUAC_FORMAT:
+		u.plua = true
+	default:
 		return errFormatMismatch
 	}
 
@@ -452,11 +546,11 @@ func (u *undumper) loadHeader() error {
 		return err
 	}
 
-	u.int, err = makeInt(intSize)
+	u.int, err = makeInt("int", intSize, true)
 	if err != nil {
 		return err
 	}
-	u.sizeT, err = makeInt(sizeTSize)
+	u.sizeT, err = makeInt("size_t", sizeTSize, false)
 	if err != nil {
 		return err
 	}
@@ -469,30 +563,20 @@ func (u *undumper) loadHeader() error {
 		return err
 	}
 
-	i, err := u.loadInteger()
+	// detect byte order by LUAC_INT
+	bs := u.bs[:integerSize]
+
+	_, err = io.ReadFull(u.r, bs)
 	if err != nil {
 		return err
 	}
 
-	if i != version.LUAC_INT {
-
-		// guess endian
-		switch u.order {
-		case binary.LittleEndian:
-			if isReverseEndian(int64(i), integerSize) {
-				u.order = binary.BigEndian
-			} else {
-				return errNumberFormatMismatch
-			}
-		case binary.BigEndian:
-			if isReverseEndian(int64(i), integerSize) {
-				u.order = binary.LittleEndian
-			} else {
-				return errNumberFormatMismatch
-			}
-		default:
-			return errEndiannessMismatch
-		}
+	if i, ok := decodeInt(bs, binary.LittleEndian, true); ok && i == version.LUAC_INT {
+		u.order = binary.LittleEndian
+	} else if i, ok := decodeInt(bs, binary.BigEndian, true); ok && i == version.LUAC_INT {
+		u.order = binary.BigEndian
+	} else {
+		return errNumberFormatMismatch
 	}
 
 	f, err := u.loadNumber()
@@ -504,87 +588,69 @@ func (u *undumper) loadHeader() error {
 		return errNumberFormatMismatch
 	}
 
+	if mode&Native != 0 {
+		if intSize != 8 || sizeTSize != 8 || integerSize != 8 || numberSize != 8 || u.order != binary.LittleEndian {
+			return &Error{fmt.Errorf("chunk is not native (int: %d, size_t: %d, lua_Integer: %d, lua_Number: %d, byte order: %s)", intSize, sizeTSize, integerSize, numberSize, u.order)}
+		}
+	}
+
 	return nil
 }
 
-func makeInt(size int) (f func(*undumper) (int, error), err error) {
-	switch size {
-	case 1:
-		f = func(u *undumper) (int, error) {
-			var i int8
-			err := binary.Read(u.r, u.order, &i)
-			return int(i), err
-		}
-	case 2:
-		f = func(u *undumper) (int, error) {
-			var i int16
-			err := binary.Read(u.r, u.order, &i)
-			return int(i), err
-		}
-	case 4:
-		f = func(u *undumper) (int, error) {
-			var i int32
-			err := binary.Read(u.r, u.order, &i)
-			return int(i), err
-		}
-	case 8:
-		f = func(u *undumper) (int, error) {
-			var i int64
-			err := binary.Read(u.r, u.order, &i)
-			if err != nil {
-				return 0, err
-			}
+// makeInt returns a loader of C's int or size_t, which is named typ.
+// values which can't be represented by int are reported as errors.
+func makeInt(typ string, size int, signed bool) (f func(*undumper) (int, error), err error) {
+	if !isValidSize(size) {
+		return nil, &Error{fmt.Errorf("%s size %d is invalid", typ, size)}
+	}
 
-			if limits.IntSize == 4 && (i > limits.MaxInt || i < limits.MinInt) {
-				return 0, errIntegerOverflow
-			}
+	f = func(u *undumper) (int, error) {
+		bs := u.bs[:size]
 
-			return int(i), nil
+		_, err := io.ReadFull(u.r, bs)
+		if err != nil {
+			return 0, err
 		}
-	default:
-		err = errInvalidIntSize
+
+		i, ok := decodeInt(bs, u.order, signed)
+		if !ok || i > limits.MaxInt || i < limits.MinInt {
+			return 0, &Error{fmt.Errorf("%d-byte %s value can't be represented by %d-bit int", size, typ, limits.IntSize*8)}
+		}
+
+		return int(i), nil
 	}
 
-	return
+	return f, nil
 }
 
+// makeInteger returns a loader of lua_Integer.
+// values which can't be represented by object.Integer are reported as errors.
 func makeInteger(size int) (f func(*undumper) (object.Integer, error), err error) {
-	switch size {
-	case 1:
-		f = func(u *undumper) (object.Integer, error) {
-			var i int8
-			err := binary.Read(u.r, u.order, &i)
-			return object.Integer(i), err
-		}
-	case 2:
-		f = func(u *undumper) (object.Integer, error) {
-			var i int16
-			err := binary.Read(u.r, u.order, &i)
-			return object.Integer(i), err
-		}
-	case 4:
-		f = func(u *undumper) (object.Integer, error) {
-			var i int32
-			err := binary.Read(u.r, u.order, &i)
-			return object.Integer(i), err
+	if !isValidSize(size) {
+		return nil, &Error{fmt.Errorf("lua_Integer size %d is invalid", size)}
+	}
+
+	f = func(u *undumper) (object.Integer, error) {
+		bs := u.bs[:size]
+
+		_, err := io.ReadFull(u.r, bs)
+		if err != nil {
+			return 0, err
 		}
-	case 8:
-		f = func(u *undumper) (object.Integer, error) {
-			var i int64
-			err := binary.Read(u.r, u.order, &i)
-			if err != nil {
-				return 0, err
-			}
 
-			return object.Integer(i), nil
+		i, ok := decodeInt(bs, u.order, true)
+		if !ok {
+			return 0, &Error{fmt.Errorf("%d-byte lua_Integer value can't be represented by 64-bit integer", size)}
 		}
-	default:
-		err = errInvalidIntegerSize
+
+		return object.Integer(i), nil
 	}
 
-	return
+	return f, nil
 }
 
+// makeNumber returns a loader of lua_Number.
+// float is converted to double exactly, other floating point formats are not supported.
 func makeNumber(size int) (f func(*undumper) (object.Number, error), err error) {
 	switch size {
 	case 4:
@@ -600,40 +666,65 @@ func makeNumber(size int) (f func(*undumper) (object.Number, error), err error)
 			return object.Number(f), err
 		}
 	default:
-		err = errInvalidNumberSize
+		err = &Error{fmt.Errorf("lua_Number size %d is not supported", size)}
 	}
 
 	return
 }
 
-func isReverseEndian(i int64, size int) bool {
-	var r int64
+func isValidSize(size int) bool {
+	return size > 0 && size <= maxIntSize && size&(size-1) == 0
+}
 
-	switch size {
-	case 1:
-		r = int64(int8(i))
-	case 2:
-		r = i & 0xFF
-		r = (r << 8) | ((i >> 8) & 0xFF)
-	case 4:
-		r = i & 0xFF
+// decodeInt decodes an integer of len(bs) bytes.
+// ok is false if the integer overflows int64.
+func decodeInt(bs []byte, order binary.ByteOrder, signed bool) (i int64, ok bool) {
+	n := len(bs)
 
-		r = (r << 8) | ((i >> 8) & 0xFF)
-		r = (r << 8) | ((i >> 16) & 0xFF)
-		r = (r << 8) | ((i >> 24) & 0xFF)
-	case 8:
-		r = i & 0xFF
-
-		r = (r << 8) | ((i >> 8) & 0xFF)
-		r = (r << 8) | ((i >> 16) & 0xFF)
-		r = (r << 8) | ((i >> 24) & 0xFF)
-		r = (r << 8) | ((i >> 32) & 0xFF)
-		r = (r << 8) | ((i >> 40) & 0xFF)
-		r = (r << 8) | ((i >> 48) & 0xFF)
-		r = (r << 8) | ((i >> 56) & 0xFF)
-	default:
-		panic("unreachable")
+	// at returns k-th byte from the most significant one
+	at := func(k int) byte {
+		if order == binary.BigEndian {
+			return bs[k]
+		}
+		return bs[n-1-k]
+	}
+
+	var x uint64
+	for k := 0; k < n; k++ {
+		x = x<<8 | uint64(at(k))
+	}
+
+	if n > 8 {
+		var ext byte
+		if signed && at(n-8)&0x80 != 0 {
+			ext = 0xFF
+		}
+		for k := 0; k < n-8; k++ {
+			if at(k) != ext {
+				return 0, false
+			}
+		}
 	}
 
-	return i == r
+	if !signed {
+		if x > limits.MaxInt64 {
+			return 0, false
+		}
+		return int64(x), true
+	}
+
+	if n < 8 {
+		shift := uint(64 - 8*n)
+
+		return int64(x<<shift) >> shift, true
+	}
+
+	return int64(x), true
+}
+
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
 }
@@ -0,0 +1,174 @@
+package undump_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/compiler/undump"
+	"github.com/hirochachacha/plua/internal/version"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+var configs = []*dump.Config{
+	{IntSize: 8, SizeTSize: 8, IntegerSize: 8, NumberSize: 8, ByteOrder: binary.LittleEndian},
+	{IntSize: 8, SizeTSize: 8, IntegerSize: 8, NumberSize: 8, ByteOrder: binary.BigEndian},
+	{IntSize: 4, SizeTSize: 4, IntegerSize: 8, NumberSize: 8, ByteOrder: binary.LittleEndian}, // 32-bit
+	{IntSize: 4, SizeTSize: 4, IntegerSize: 8, NumberSize: 8, ByteOrder: binary.BigEndian},
+	{IntSize: 4, SizeTSize: 4, IntegerSize: 4, NumberSize: 4, ByteOrder: binary.LittleEndian}, // LUA_32BITS
+	{IntSize: 4, SizeTSize: 8, IntegerSize: 4, NumberSize: 4, ByteOrder: binary.BigEndian},
+	{IntSize: 2, SizeTSize: 2, IntegerSize: 2, NumberSize: 4, ByteOrder: binary.LittleEndian},
+}
+
+const configSource = `
+local t = {1, -2, 0.5, "long string constant, longer than the buffer for short strings"}
+local function f(...)
+  return select('#', ...), t[3] * 2
+end
+return f(1, 2)
+`
+
+func TestUndumpConfig(t *testing.T) {
+	p := compile(t, configSource)
+
+	for _, cfg := range configs {
+		var buf bytes.Buffer
+
+		err := cfg.DumpTo(&buf, p, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		bs := buf.Bytes()
+
+		q, err := undump.Undump(bytes.NewReader(bs), 0)
+		if err != nil {
+			t.Errorf("%+v: %v", cfg, err)
+
+			continue
+		}
+
+		if !bytes.Equal(dumpProto(t, p), dumpProto(t, q)) {
+			t.Errorf("%+v: prototype is changed", cfg)
+		}
+
+		_, err = undump.Undump(bytes.NewReader(bs), undump.Native)
+		if native := *cfg == *configs[0]; native != (err == nil) {
+			t.Errorf("%+v: unexpected result in native mode: %v", cfg, err)
+		}
+	}
+}
+
+func TestUndumpColumnInfo(t *testing.T) {
+	p := compile(t, configSource)
+
+	if len(p.ColumnInfo) != len(p.Code) {
+		t.Fatalf("expected %d columns, got %d", len(p.Code), len(p.ColumnInfo))
+	}
+
+	var buf bytes.Buffer
+
+	err := dump.DumpTo(&buf, p, dump.ColumnInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format := buf.Bytes()[5]; format != version.PLUAC_FORMAT {
+		t.Errorf("expected format %d, got %d", version.PLUAC_FORMAT, format)
+	}
+
+	q, err := undump.Undump(&buf, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p.ColumnInfo, q.ColumnInfo) || !reflect.DeepEqual(p.Protos[0].ColumnInfo, q.Protos[0].ColumnInfo) {
+		t.Errorf("column info is changed")
+	}
+
+	buf.Reset()
+
+	err = dump.DumpTo(&buf, p, dump.ColumnInfo|dump.StripDebugInfo)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if format := buf.Bytes()[5]; format != version.LUAC_FORMAT {
+		t.Errorf("expected format %d, got %d", version.LUAC_FORMAT, format)
+	}
+
+	// column info is only available in plua format
+	q, err = undump.Undump(bytes.NewReader(dumpProto(t, p)), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.ColumnInfo != nil {
+		t.Errorf("unexpected column info")
+	}
+}
+
+func TestUndumpOverflow(t *testing.T) {
+	// a chunk with 16-byte lua_Integer, returning a constant
+	chunk := func(k []byte) []byte {
+		var buf bytes.Buffer
+
+		buf.WriteString(version.LUA_SIGNATURE)
+		buf.WriteByte(version.LUAC_VERSION)
+		buf.WriteByte(version.LUAC_FORMAT)
+		buf.WriteString(version.LUAC_DATA)
+		buf.Write([]byte{4, 4, opcode.InstructionSize, 16, 8})
+		buf.Write(int128(version.LUAC_INT))
+		binary.Write(&buf, binary.LittleEndian, float64(version.LUAC_NUM))
+
+		buf.WriteByte(0)                                       // number of upvalues
+		buf.WriteByte(0)                                       // source
+		binary.Write(&buf, binary.LittleEndian, []int32{0, 0}) // line defined, last line defined
+		buf.Write([]byte{0, 1, 2})                             // number of parameters, is vararg, max stack size
+		binary.Write(&buf, binary.LittleEndian, int32(3))      // number of instructions
+		binary.Write(&buf, binary.LittleEndian, []opcode.Instruction{
+			opcode.ABx(opcode.LOADK, 0, 0),
+			opcode.ABC(opcode.RETURN, 0, 2, 0),
+			opcode.ABC(opcode.RETURN, 0, 1, 0),
+		})
+		binary.Write(&buf, binary.LittleEndian, int32(1)) // number of constants
+		buf.WriteByte(byte(object.TNUMINT))
+		buf.Write(k)
+		binary.Write(&buf, binary.LittleEndian, []int32{0, 0, 0, 0, 0}) // upvalues, protos, debug info
+
+		return buf.Bytes()
+	}
+
+	p, err := undump.Undump(bytes.NewReader(chunk(int128(math.MinInt64))), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if k := p.Constants[0]; k != object.Integer(math.MinInt64) {
+		t.Errorf("expected %d, got %v", int64(math.MinInt64), k)
+	}
+
+	k := int128(math.MaxInt64)
+	k[8] = 1
+
+	_, err = undump.Undump(bytes.NewReader(chunk(k)), 0)
+	if err == nil || !strings.Contains(err.Error(), "16-byte lua_Integer value can't be represented") {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+}
+
+// int128 returns i as a little endian 16-byte integer.
+func int128(i int64) []byte {
+	bs := make([]byte, 16)
+	binary.LittleEndian.PutUint64(bs, uint64(i))
+	if i < 0 {
+		for j := 8; j < 16; j++ {
+			bs[j] = 0xFF
+		}
+	}
+	return bs
+}
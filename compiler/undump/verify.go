@@ -0,0 +1,309 @@
+package undump
+
+import (
+	"fmt"
+
+	"github.com/hirochachacha/plua/internal/version"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// verifier checks that a prototype loaded from an untrusted chunk can be executed safely.
+// it doesn't prove anything about types of values,
+// it only guarantees that every operand refers valid registers, constants, upvalues,
+// prototypes and instructions.
+type verifier struct {
+	p  *object.Proto
+	pc int
+}
+
+// verify checks p and its nested prototypes recursively.
+// parent is the enclosing prototype, or nil if p is the main function.
+func verify(p, parent *object.Proto) error {
+	v := &verifier{p: p}
+
+	if err := v.verifyHeader(parent); err != nil {
+		return err
+	}
+
+	for v.pc = 0; v.pc < len(p.Code); v.pc++ {
+		if err := v.verifyInst(p.Code[v.pc]); err != nil {
+			return err
+		}
+	}
+
+	for _, q := range p.Protos {
+		if err := verify(q, p); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (v *verifier) errorf(format string, args ...interface{}) error {
+	return &Error{fmt.Errorf("malformed byte code: function at line %d: %s", v.p.LineDefined, fmt.Sprintf(format, args...))}
+}
+
+func (v *verifier) instErrorf(format string, args ...interface{}) error {
+	return v.errorf("pc %d (%s): %s", v.pc, v.p.Code[v.pc].OpName(), fmt.Sprintf(format, args...))
+}
+
+func (v *verifier) verifyHeader(parent *object.Proto) error {
+	p := v.p
+
+	if p.NParams > p.MaxStackSize {
+		return v.errorf("number of parameters %d exceeds max stack size %d", p.NParams, p.MaxStackSize)
+	}
+
+	if len(p.Upvalues) > version.MAXUPVAL+1 {
+		return v.errorf("too many upvalues %d", len(p.Upvalues))
+	}
+
+	if parent != nil {
+		for i, uv := range p.Upvalues {
+			if uv.Instack {
+				if uv.Index >= parent.MaxStackSize {
+					return v.errorf("upvalue %d refers register %d out of range", i, uv.Index)
+				}
+			} else {
+				if uv.Index >= len(parent.Upvalues) {
+					return v.errorf("upvalue %d refers upvalue %d out of range", i, uv.Index)
+				}
+			}
+		}
+	}
+
+	n := len(p.Code)
+
+	if n == 0 {
+		return v.errorf("empty code")
+	}
+
+	if p.Code[n-1].OpCode() != opcode.RETURN {
+		return v.errorf("code doesn't end with RETURN")
+	}
+
+	if len(p.LineInfo) != 0 && len(p.LineInfo) != n {
+		return v.errorf("line info length %d doesn't match code length %d", len(p.LineInfo), n)
+	}
+
+	if len(p.ColumnInfo) != 0 && len(p.ColumnInfo) != len(p.LineInfo) {
+		return v.errorf("column info length %d doesn't match line info length %d", len(p.ColumnInfo), len(p.LineInfo))
+	}
+
+	for i, locvar := range p.LocVars {
+		if locvar.StartPC < 0 || locvar.EndPC < locvar.StartPC || locvar.EndPC > n {
+			return v.errorf("local variable %d has invalid range [%d, %d)", i, locvar.StartPC, locvar.EndPC)
+		}
+	}
+
+	return nil
+}
+
+func (v *verifier) verifyInst(inst opcode.Instruction) error {
+	p := v.p
+
+	op := inst.OpCode()
+
+	if op > opcode.EXTRAARG {
+		return v.errorf("pc %d: unknown opcode %d", v.pc, op)
+	}
+
+	a := inst.A()
+
+	switch op {
+	case opcode.SETTABUP:
+		if err := v.checkUpvalue(a); err != nil {
+			return err
+		}
+	case opcode.JMP:
+		if a > 0 {
+			if err := v.checkReg(a - 1); err != nil {
+				return err
+			}
+		}
+	case opcode.RETURN:
+		// R(A) may be the top of the stack, if there are no results
+		if a > p.MaxStackSize {
+			return v.instErrorf("register %d out of range (max stack size is %d)", a, p.MaxStackSize)
+		}
+	case opcode.EQ, opcode.LT, opcode.LE, opcode.EXTRAARG:
+		// A is not a register
+	default:
+		if err := v.checkReg(a); err != nil {
+			return err
+		}
+	}
+
+	switch inst.OpMode() {
+	case opcode.IABC:
+		if err := v.checkArg(inst.BMode(), inst.B()); err != nil {
+			return err
+		}
+		if err := v.checkArg(inst.CMode(), inst.C()); err != nil {
+			return err
+		}
+	case opcode.IABx:
+		if inst.BMode() == opcode.OpArgK {
+			if err := v.checkConstant(inst.Bx()); err != nil {
+				return err
+			}
+		}
+	case opcode.IAsBx:
+		if err := v.checkJump(inst.SBx()); err != nil {
+			return err
+		}
+	}
+
+	switch op {
+	case opcode.LOADKX:
+		extra, err := v.extraArg()
+		if err != nil {
+			return err
+		}
+		if err := v.checkConstant(extra.Ax()); err != nil {
+			return err
+		}
+	case opcode.LOADBOOL:
+		if inst.C() != 0 {
+			if err := v.checkJump(1); err != nil {
+				return err
+			}
+		}
+	case opcode.LOADNIL:
+		if err := v.checkReg(a + inst.B()); err != nil {
+			return err
+		}
+	case opcode.GETUPVAL, opcode.GETTABUP, opcode.SETUPVAL:
+		if err := v.checkUpvalue(inst.B()); err != nil {
+			return err
+		}
+	case opcode.NEWTABLE:
+		// each field of table constructors requires one instruction at least,
+		// except nils loaded by one LOADNIL. sizes are rounded up to double at most.
+		if asize := opcode.LogToInt(inst.B()); asize > 2*(opcode.MaxB+1)*len(p.Code) {
+			return v.instErrorf("array size %d is too large", asize)
+		}
+		if msize := opcode.LogToInt(inst.C()); msize > 2*len(p.Code) {
+			return v.instErrorf("hash size %d is too large", msize)
+		}
+	case opcode.SELF:
+		if err := v.checkReg(a + 1); err != nil {
+			return err
+		}
+	case opcode.CONCAT:
+		if inst.B() >= inst.C() {
+			return v.instErrorf("invalid register range [%d, %d]", inst.B(), inst.C())
+		}
+	case opcode.EQ, opcode.LT, opcode.LE, opcode.TEST, opcode.TESTSET:
+		if v.pc+1 >= len(p.Code) || p.Code[v.pc+1].OpCode() != opcode.JMP {
+			return v.instErrorf("missing JMP")
+		}
+	case opcode.CALL:
+		if b := inst.B(); b > 0 {
+			if err := v.checkReg(a + b - 1); err != nil {
+				return err
+			}
+		}
+		if c := inst.C(); c > 1 {
+			if err := v.checkReg(a + c - 2); err != nil {
+				return err
+			}
+		}
+	case opcode.TAILCALL, opcode.RETURN, opcode.VARARG:
+		if b := inst.B(); b > 1 {
+			if err := v.checkReg(a + b - 2); err != nil {
+				return err
+			}
+		}
+	case opcode.FORLOOP, opcode.FORPREP:
+		if err := v.checkReg(a + 3); err != nil {
+			return err
+		}
+	case opcode.TFORCALL:
+		if err := v.checkReg(a + 2 + inst.C()); err != nil {
+			return err
+		}
+		if v.pc+1 >= len(p.Code) || p.Code[v.pc+1].OpCode() != opcode.TFORLOOP {
+			return v.instErrorf("missing TFORLOOP")
+		}
+	case opcode.TFORLOOP:
+		if err := v.checkReg(a + 1); err != nil {
+			return err
+		}
+	case opcode.SETLIST:
+		if b := inst.B(); b > 0 {
+			if err := v.checkReg(a + b); err != nil {
+				return err
+			}
+		}
+		if inst.C() == 0 {
+			extra, err := v.extraArg()
+			if err != nil {
+				return err
+			}
+			if extra.Ax() == 0 {
+				return v.instErrorf("invalid block number 0")
+			}
+		}
+	case opcode.CLOSURE:
+		if bx := inst.Bx(); bx >= len(p.Protos) {
+			return v.instErrorf("prototype %d out of range", bx)
+		}
+	}
+
+	return nil
+}
+
+func (v *verifier) checkArg(mode opcode.OpArgMask, x int) error {
+	switch mode {
+	case opcode.OpArgR:
+		return v.checkReg(x)
+	case opcode.OpArgK:
+		if x&opcode.BitRK != 0 {
+			return v.checkConstant(x & ^opcode.BitRK)
+		}
+		return v.checkReg(x)
+	}
+	return nil
+}
+
+func (v *verifier) checkReg(r int) error {
+	if r < 0 || r >= v.p.MaxStackSize {
+		return v.instErrorf("register %d out of range (max stack size is %d)", r, v.p.MaxStackSize)
+	}
+	return nil
+}
+
+func (v *verifier) checkConstant(k int) error {
+	if k < 0 || k >= len(v.p.Constants) {
+		return v.instErrorf("constant %d out of range", k)
+	}
+	return nil
+}
+
+func (v *verifier) checkUpvalue(u int) error {
+	if u < 0 || u >= len(v.p.Upvalues) {
+		return v.instErrorf("upvalue %d out of range", u)
+	}
+	return nil
+}
+
+// checkJump checks the destination of a jump by sbx from the next instruction.
+func (v *verifier) checkJump(sbx int) error {
+	dest := v.pc + 1 + sbx
+	if dest < 0 || dest >= len(v.p.Code) {
+		return v.instErrorf("jump to %d out of range", dest)
+	}
+	return nil
+}
+
+// extraArg returns EXTRAARG which the current instruction requires, and skips it.
+func (v *verifier) extraArg() (opcode.Instruction, error) {
+	if v.pc+1 >= len(v.p.Code) || v.p.Code[v.pc+1].OpCode() != opcode.EXTRAARG {
+		return 0, v.instErrorf("missing EXTRAARG")
+	}
+	v.pc++
+	return v.p.Code[v.pc], nil
+}
@@ -0,0 +1,188 @@
+package undump_test
+
+import (
+	"bytes"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/dump"
+	"github.com/hirochachacha/plua/compiler/undump"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib/base"
+	"github.com/hirochachacha/plua/stdlib/debug"
+)
+
+const testSource = `
+local t = {1, 2, 3}
+for i, v in ipairs(t) do
+  t[i] = v * 2
+end
+local function f(...)
+  return select('#', ...), t
+end
+return f(1, 2)
+`
+
+var verifyTests = []struct {
+	modify func(p *object.Proto)
+	err    string
+}{
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.ABC(opcode.MOVE, p.MaxStackSize, 0, 0) },
+		"register",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.ABx(opcode.LOADK, 0, len(p.Constants)) },
+		"constant",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.ABC(opcode.GETUPVAL, 0, len(p.Upvalues), 0) },
+		"upvalue",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.ABx(opcode.CLOSURE, 0, len(p.Protos)) },
+		"prototype",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.AsBx(opcode.JMP, 0, len(p.Code)) },
+		"jump",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.AsBx(opcode.JMP, 0, -2) },
+		"jump",
+	},
+	{
+		func(p *object.Proto) { p.Code[len(p.Code)-2] = opcode.ABC(opcode.EQ, 0, 0, 0) },
+		"missing JMP",
+	},
+	{
+		func(p *object.Proto) { p.Code[len(p.Code)-2] = opcode.ABC(opcode.SETLIST, 0, 1, 0) },
+		"missing EXTRAARG",
+	},
+	{
+		func(p *object.Proto) { p.Code[len(p.Code)-1] = opcode.AsBx(opcode.JMP, 0, -1) },
+		"RETURN",
+	},
+	{
+		func(p *object.Proto) { p.Code[0] = opcode.Instruction(opcode.EXTRAARG + 1) },
+		"unknown opcode",
+	},
+	{
+		func(p *object.Proto) { p.LineInfo = p.LineInfo[1:] },
+		"line info",
+	},
+	{
+		func(p *object.Proto) { p.LocVars[0].EndPC = len(p.Code) + 1 },
+		"local variable",
+	},
+	{
+		func(p *object.Proto) {
+			p.Protos[0].Upvalues[0].Instack = false
+			p.Protos[0].Upvalues[0].Index = len(p.Upvalues)
+		},
+		"upvalue",
+	},
+	{
+		func(p *object.Proto) { p.NParams = p.MaxStackSize + 1 },
+		"parameters",
+	},
+}
+
+func TestVerify(t *testing.T) {
+	for i, test := range verifyTests {
+		p := compile(t, testSource)
+
+		test.modify(p)
+
+		_, err := undump.Undump(bytes.NewReader(dumpProto(t, p)), 0)
+		if err == nil {
+			t.Errorf("%d: expected error", i)
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%d: expected error about %q, got %q", i, test.err, err)
+		}
+	}
+}
+
+// TestVerifyCorpus undumps byte code generated from the fuzzing corpus,
+// and randomly corrupted one.
+// Corrupted chunks must be rejected, or be executed without crash.
+// They are executed in an empty environment with limited instructions.
+func TestVerifyCorpus(t *testing.T) {
+	names, err := filepath.Glob(filepath.Join("..", "..", "_fuzz", "corpus", "*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if testing.Short() && len(names) > 300 {
+		names = names[:300]
+	}
+
+	driver := compile(t, `
+		local chunk = ...
+		debug.sethook(function() error("budget exceeded") end, "", 1000)
+		local f = load(chunk, "=corrupted", "b", {})
+		if f then
+		  pcall(f)
+		end
+	`)
+
+	r := rand.New(rand.NewSource(1))
+
+	c := compiler.NewCompiler()
+
+	for _, name := range names {
+		p, err := c.CompileFile(name, compiler.Either)
+		if err != nil {
+			continue
+		}
+
+		bs := dumpProto(t, p)
+
+		_, err = undump.Undump(bytes.NewReader(bs), 0)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+
+			continue
+		}
+
+		for i := 0; i < 8; i++ {
+			corrupted := append([]byte(nil), bs...)
+			for j := r.Intn(4); j >= 0; j-- {
+				corrupted[r.Intn(len(corrupted))] = byte(r.Intn(256))
+			}
+
+			process := runtime.NewProcess()
+			process.Require("_G", base.Open)
+			process.Require("debug", debug.Open)
+
+			process.Exec(driver, object.String(corrupted))
+		}
+	}
+}
+
+func compile(t *testing.T, src string) *object.Proto {
+	c := compiler.NewCompiler()
+
+	p, err := c.Compile(strings.NewReader(src), "=test", compiler.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return p
+}
+
+func dumpProto(t *testing.T, p *object.Proto) []byte {
+	var buf bytes.Buffer
+
+	err := dump.DumpTo(&buf, p, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
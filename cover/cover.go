@@ -0,0 +1,148 @@
+// Package cover implements coverage profiles of Lua sources.
+//
+// Profiles are collected by runtime.Coverage, and can be merged and written
+// in the lcov tracefile format, the Go cover profile format, or as HTML.
+package cover
+
+import (
+	"sort"
+)
+
+// Profile is a coverage profile, keyed by file name.
+type Profile struct {
+	Files map[string]*File
+}
+
+func NewProfile() *Profile {
+	return &Profile{Files: make(map[string]*File)}
+}
+
+// File is a coverage of a file.
+// Lines only contains executable lines, unexecuted lines have zero count.
+type File struct {
+	Name     string
+	Lines    map[int]int64
+	Branches map[BranchKey]*Branch
+}
+
+func newFile(name string) *File {
+	return &File{
+		Name:     name,
+		Lines:    make(map[int]int64),
+		Branches: make(map[BranchKey]*Branch),
+	}
+}
+
+type BranchKey struct {
+	Line  int
+	Block int
+}
+
+// Branch is a coverage of a conditional instruction.
+// Taken[0] counts the jumps and Taken[1] counts the fall-throughs.
+type Branch struct {
+	BranchKey
+
+	Taken [2]int64
+}
+
+// File returns the coverage of name, creating it if it doesn't exist yet.
+func (p *Profile) File(name string) *File {
+	f := p.Files[name]
+	if f == nil {
+		f = newFile(name)
+		p.Files[name] = f
+	}
+	return f
+}
+
+// Merge adds all counts of other to p.
+func (p *Profile) Merge(other *Profile) {
+	for name, of := range other.Files {
+		f := p.File(name)
+		for line, count := range of.Lines {
+			f.Lines[line] += count
+		}
+		for key, ob := range of.Branches {
+			f.Branch(key.Line, key.Block).add(ob.Taken)
+		}
+	}
+}
+
+// Names returns sorted file names.
+func (p *Profile) Names() []string {
+	names := make([]string, 0, len(p.Files))
+	for name := range p.Files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Coverage returns the number of executed lines and executable lines.
+func (p *Profile) Coverage() (covered, total int) {
+	for _, f := range p.Files {
+		c, t := f.Coverage()
+		covered += c
+		total += t
+	}
+	return
+}
+
+// Branch returns the branch at (line, block), creating it if it doesn't exist yet.
+func (f *File) Branch(line, block int) *Branch {
+	key := BranchKey{Line: line, Block: block}
+	b := f.Branches[key]
+	if b == nil {
+		b = &Branch{BranchKey: key}
+		f.Branches[key] = b
+	}
+	return b
+}
+
+func (b *Branch) add(taken [2]int64) {
+	b.Taken[0] += taken[0]
+	b.Taken[1] += taken[1]
+}
+
+// Coverage returns the number of executed lines and executable lines.
+func (f *File) Coverage() (covered, total int) {
+	for _, count := range f.Lines {
+		if count > 0 {
+			covered++
+		}
+		total++
+	}
+	return
+}
+
+// SortedLines returns executable lines in ascending order.
+func (f *File) SortedLines() []int {
+	lines := make([]int, 0, len(f.Lines))
+	for line := range f.Lines {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// SortedBranches returns branches ordered by line and block.
+func (f *File) SortedBranches() []*Branch {
+	bs := make([]*Branch, 0, len(f.Branches))
+	for _, b := range f.Branches {
+		bs = append(bs, b)
+	}
+	sort.Sort(byPos(bs))
+	return bs
+}
+
+type byPos []*Branch
+
+func (bs byPos) Len() int      { return len(bs) }
+func (bs byPos) Swap(i, j int) { bs[i], bs[j] = bs[j], bs[i] }
+func (bs byPos) Less(i, j int) bool {
+	if bs[i].Line != bs[j].Line {
+		return bs[i].Line < bs[j].Line
+	}
+	return bs[i].Block < bs[j].Block
+}
@@ -0,0 +1,100 @@
+package cover
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testProfile() *Profile {
+	p := NewProfile()
+
+	f := p.File("a.lua")
+	f.Lines[1] = 3
+	f.Lines[2] = 0
+	f.Lines[4] = 1
+	f.Branch(1, 0).Taken = [2]int64{2, 1}
+	f.Branch(2, 5).Taken = [2]int64{0, 0}
+
+	g := p.File("b/c.lua")
+	g.Lines[10] = 7
+
+	return p
+}
+
+func TestLcov(t *testing.T) {
+	p := testProfile()
+
+	var buf bytes.Buffer
+
+	if err := p.WriteLcov(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "BRDA:2,5,0,-\n") {
+		t.Errorf("expected unexecuted branch, got\n%s", buf.String())
+	}
+
+	q, err := ReadLcov(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(p, q) {
+		t.Errorf("expected %v, got %v", p, q)
+	}
+}
+
+func TestGoCover(t *testing.T) {
+	p := testProfile()
+
+	var buf bytes.Buffer
+
+	if err := p.WriteGoCover(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := ReadGoCover(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, f := range p.Files {
+		if !reflect.DeepEqual(f.Lines, q.Files[name].Lines) {
+			t.Errorf("%s: expected %v, got %v", name, f.Lines, q.Files[name].Lines)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	p := testProfile()
+
+	p.Merge(testProfile())
+
+	f := p.Files["a.lua"]
+
+	if f.Lines[1] != 6 || f.Lines[2] != 0 {
+		t.Errorf("unexpected line counts %v", f.Lines)
+	}
+
+	if taken := f.Branches[BranchKey{1, 0}].Taken; taken != [2]int64{4, 2} {
+		t.Errorf("unexpected branch counts %v", taken)
+	}
+
+	if covered, total := p.Coverage(); covered != 3 || total != 4 {
+		t.Errorf("expected 3/4, got %d/%d", covered, total)
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := testProfile().WriteHTML(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "a.lua") {
+		t.Errorf("expected file name in the report")
+	}
+}
@@ -0,0 +1,87 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteGoCover writes p in the cover profile format of the go tool.
+// Each executable line is written as a block of one statement.
+func (p *Profile) WriteGoCover(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "mode: count")
+
+	for _, name := range p.Names() {
+		f := p.Files[name]
+
+		for _, line := range f.SortedLines() {
+			fmt.Fprintf(bw, "%s:%d.1,%d.1 1 %d\n", f.Name, line, line+1, f.Lines[line])
+		}
+	}
+
+	return bw.Flush()
+}
+
+// ReadGoCover reads a profile in the cover profile format of the go tool.
+// Blocks are attributed to their start lines.
+func ReadGoCover(r io.Reader) (*Profile, error) {
+	p := NewProfile()
+
+	s := bufio.NewScanner(r)
+
+	lineno := 0
+
+	for s.Scan() {
+		lineno++
+
+		line := strings.TrimSpace(s.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if lineno == 1 {
+			if !strings.HasPrefix(line, "mode: ") {
+				return nil, fmt.Errorf("cover: line %d: missing mode line", lineno)
+			}
+
+			continue
+		}
+
+		i := strings.LastIndex(line, ":")
+		if i == -1 {
+			return nil, fmt.Errorf("cover: line %d: malformed block %q", lineno, line)
+		}
+
+		name := line[:i]
+
+		// startLine.startCol,endLine.endCol numStmt count
+		fields := strings.Fields(line[i+1:])
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("cover: line %d: malformed block %q", lineno, line)
+		}
+
+		j := strings.IndexByte(fields[0], '.')
+		if j == -1 {
+			return nil, fmt.Errorf("cover: line %d: malformed block %q", lineno, line)
+		}
+
+		start, err1 := strconv.Atoi(fields[0][:j])
+		count, err2 := strconv.ParseInt(fields[2], 10, 64)
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("cover: line %d: malformed block %q", lineno, line)
+		}
+
+		p.File(name).Lines[start] += count
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
@@ -0,0 +1,121 @@
+package cover
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+)
+
+type htmlLine struct {
+	Number   int
+	Text     string
+	Class    string
+	Count    string
+	Branches string
+}
+
+type htmlFile struct {
+	Name     string
+	Percent  string
+	Lines    []*htmlLine
+	NoSource bool
+}
+
+// WriteHTML writes an HTML report of p to w.
+// Sources are read from the file system by their names,
+// files which can't be read are reported by their line counts only.
+func (p *Profile) WriteHTML(w io.Writer) error {
+	var files []*htmlFile
+
+	for _, name := range p.Names() {
+		f := p.Files[name]
+
+		hf := &htmlFile{Name: name, Percent: percent(f.Coverage())}
+
+		branches := make(map[int]string)
+		for _, b := range f.SortedBranches() {
+			branches[b.Line] += fmt.Sprintf("[%d/%d]", b.Taken[0], b.Taken[1])
+		}
+
+		src, err := ioutil.ReadFile(name)
+		if err != nil {
+			hf.NoSource = true
+
+			for _, line := range f.SortedLines() {
+				hf.Lines = append(hf.Lines, newHTMLLine(f, line, "", branches))
+			}
+		} else {
+			s := bufio.NewScanner(bytes.NewReader(src))
+			line := 0
+			for s.Scan() {
+				line++
+				hf.Lines = append(hf.Lines, newHTMLLine(f, line, s.Text(), branches))
+			}
+		}
+
+		files = append(files, hf)
+	}
+
+	return htmlTemplate.Execute(w, map[string]interface{}{
+		"Percent": percent(p.Coverage()),
+		"Files":   files,
+	})
+}
+
+func newHTMLLine(f *File, line int, text string, branches map[int]string) *htmlLine {
+	l := &htmlLine{Number: line, Text: text, Branches: branches[line]}
+
+	if count, ok := f.Lines[line]; ok {
+		l.Count = fmt.Sprint(count)
+		if count > 0 {
+			l.Class = "cov"
+		} else {
+			l.Class = "uncov"
+		}
+	} else {
+		l.Class = "none"
+	}
+
+	return l
+}
+
+func percent(covered, total int) string {
+	if total == 0 {
+		return "0.0%"
+	}
+	return fmt.Sprintf("%.1f%%", 100*float64(covered)/float64(total))
+}
+
+var htmlTemplate = template.Must(template.New("html").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta http-equiv="Content-Type" content="text/html; charset=utf-8">
+<title>Lua coverage</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; font-family: monospace; }
+td { padding: 0 8px; white-space: pre; }
+td.num, td.count { text-align: right; color: #888; }
+tr.cov td.src { background: #dfd; }
+tr.uncov td.src { background: #fdd; }
+td.branch { color: #a60; }
+</style>
+</head>
+<body>
+<h1>Lua coverage: {{.Percent}} of lines</h1>
+<ul>
+{{range $i, $f := .Files}}<li><a href="#file{{$i}}">{{$f.Name}}</a> ({{$f.Percent}})</li>
+{{end}}</ul>
+{{range $i, $f := .Files}}
+<h2 id="file{{$i}}">{{$f.Name}} ({{$f.Percent}})</h2>
+{{if $f.NoSource}}<p>source is not available</p>{{end}}
+<table>
+{{range $f.Lines}}<tr class="{{.Class}}"><td class="num">{{.Number}}</td><td class="count">{{.Count}}</td><td class="src">{{.Text}}</td><td class="branch">{{.Branches}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,133 @@
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WriteLcov writes p in the lcov tracefile format.
+func (p *Profile) WriteLcov(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for _, name := range p.Names() {
+		f := p.Files[name]
+
+		fmt.Fprintf(bw, "TN:\nSF:%s\n", f.Name)
+
+		var brf, brh int
+		for _, b := range f.SortedBranches() {
+			for i, taken := range b.Taken {
+				if b.Taken[0] == 0 && b.Taken[1] == 0 {
+					fmt.Fprintf(bw, "BRDA:%d,%d,%d,-\n", b.Line, b.Block, i)
+				} else {
+					fmt.Fprintf(bw, "BRDA:%d,%d,%d,%d\n", b.Line, b.Block, i, taken)
+				}
+				if taken > 0 {
+					brh++
+				}
+				brf++
+			}
+		}
+		fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", brf, brh)
+
+		for _, line := range f.SortedLines() {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line, f.Lines[line])
+		}
+
+		covered, total := f.Coverage()
+
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", total, covered)
+	}
+
+	return bw.Flush()
+}
+
+// ReadLcov reads a profile in the lcov tracefile format.
+func ReadLcov(r io.Reader) (*Profile, error) {
+	p := NewProfile()
+
+	var f *File
+
+	s := bufio.NewScanner(r)
+
+	lineno := 0
+
+	for s.Scan() {
+		lineno++
+
+		line := strings.TrimSpace(s.Text())
+
+		if line == "end_of_record" {
+			f = nil
+
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i == -1 {
+			if line == "" {
+				continue
+			}
+
+			return nil, fmt.Errorf("cover: line %d: malformed lcov record %q", lineno, line)
+		}
+
+		tag, val := line[:i], line[i+1:]
+
+		switch tag {
+		case "SF":
+			f = p.File(val)
+		case "DA", "BRDA":
+			if f == nil {
+				return nil, fmt.Errorf("cover: line %d: %s record outside of a file", lineno, tag)
+			}
+
+			fields := strings.Split(val, ",")
+
+			if tag == "DA" {
+				if len(fields) < 2 {
+					return nil, fmt.Errorf("cover: line %d: malformed DA record %q", lineno, val)
+				}
+
+				ln, err1 := strconv.Atoi(fields[0])
+				count, err2 := strconv.ParseInt(fields[1], 10, 64)
+				if err1 != nil || err2 != nil {
+					return nil, fmt.Errorf("cover: line %d: malformed DA record %q", lineno, val)
+				}
+
+				f.Lines[ln] += count
+			} else {
+				if len(fields) != 4 {
+					return nil, fmt.Errorf("cover: line %d: malformed BRDA record %q", lineno, val)
+				}
+
+				ln, err1 := strconv.Atoi(fields[0])
+				block, err2 := strconv.Atoi(fields[1])
+				branch, err3 := strconv.Atoi(fields[2])
+				if err1 != nil || err2 != nil || err3 != nil || branch < 0 || branch > 1 {
+					return nil, fmt.Errorf("cover: line %d: malformed BRDA record %q", lineno, val)
+				}
+
+				var taken int64
+				if fields[3] != "-" {
+					t, err := strconv.ParseInt(fields[3], 10, 64)
+					if err != nil {
+						return nil, fmt.Errorf("cover: line %d: malformed BRDA record %q", lineno, val)
+					}
+					taken = t
+				}
+
+				f.Branch(ln, block).Taken[branch] += taken
+			}
+		}
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
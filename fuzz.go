@@ -3,12 +3,17 @@
 package plua
 
 import (
+	"bytes"
+	"fmt"
 	"io/ioutil"
 	"os"
 
 	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/asm"
 	"github.com/hirochachacha/plua/compiler/ast/printer"
+	"github.com/hirochachacha/plua/compiler/dump"
 	"github.com/hirochachacha/plua/compiler/parser"
+	"github.com/hirochachacha/plua/compiler/undump"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/runtime"
 	"github.com/hirochachacha/plua/stdlib"
@@ -50,6 +55,25 @@ func Fuzz(data []byte) int {
 		text = true
 	}
 
+	// error recovery must terminate, and report errors iff the normal mode does
+	if _, err := parser.ParseFile(f.Name(), parser.ParseComments|parser.AllErrors); (err == nil) != text {
+		panic(fmt.Sprintf("inconsistent error in AllErrors mode: %v", err))
+	}
+
+	// unmodified files must be reproduced in lossless mode
+	if ast, err := parser.ParseFile(f.Name(), parser.Lossless); err == nil {
+		var buf bytes.Buffer
+
+		err = (&printer.Config{Mode: printer.Lossless}).Fprint(&buf, ast)
+		if err != nil {
+			panic(err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), data) {
+			panic("lossless printing differs from the source")
+		}
+	}
+
 	c := compiler.NewCompiler()
 
 	proto, err := c.CompileFile(f.Name(), compiler.Either)
@@ -60,11 +84,57 @@ func Fuzz(data []byte) int {
 		return 0
 	}
 
-	err = object.FprintProto(ioutil.Discard, proto)
+	// listing must be assembled
+	var buf bytes.Buffer
+
+	err = object.FprintProto(&buf, proto)
 	if err != nil {
 		panic(err)
 	}
 
+	_, err = asm.Assemble(&buf, "=listing")
+	if err != nil {
+		panic(err)
+	}
+
+	// byte code must pass the verifier, if it is generated by the compiler
+	buf.Reset()
+
+	err = dump.DumpTo(&buf, proto, dump.ColumnInfo)
+	if err != nil {
+		panic(err)
+	}
+
+	_, err = undump.Undump(&buf, 0)
+	if err != nil {
+		panic(err)
+	}
+
+	p := runtime.NewProcess()
+
+	p.Require("", stdlib.Open)
+
+	_, err = p.Exec(proto)
+	if err != nil {
+		err = object.FprintError(ioutil.Discard, err)
+		if err != nil {
+			panic(err)
+		}
+
+		return 0
+	}
+
+	return 1
+}
+
+// FuzzUndump mutates binary chunks, seeds are generated by _fuzz/undump/gen.go.
+func FuzzUndump(data []byte) int {
+	proto, err := undump.Undump(bytes.NewReader(data), 0)
+	if err != nil {
+		return 0
+	}
+
+	// byte code accepted by the verifier must run without crashing
 	p := runtime.NewProcess()
 
 	p.Require("", stdlib.Open)
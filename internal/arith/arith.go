@@ -196,31 +196,40 @@ func Idiv(x, y object.Value) (object.Value, bool) {
 				return nil, false
 			}
 
-			z := x / y
-
-			if (x^y) < 0 && x%y != 0 {
-				z--
-			}
-
-			return z, true
+			return IdivInteger(x, y), true
 		}
 	}
 
 	if xf, ok := object.ToGoFloat64(x); ok {
 		if yf, ok := object.ToGoFloat64(y); ok {
-			zf, frac := math.Modf(xf / yf)
-
-			if math.Signbit(xf) != math.Signbit(yf) && frac != 0 {
-				zf--
-			}
-
-			return object.Number(zf), true
+			return IdivNumber(object.Number(xf), object.Number(yf)), true
 		}
 	}
 
 	return nil, true
 }
 
+// IdivInteger returns x // y, y must not be 0.
+func IdivInteger(x, y object.Integer) object.Integer {
+	z := x / y
+
+	if (x^y) < 0 && x%y != 0 {
+		z--
+	}
+
+	return z
+}
+
+func IdivNumber(x, y object.Number) object.Number {
+	zf, frac := math.Modf(float64(x / y))
+
+	if math.Signbit(float64(x)) != math.Signbit(float64(y)) && frac != 0 {
+		zf--
+	}
+
+	return object.Number(zf)
+}
+
 func Mod(x, y object.Value) (object.Value, bool) {
 	if x, ok := x.(object.Integer); ok {
 		if y, ok := y.(object.Integer); ok {
@@ -228,35 +237,44 @@ func Mod(x, y object.Value) (object.Value, bool) {
 				return nil, false
 			}
 
-			if x == object.MinInteger && y == -1 {
-				return object.Integer(0), true
-			}
-
-			rem := x % y
-
-			if x^y < 0 && rem != 0 {
-				rem += y
-			}
-
-			return rem, true
+			return ModInteger(x, y), true
 		}
 	}
 
 	if xf, ok := object.ToGoFloat64(x); ok {
 		if yf, ok := object.ToGoFloat64(y); ok {
-			rem := math.Mod(xf, yf)
-
-			if math.Signbit(xf) != math.Signbit(yf) && rem != 0 {
-				rem += yf
-			}
-
-			return object.Number(rem), true
+			return ModNumber(object.Number(xf), object.Number(yf)), true
 		}
 	}
 
 	return nil, true
 }
 
+// ModInteger returns x % y, y must not be 0.
+func ModInteger(x, y object.Integer) object.Integer {
+	if x == object.MinInteger && y == -1 {
+		return 0
+	}
+
+	rem := x % y
+
+	if x^y < 0 && rem != 0 {
+		rem += y
+	}
+
+	return rem
+}
+
+func ModNumber(x, y object.Number) object.Number {
+	rem := math.Mod(float64(x), float64(y))
+
+	if math.Signbit(float64(x)) != math.Signbit(float64(y)) && rem != 0 {
+		rem += float64(y)
+	}
+
+	return object.Number(rem)
+}
+
 func Pow(x, y object.Value) object.Value {
 	if x, ok := object.ToNumber(x); ok {
 		if y, ok := object.ToNumber(y); ok {
@@ -300,31 +318,39 @@ func Bxor(x, y object.Value) object.Value {
 func Shl(x, y object.Value) object.Value {
 	if x, ok := object.ToInteger(x); ok {
 		if y, ok := object.ToInteger(y); ok {
-			if y < 0 {
-				return object.Integer(uint64(x) >> uint64(-y))
-			}
-
-			return object.Integer(uint64(x) << uint64(y))
+			return ShlInteger(x, y)
 		}
 	}
 
 	return nil
 }
 
+func ShlInteger(x, y object.Integer) object.Integer {
+	if y < 0 {
+		return object.Integer(uint64(x) >> uint64(-y))
+	}
+
+	return object.Integer(uint64(x) << uint64(y))
+}
+
 func Shr(x, y object.Value) object.Value {
 	if x, ok := object.ToInteger(x); ok {
 		if y, ok := object.ToInteger(y); ok {
-			if y < 0 {
-				return object.Integer(uint64(x) << uint64(-y))
-			}
-
-			return object.Integer(uint64(x) >> uint64(y))
+			return ShrInteger(x, y)
 		}
 	}
 
 	return nil
 }
 
+func ShrInteger(x, y object.Integer) object.Integer {
+	if y < 0 {
+		return object.Integer(uint64(x) << uint64(-y))
+	}
+
+	return object.Integer(uint64(x) >> uint64(y))
+}
+
 // not arithmetic though
 func Concat(x, y object.Value) object.Value {
 	if x, ok := object.ToString(x); ok {
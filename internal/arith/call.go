@@ -1,116 +1,47 @@
 package arith
 
 import (
-	"github.com/hirochachacha/plua/internal/errors"
 	"github.com/hirochachacha/plua/object"
 )
 
 func CallLen(th object.Thread, x object.Value) (object.Value, *object.RuntimeError) {
-	switch x := x.(type) {
-	case object.String:
-		return object.Integer(len(x)), nil
-	case object.Table:
-		tm := gettm(x.Metatable(), object.TM_LEN)
-		if tm != nil {
-			return calltm(th, tm, x)
-		}
-		return object.Integer(x.Len()), nil
-	default:
-		return calluntm(th, x, object.TM_LEN)
-	}
+	return callun(th, object.TM_LEN, x)
 }
 
 func CallUnm(th object.Thread, x object.Value) (object.Value, *object.RuntimeError) {
-	if unm := Unm(x); unm != nil {
-		return unm, nil
-	}
-	return calluntm(th, x, object.TM_UNM)
+	return callun(th, object.TM_UNM, x)
 }
 
 func CallBnot(th object.Thread, x object.Value) (object.Value, *object.RuntimeError) {
-	if unm := Bnot(x); unm != nil {
-		return unm, nil
-	}
-	return calluntm(th, x, object.TM_BNOT)
+	return callun(th, object.TM_BNOT, x)
 }
 
 func CallEqual(th object.Thread, not bool, x, y object.Value) (bool, *object.RuntimeError) {
 	// fast path for avoiding assertI2I2
-	eq := object.Equal(x, y)
-	if eq {
+	if object.Equal(x, y) {
 		return true != not, nil
 	}
 
-	switch x := x.(type) {
-	case object.Table:
-		// tm := gettm(x.Metatable(), object.TM_EQ)
-		// if tm != nil {
-		// return callcmptm(th, not, tm, x, y)
-		// }
-
-		// tm = gettmbyobj(th, y, object.TM_EQ)
-		// if tm != nil {
-		// return callcmptm(th, not, tm, x, y)
-		// }
-
-		// return false != not, nil
-
-		if y, ok := y.(object.Table); ok {
-			tm := gettm(x.Metatable(), object.TM_EQ)
-			if tm == nil {
-				tm = gettm(y.Metatable(), object.TM_EQ)
-				if tm == nil {
-					return false != not, nil
-				}
-			}
-
-			return callcmptm(th, not, tm, x, y)
-		}
-
-		return false != not, nil
-	case *object.Userdata:
-		// tm := gettm(x.Metatable, object.TM_EQ)
-		// if tm != nil {
-		// return callcmptm(th, not, tm, x, y)
-		// }
-
-		// tm = gettmbyobj(th, y, object.TM_EQ)
-		// if tm != nil {
-		// return callcmptm(th, not, tm, x, y)
-		// }
-
-		// return false != not, nil
-
-		if y, ok := y.(*object.Userdata); ok {
-			tm := gettm(x.Metatable, object.TM_EQ)
-			if tm == nil {
-				tm = gettm(y.Metatable, object.TM_EQ)
-				if tm == nil {
-					return false != not, nil
-				}
-			}
-
-			return callcmptm(th, not, tm, x, y)
-		}
-
+	tm := EqualTM(x, y)
+	if tm == nil {
 		return false != not, nil
-	default:
-		return eq != not, nil
 	}
+
+	return callcmptm(th, not, tm, x, y)
 }
 
 func CallLessThan(th object.Thread, not bool, x, y object.Value) (bool, *object.RuntimeError) {
 	if b := LessThan(x, y); b != nil {
 		return b != object.Boolean(not), nil
 	}
-	return callordertm(th, not, x, y, object.TM_LT)
+	return callorder(th, object.TM_LT, not, x, y)
 }
 
 func CallLessThanOrEqualTo(th object.Thread, not bool, x, y object.Value) (bool, *object.RuntimeError) {
 	if b := LessThanOrEqualTo(x, y); b != nil {
 		return b != object.Boolean(not), nil
 	}
-	return callordertm(th, not, x, y, object.TM_LE)
+	return callorder(th, object.TM_LE, not, x, y)
 }
 
 func CallAdd(th object.Thread, x, y object.Value) (object.Value, *object.RuntimeError) {
@@ -144,7 +75,7 @@ func CallDiv(th object.Thread, x, y object.Value) (object.Value, *object.Runtime
 func CallIdiv(th object.Thread, x, y object.Value) (object.Value, *object.RuntimeError) {
 	quo, ok := Idiv(x, y)
 	if !ok {
-		return nil, object.NewRuntimeError("attempt to divide by zero")
+		return nil, divideByZeroError()
 	}
 	if quo != nil {
 		return quo, nil
@@ -155,7 +86,7 @@ func CallIdiv(th object.Thread, x, y object.Value) (object.Value, *object.Runtim
 func CallMod(th object.Thread, x, y object.Value) (object.Value, *object.RuntimeError) {
 	rem, ok := Mod(x, y)
 	if !ok {
-		return nil, object.NewRuntimeError("attempt to perform 'n%0'")
+		return nil, modByZeroError()
 	}
 	if rem != nil {
 		return rem, nil
@@ -206,15 +137,9 @@ func CallShr(th object.Thread, x, y object.Value) (object.Value, *object.Runtime
 }
 
 func CallConcat(th object.Thread, x, y object.Value) (object.Value, *object.RuntimeError) {
-	if con := Concat(x, y); con != nil {
-		return con, nil
-	}
-	tm := gettmbyobj(th, x, object.TM_CONCAT)
+	con, tm, err := ConcatTM(th, x, y)
 	if tm == nil {
-		tm = gettmbyobj(th, y, object.TM_CONCAT)
-		if tm == nil {
-			return nil, errors.BinaryError(th, object.TM_CONCAT, x, y)
-		}
+		return con, err
 	}
 	return calltm(th, tm, x, y)
 }
@@ -260,59 +185,32 @@ func callcmptm(th object.Thread, not bool, tm object.Value, x, y object.Value) (
 	return object.ToGoBool(ret) != not, nil
 }
 
-func calluntm(th object.Thread, x object.Value, tag object.Value) (object.Value, *object.RuntimeError) {
-	tm := gettmbyobj(th, x, tag)
+func callun(th object.Thread, tag, x object.Value) (object.Value, *object.RuntimeError) {
+	val, tm, err := UnaryTM(th, tag, x)
 	if tm == nil {
-		return nil, errors.UnaryError(th, tag, x)
+		return val, err
 	}
 	return calltm(th, tm, x)
 }
 
 func callbintm(th object.Thread, x, y object.Value, tag object.Value) (object.Value, *object.RuntimeError) {
-	tm := gettmbyobj(th, x, tag)
-	if tm == nil {
-		tm = gettmbyobj(th, y, tag)
-		if tm == nil {
-			return nil, errors.BinaryError(th, tag, x, y)
-		}
+	tm, err := bintm(th, x, y, tag)
+	if err != nil {
+		return nil, err
 	}
 	return calltm(th, tm, x, y)
 }
 
-func callordertm(th object.Thread, not bool, x, y object.Value, tag object.Value) (bool, *object.RuntimeError) {
-	tm := gettmbyobj(th, x, tag)
-	if tm == nil {
-		tm = gettmbyobj(th, y, tag)
-		if tm == nil {
-			switch tag {
-			case object.TM_LT:
-				tm = gettmbyobj(th, x, object.TM_LE)
-				if tm == nil {
-					tm = gettmbyobj(th, y, object.TM_LE)
-					if tm == nil {
-						return false, errors.CompareError(th, x, y)
-					}
-				}
-
-				x, y = y, x
-
-				not = !not
-			case object.TM_LE:
-				tm = gettmbyobj(th, x, object.TM_LT)
-				if tm == nil {
-					tm = gettmbyobj(th, y, object.TM_LT)
-					if tm == nil {
-						return false, errors.CompareError(th, x, y)
-					}
-				}
-
-				x, y = y, x
-
-				not = !not
-			default:
-				panic("unreachable")
-			}
-		}
+func callorder(th object.Thread, tag object.Value, not bool, x, y object.Value) (bool, *object.RuntimeError) {
+	tm, negate, err := OrderTM(th, tag, x, y)
+	if err != nil {
+		return false, err
+	}
+
+	if negate {
+		x, y = y, x
+
+		not = !not
 	}
 
 	return callcmptm(th, not, tm, x, y)
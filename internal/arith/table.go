@@ -7,70 +7,88 @@ import (
 )
 
 func CallGettable(th object.Thread, t, key object.Value) (object.Value, *object.RuntimeError) {
+	val, tm, t, err := GettableTM(th, t, key)
+	if tm == nil {
+		return val, err
+	}
+	return calltm(th, tm, t, key)
+}
+
+func CallSettable(th object.Thread, t, key, val object.Value) *object.RuntimeError {
+	tm, t, err := SettableTM(th, t, key, val)
+	if tm == nil {
+		return err
+	}
+	_, err = calltm(th, tm, t, key, val)
+	return err
+}
+
+// GettableTM follows __index of t until it finds a value of key or a function.
+// if tm is not nil, the value is tm(self, key).
+func GettableTM(th object.Thread, t, key object.Value) (val, tm, self object.Value, err *object.RuntimeError) {
 	for i := 0; i < version.MAX_TAG_LOOP; i++ {
-		var tm object.Value
 		if tab, ok := t.(object.Table); ok {
 			val := tab.Get(key)
 			if val != nil {
-				return val, nil
+				return val, nil, nil, nil
 			}
 			tm = gettm(tab.Metatable(), object.TM_INDEX)
 			if tm == nil {
-				return nil, nil
+				return nil, nil, nil, nil
 			}
 		} else {
 			tm = gettmbyobj(th, t, object.TM_INDEX)
 		}
 
 		if tm == nil {
-			return nil, errors.IndexError(th, t)
+			return nil, nil, nil, errors.IndexError(th, t)
 		}
 
 		if isFunction(tm) {
-			return calltm(th, tm, t, key)
+			return nil, tm, t, nil
 		}
 
 		t = tm
 	}
 
-	return nil, object.NewRuntimeError("gettable chain too long; possible loop")
+	return nil, nil, nil, object.NewRuntimeError("gettable chain too long; possible loop")
 }
 
-func CallSettable(th object.Thread, t, key, val object.Value) *object.RuntimeError {
+// SettableTM follows __newindex of t until it stores val or finds a function.
+// if tm is not nil, the assignment is done by tm(self, key, val).
+func SettableTM(th object.Thread, t, key, val object.Value) (tm, self object.Value, err *object.RuntimeError) {
 	for i := 0; i < version.MAX_TAG_LOOP; i++ {
-		var tm object.Value
 		if tab, ok := t.(object.Table); ok {
 			tm = gettm(tab.Metatable(), object.TM_NEWINDEX)
 			if tm == nil || tab.Get(key) != nil {
 				if key == nil {
-					return errors.NilIndexError()
+					return nil, nil, errors.NilIndexError()
 				}
 
 				if object.IsNaN(key) {
-					return errors.NaNIndexError()
+					return nil, nil, errors.NaNIndexError()
 				}
 
 				tab.Set(key, val)
 
-				return nil
+				return nil, nil, nil
 			}
 		} else {
 			tm = gettmbyobj(th, t, object.TM_NEWINDEX)
 		}
 
 		if tm == nil {
-			return errors.IndexError(th, t)
+			return nil, nil, errors.IndexError(th, t)
 		}
 
 		if isFunction(tm) {
-			_, err := calltm(th, tm, t, key, val)
-			return err
+			return tm, t, nil
 		}
 
 		t = tm
 	}
 
-	return object.NewRuntimeError("settable chain too long; possible loop")
+	return nil, nil, object.NewRuntimeError("settable chain too long; possible loop")
 }
 
 func isFunction(val object.Value) bool {
@@ -0,0 +1,178 @@
+package arith
+
+import (
+	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/object"
+)
+
+// the functions below perform operations the same as Call* functions,
+// but return metamethods instead of calling them.
+// if tm is not nil, the result of the operation is the first result of the call,
+// so that the VM can call Lua functions without re-entering itself.
+
+// ArithTM performs a binary arithmetic or bitwise operation of tag, such as object.TM_ADD.
+// if tm is not nil, the result is tm(x, y).
+func ArithTM(th object.Thread, tag, x, y object.Value) (val, tm object.Value, err *object.RuntimeError) {
+	switch tag {
+	case object.TM_ADD:
+		val = Add(x, y)
+	case object.TM_SUB:
+		val = Sub(x, y)
+	case object.TM_MUL:
+		val = Mul(x, y)
+	case object.TM_DIV:
+		val = Div(x, y)
+	case object.TM_IDIV:
+		quo, ok := Idiv(x, y)
+		if !ok {
+			return nil, nil, divideByZeroError()
+		}
+		val = quo
+	case object.TM_MOD:
+		rem, ok := Mod(x, y)
+		if !ok {
+			return nil, nil, modByZeroError()
+		}
+		val = rem
+	case object.TM_POW:
+		val = Pow(x, y)
+	case object.TM_BAND:
+		val = Band(x, y)
+	case object.TM_BOR:
+		val = Bor(x, y)
+	case object.TM_BXOR:
+		val = Bxor(x, y)
+	case object.TM_SHL:
+		val = Shl(x, y)
+	case object.TM_SHR:
+		val = Shr(x, y)
+	default:
+		panic("unreachable")
+	}
+
+	if val != nil {
+		return val, nil, nil
+	}
+
+	tm, err = bintm(th, x, y, tag)
+
+	return nil, tm, err
+}
+
+// UnaryTM performs an unary operation of tag, object.TM_UNM, object.TM_BNOT or object.TM_LEN.
+// if tm is not nil, the result is tm(x).
+func UnaryTM(th object.Thread, tag, x object.Value) (val, tm object.Value, err *object.RuntimeError) {
+	switch tag {
+	case object.TM_UNM:
+		val = Unm(x)
+	case object.TM_BNOT:
+		val = Bnot(x)
+	case object.TM_LEN:
+		switch x := x.(type) {
+		case object.String:
+			return object.Integer(len(x)), nil, nil
+		case object.Table:
+			tm = gettm(x.Metatable(), object.TM_LEN)
+			if tm == nil {
+				return object.Integer(x.Len()), nil, nil
+			}
+			return nil, tm, nil
+		}
+	default:
+		panic("unreachable")
+	}
+
+	if val != nil {
+		return val, nil, nil
+	}
+
+	tm = gettmbyobj(th, x, tag)
+	if tm == nil {
+		return nil, nil, errors.UnaryError(th, tag, x)
+	}
+
+	return nil, tm, nil
+}
+
+// ConcatTM performs "x .. y".
+// if tm is not nil, the result is tm(x, y).
+func ConcatTM(th object.Thread, x, y object.Value) (val, tm object.Value, err *object.RuntimeError) {
+	if con := Concat(x, y); con != nil {
+		return con, nil, nil
+	}
+
+	tm, err = bintm(th, x, y, object.TM_CONCAT)
+
+	return nil, tm, err
+}
+
+// EqualTM returns the metamethod which compares x and y,
+// or nil if they are compared by object.Equal.
+func EqualTM(x, y object.Value) object.Value {
+	switch x := x.(type) {
+	case object.Table:
+		if y, ok := y.(object.Table); ok {
+			tm := gettm(x.Metatable(), object.TM_EQ)
+			if tm == nil {
+				tm = gettm(y.Metatable(), object.TM_EQ)
+			}
+			return tm
+		}
+	case *object.Userdata:
+		if y, ok := y.(*object.Userdata); ok {
+			tm := gettm(x.Metatable, object.TM_EQ)
+			if tm == nil {
+				tm = gettm(y.Metatable, object.TM_EQ)
+			}
+			return tm
+		}
+	}
+
+	return nil
+}
+
+// OrderTM returns the metamethod which performs a comparison of tag, object.TM_LT or object.TM_LE.
+// if negate is true, the metamethod is the other one, and the result is "not tm(y, x)".
+func OrderTM(th object.Thread, tag, x, y object.Value) (tm object.Value, negate bool, err *object.RuntimeError) {
+	tm = gettmbyobj(th, x, tag)
+	if tm == nil {
+		tm = gettmbyobj(th, y, tag)
+	}
+	if tm != nil {
+		return tm, false, nil
+	}
+
+	other := object.TM_LE
+	if tag == object.TM_LE {
+		other = object.TM_LT
+	}
+
+	tm = gettmbyobj(th, x, other)
+	if tm == nil {
+		tm = gettmbyobj(th, y, other)
+		if tm == nil {
+			return nil, false, errors.CompareError(th, x, y)
+		}
+	}
+
+	return tm, true, nil
+}
+
+func bintm(th object.Thread, x, y object.Value, tag object.Value) (object.Value, *object.RuntimeError) {
+	tm := gettmbyobj(th, x, tag)
+	if tm == nil {
+		tm = gettmbyobj(th, y, tag)
+		if tm == nil {
+			return nil, errors.BinaryError(th, tag, x, y)
+		}
+	}
+	return tm, nil
+}
+
+func divideByZeroError() *object.RuntimeError {
+	return object.NewRuntimeError("attempt to divide by zero")
+}
+
+func modByZeroError() *object.RuntimeError {
+	return object.NewRuntimeError("attempt to perform 'n%0'")
+}
@@ -8,6 +8,7 @@ import (
 	"sync"
 
 	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/cache"
 	"github.com/hirochachacha/plua/compiler/codegen"
 	"github.com/hirochachacha/plua/compiler/dump"
 	"github.com/hirochachacha/plua/compiler/parser"
@@ -16,13 +17,37 @@ import (
 	"github.com/hirochachacha/plua/object"
 )
 
+// optionsThread is implemented by threads which have compile options of their processes,
+// see runtime.SetCompileOptions.
+type optionsThread interface {
+	CompileOptions() (codegen.Mode, *cache.Cache)
+}
+
+func options(th object.Thread) (codegen.Mode, *cache.Cache) {
+	if th, ok := th.(optionsThread); ok {
+		return th.CompileOptions()
+	}
+
+	return 0, nil
+}
+
 var pool = &sync.Pool{
 	New: func() interface{} {
 		return new(compiler.Compiler)
 	},
 }
 
-func CompileFile(path string, typ compiler.FormatType) (*object.Proto, *object.RuntimeError) {
+// CompileFile compiles the file of path, or stdin if path is empty,
+// with the compile options of th.
+func CompileFile(th object.Thread, path string, typ compiler.FormatType) (*object.Proto, *object.RuntimeError) {
+	mode, c := options(th)
+
+	if len(path) > 0 && c != nil {
+		p, err := c.CompileFile(path, typ, mode)
+
+		return p, newRuntimeError(err)
+	}
+
 	var r io.Reader
 
 	if len(path) == 0 {
@@ -41,19 +66,28 @@ func CompileFile(path string, typ compiler.FormatType) (*object.Proto, *object.R
 		path = "@" + path
 	}
 
-	c := pool.Get().(*compiler.Compiler)
+	return compile(r, path, typ, mode)
+}
 
-	p, err := c.Compile(r, path, typ)
+// CompileString compiles s with the compile options of th.
+func CompileString(th object.Thread, s, srcname string, typ compiler.FormatType) (*object.Proto, *object.RuntimeError) {
+	mode, c := options(th)
 
-	pool.Put(c)
+	if c != nil {
+		p, err := c.CompileString(s, srcname, typ, mode)
 
-	return p, newRuntimeError(err)
+		return p, newRuntimeError(err)
+	}
+
+	return compile(strings.NewReader(s), srcname, typ, mode)
 }
 
-func CompileString(s, srcname string, typ compiler.FormatType) (*object.Proto, *object.RuntimeError) {
+func compile(r io.Reader, srcname string, typ compiler.FormatType, mode codegen.Mode) (*object.Proto, *object.RuntimeError) {
 	c := pool.Get().(*compiler.Compiler)
 
-	p, err := c.Compile(strings.NewReader(s), srcname, typ)
+	c.Mode = mode
+
+	p, err := c.Compile(r, srcname, typ)
 
 	pool.Put(c)
 
@@ -15,6 +15,10 @@ func NilIndexError() *object.RuntimeError {
 }
 
 func StackOverflowError() *object.RuntimeError {
+	return object.NewRuntimeError("stack overflow")
+}
+
+func GoStackOverflowError() *object.RuntimeError {
 	return object.NewRuntimeError("Go stack overflow")
 }
 
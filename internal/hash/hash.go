@@ -1,63 +1,76 @@
 package hash
 
 import (
-	"hash"
+	"math"
 	"reflect"
-	"sync"
 	"unsafe"
 
 	"github.com/dchest/siphash"
 
-	"github.com/hirochachacha/plua/internal/limits"
 	"github.com/hirochachacha/plua/internal/rand"
 
 	"github.com/hirochachacha/plua/object"
 )
 
+// Hash is a seeded hash function of table keys.
+//
+// Sum is safe for concurrent use, since Hash has no state except seeds.
+// Strings are hashed by SipHash to resist hash flooding,
+// other keys are mixed by a cheap bijective finalizer.
 type Hash struct {
-	h hash.Hash64
-	m sync.Mutex
+	k0, k1 uint64
 }
 
 func New() *Hash {
-	return &Hash{h: siphash.New(rand.Bytes(16))}
+	return &Hash{
+		k0: uint64(rand.Int63())<<1 ^ uint64(rand.Int63()),
+		k1: uint64(rand.Int63())<<1 ^ uint64(rand.Int63()),
+	}
 }
 
 func (h *Hash) Sum(key object.Value) (sum uint64) {
-	h.m.Lock()
-	defer h.m.Unlock()
-
-	h.h.Reset()
-
 	switch key := key.(type) {
 	case nil:
 		return 0
 	case object.Integer:
-		h.h.Write((*(*[8]byte)(unsafe.Pointer(&key)))[:])
+		sum = mix(uint64(key) ^ h.k0)
 	case object.Number:
-		h.h.Write((*(*[8]byte)(unsafe.Pointer(&key)))[:])
+		sum = mix(math.Float64bits(float64(key)) ^ h.k1)
 	case object.String:
-		sheader := (*reflect.StringHeader)(unsafe.Pointer(&key))
-
-		bheader := &reflect.SliceHeader{
-			Data: sheader.Data,
-			Len:  sheader.Len,
-			Cap:  sheader.Len,
-		}
-
-		h.h.Write(*(*[]byte)(unsafe.Pointer(bheader)))
+		sum = h.str(string(key))
 	case object.Boolean:
-		h.h.Write((*(*[1]byte)(unsafe.Pointer(&key)))[:])
+		if key {
+			sum = mix(1 ^ h.k0)
+		} else {
+			sum = mix(2 ^ h.k0)
+		}
 	case object.LightUserdata:
-		ikey := uintptr(key.Pointer)
-		h.h.Write((*(*[limits.PtrSize]byte)(unsafe.Pointer(&ikey)))[:])
-	case object.GoFunction:
-		ikey := uintptr(reflect.ValueOf(key).Pointer())
-		h.h.Write((*(*[limits.PtrSize]byte)(unsafe.Pointer(&ikey)))[:])
+		sum = mix(uint64(uintptr(key.Pointer)) ^ h.k1)
 	default:
-		ikey := uintptr(reflect.ValueOf(key).Pointer())
-		h.h.Write((*(*[limits.PtrSize]byte)(unsafe.Pointer(&ikey)))[:])
+		// GoFunction and reference types are compared by pointers, see object.Equal
+		sum = mix(uint64(reflect.ValueOf(key).Pointer()) ^ h.k1)
 	}
 
-	return h.h.Sum64() + 1
+	return sum + 1
+}
+
+// str returns the SipHash-2-4 of s, without copying s.
+func (h *Hash) str(s string) uint64 {
+	// a slice header is a string header followed by the capacity
+	b := *(*[]byte)(unsafe.Pointer(&struct {
+		string
+		int
+	}{s, len(s)}))
+
+	return siphash.Hash(h.k0, h.k1, b)
+}
+
+// mix is the finalizer of MurmurHash3.
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
 }
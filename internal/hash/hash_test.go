@@ -0,0 +1,49 @@
+package hash_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/internal/hash"
+	"github.com/hirochachacha/plua/object"
+)
+
+func TestSum(t *testing.T) {
+	h := hash.New()
+
+	if h.Sum(nil) != 0 {
+		t.Error("expected 0 for nil")
+	}
+
+	s1 := object.String("key")
+	s2 := object.String(strings.Repeat("k", 1) + "ey") // different memory
+
+	keys := [][2]object.Value{
+		{s1, s2},
+		{object.Integer(42), object.Integer(42)},
+		{object.Number(0.5), object.Number(0.5)},
+		{object.True, object.True},
+	}
+
+	for _, pair := range keys {
+		if h.Sum(pair[0]) != h.Sum(pair[1]) {
+			t.Errorf("expected same sums for %v", pair[0])
+		}
+		if h.Sum(pair[0]) == 0 {
+			t.Errorf("unexpected 0 for %v", pair[0])
+		}
+	}
+
+	if h.Sum(object.True) == h.Sum(object.False) {
+		t.Error("expected different sums for booleans")
+	}
+
+	if h.Sum(s1) == hash.New().Sum(s1) {
+		t.Error("expected different seeds")
+	}
+
+	var key object.Value = s1
+	if n := testing.AllocsPerRun(100, func() { h.Sum(key) }); n != 0 {
+		t.Errorf("unexpected %v allocations", n)
+	}
+}
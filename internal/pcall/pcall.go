@@ -0,0 +1,27 @@
+// Package pcall implements pcall of the base library.
+//
+// the runtime recognizes Call, and runs Lua functions called by it
+// in the current execution of the VM, without nesting Go calls.
+package pcall
+
+import (
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/object/fnutil"
+)
+
+// Call is pcall(f [, arg1, ...]) -> ((true, ...) | (false, err)).
+func Call(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+	ap := fnutil.NewArgParser(th, args)
+
+	fn, err := ap.ToFunctionOrNil(0)
+	if err != nil {
+		return nil, err
+	}
+
+	rets, err := th.Call(fn, args[1:]...)
+	if err != nil {
+		return []object.Value{object.False, err.Value()}, nil
+	}
+
+	return append([]object.Value{object.True}, rets...), nil
+}
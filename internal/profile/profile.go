@@ -0,0 +1,248 @@
+// Package profile implements an encoder for the pprof profile format.
+//
+// See https://github.com/google/pprof/blob/master/proto/profile.proto
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+type ValueType struct {
+	Type string
+	Unit string
+}
+
+type Sample struct {
+	Location []*Location // leaf first
+	Value    []int64
+}
+
+type Location struct {
+	ID       uint64
+	Function *Function
+	Line     int64
+}
+
+type Function struct {
+	ID         uint64
+	Name       string
+	SystemName string
+	Filename   string
+	StartLine  int64
+}
+
+type Profile struct {
+	SampleType []ValueType
+	Sample     []*Sample
+	Location   []*Location
+	Function   []*Function
+
+	TimeNanos     int64
+	DurationNanos int64
+	PeriodType    ValueType
+	Period        int64
+}
+
+// Write writes p to w as a gzip compressed protocol buffer.
+func (p *Profile) Write(w io.Writer) error {
+	zw := gzip.NewWriter(w)
+
+	if _, err := zw.Write(p.encode()); err != nil {
+		zw.Close()
+
+		return err
+	}
+
+	return zw.Close()
+}
+
+// field numbers of profile.proto
+const (
+	profileSampleType    = 1
+	profileSample        = 2
+	profileLocation      = 4
+	profileFunction      = 5
+	profileStringTable   = 6
+	profileTimeNanos     = 9
+	profileDurationNanos = 10
+	profilePeriodType    = 11
+	profilePeriod        = 12
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocation = 1
+	sampleValue    = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+func (p *Profile) encode() []byte {
+	b := &buffer{strings: map[string]int{"": 0}, stringTable: []string{""}}
+
+	for _, typ := range p.SampleType {
+		b.encodeValueType(profileSampleType, typ)
+	}
+
+	for _, s := range p.Sample {
+		start := b.startMessage()
+		ids := make([]uint64, len(s.Location))
+		for i, loc := range s.Location {
+			ids[i] = loc.ID
+		}
+		b.uint64s(sampleLocation, ids)
+		b.int64s(sampleValue, s.Value)
+		b.endMessage(profileSample, start)
+	}
+
+	for _, loc := range p.Location {
+		start := b.startMessage()
+		b.uint64Opt(locationID, loc.ID)
+		if loc.Function != nil {
+			line := b.startMessage()
+			b.uint64Opt(lineFunctionID, loc.Function.ID)
+			b.int64Opt(lineLine, loc.Line)
+			b.endMessage(locationLine, line)
+		}
+		b.endMessage(profileLocation, start)
+	}
+
+	for _, fn := range p.Function {
+		start := b.startMessage()
+		b.uint64Opt(functionID, fn.ID)
+		b.int64Opt(functionName, b.stringIndex(fn.Name))
+		b.int64Opt(functionSystemName, b.stringIndex(fn.SystemName))
+		b.int64Opt(functionFilename, b.stringIndex(fn.Filename))
+		b.int64Opt(functionStartLine, fn.StartLine)
+		b.endMessage(profileFunction, start)
+	}
+
+	b.int64Opt(profileTimeNanos, p.TimeNanos)
+	b.int64Opt(profileDurationNanos, p.DurationNanos)
+
+	if p.PeriodType.Type != "" || p.PeriodType.Unit != "" {
+		b.encodeValueType(profilePeriodType, p.PeriodType)
+	}
+
+	b.int64Opt(profilePeriod, p.Period)
+
+	// the string table must be emitted last, since encoding the other messages fills it.
+	for _, s := range b.stringTable {
+		b.string(profileStringTable, s)
+	}
+
+	return b.data
+}
+
+type buffer struct {
+	data []byte
+	tmp  [16]byte
+
+	strings     map[string]int
+	stringTable []string
+}
+
+func (b *buffer) stringIndex(s string) int64 {
+	if i, ok := b.strings[s]; ok {
+		return int64(i)
+	}
+
+	i := len(b.stringTable)
+
+	b.strings[s] = i
+	b.stringTable = append(b.stringTable, s)
+
+	return int64(i)
+}
+
+func (b *buffer) encodeValueType(tag int, typ ValueType) {
+	start := b.startMessage()
+	b.int64Opt(valueTypeType, b.stringIndex(typ.Type))
+	b.int64Opt(valueTypeUnit, b.stringIndex(typ.Unit))
+	b.endMessage(tag, start)
+}
+
+func (b *buffer) varint(x uint64) {
+	for x >= 128 {
+		b.data = append(b.data, byte(x)|0x80)
+		x >>= 7
+	}
+	b.data = append(b.data, byte(x))
+}
+
+func (b *buffer) length(tag, n int) {
+	b.varint(uint64(tag)<<3 | 2)
+	b.varint(uint64(n))
+}
+
+func (b *buffer) uint64Opt(tag int, x uint64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(tag) << 3)
+	b.varint(x)
+}
+
+func (b *buffer) int64Opt(tag int, x int64) {
+	if x == 0 {
+		return
+	}
+	b.varint(uint64(tag) << 3)
+	b.varint(uint64(x))
+}
+
+func (b *buffer) uint64s(tag int, xs []uint64) {
+	if len(xs) == 0 {
+		return
+	}
+
+	// packed encoding
+	n1 := len(b.data)
+	for _, x := range xs {
+		b.varint(x)
+	}
+	n2 := len(b.data)
+	b.length(tag, n2-n1)
+	n3 := len(b.data)
+	copy(b.tmp[:], b.data[n2:n3])
+	copy(b.data[n1+(n3-n2):], b.data[n1:n2])
+	copy(b.data[n1:], b.tmp[:n3-n2])
+}
+
+func (b *buffer) int64s(tag int, xs []int64) {
+	us := make([]uint64, len(xs))
+	for i, x := range xs {
+		us[i] = uint64(x)
+	}
+	b.uint64s(tag, us)
+}
+
+func (b *buffer) string(tag int, s string) {
+	b.length(tag, len(s))
+	b.data = append(b.data, s...)
+}
+
+func (b *buffer) startMessage() int {
+	return len(b.data)
+}
+
+func (b *buffer) endMessage(tag int, start int) {
+	n1 := start
+	n2 := len(b.data)
+	b.length(tag, n2-n1)
+	n3 := len(b.data)
+	copy(b.tmp[:], b.data[n2:n3])
+	copy(b.data[n1+(n3-n2):], b.data[n1:n2])
+	copy(b.data[n1:], b.tmp[:n3-n2])
+}
@@ -3,12 +3,15 @@ package tables
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/hirochachacha/plua/internal/limits"
 	"github.com/hirochachacha/plua/object"
 )
 
 type concurrentTable struct {
+	version uint64 // accessed atomically, must be 64-bit aligned
+
 	a []object.Value
 	m *concurrentMap
 
@@ -48,10 +51,12 @@ func (t *concurrentTable) Get(key object.Value) object.Value {
 
 func (t *concurrentTable) Set(key, val object.Value) {
 	t.set(normKey(key), val)
+	atomic.AddUint64(&t.version, 1)
 }
 
 func (t *concurrentTable) Del(key object.Value) {
 	t.del(normKey(key))
+	atomic.AddUint64(&t.version, 1)
 }
 
 func (t *concurrentTable) Next(key object.Value) (nkey, nval object.Value, ok bool) {
@@ -60,10 +65,12 @@ func (t *concurrentTable) Next(key object.Value) (nkey, nval object.Value, ok bo
 
 func (t *concurrentTable) SetList(base int, src []object.Value) {
 	t.setList(base, src)
+	atomic.AddUint64(&t.version, 1)
 }
 
 func (t *concurrentTable) SetMetatable(mt object.Table) {
 	t.setMetatable(mt)
+	atomic.AddUint64(&t.version, 1)
 }
 
 func (t *concurrentTable) Metatable() object.Table {
@@ -71,6 +78,13 @@ func (t *concurrentTable) Metatable() object.Table {
 	return mt
 }
 
+// Version returns a number which changes whenever the contents
+// or the metatable of t change.
+// it's incremented after mutations, so it must be loaded before reading t.
+func (t *concurrentTable) Version() uint64 {
+	return atomic.LoadUint64(&t.version)
+}
+
 func (t *concurrentTable) ikey(key object.Value) (object.Integer, bool) {
 	if ikey, ok := key.(object.Integer); ok {
 		return ikey, !(int64(ikey) > limits.MaxInt || int64(ikey) < limits.MinInt)
@@ -214,10 +228,21 @@ func (t *concurrentTable) setList(base int, src []object.Value) {
 	t.Lock()
 	defer t.Unlock()
 
+	if base > len(t.a) {
+		// malformed byte code may skip elements, they don't fit in the array part
+		for i, val := range src {
+			if val != nil {
+				t.m.Set(object.Integer(base+i+1), val)
+			}
+		}
+
+		return
+	}
+
 	if len(src) < len(t.a)-base {
 		copy(t.a[base:], src)
 	} else {
-		t.a = append(t.a[base:], src...)
+		t.a = append(t.a[:base], src...)
 	}
 }
 
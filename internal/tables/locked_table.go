@@ -97,3 +97,13 @@ func (t *lockedTable) Metatable() object.Table {
 
 	return mt
 }
+
+func (t *lockedTable) Version() uint64 {
+	t.m.Lock()
+
+	version := t.t.Version()
+
+	t.m.Unlock()
+
+	return version
+}
@@ -75,7 +75,13 @@ func (m *luaMap) Get(key object.Value) object.Value {
 }
 
 func (m *luaMap) Set(key, val object.Value) (elem *bucket) {
-	return m.setBucket(m.sum(key), key, val)
+	return m.setBucket(m.sum(key), key, val, true)
+}
+
+// TrySet is the same as Set, but reports false instead of growing the map,
+// if there is no room for a new key.
+func (m *luaMap) TrySet(key, val object.Value) bool {
+	return m.setBucket(m.sum(key), key, val, false) != nil
 }
 
 func (m *luaMap) Delete(key object.Value) {
@@ -140,23 +146,23 @@ func (m *luaMap) mod(sum uint64) int {
 	return int(sum & uint64(m.Cap()-1))
 }
 
-func (m *luaMap) index(key object.Value) int {
-	return m.mod(m.sum(key))
-}
-
-func (m *luaMap) insertBucket(index int, key object.Value) (elem *bucket) {
+func (m *luaMap) insertBucket(index int, sum uint64, key object.Value, grow bool) (elem *bucket) {
 	elem = &m.buckets[index]
 
 	// collision
 	if elem.isActive {
 		_new := m.findEmptyBucket()
 		if _new == nil {
+			if !grow {
+				return nil
+			}
+
 			m.grow()
 
-			return m.insertBucket(m.index(key), key)
+			return m.insertBucket(m.mod(sum), sum, key, true)
 		}
 
-		other := &m.buckets[m.index(elem.key)]
+		other := &m.buckets[m.mod(elem.sum)]
 		if other != elem {
 			for other.next != elem {
 				other = other.next
@@ -210,11 +216,7 @@ func (m *luaMap) findEmptyBucket() (elem *bucket) {
 	for i := len(m.inactives) - 1; i >= 0; i-- {
 		elem = &m.inactives[i]
 		if !elem.isActive {
-			if i == 0 {
-				m.inactives = m.buckets[:0]
-			} else {
-				m.inactives = m.buckets[:i-1]
-			}
+			m.inactives = m.buckets[:i]
 
 			return
 		}
@@ -223,12 +225,15 @@ func (m *luaMap) findEmptyBucket() (elem *bucket) {
 	return nil
 }
 
-func (m *luaMap) setBucket(sum uint64, key, val object.Value) *bucket {
+func (m *luaMap) setBucket(sum uint64, key, val object.Value, grow bool) *bucket {
 	index := m.mod(sum)
 
 	_, elem := m.findBucket(index, sum, key, nil)
 	if elem == nil {
-		elem = m.insertBucket(index, key)
+		elem = m.insertBucket(index, sum, key, grow)
+		if elem == nil {
+			return nil
+		}
 	}
 
 	elem.val = val
@@ -242,7 +247,7 @@ func (m *luaMap) deleteBucket(elem, prev *bucket) {
 	if prev != nil {
 		prev.next = next
 	} else if next != nil {
-		_next := &m.buckets[m.index(next.key)]
+		_next := &m.buckets[m.mod(next.sum)]
 
 		if _next == elem {
 			elem.key = next.key
@@ -309,7 +314,7 @@ func (m *luaMap) grow() {
 
 	for _, elem := range old {
 		if elem.isActive {
-			m.setBucket(elem.sum, elem.key, elem.val)
+			m.setBucket(elem.sum, elem.key, elem.val, true)
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package tables
 
 import (
+	"fmt"
 	"testing"
 	"testing/quick"
 
@@ -136,3 +137,71 @@ func TestConcurrentMap(t *testing.T) {
 		t.Error(err)
 	}
 }
+
+func benchKeys(n int) (ikeys, skeys []object.Value) {
+	for i := 0; i < n; i++ {
+		ikeys = append(ikeys, object.Integer(i*7919))
+		skeys = append(skeys, object.String(fmt.Sprintf("key%d", i)))
+	}
+	return
+}
+
+func benchmarkMapGet(b *testing.B, keys []object.Value) {
+	m := newMap()
+	for _, key := range keys {
+		m.Set(key, object.True)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if m.Get(keys[i%len(keys)]) == nil {
+			b.Fatal("missing key")
+		}
+	}
+}
+
+func BenchmarkMapGetInteger(b *testing.B) {
+	ikeys, _ := benchKeys(1000)
+	benchmarkMapGet(b, ikeys)
+}
+
+func BenchmarkMapGetString(b *testing.B) {
+	_, skeys := benchKeys(1000)
+	benchmarkMapGet(b, skeys)
+}
+
+func BenchmarkMapSet(b *testing.B) {
+	ikeys, skeys := benchKeys(1000)
+	keys := append(ikeys, skeys...)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		m := newMap()
+		for _, key := range keys {
+			m.Set(key, object.True)
+		}
+	}
+}
+
+func BenchmarkConcurrentMapGetString(b *testing.B) {
+	_, skeys := benchKeys(1000)
+
+	m := newConcurrentMap()
+	for _, key := range skeys {
+		m.Set(key, object.True)
+	}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			if m.Get(skeys[i%len(skeys)]) == nil {
+				b.Fatal("missing key")
+			}
+			i++
+		}
+	})
+}
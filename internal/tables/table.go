@@ -4,26 +4,34 @@ import (
 	"fmt"
 
 	"github.com/hirochachacha/plua/internal/limits"
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/object"
 )
 
+// table is a Lua table, which has an array part and a hash part.
+//
+// integer keys from 1 to len(a) are stored in the array part, which may contain nils.
+// the other keys are stored in the hash part.
+// when the hash part is full, keys are redistributed by rehash, the same as the reference implementation.
 type table struct {
-	a []object.Value
+	a []tvalue.Value
 	m *luaMap
 
 	mt object.Table
+
+	version uint64 // incremented by every mutation, see Version
 }
 
 func NewTableSize(asize, msize int) object.Table {
 	return &table{
-		a: make([]object.Value, 0, asize),
+		a: make([]tvalue.Value, 0, asize),
 		m: newMapSize(msize),
 	}
 }
 
 func NewTableArray(a []object.Value) object.Table {
 	return &table{
-		a: a,
+		a: tvalue.Unbox(make([]tvalue.Value, len(a)), a),
 		m: newMapSize(0),
 	}
 }
@@ -36,8 +44,68 @@ func (t *table) String() string {
 	return fmt.Sprintf("table: %p", t)
 }
 
+// Len returns a border of t, the same as luaH_getn.
 func (t *table) Len() int {
-	return len(t.a)
+	n := len(t.a)
+
+	if t.m.Len() == 0 && (n == 0 || !t.a[n-1].IsNil()) {
+		return n
+	}
+
+	return t.border(n)
+}
+
+func (t *table) border(n int) int {
+	if n > 0 && t.a[n-1].IsNil() {
+		// binary search a border in the array part
+		i, j := 0, n
+		for j-i > 1 {
+			m := (i + j) / 2
+			if t.a[m-1].IsNil() {
+				j = m
+			} else {
+				i = m
+			}
+		}
+		return i
+	}
+
+	if t.m.Len() == 0 {
+		return n
+	}
+
+	return t.unboundSearch(n)
+}
+
+// unboundSearch returns a border after j, where j is 0 or t[j] is not nil.
+func (t *table) unboundSearch(j int) int {
+	i := j
+	j++
+
+	// find i and j such that t[i] is not nil and t[j] is nil
+	for t.m.Get(object.Integer(j)) != nil {
+		i = j
+		if j > int(limits.MaxInt)/2 {
+			// table was built with bad purposes, resort to linear search
+			i = 1
+			for t.iget(object.Integer(i)) != nil {
+				i++
+			}
+			return i - 1
+		}
+		j *= 2
+	}
+
+	for j-i > 1 {
+		m := (i + j) / 2
+		if t.iget(object.Integer(m)) == nil {
+			j = m
+		} else {
+			i = m
+		}
+	}
+
+	return i
 }
 
 func (t *table) Get(key object.Value) object.Value {
@@ -46,16 +114,46 @@ func (t *table) Get(key object.Value) object.Value {
 
 func (t *table) Set(key, val object.Value) {
 	t.set(normKey(key), val)
+	t.version++
 }
 
 func (t *table) Del(key object.Value) {
 	t.del(normKey(key))
+	t.version++
 }
 
 func (t *table) Next(key object.Value) (nkey, nval object.Value, ok bool) {
 	return t.next(normKey(key))
 }
 
+// GetValue is the same as Get, but doesn't box numbers.
+func (t *table) GetValue(key tvalue.Value) tvalue.Value {
+	switch key.Kind {
+	case tvalue.IntKind:
+		if i := key.Integer(); 0 < i && i <= object.Integer(len(t.a)) {
+			return t.a[i-1]
+		}
+	case tvalue.BoxedKind:
+		if _, ok := key.V.(object.String); ok {
+			return tvalue.Of(t.m.Get(key.V))
+		}
+	}
+	return tvalue.Of(t.Get(key.Box()))
+}
+
+// SetValue is the same as Set, but doesn't box numbers.
+func (t *table) SetValue(key, val tvalue.Value) {
+	if key.Kind == tvalue.IntKind && !val.IsNil() {
+		if i := key.Integer(); 0 < i && i <= object.Integer(len(t.a))+1 {
+			t.iset(i, val)
+			t.version++
+
+			return
+		}
+	}
+	t.Set(key.Box(), val.Box())
+}
+
 func (t *table) ikey(key object.Value) (object.Integer, bool) {
 	if ikey, ok := key.(object.Integer); ok {
 		return ikey, !(int64(ikey) > limits.MaxInt || int64(ikey) < limits.MinInt)
@@ -80,7 +178,7 @@ func (t *table) get(key object.Value) object.Value {
 func (t *table) iget(ikey object.Integer) object.Value {
 	i := int(ikey)
 	if 0 < i && i <= len(t.a) {
-		return t.a[i-1]
+		return t.a[i-1].Box()
 	}
 	return t.m.Get(ikey)
 }
@@ -93,13 +191,13 @@ func (t *table) set(key, val object.Value) {
 	}
 
 	if ikey, ok := t.ikey(key); ok {
-		t.iset(ikey, val)
+		t.iset(ikey, tvalue.Of(val))
 	} else {
-		t.m.Set(key, val)
+		t.mset(key, val)
 	}
 }
 
-func (t *table) iset(ikey object.Integer, val object.Value) {
+func (t *table) iset(ikey object.Integer, val tvalue.Value) {
 	i := int(ikey)
 	switch {
 	case 0 < i && i <= len(t.a):
@@ -108,17 +206,36 @@ func (t *table) iset(ikey object.Integer, val object.Value) {
 		t.a = append(t.a, val)
 
 		// migration from map to array
+		if t.m.Len() == 0 {
+			break
+		}
+		t.m.Delete(ikey)
 		for {
 			ikey++
 			val := t.m.Get(ikey)
 			if val == nil {
 				break
 			}
-			t.a = append(t.a, val)
+			t.a = append(t.a, tvalue.Of(val))
 			t.m.Delete(ikey)
 		}
 	default:
-		t.m.Set(ikey, val)
+		t.mset(ikey, val.Box())
+	}
+}
+
+// mset stores key in the hash part, or rehashes t if the hash part is full.
+func (t *table) mset(key, val object.Value) {
+	if t.m.TrySet(key, val) {
+		return
+	}
+
+	t.rehash(key)
+
+	if ikey, ok := key.(object.Integer); ok && 0 < ikey && int64(ikey) <= int64(len(t.a)) {
+		t.a[ikey-1] = tvalue.Of(val)
+	} else {
+		t.m.Set(key, val)
 	}
 }
 
@@ -134,10 +251,11 @@ func (t *table) idel(ikey object.Integer) {
 	i := int(ikey)
 	switch {
 	case 0 < i && i < len(t.a):
-		t.a[i-1] = nil
+		t.a[i-1] = tvalue.Nil
 	case 0 < i && i == len(t.a):
+		t.a[i-1] = tvalue.Nil
 		t.a = t.a[:len(t.a)-1]
-		for len(t.a) > 0 && t.a[len(t.a)-1] == nil {
+		for len(t.a) > 0 && t.a[len(t.a)-1].IsNil() {
 			t.a = t.a[:len(t.a)-1]
 		}
 	case i == len(t.a)+1:
@@ -151,8 +269,8 @@ func (t *table) next(key object.Value) (nkey, nval object.Value, ok bool) {
 	if key == nil {
 		for i := 0; i < len(t.a); i++ {
 			v := t.a[i]
-			if v != nil {
-				return object.Integer(i + 1), v, true
+			if !v.IsNil() {
+				return object.Integer(i + 1), v.Box(), true
 			}
 		}
 		return t.m.Next(nil)
@@ -162,8 +280,8 @@ func (t *table) next(key object.Value) (nkey, nval object.Value, ok bool) {
 		if i := int(ikey); i > 0 {
 			for ; i < len(t.a); i++ {
 				v := t.a[i]
-				if v != nil {
-					return object.Integer(i + 1), t.a[i], true
+				if !v.IsNil() {
+					return object.Integer(i + 1), v.Box(), true
 				}
 			}
 			if i == len(t.a) {
@@ -176,17 +294,209 @@ func (t *table) next(key object.Value) (nkey, nval object.Value, ok bool) {
 }
 
 func (t *table) SetList(base int, src []object.Value) {
+	if base > len(t.a) {
+		// malformed byte code may skip elements
+		for i, val := range src {
+			t.Set(object.Integer(base+i+1), val)
+		}
+
+		return
+	}
+
+	if len(src) < len(t.a)-base {
+		tvalue.Unbox(t.a[base:], src)
+	} else {
+		t.a = t.grow(base + len(src))
+		tvalue.Unbox(t.a[base:], src)
+	}
+
+	t.version++
+}
+
+// SetListValue is the same as SetList, but doesn't box numbers.
+func (t *table) SetListValue(base int, src []tvalue.Value) {
+	if base > len(t.a) {
+		for i, val := range src {
+			t.Set(object.Integer(base+i+1), val.Box())
+		}
+
+		return
+	}
+
 	if len(src) < len(t.a)-base {
 		copy(t.a[base:], src)
 	} else {
 		t.a = append(t.a[:base], src...)
 	}
+
+	t.version++
+}
+
+// grow returns the array part of length n, keeping the first elements.
+func (t *table) grow(n int) []tvalue.Value {
+	if n <= cap(t.a) {
+		return t.a[:n]
+	}
+	a := make([]tvalue.Value, n)
+	copy(a, t.a)
+	return a
 }
 
 func (t *table) SetMetatable(mt object.Table) {
 	t.mt = mt
+	t.version++
 }
 
 func (t *table) Metatable() object.Table {
 	return t.mt
 }
+
+// Version returns a number which changes whenever the contents
+// or the metatable of t change.
+func (t *table) Version() uint64 {
+	return t.version
+}
+
+// maxABits is the log2 of the maximum size of the array part.
+const maxABits = 30
+
+// rehash resizes both parts of t, so that the array part is as large as possible
+// while more than half of it is used. key is the new key to be inserted.
+func (t *table) rehash(key object.Value) {
+	var nums [maxABits + 1]int // nums[i] is the number of keys k, 2^(i-1) < k <= 2^i
+
+	na := t.numusearray(&nums)
+	total := na
+
+	n, ntotal := t.numusehash(&nums)
+	na += n
+	total += ntotal
+
+	if ikey, ok := key.(object.Integer); ok {
+		na += countint(ikey, &nums)
+	}
+	total++
+
+	asize, na := computesizes(&nums, na)
+
+	t.resize(asize, total-na)
+}
+
+// countint counts key in nums, if it can be stored in the array part.
+func countint(key object.Integer, nums *[maxABits + 1]int) int {
+	if 0 < key && key <= 1<<maxABits {
+		nums[ceillog2(int(key))]++
+		return 1
+	}
+	return 0
+}
+
+// ceillog2 returns ceil(log2(x)).
+func ceillog2(x int) int {
+	l := 0
+	for x--; x > 0; x >>= 1 {
+		l++
+	}
+	return l
+}
+
+// numusearray counts keys in the array part.
+func (t *table) numusearray(nums *[maxABits + 1]int) (ause int) {
+	i := 1
+	for lg, ttlg := 0, 1; lg <= maxABits; lg, ttlg = lg+1, ttlg*2 {
+		lim := ttlg
+		if lim > len(t.a) {
+			lim = len(t.a)
+			if i > lim {
+				break
+			}
+		}
+
+		// count keys in (2^(lg-1), 2^lg]
+		lc := 0
+		for ; i <= lim; i++ {
+			if !t.a[i-1].IsNil() {
+				lc++
+			}
+		}
+
+		nums[lg] += lc
+		ause += lc
+	}
+
+	return ause
+}
+
+// numusehash counts keys in the hash part, na is the number of integer keys of them.
+func (t *table) numusehash(nums *[maxABits + 1]int) (na, total int) {
+	for i := range t.m.buckets {
+		elem := &t.m.buckets[i]
+		if elem.isActive {
+			if ikey, ok := elem.key.(object.Integer); ok {
+				na += countint(ikey, nums)
+			}
+			total++
+		}
+	}
+
+	return na, total
+}
+
+// computesizes returns the optimal size of the array part,
+// and the number of integer keys which go to it.
+func computesizes(nums *[maxABits + 1]int, na int) (size, nasize int) {
+	a := 0 // number of keys smaller than 2^i
+	for i, twotoi := 0, 1; i <= maxABits && na > twotoi/2; i, twotoi = i+1, twotoi*2 {
+		if nums[i] > 0 {
+			a += nums[i]
+			if a > twotoi/2 {
+				size = twotoi
+				nasize = a
+			}
+		}
+	}
+
+	return size, nasize
+}
+
+func (t *table) resize(asize, msize int) {
+	olda := t.a
+	oldm := t.m
+
+	if asize <= cap(olda) {
+		t.a = olda[:asize]
+		for i := len(olda); i < asize; i++ {
+			t.a[i] = tvalue.Nil
+		}
+	} else {
+		t.a = make([]tvalue.Value, asize)
+		copy(t.a, olda)
+	}
+
+	t.m = newMapSize(msize)
+	t.m.h = oldm.h // keep sums valid
+
+	// move the rest of the array part to the hash part
+	for i := asize; i < len(olda); i++ {
+		if !olda[i].IsNil() {
+			t.m.Set(object.Integer(i+1), olda[i].Box())
+		}
+		olda[i] = tvalue.Nil
+	}
+
+	for i := range oldm.buckets {
+		elem := &oldm.buckets[i]
+		if elem.isActive {
+			if ikey, ok := elem.key.(object.Integer); ok && 0 < ikey && int64(ikey) <= int64(asize) {
+				t.a[ikey-1] = tvalue.Of(elem.val)
+			} else {
+				t.m.setBucket(elem.sum, elem.key, elem.val, true)
+			}
+		}
+	}
+
+	// trailing nils aren't needed, then Len doesn't have to search a border
+	for len(t.a) > 0 && t.a[len(t.a)-1].IsNil() {
+		t.a = t.a[:len(t.a)-1]
+	}
+}
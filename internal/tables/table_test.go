@@ -2,6 +2,7 @@ package tables
 
 import (
 	"testing"
+	"testing/quick"
 
 	"github.com/hirochachacha/plua/object"
 )
@@ -117,3 +118,196 @@ func TestConcurrentTableArray(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestVersion(t *testing.T) {
+	tabs := []object.Table{
+		NewTableSize(0, 0),
+		NewConcurrentTableSize(0, 0),
+		NewLockedTableSize(0, 0),
+	}
+
+	for _, tab := range tabs {
+		vt := tab.(interface {
+			Version() uint64
+		})
+
+		mutations := []func(){
+			func() { tab.Set(object.String("x"), object.Integer(1)) },
+			func() { tab.Set(object.String("x"), object.Integer(2)) },
+			func() { tab.Set(object.Integer(1), object.True) },
+			func() { tab.Del(object.String("x")) },
+			func() { tab.SetList(0, []object.Value{object.False}) },
+			func() { tab.SetMetatable(NewTableSize(0, 0)) },
+		}
+
+		for i, mutate := range mutations {
+			v := vt.Version()
+			mutate()
+			if vt.Version() == v {
+				t.Errorf("%T: %d: expected a new version", tab, i)
+			}
+		}
+
+		v := vt.Version()
+		tab.Get(object.String("x"))
+		tab.Next(nil)
+		if vt.Version() != v {
+			t.Errorf("%T: unexpected new version", tab)
+		}
+	}
+}
+
+func TestTableRehash(t *testing.T) {
+	// ops are pairs of keys, and whether to delete them
+	f := func(keys []uint8, dels []bool) bool {
+		tab := NewTableSize(0, 0).(*table)
+		mm := make(map[object.Integer]bool)
+
+		for i, k := range keys {
+			key := object.Integer(k%64) + 1
+			if i < len(dels) && dels[i] {
+				tab.Set(key, nil)
+				delete(mm, key)
+			} else {
+				tab.Set(key, object.True)
+				tab.Set(object.String(string('a'+k%8)), object.False)
+				mm[key] = true
+			}
+		}
+
+		for k := object.Integer(-1); k <= 66; k++ {
+			if (tab.Get(k) != nil) != mm[k] {
+				return false
+			}
+		}
+
+		n := 0
+		for key, _, _ := tab.Next(nil); key != nil; key, _, _ = tab.Next(key) {
+			if ikey, ok := key.(object.Integer); ok {
+				if !mm[ikey] {
+					return false
+				}
+				n++
+			}
+		}
+		if n != len(mm) {
+			return false
+		}
+
+		// Len must be a border
+		l := object.Integer(tab.Len())
+		if l > 0 && !mm[l] || mm[l+1] {
+			return false
+		}
+
+		// integer keys in the array part are never in the hash part
+		for i := range tab.a {
+			if tab.m.Get(object.Integer(i+1)) != nil {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+
+	tab := NewTableSize(0, 0).(*table)
+	for i := 100; i >= 1; i-- {
+		tab.Set(object.Integer(i), object.True)
+	}
+	if len(tab.a) != 100 || tab.m.Len() != 0 || tab.Len() != 100 {
+		t.Errorf("expected all keys in the array part, got %d, %d", len(tab.a), tab.m.Len())
+	}
+
+	// key 9 stays in the hash part, since the array part is half empty
+	tab = NewTableSize(0, 0).(*table)
+	for _, i := range []int{1, 2, 3, 4, 5, 7, 8, 9} {
+		tab.Set(object.Integer(i), object.True)
+	}
+	tab.rehash(object.String("x"))
+	if len(tab.a) != 8 || tab.m.Get(object.Integer(9)) == nil {
+		t.Fatalf("unexpected sizes %d, %d", len(tab.a), tab.m.Len())
+	}
+	tab.Set(object.Integer(9), object.False)
+	tab.Set(object.Integer(9), nil)
+	if tab.Get(object.Integer(9)) != nil {
+		t.Error("expected nil")
+	}
+}
+
+const benchTableSize = 1000
+
+// benchmarkTableFill fills a table, then traverses it like ipairs.
+func benchmarkTableFill(b *testing.B, fill func(t object.Table)) {
+	for i := 0; i < b.N; i++ {
+		tab := NewTableSize(0, 0)
+
+		fill(tab)
+
+		n := tab.Len()
+		if n > 0 && tab.Get(object.Integer(n)) == nil || tab.Get(object.Integer(n+1)) != nil {
+			b.Fatalf("%d is not a border", n)
+		}
+
+		for j := 1; tab.Get(object.Integer(j)) != nil; j++ {
+		}
+	}
+}
+
+func BenchmarkTableFillAppend(b *testing.B) {
+	benchmarkTableFill(b, func(t object.Table) {
+		for i := 1; i <= benchTableSize; i++ {
+			t.Set(object.Integer(i), object.True)
+		}
+	})
+}
+
+func BenchmarkTableFillReverse(b *testing.B) {
+	benchmarkTableFill(b, func(t object.Table) {
+		for i := benchTableSize; i >= 1; i-- {
+			t.Set(object.Integer(i), object.True)
+		}
+	})
+}
+
+func BenchmarkTableFillSparse(b *testing.B) {
+	benchmarkTableFill(b, func(t object.Table) {
+		for i := 1; i <= benchTableSize; i += 2 {
+			t.Set(object.Integer(i), object.True)
+		}
+		for i := 2; i <= benchTableSize; i += 2 {
+			t.Set(object.Integer(i), object.True)
+		}
+	})
+}
+
+func BenchmarkTableFillAfterHoles(b *testing.B) {
+	benchmarkTableFill(b, func(t object.Table) {
+		for i := 1; i <= benchTableSize; i++ {
+			if i%3 != 0 {
+				t.Set(object.Integer(i), object.True)
+			}
+		}
+		for i := 0; i < benchTableSize/3; i++ {
+			t.Set(object.Integer(t.Len()+1), object.True)
+		}
+	})
+}
+
+func BenchmarkTableLen(b *testing.B) {
+	tab := NewTableSize(0, 0)
+	for i := benchTableSize; i >= 1; i-- {
+		tab.Set(object.Integer(i), object.True)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if tab.Len() != benchTableSize {
+			b.Fatal("unexpected length")
+		}
+	}
+}
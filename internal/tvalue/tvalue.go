@@ -0,0 +1,147 @@
+// Package tvalue implements tagged values, an unboxed representation of object.Value.
+//
+// Storing an object.Integer or an object.Number into an interface allocates,
+// so registers of the VM and array parts of tables hold tagged values instead.
+// Values are boxed only when they are passed to the public API.
+package tvalue
+
+import (
+	"math"
+
+	"github.com/hirochachacha/plua/object"
+)
+
+type Kind uint8
+
+const (
+	BoxedKind Kind = iota // V holds the value, or nil
+	IntKind               // N holds the bits of an object.Integer
+	FloatKind             // N holds the bits of an object.Number
+	BoolKind              // N holds 1 for true, or 0 for false
+)
+
+type Value struct {
+	V    object.Value
+	N    uint64
+	Kind Kind
+}
+
+var Nil Value
+
+func Of(v object.Value) Value {
+	switch v := v.(type) {
+	case object.Integer:
+		return Integer(v)
+	case object.Number:
+		return Number(v)
+	case object.Boolean:
+		return Boolean(bool(v))
+	}
+	return Value{V: v}
+}
+
+func Integer(i object.Integer) Value {
+	return Value{N: uint64(i), Kind: IntKind}
+}
+
+func Number(n object.Number) Value {
+	return Value{N: math.Float64bits(float64(n)), Kind: FloatKind}
+}
+
+func Boolean(b bool) Value {
+	if b {
+		return Value{N: 1, Kind: BoolKind}
+	}
+	return Value{Kind: BoolKind}
+}
+
+// Box returns x as an object.Value.
+func (x Value) Box() object.Value {
+	switch x.Kind {
+	case IntKind:
+		return object.Integer(x.N)
+	case FloatKind:
+		return object.Number(math.Float64frombits(x.N))
+	case BoolKind:
+		if x.N != 0 {
+			return object.True
+		}
+		return object.False
+	}
+	return x.V
+}
+
+func (x Value) IsNil() bool {
+	return x.Kind == BoxedKind && x.V == nil
+}
+
+// Integer returns the integer of x, x.Kind must be IntKind.
+func (x Value) Integer() object.Integer {
+	return object.Integer(x.N)
+}
+
+// Number returns the number of x, x.Kind must be FloatKind.
+func (x Value) Number() object.Number {
+	return object.Number(math.Float64frombits(x.N))
+}
+
+// ToNumber is the same as object.ToNumber, except that strings aren't converted.
+func (x Value) ToNumber() (object.Number, bool) {
+	switch x.Kind {
+	case IntKind:
+		return object.Number(object.Integer(x.N)), true
+	case FloatKind:
+		return x.Number(), true
+	}
+	return 0, false
+}
+
+// ToBoolean returns the truth of x, the same as object.ToGoBool.
+func (x Value) ToBoolean() bool {
+	switch x.Kind {
+	case BoxedKind:
+		return object.ToGoBool(x.V)
+	case BoolKind:
+		return x.N != 0
+	}
+	return true
+}
+
+// Equal is the same as object.Equal.
+func Equal(x, y Value) bool {
+	switch {
+	case x.Kind == BoxedKind && y.Kind == BoxedKind:
+		return object.Equal(x.V, y.V)
+	case x.Kind == y.Kind:
+		if x.Kind == FloatKind {
+			return x.Number() == y.Number()
+		}
+		return x.N == y.N
+	case x.Kind == IntKind && y.Kind == FloatKind:
+		return eqIntNum(x.Integer(), y.Number())
+	case x.Kind == FloatKind && y.Kind == IntKind:
+		return eqIntNum(y.Integer(), x.Number())
+	}
+	return false
+}
+
+func eqIntNum(x object.Integer, y object.Number) bool {
+	ix := object.Integer(y)
+	return x == ix && y == object.Number(ix)
+}
+
+// Box stores boxed values of src to dst, and returns dst.
+func Box(dst []object.Value, src []Value) []object.Value {
+	for i, v := range src {
+		dst[i] = v.Box()
+	}
+	return dst[:len(src)]
+}
+
+// Unbox stores tagged values of src to dst, and returns dst.
+func Unbox(dst []Value, src []object.Value) []Value {
+	for i, v := range src {
+		dst[i] = Of(v)
+	}
+	return dst[:len(src)]
+}
@@ -0,0 +1,66 @@
+package tvalue_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+)
+
+func TestBox(t *testing.T) {
+	vals := []object.Value{
+		nil,
+		object.Integer(-3),
+		object.Number(0.5),
+		object.True,
+		object.False,
+		object.String("x"),
+	}
+
+	for _, val := range vals {
+		if got := tvalue.Of(val).Box(); got != val {
+			t.Errorf("expected %v, got %v", val, got)
+		}
+	}
+
+	if !tvalue.Nil.IsNil() || tvalue.Of(object.False).IsNil() {
+		t.Error("unexpected IsNil")
+	}
+
+	if tvalue.Of(object.False).ToBoolean() || !tvalue.Of(object.Integer(0)).ToBoolean() {
+		t.Error("unexpected ToBoolean")
+	}
+
+	if n := testing.AllocsPerRun(100, func() { tvalue.Integer(tvalue.Integer(1).Integer() + 1) }); n != 0 {
+		t.Errorf("unexpected %v allocations", n)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	nan := object.Number(math.NaN())
+
+	tests := []struct {
+		x, y object.Value
+	}{
+		{nil, nil},
+		{object.Integer(1), object.Integer(1)},
+		{object.Integer(1), object.Number(1)},
+		{object.Number(1), object.Integer(1)},
+		{object.Integer(1), object.Number(1.5)},
+		{object.Integer(math.MaxInt64), object.Number(math.MaxInt64)},
+		{object.Number(-0.0), object.Number(0)},
+		{nan, nan},
+		{object.True, object.True},
+		{object.True, object.Integer(1)},
+		{object.String("a"), object.String("a")},
+		{object.String("1"), object.Integer(1)},
+	}
+
+	for _, test := range tests {
+		want := object.Equal(test.x, test.y)
+		if got := tvalue.Equal(tvalue.Of(test.x), tvalue.Of(test.y)); got != want {
+			t.Errorf("Equal(%v, %v): expected %v, got %v", test.x, test.y, want, got)
+		}
+	}
+}
@@ -6,6 +6,13 @@ const (
 	LUAC_VERSION = 0x53
 	LUAC_FORMAT  = 0
 
+	// format of chunks with plua extensions, which the reference implementation can't load
+	PLUAC_FORMAT = 'P'
+
+	// version of chunks saved by compiler/cache.
+	// bump it whenever the output of codegen changes, so that chunks compiled by older builds aren't loaded.
+	PLUA_CACHE_FORMAT = 1
+
 	LUAC_DATA = "\x19\x93\r\n\x1a\n"
 	LUAC_INT  = 0x5678
 	LUAC_NUM  = 370.5
@@ -25,6 +32,7 @@ const (
 
 	MAX_TAG_LOOP     = 2000
 	MAX_VM_RECURSION = 200
+	MAX_STACK_SIZE   = 1000000
 
 	MAXUPVAL = 255
 	MAXVAR   = 200
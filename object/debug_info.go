@@ -6,6 +6,7 @@ type DebugInfo struct {
 	What            string
 	Source          string
 	CurrentLine     int
+	CurrentColumn   int
 	LineDefined     int
 	LastLineDefined int
 	NUpvalues       int
@@ -5,6 +5,7 @@ import "fmt"
 type StackTrace struct {
 	Source     string
 	Line       int
+	Column     int
 	Signature  string
 	IsTailCall bool
 }
@@ -19,13 +20,21 @@ func NewRuntimeError(msg string) *RuntimeError {
 	return &RuntimeError{RawValue: String(msg), Level: 1}
 }
 
+// Where returns the position prefixed to error messages, such as "chunk:1:5: ".
+func (st *StackTrace) Where() string {
+	if st.Source == "[Go]" {
+		return ""
+	}
+	if st.Column > 0 {
+		return fmt.Sprintf("%s:%d:%d: ", st.Source, st.Line, st.Column)
+	}
+	return fmt.Sprintf("%s:%d: ", st.Source, st.Line)
+}
+
 func (err *RuntimeError) Value() Value {
 	if msg, ok := err.RawValue.(String); ok {
 		if 0 < err.Level && err.Level < len(err.Traceback) {
-			tb := err.Traceback[err.Level]
-			if tb.Source != "[Go]" {
-				return String(fmt.Sprintf("%s:%d: %s", tb.Source, tb.Line, msg))
-			}
+			return String(err.Traceback[err.Level].Where()) + msg
 		}
 	}
 	return err.RawValue
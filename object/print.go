@@ -3,10 +3,11 @@ package object
 import (
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strings"
 
 	"github.com/hirochachacha/plua/internal/strconv"
-	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/opcode"
 )
 
@@ -71,6 +72,10 @@ func printStackTrace(w io.Writer, st *StackTrace) {
 	if st.Line > 0 {
 		fmt.Fprint(w, st.Line)
 		fmt.Fprint(w, ":")
+		if st.Column > 0 {
+			fmt.Fprint(w, st.Column)
+			fmt.Fprint(w, ":")
+		}
 		write = true
 	}
 
@@ -90,6 +95,8 @@ func PrintProto(p *Proto) error {
 	return FprintProto(os.Stdout, p)
 }
 
+// FprintProto prints p in the assembly syntax of compiler/asm.
+// The output can be assembled into an equivalent prototype.
 func FprintProto(w io.Writer, p *Proto) error {
 	pr := &printer{w: w}
 	pr.printFunc(p)
@@ -130,19 +137,6 @@ func (pr *printer) printFunc(p *Proto) {
 }
 
 func (pr *printer) printHeader(p *Proto) {
-	s := "=?"
-	if len(p.Source) != 0 {
-		s = string(p.Source)
-	}
-
-	if s[0] == '@' || s[0] == '=' {
-		s = s[1:]
-	} else if s[:len(version.LUA_SIGNATURE)] == version.LUA_SIGNATURE {
-		s = "(bstring)"
-	} else {
-		s = "(string)"
-	}
-
 	var typ string
 	if p.LineDefined == 0 {
 		typ = "main"
@@ -151,8 +145,8 @@ func (pr *printer) printHeader(p *Proto) {
 	}
 
 	pr.printf(
-		"\n%s <%s:%d,%d> (%d instructions at %p)\n",
-		typ, s, p.LineDefined, p.LastLineDefined, len(p.Code), p,
+		"\n%s <%s:%d,%d> (%d instructions)\n",
+		typ, strconv.Quote(p.Source), p.LineDefined, p.LastLineDefined, len(p.Code),
 	)
 
 	var vararg string
@@ -172,13 +166,24 @@ func (pr *printer) printHeader(p *Proto) {
 }
 
 func (pr *printer) printValue(val Value) {
-	if val, ok := val.(String); ok {
+	switch val := val.(type) {
+	case String:
 		pr.print(strconv.Quote(string(val)))
-
-		return
+	case Number:
+		pr.print(formatNumber(val))
+	default:
+		pr.print(val)
 	}
+}
 
-	pr.print(val)
+// printName prints name of local variables and upvalues,
+// name is quoted unless it is an identifier.
+func (pr *printer) printName(name string) {
+	if isName(name) {
+		pr.print(name)
+	} else {
+		pr.print(strconv.Quote(name))
+	}
 }
 
 func (pr *printer) printCode(p *Proto) {
@@ -192,7 +197,9 @@ func (pr *printer) printCode(p *Proto) {
 
 		pr.printf("\t%d\t", pc+1)
 
-		if p.LineInfo != nil {
+		if pc < len(p.ColumnInfo) {
+			pr.printf("[%d:%d]\t", p.LineInfo[pc], p.ColumnInfo[pc])
+		} else if pc < len(p.LineInfo) {
 			pr.printf("[%d]\t", p.LineInfo[pc])
 		} else {
 			pr.printf("[-]\t")
@@ -286,11 +293,14 @@ func (pr *printer) printCode(p *Proto) {
 		case opcode.JMP, opcode.FORLOOP, opcode.FORPREP, opcode.TFORLOOP:
 			pr.printf("\t; to %d", sbx+pc+2)
 		case opcode.CLOSURE:
-			pr.printf("\t; %p", p.Protos[bx])
+			if bx < len(p.Protos) {
+				pr.printf("\t; function <%d,%d>", p.Protos[bx].LineDefined, p.Protos[bx].LastLineDefined)
+			}
 		case opcode.SETLIST:
 			if c == 0 {
-				pc++
-				pr.printf("\t; %d", p.Code[pc])
+				if pc+1 < len(p.Code) {
+					pr.printf("\t; %d", p.Code[pc+1].Ax())
+				}
 			} else {
 				pr.printf("\t; %d", c)
 			}
@@ -301,7 +311,7 @@ func (pr *printer) printCode(p *Proto) {
 }
 
 func (pr *printer) printConstants(p *Proto) {
-	pr.printf("constants (%d) for %p: \n", len(p.Constants), p)
+	pr.printf("constants (%d):\n", len(p.Constants))
 	for i, c := range p.Constants {
 		pr.printf("\t%d\t", i+1)
 		pr.printValue(c)
@@ -310,16 +320,20 @@ func (pr *printer) printConstants(p *Proto) {
 }
 
 func (pr *printer) printLocals(p *Proto) {
-	pr.printf("locals (%d) for %p: \n", len(p.LocVars), p)
+	pr.printf("locals (%d):\n", len(p.LocVars))
 	for i, locvar := range p.LocVars {
-		pr.printf("\t%d\t%s\t%d\t%d\n", i, locvar.Name, locvar.StartPC, locvar.EndPC)
+		pr.printf("\t%d\t", i)
+		pr.printName(locvar.Name)
+		pr.printf("\t%d\t%d\n", locvar.StartPC, locvar.EndPC)
 	}
 }
 
 func (pr *printer) printUpvalues(p *Proto) {
-	pr.printf("upvalues (%d) for %p: \n", len(p.Upvalues), p)
+	pr.printf("upvalues (%d):\n", len(p.Upvalues))
 	for i, upval := range p.Upvalues {
-		pr.printf("\t%d\t%s\t%t\t%d\n", i, upval.Name, upval.Instack, upval.Index)
+		pr.printf("\t%d\t", i)
+		pr.printName(upval.Name)
+		pr.printf("\t%t\t%d\n", upval.Instack, upval.Index)
 	}
 }
 
@@ -329,6 +343,36 @@ func (pr *printer) printProtos(p *Proto) {
 	}
 }
 
+// formatNumber returns the shortest representation of n,
+// which is distinguishable from integers.
+func formatNumber(n Number) string {
+	if f := float64(n); f != f {
+		if math.Signbit(f) {
+			return "-nan"
+		}
+		return "nan"
+	}
+
+	s := strconv.FormatFloat(float64(n), 'g', -1, 64)
+	if !strings.ContainsAny(s, ".ein") {
+		s += ".0"
+	}
+	return s
+}
+
+func isName(s string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || i > 0 && '0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
 func upvalName(p *Proto, r int) (name string) {
 	name = string(p.Upvalues[r].Name)
 	if len(name) == 0 {
@@ -17,6 +17,7 @@ type Proto struct {
 	IsVararg        bool
 	MaxStackSize    int
 	LineInfo        []int
+	ColumnInfo      []int // optional
 	LocVars         []LocVar
 }
 
@@ -1,16 +1,44 @@
 package plua_test
 
 import (
+	"io/ioutil"
 	"os"
 	"testing"
 
 	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/cache"
+	"github.com/hirochachacha/plua/compiler/codegen"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/runtime"
 	"github.com/hirochachacha/plua/stdlib"
 )
 
 func TestLuaTest(t *testing.T) {
+	testLuaTest(t, runtime.CompileOptions{}, runtime.Interpreter)
+}
+
+func TestLuaTestOptimize(t *testing.T) {
+	testLuaTest(t, runtime.CompileOptions{Mode: codegen.Optimize}, runtime.Interpreter)
+}
+
+func TestLuaTestClosureCompiler(t *testing.T) {
+	testLuaTest(t, runtime.CompileOptions{}, runtime.ClosureCompiler)
+}
+
+func TestLuaTestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plua")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the first run saves compiled chunks, and the second one loads them.
+	for i := 0; i < 2; i++ {
+		testLuaTest(t, runtime.CompileOptions{Cache: cache.New(dir)}, runtime.Interpreter)
+	}
+}
+
+func testLuaTest(t *testing.T, opts runtime.CompileOptions, backend runtime.Backend) {
 	wd, err := os.Getwd()
 	if err != nil {
 		t.Fatal(err)
@@ -29,6 +57,8 @@ func TestLuaTest(t *testing.T) {
 
 	c := compiler.NewCompiler()
 
+	c.Mode = opts.Mode
+
 	proto, err := c.CompileFile("all.lua", 0)
 	if err != nil {
 		t.Fatal(err)
@@ -36,6 +66,14 @@ func TestLuaTest(t *testing.T) {
 
 	p := runtime.NewProcess()
 
+	if err := runtime.SetBackend(p, backend); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runtime.SetCompileOptions(p, opts); err != nil {
+		t.Fatal(err)
+	}
+
 	p.Require("", stdlib.Open)
 
 	p.Globals().Set(object.String("_U"), object.True) // set user test flag
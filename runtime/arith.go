@@ -0,0 +1,223 @@
+package runtime
+
+import (
+	"math"
+
+	"github.com/hirochachacha/plua/internal/arith"
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+var arithTags = [...]object.Value{
+	opcode.ADD:  object.TM_ADD,
+	opcode.SUB:  object.TM_SUB,
+	opcode.MUL:  object.TM_MUL,
+	opcode.MOD:  object.TM_MOD,
+	opcode.POW:  object.TM_POW,
+	opcode.DIV:  object.TM_DIV,
+	opcode.IDIV: object.TM_IDIV,
+	opcode.BAND: object.TM_BAND,
+	opcode.BOR:  object.TM_BOR,
+	opcode.BXOR: object.TM_BXOR,
+	opcode.SHL:  object.TM_SHL,
+	opcode.SHR:  object.TM_SHR,
+}
+
+// arith stores "x op y" to R(a).
+func (th *thread) arith(op opcode.OpCode, a int, x, y tvalue.Value) *object.RuntimeError {
+	if val, ok := fastArith(op, x, y); ok {
+		th.setR(a, val)
+
+		return nil
+	}
+
+	bx, by := x.Box(), y.Box()
+
+	val, tm, err := arith.ArithTM(th, arithTags[op], bx, by)
+	if err != nil {
+		return err
+	}
+
+	if tm != nil {
+		var done bool
+
+		val, done, err = th.calltm(tm, bx, by)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	th.setR(a, tvalue.Of(val))
+
+	return nil
+}
+
+// unary stores the result of an unary operation of tag to R(a).
+func (th *thread) unary(tag object.Value, a int, x tvalue.Value) *object.RuntimeError {
+	bx := x.Box()
+
+	val, tm, err := arith.UnaryTM(th, tag, bx)
+	if err != nil {
+		return err
+	}
+
+	if tm != nil {
+		var done bool
+
+		val, done, err = th.calltm(tm, bx)
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	th.setR(a, tvalue.Of(val))
+
+	return nil
+}
+
+// compare reports whether "x op y" != not, op is EQ, LT or LE.
+// if a metamethod is called in a new frame, the result is meaningless,
+// and finishOp completes the instruction.
+func (th *thread) compare(op opcode.OpCode, not bool, x, y object.Value) (bool, *object.RuntimeError) {
+	var tm object.Value
+	var negate bool
+	var err *object.RuntimeError
+
+	switch op {
+	case opcode.EQ:
+		if object.Equal(x, y) {
+			return true != not, nil
+		}
+
+		tm = arith.EqualTM(x, y)
+		if tm == nil {
+			return false != not, nil
+		}
+	case opcode.LT:
+		if b := arith.LessThan(x, y); b != nil {
+			return b != object.Boolean(not), nil
+		}
+
+		tm, negate, err = arith.OrderTM(th, object.TM_LT, x, y)
+	case opcode.LE:
+		if b := arith.LessThanOrEqualTo(x, y); b != nil {
+			return b != object.Boolean(not), nil
+		}
+
+		tm, negate, err = arith.OrderTM(th, object.TM_LE, x, y)
+	default:
+		panic("unreachable")
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	if negate {
+		x, y = y, x
+	}
+
+	val, done, err := th.calltm(tm, x, y)
+	if err != nil {
+		return false, err
+	}
+
+	if !done {
+		th.ci.negate = negate
+
+		return false, nil
+	}
+
+	return object.ToGoBool(val) != (not != negate), nil
+}
+
+// fastArith performs a binary operation of numbers without boxing.
+// if ok is false, operands need conversions or metamethods.
+func fastArith(op opcode.OpCode, x, y tvalue.Value) (z tvalue.Value, ok bool) {
+	if x.Kind == tvalue.IntKind && y.Kind == tvalue.IntKind {
+		a, b := x.Integer(), y.Integer()
+
+		switch op {
+		case opcode.ADD:
+			return tvalue.Integer(a + b), true
+		case opcode.SUB:
+			return tvalue.Integer(a - b), true
+		case opcode.MUL:
+			return tvalue.Integer(a * b), true
+		case opcode.MOD:
+			if b == 0 {
+				return z, false
+			}
+			return tvalue.Integer(arith.ModInteger(a, b)), true
+		case opcode.IDIV:
+			if b == 0 {
+				return z, false
+			}
+			return tvalue.Integer(arith.IdivInteger(a, b)), true
+		case opcode.BAND:
+			return tvalue.Integer(a & b), true
+		case opcode.BOR:
+			return tvalue.Integer(a | b), true
+		case opcode.BXOR:
+			return tvalue.Integer(a ^ b), true
+		case opcode.SHL:
+			return tvalue.Integer(arith.ShlInteger(a, b)), true
+		case opcode.SHR:
+			return tvalue.Integer(arith.ShrInteger(a, b)), true
+		}
+	}
+
+	a, ok := x.ToNumber()
+	if !ok {
+		return z, false
+	}
+
+	b, ok := y.ToNumber()
+	if !ok {
+		return z, false
+	}
+
+	switch op {
+	case opcode.ADD:
+		return tvalue.Number(a + b), true
+	case opcode.SUB:
+		return tvalue.Number(a - b), true
+	case opcode.MUL:
+		return tvalue.Number(a * b), true
+	case opcode.MOD:
+		return tvalue.Number(arith.ModNumber(a, b)), true
+	case opcode.POW:
+		return tvalue.Number(object.Number(math.Pow(float64(a), float64(b)))), true
+	case opcode.DIV:
+		return tvalue.Number(a / b), true
+	case opcode.IDIV:
+		return tvalue.Number(arith.IdivNumber(a, b)), true
+	}
+
+	// bitwise operations need conversions
+	return z, false
+}
+
+// fastLessThan compares numbers without boxing.
+// if ok is false, operands need metamethods, or they are mixed integer and number.
+func fastLessThan(op opcode.OpCode, x, y tvalue.Value) (b, ok bool) {
+	if x.Kind != y.Kind {
+		return false, false
+	}
+
+	switch x.Kind {
+	case tvalue.IntKind:
+		if op == opcode.LT {
+			return x.Integer() < y.Integer(), true
+		}
+		return x.Integer() <= y.Integer(), true
+	case tvalue.FloatKind:
+		if op == opcode.LT {
+			return x.Number() < y.Number(), true
+		}
+		return x.Number() <= y.Number(), true
+	}
+
+	return false, false
+}
@@ -0,0 +1,95 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler/asm"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/runtime"
+)
+
+// instruction sequences which the code generator doesn't emit
+var testAsm = []struct {
+	Listing string
+	Rets    []object.Value
+}{
+	{
+		`
+		main <"=test_asm":0,0> (3 instructions)
+		0+ params, 2 slots, 0 upvalues, 0 locals, 2 constants, 0 functions
+			1	[1]	LOADKX   	0
+			2	[1]	EXTRAARG 	-2	; "x"
+			3	[1]	RETURN   	0 2
+		constants (2):
+			1	nil
+			2	"x"
+		locals (0):
+		upvalues (0):
+		`,
+		[]object.Value{object.String("x")},
+	},
+	{
+		`
+		main <"=test_asm":0,0> (6 instructions)
+		0+ params, 2 slots, 0 upvalues, 0 locals, 2 constants, 0 functions
+			1	[1]	NEWTABLE 	0 0 0
+			2	[1]	LOADK    	1 -1	; 7
+			3	[1]	SETLIST  	0 1 0
+			4	[1]	EXTRAARG 	-3	; block 2
+			5	[1]	GETTABLE 	1 0 -2	; 51
+			6	[1]	RETURN   	1 2
+		constants (2):
+			1	7
+			2	51
+		locals (0):
+		upvalues (0):
+		`,
+		[]object.Value{object.Integer(7)},
+	},
+	{
+		`
+		main <"=test_asm":0,0> (5 instructions)
+		0+ params, 2 slots, 0 upvalues, 0 locals, 0 constants, 0 functions
+			1	[1]	LOADBOOL 	0 1 1
+			2	[1]	LOADBOOL 	0 0 0	; skipped
+			3	[1]	TESTSET  	1 0 1
+			4	[1]	JMP      	0 0
+			5	[1]	RETURN   	0 3
+		constants (0):
+		locals (0):
+		upvalues (0):
+		`,
+		[]object.Value{object.True, object.True},
+	},
+}
+
+func TestExecAsm(t *testing.T) {
+	for i, test := range testAsm {
+		proto, err := asm.Assemble(strings.NewReader(test.Listing), "=test_asm")
+		if err != nil {
+			t.Fatalf("%d: %v", i+1, err)
+		}
+
+		for _, backend := range backends {
+			p := runtime.NewProcess()
+
+			runtime.SetBackend(p, backend)
+
+			rets, err := p.Exec(proto)
+			if err != nil {
+				t.Fatalf("%d: %v: %v", i+1, backend, err)
+			}
+
+			if len(rets) != len(test.Rets) {
+				t.Errorf("%d: %v: expected %v, got %v", i+1, backend, test.Rets, rets)
+			} else {
+				for j := range rets {
+					if !object.Equal(rets[j], test.Rets[j]) {
+						t.Errorf("%d: %v: expected %v, got %v", i+1, backend, test.Rets[j], rets[j])
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package runtime
+
+import (
+	"errors"
+
+	"github.com/hirochachacha/plua/object"
+)
+
+// Backend is a way to execute Lua functions.
+type Backend int
+
+const (
+	// Interpreter decodes and dispatches each instruction every time.
+	Interpreter Backend = iota
+
+	// ClosureCompiler translates each function into Go closures once, and runs them.
+	// hooks, debug informations and error positions are the same as Interpreter.
+	ClosureCompiler
+)
+
+func (b Backend) String() string {
+	switch b {
+	case Interpreter:
+		return "interpreter"
+	case ClosureCompiler:
+		return "closure"
+	}
+	return "unknown"
+}
+
+// SetBackend sets the backend of p and processes forked from it after that.
+// It must not be called while p is running in another goroutine.
+// Lua functions which are running already keep the previous one, and so do Lua functions called by them,
+// but ones called by Go functions, e.g. comparators of table.sort, use the new one.
+func SetBackend(p object.Process, b Backend) error {
+	th, ok := mainThread(p)
+	if !ok {
+		return errors.New("runtime: unknown process implementation")
+	}
+
+	switch b {
+	case Interpreter, ClosureCompiler:
+	default:
+		return errors.New("runtime: unknown backend")
+	}
+
+	th.settings.backend = b
+
+	return nil
+}
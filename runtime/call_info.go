@@ -1,7 +1,7 @@
 package runtime
 
 import (
-	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/internal/tvalue"
 )
 
 type callInfo struct {
@@ -15,7 +15,23 @@ type callInfo struct {
 
 	isTailCall bool
 
-	varargs []object.Value
+	varargs []tvalue.Value
+
+	icache []inlineCache // inline caches by pc
+
+	ccode []cinst // for ClosureCompiler
+
+	pnode *profNode // for ProfileCount
+
+	cproto  *covProto // for Coverage
+	cbranch int       // pc+1 of the last conditional instruction, if any
+
+	isProtected bool // pcall running a Lua function in the next frame, see pcallLua
+
+	// for metamethods called by the instruction of the previous frame, see finishOp
+	isMeta   bool
+	negate   bool // "x < y" is "not (y <= x)" or vice versa
+	concatAt int  // register of the left operand of __concat
 }
 
 func (ci *callInfo) isGoFunction() bool {
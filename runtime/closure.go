@@ -2,18 +2,20 @@ package runtime
 
 import (
 	"fmt"
+	"sync/atomic"
 	"unsafe"
 
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/object"
 )
 
 type upvalue struct {
 	ctx   *context
 	index int
-	val   object.Value
+	val   tvalue.Value
 }
 
-func (up *upvalue) get() (val object.Value) {
+func (up *upvalue) get() (val tvalue.Value) {
 	if up.index == -1 {
 		val = up.val
 	} else {
@@ -23,7 +25,7 @@ func (up *upvalue) get() (val object.Value) {
 	return val
 }
 
-func (up *upvalue) set(val object.Value) {
+func (up *upvalue) set(val tvalue.Value) {
 	if up.index == -1 {
 		up.val = val
 	} else {
@@ -42,6 +44,8 @@ type closure struct {
 	closures []object.Closure // cache for children
 
 	upvals []*upvalue
+
+	cfunc *compiledFunc // for ClosureCompiler, see compiled
 }
 
 func (cl *closure) Type() object.Type {
@@ -61,7 +65,7 @@ func (cl *closure) GetUpvalue(i int) (val object.Value) {
 		return nil
 	}
 
-	return cl.upvals[i].get()
+	return cl.upvals[i].get().Box()
 }
 
 func (cl *closure) GetUpvalueName(i int) string {
@@ -85,7 +89,7 @@ func (cl *closure) SetUpvalue(i int, val object.Value) {
 		return
 	}
 
-	cl.upvals[i].set(val)
+	cl.upvals[i].set(tvalue.Of(val))
 }
 
 func (cl *closure) NUpvalues() int {
@@ -185,7 +189,7 @@ func (th *thread) newClosure(p *object.Proto) object.Closure {
 	var upvals []*upvalue
 	if len(p.Upvalues) > 0 {
 		upvals = make([]*upvalue, len(p.Upvalues))
-		upvals[0] = &upvalue{index: -1, val: th.env.globals}
+		upvals[0] = &upvalue{index: -1, val: tvalue.Of(th.env.globals)}
 		for i := range upvals[1:] {
 			upvals[1+i] = &upvalue{index: -1}
 		}
@@ -200,6 +204,32 @@ func (th *thread) newClosure(p *object.Proto) object.Closure {
 	return cl
 }
 
+// compiled returns the compiled function of cl.
+// closures made by the interpreter don't have it until they are called by ClosureCompiler.
+func (cl *closure) compiled() *compiledFunc {
+	ptr := (*unsafe.Pointer)(unsafe.Pointer(&cl.cfunc))
+
+	if cf := atomic.LoadPointer(ptr); cf != nil {
+		return (*compiledFunc)(cf)
+	}
+
+	atomic.CompareAndSwapPointer(ptr, nil, unsafe.Pointer(new(compiledFunc)))
+
+	return (*compiledFunc)(atomic.LoadPointer(ptr))
+}
+
+// compiledChild returns the compiled function of the child prototype bx of ci,
+// or nil if it isn't needed by the backend.
+func (th *thread) compiledChild(bx int) *compiledFunc {
+	if th.settings.backend != ClosureCompiler {
+		return nil
+	}
+
+	ci := th.context.ci
+
+	return ci.compiled().child(bx, len(ci.Protos))
+}
+
 func (th *thread) makeClosure(bx int) object.Closure {
 	ctx := th.context
 
@@ -236,6 +266,7 @@ makeNew:
 			Proto:    p,
 			upvals:   make([]*upvalue, len(p.Upvalues)),
 			closures: make([]object.Closure, len(p.Protos)),
+			cfunc:    th.compiledChild(bx),
 		}
 
 		for i, uv := range p.Upvalues {
@@ -257,6 +288,7 @@ makeAnother:
 			Proto:    p,
 			upvals:   make([]*upvalue, len(p.Upvalues)),
 			closures: make([]object.Closure, len(p.Protos)),
+			cfunc:    th.compiledChild(bx),
 		}
 
 		for i, uv := range p.Upvalues {
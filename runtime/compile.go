@@ -0,0 +1,594 @@
+package runtime
+
+import (
+	"sync"
+
+	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// translation of functions into Go closures, for ClosureCompiler.
+//
+// every instruction becomes a closure with pre-decoded operands and constants.
+// the closures are run by executeCompiled one by one,
+// so ci.pc, hooks and errors work as well as the interpreter.
+
+// cop runs an instruction. ci.pc points to the next instruction already.
+type cop func(th *thread, ci *callInfo) *object.RuntimeError
+
+// cinst is a compiled instruction.
+// CALL, TAILCALL and RETURN change the current call info,
+// they are run by executeCompiled with the operands.
+type cinst struct {
+	op      opcode.OpCode
+	a, b, c int
+	fn      cop
+}
+
+// operand is a pre-decoded RK operand.
+type operand struct {
+	isK bool
+	r   int
+	k   tvalue.Value
+}
+
+func (o *operand) get(th *thread, ci *callInfo) tvalue.Value {
+	if o.isK {
+		return o.k
+	}
+	return th.stack[ci.base+o.r]
+}
+
+// compiledFunc is the compiled code of a prototype, shared by closures of it made by ClosureCompiler.
+// it's compiled at the first call, and lives as long as the closures.
+type compiledFunc struct {
+	once sync.Once
+	code []cinst
+
+	mu       sync.Mutex
+	children []*compiledFunc // by index of Protos
+}
+
+func (cf *compiledFunc) get(p *object.Proto) []cinst {
+	cf.once.Do(func() {
+		cf.code = compile(p)
+	})
+
+	return cf.code
+}
+
+func (cf *compiledFunc) child(i, n int) *compiledFunc {
+	cf.mu.Lock()
+
+	if cf.children == nil {
+		cf.children = make([]*compiledFunc, n)
+	}
+
+	child := cf.children[i]
+	if child == nil {
+		child = new(compiledFunc)
+
+		cf.children[i] = child
+	}
+
+	cf.mu.Unlock()
+
+	return child
+}
+
+// compiledCode returns the compiled code of the current function.
+func (th *thread) compiledCode() []cinst {
+	ci := th.context.ci
+
+	if ci.ccode == nil {
+		ci.ccode = ci.compiled().get(ci.Proto)
+	}
+
+	return ci.ccode
+}
+
+func compile(p *object.Proto) []cinst {
+	code := make([]cinst, len(p.Code))
+
+	for pc, inst := range p.Code {
+		code[pc] = compileInst(p, pc, inst)
+	}
+
+	return code
+}
+
+func rk(p *object.Proto, r int) operand {
+	if r&opcode.BitRK != 0 {
+		return operand{isK: true, k: constant(p, r & ^opcode.BitRK)}
+	}
+	return operand{r: r}
+}
+
+func constant(p *object.Proto, k int) tvalue.Value {
+	if k < 0 || k >= len(p.Constants) {
+		return tvalue.Nil
+	}
+	return tvalue.Of(p.Constants[k])
+}
+
+func invalid(th *thread, ci *callInfo) *object.RuntimeError {
+	return errors.InvalidByteCodeError()
+}
+
+// next returns the instruction after pc, and whether its opcode is op.
+func next(p *object.Proto, pc int, op opcode.OpCode) (opcode.Instruction, bool) {
+	if pc+1 >= len(p.Code) || p.Code[pc+1].OpCode() != op {
+		return 0, false
+	}
+	return p.Code[pc+1], true
+}
+
+// jump returns a closure which runs jmp at pc.
+func jump(pc int, jmp opcode.Instruction) func(th *thread, ci *callInfo) {
+	to := pc + 1 + jmp.SBx()
+
+	if a := jmp.A(); a > 0 {
+		return func(th *thread, ci *callInfo) {
+			th.closeUpvals(ci.base + a - 1)
+			ci.pc = to
+		}
+	}
+
+	return func(th *thread, ci *callInfo) {
+		ci.pc = to
+	}
+}
+
+// conditional returns a closure which runs a test instruction at pc,
+// and the following jump.
+func conditional(p *object.Proto, pc int, test func(th *thread, ci *callInfo) (bool, *object.RuntimeError)) cop {
+	jmp, ok := next(p, pc, opcode.JMP)
+	if !ok {
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			skip, err := test(th, ci)
+			if err != nil {
+				return err
+			}
+			if th.ci != ci {
+				return nil // see finishOp
+			}
+			if !skip {
+				return errors.InvalidByteCodeError()
+			}
+			ci.pc++
+			return nil
+		}
+	}
+
+	dojmp := jump(pc+1, jmp)
+
+	return func(th *thread, ci *callInfo) *object.RuntimeError {
+		skip, err := test(th, ci)
+		if err != nil {
+			return err
+		}
+		if th.ci != ci {
+			return nil // see finishOp
+		}
+		if skip {
+			ci.pc++
+		} else {
+			dojmp(th, ci)
+		}
+		return nil
+	}
+}
+
+func compileInst(p *object.Proto, pc int, inst opcode.Instruction) cinst {
+	switch op := inst.OpCode(); op {
+	case opcode.CALL, opcode.TAILCALL, opcode.RETURN:
+		return cinst{op: op, a: inst.A(), b: inst.B(), c: inst.C()}
+	}
+
+	return cinst{op: inst.OpCode(), fn: compileOp(p, pc, inst)}
+}
+
+func compileOp(p *object.Proto, pc int, inst opcode.Instruction) cop {
+	op := inst.OpCode()
+	a := inst.A()
+
+	switch op {
+	case opcode.MOVE:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = th.stack[ci.base+b]
+			return nil
+		}
+	case opcode.LOADK:
+		k := constant(p, inst.Bx())
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = k
+			return nil
+		}
+	case opcode.LOADKX:
+		extra, ok := next(p, pc, opcode.EXTRAARG)
+		if !ok {
+			return invalid
+		}
+
+		k := constant(p, extra.Ax())
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = k
+			ci.pc++
+			return nil
+		}
+	case opcode.LOADBOOL:
+		val := tvalue.Boolean(inst.B() != 0)
+
+		if inst.C() != 0 {
+			return func(th *thread, ci *callInfo) *object.RuntimeError {
+				th.stack[ci.base+a] = val
+				ci.pc++
+				return nil
+			}
+		}
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = val
+			return nil
+		}
+	case opcode.LOADNIL:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			for i := 0; i <= b; i++ {
+				th.stack[ci.base+a+i] = tvalue.Nil
+			}
+			return nil
+		}
+	case opcode.GETUPVAL:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = th.getU(b)
+			return nil
+		}
+	case opcode.GETTABUP:
+		b, c := inst.B(), inst.C()
+		key := rk(p, c)
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.getfield(a, c, th.getU(b), key.get(th, ci))
+		}
+	case opcode.GETTABLE:
+		b, c := inst.B(), inst.C()
+		key := rk(p, c)
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.getfield(a, c, th.stack[ci.base+b], key.get(th, ci))
+		}
+	case opcode.SETTABUP:
+		key, val := rk(p, inst.B()), rk(p, inst.C())
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.settable(th.getU(a), key.get(th, ci), val.get(th, ci))
+		}
+	case opcode.SETUPVAL:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.setU(b, th.stack[ci.base+a])
+			return nil
+		}
+	case opcode.SETTABLE:
+		key, val := rk(p, inst.B()), rk(p, inst.C())
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.settable(th.stack[ci.base+a], key.get(th, ci), val.get(th, ci))
+		}
+	case opcode.NEWTABLE:
+		asize := opcode.LogToInt(inst.B())
+		msize := opcode.LogToInt(inst.C())
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = tvalue.Of(newTableSize(asize, msize))
+			return nil
+		}
+	case opcode.SELF:
+		b, c := inst.B(), inst.C()
+		key := rk(p, c)
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			t := th.stack[ci.base+b]
+			k := key.get(th, ci)
+
+			th.stack[ci.base+a+1] = t
+			return th.getfield(a, c, t, k)
+		}
+	case opcode.ADD, opcode.SUB, opcode.MUL, opcode.MOD, opcode.POW, opcode.DIV, opcode.IDIV,
+		opcode.BAND, opcode.BOR, opcode.BXOR, opcode.SHL, opcode.SHR:
+		return compileArith(op, a, rk(p, inst.B()), rk(p, inst.C()))
+	case opcode.UNM:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			rb := th.stack[ci.base+b]
+
+			switch rb.Kind {
+			case tvalue.IntKind:
+				th.stack[ci.base+a] = tvalue.Integer(-rb.Integer())
+			case tvalue.FloatKind:
+				th.stack[ci.base+a] = tvalue.Number(-rb.Number())
+			default:
+				return th.unary(object.TM_UNM, a, rb)
+			}
+			return nil
+		}
+	case opcode.BNOT:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			rb := th.stack[ci.base+b]
+
+			if rb.Kind == tvalue.IntKind {
+				th.stack[ci.base+a] = tvalue.Integer(^rb.Integer())
+				return nil
+			}
+
+			return th.unary(object.TM_BNOT, a, rb)
+		}
+	case opcode.NOT:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = tvalue.Boolean(!th.stack[ci.base+b].ToBoolean())
+			return nil
+		}
+	case opcode.LEN:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.unary(object.TM_LEN, a, th.stack[ci.base+b])
+		}
+	case opcode.CONCAT:
+		b, c := inst.B(), inst.C()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.concat(a, b, c)
+		}
+	case opcode.JMP:
+		dojmp := jump(pc, inst)
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			dojmp(th, ci)
+			return nil
+		}
+	case opcode.EQ, opcode.LT, opcode.LE:
+		return compileCompare(p, pc, op, a != 0, rk(p, inst.B()), rk(p, inst.C()))
+	case opcode.TEST:
+		c := inst.C() != 0
+
+		return conditional(p, pc, func(th *thread, ci *callInfo) (bool, *object.RuntimeError) {
+			return th.stack[ci.base+a].ToBoolean() != c, nil
+		})
+	case opcode.TESTSET:
+		b := inst.B()
+		c := inst.C() != 0
+
+		return conditional(p, pc, func(th *thread, ci *callInfo) (bool, *object.RuntimeError) {
+			rb := th.stack[ci.base+b]
+			if rb.ToBoolean() != c {
+				return true, nil
+			}
+			th.stack[ci.base+a] = rb
+			return false, nil
+		})
+	case opcode.FORLOOP:
+		to := pc + 1 + inst.SBx()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			ra := &th.stack[ci.base+a]
+			ra1 := th.stack[ci.base+a+1]
+			ra2 := th.stack[ci.base+a+2]
+
+			// forprep already convert val to integer or number.
+			// types are checked only for malformed byte code.
+			if ra.Kind == tvalue.IntKind {
+				if ra1.Kind != tvalue.IntKind || ra2.Kind != tvalue.IntKind {
+					return errors.InvalidByteCodeError()
+				}
+				idx, limit, step := ra.Integer(), ra1.Integer(), ra2.Integer()
+				idx += step
+				if 0 < step && idx <= limit || step <= 0 && idx >= limit {
+					ci.pc = to
+					*ra = tvalue.Integer(idx)
+					th.stack[ci.base+a+3] = *ra
+				}
+				return nil
+			}
+
+			if ra.Kind != tvalue.FloatKind || ra1.Kind != tvalue.FloatKind || ra2.Kind != tvalue.FloatKind {
+				return errors.InvalidByteCodeError()
+			}
+			idx, limit, step := ra.Number(), ra1.Number(), ra2.Number()
+			idx += step
+			if 0 < step && idx <= limit || step <= 0 && idx >= limit {
+				ci.pc = to
+				*ra = tvalue.Number(idx)
+				th.stack[ci.base+a+3] = *ra
+			}
+			return nil
+		}
+	case opcode.FORPREP:
+		to := pc + 1 + inst.SBx()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			if err := th.forprep(a); err != nil {
+				return err
+			}
+			ci.pc = to
+			return nil
+		}
+	case opcode.TFORCALL:
+		c := inst.C()
+
+		if _, ok := next(p, pc, opcode.TFORLOOP); !ok {
+			return invalid
+		}
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.tforcall(a, c)
+		}
+	case opcode.TFORLOOP:
+		to := pc + 1 + inst.SBx()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			if raplus := th.stack[ci.base+a+1]; !raplus.IsNil() {
+				th.stack[ci.base+a] = raplus
+				ci.pc = to
+			}
+			return nil
+		}
+	case opcode.SETLIST:
+		b, c := inst.B(), inst.C()
+
+		if c == 0 {
+			extra, ok := next(p, pc, opcode.EXTRAARG)
+			if !ok {
+				return invalid
+			}
+
+			c = extra.Ax()
+
+			return func(th *thread, ci *callInfo) *object.RuntimeError {
+				ci.pc++
+				return th.setlist(a, b, c)
+			}
+		}
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.setlist(a, b, c)
+		}
+	case opcode.CLOSURE:
+		bx := inst.Bx()
+
+		if len(p.Protos) <= bx {
+			return invalid
+		}
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			th.stack[ci.base+a] = tvalue.Of(th.makeClosure(bx))
+			return nil
+		}
+	case opcode.VARARG:
+		b := inst.B()
+
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			return th.vararg(a, b)
+		}
+	}
+
+	return invalid
+}
+
+func compileArith(op opcode.OpCode, a int, x, y operand) cop {
+	slow := func(th *thread, ci *callInfo, rb, rc tvalue.Value) *object.RuntimeError {
+		return th.arith(op, a, rb, rc)
+	}
+
+	// the most common operations have fast paths of integers and numbers inline
+	switch op {
+	case opcode.ADD:
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+			if rb.Kind == rc.Kind {
+				switch rb.Kind {
+				case tvalue.IntKind:
+					th.stack[ci.base+a] = tvalue.Integer(rb.Integer() + rc.Integer())
+					return nil
+				case tvalue.FloatKind:
+					th.stack[ci.base+a] = tvalue.Number(rb.Number() + rc.Number())
+					return nil
+				}
+			}
+			return slow(th, ci, rb, rc)
+		}
+	case opcode.SUB:
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+			if rb.Kind == rc.Kind {
+				switch rb.Kind {
+				case tvalue.IntKind:
+					th.stack[ci.base+a] = tvalue.Integer(rb.Integer() - rc.Integer())
+					return nil
+				case tvalue.FloatKind:
+					th.stack[ci.base+a] = tvalue.Number(rb.Number() - rc.Number())
+					return nil
+				}
+			}
+			return slow(th, ci, rb, rc)
+		}
+	case opcode.MUL:
+		return func(th *thread, ci *callInfo) *object.RuntimeError {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+			if rb.Kind == rc.Kind {
+				switch rb.Kind {
+				case tvalue.IntKind:
+					th.stack[ci.base+a] = tvalue.Integer(rb.Integer() * rc.Integer())
+					return nil
+				case tvalue.FloatKind:
+					th.stack[ci.base+a] = tvalue.Number(rb.Number() * rc.Number())
+					return nil
+				}
+			}
+			return slow(th, ci, rb, rc)
+		}
+	}
+
+	return func(th *thread, ci *callInfo) *object.RuntimeError {
+		return slow(th, ci, x.get(th, ci), y.get(th, ci))
+	}
+}
+
+func compileCompare(p *object.Proto, pc int, op opcode.OpCode, not bool, x, y operand) cop {
+	var test func(th *thread, ci *callInfo) (bool, *object.RuntimeError)
+
+	switch op {
+	case opcode.EQ:
+		test = func(th *thread, ci *callInfo) (bool, *object.RuntimeError) {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+
+			// numbers and booleans have no metamethods
+			if rb.Kind != tvalue.BoxedKind || rc.Kind != tvalue.BoxedKind {
+				return tvalue.Equal(rb, rc) != not, nil
+			}
+			return th.compare(op, not, rb.V, rc.V)
+		}
+	case opcode.LT:
+		test = func(th *thread, ci *callInfo) (bool, *object.RuntimeError) {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+
+			if rb.Kind == tvalue.IntKind && rc.Kind == tvalue.IntKind {
+				return (rb.Integer() < rc.Integer()) != not, nil
+			}
+			if lt, ok := fastLessThan(op, rb, rc); ok {
+				return lt != not, nil
+			}
+			return th.compare(op, not, rb.Box(), rc.Box())
+		}
+	case opcode.LE:
+		test = func(th *thread, ci *callInfo) (bool, *object.RuntimeError) {
+			rb, rc := x.get(th, ci), y.get(th, ci)
+
+			if rb.Kind == tvalue.IntKind && rc.Kind == tvalue.IntKind {
+				return (rb.Integer() <= rc.Integer()) != not, nil
+			}
+			if le, ok := fastLessThan(op, rb, rc); ok {
+				return le != not, nil
+			}
+			return th.compare(op, not, rb.Box(), rc.Box())
+		}
+	}
+
+	return conditional(p, pc, test)
+}
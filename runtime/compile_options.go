@@ -0,0 +1,39 @@
+package runtime
+
+import (
+	"errors"
+
+	"github.com/hirochachacha/plua/compiler/cache"
+	"github.com/hirochachacha/plua/compiler/codegen"
+	"github.com/hirochachacha/plua/object"
+)
+
+// CompileOptions are options of compilers used by require, load, loadfile and dofile of the standard library.
+type CompileOptions struct {
+	// Mode is the codegen mode of chunks.
+	Mode codegen.Mode
+
+	// Cache caches compiled chunks of files and strings.
+	// nil disables caching.
+	Cache *cache.Cache
+}
+
+// SetCompileOptions sets the compile options of p and processes forked from it after that.
+// It must not be called while p is running in another goroutine.
+func SetCompileOptions(p object.Process, opts CompileOptions) error {
+	th, ok := mainThread(p)
+	if !ok {
+		return errors.New("runtime: unknown process implementation")
+	}
+
+	th.settings.compile = opts
+
+	return nil
+}
+
+// CompileOptions returns the compile options of the process of th, for the standard library.
+func (th *thread) CompileOptions() (codegen.Mode, *cache.Cache) {
+	opts := th.settings.compile
+
+	return opts.Mode, opts.Cache
+}
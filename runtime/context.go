@@ -1,7 +1,7 @@
 package runtime
 
 import (
-	"github.com/hirochachacha/plua/internal/limits"
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/object"
 )
 
@@ -16,7 +16,8 @@ const (
 type context struct {
 	ci      *callInfo
 	ciStack []callInfo
-	stack   []object.Value
+	stack   []tvalue.Value
+	gostack []object.Value // arguments of go functions, see callGo
 
 	uvcache *uvlist
 
@@ -32,19 +33,20 @@ func (ctx *context) isRoot() bool {
 }
 
 func (ctx *context) loadfn(fn object.Value) {
-	ctx.stack[ctx.ci.base-1] = fn
+	ctx.stack[ctx.ci.base-1] = tvalue.Of(fn)
 }
 
 func (ctx *context) fn(ci *callInfo) object.Value {
-	return ctx.stack[ci.base-1]
+	return ctx.stack[ci.base-1].V
 }
 
 func (th *thread) pushContext(stackSize int, newHook bool) {
 	th.depth++
+	th.stackSize += stackSize
 
 	ctx := &context{
 		ciStack: make([]callInfo, 1, 16),
-		stack:   make([]object.Value, stackSize),
+		stack:   make([]tvalue.Value, stackSize),
 	}
 
 	prev := th.context
@@ -54,7 +56,7 @@ func (th *thread) pushContext(stackSize int, newHook bool) {
 	ctx.ci.top = 2
 	ctx.ci.nrets = -1
 	ctx.prev = prev
-	ctx.stack[0] = th.env.globals // _ENV
+	ctx.stack[0] = tvalue.Of(th.env.globals) // _ENV
 	if newHook {
 		ctx.hookState = isHook
 	} else {
@@ -71,6 +73,8 @@ func (th *thread) popContext() *context {
 
 	ctx := th.context
 
+	th.stackSize -= len(ctx.stack)
+
 	th.context = th.context.prev
 
 	return ctx
@@ -86,33 +90,71 @@ func (ctx *context) popFrame() {
 	ctx.ci = &ctx.ciStack[len(ctx.ciStack)-1]
 }
 
-func (ctx *context) growStack(top int) bool {
-	if top > int(limits.MaxInt) {
-		return false
-	}
+// growStack makes the stack of the current context longer than top.
+// it fails if stacks of the thread exceed the limit.
+func (th *thread) growStack(top int) bool {
+	ctx := th.context
 
 	if top < len(ctx.stack) {
 		return true
 	}
 
+	// the limit is shared with stacks of outer contexts
+	max := th.settings.limits.StackSize - (th.stackSize - len(ctx.stack))
+
+	if top > max {
+		return false
+	}
+
 	var newsize int
 
-	if len(ctx.stack) > int(limits.MaxInt)/2 {
-		newsize = int(limits.MaxInt)
+	if len(ctx.stack) > max/2 {
+		newsize = max
 	} else {
-		newsize *= 2
+		newsize = len(ctx.stack) * 2
 		if newsize < top {
 			newsize = top
 		}
 	}
 
-	newstack := make([]object.Value, newsize)
+	th.stackSize += newsize - len(ctx.stack)
+
+	newstack := make([]tvalue.Value, newsize)
 	copy(newstack, ctx.stack)
 	ctx.stack = newstack
 
 	return true
 }
 
-func dup(stack []object.Value) []object.Value {
-	return append([]object.Value(nil), stack...)
+func dup(stack []tvalue.Value) []tvalue.Value {
+	return append([]tvalue.Value(nil), stack...)
+}
+
+func dupValues(args []object.Value) []object.Value {
+	return append([]object.Value(nil), args...)
+}
+
+// box returns boxed values of stack.
+func box(stack []tvalue.Value) []object.Value {
+	return tvalue.Box(make([]object.Value, len(stack)), stack)
+}
+
+// gopush pushes boxed values of stack to gostack, and returns them.
+// they must be popped by gopop, with the length of gostack before pushing.
+func (ctx *context) gopush(stack []tvalue.Value) []object.Value {
+	n := len(ctx.gostack)
+	if n+len(stack) > cap(ctx.gostack) {
+		gostack := make([]object.Value, n, 2*cap(ctx.gostack)+len(stack))
+		copy(gostack, ctx.gostack)
+		ctx.gostack = gostack
+	}
+	ctx.gostack = ctx.gostack[:n+len(stack)]
+	return tvalue.Box(ctx.gostack[n:], stack)
+}
+
+func (ctx *context) gopop(n int) {
+	for i := n; i < len(ctx.gostack); i++ {
+		ctx.gostack[i] = nil
+	}
+	ctx.gostack = ctx.gostack[:n]
 }
@@ -0,0 +1,173 @@
+package runtime
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hirochachacha/plua/cover"
+	"github.com/hirochachacha/plua/internal/util"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// Coverage counts executed lines and branches of Lua functions.
+//
+// A coverage can be attached to multiple processes by Start,
+// and collected counts are returned by Profile.
+type Coverage struct {
+	running int32
+
+	m      sync.Mutex
+	protos map[*object.Proto]*covProto
+}
+
+type covProto struct {
+	cov *Coverage
+
+	counts   []int64    // execution counts by pc
+	branches [][2]int64 // jumps and fall-throughs of conditional instructions by pc
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{protos: make(map[*object.Proto]*covProto)}
+}
+
+func (cov *Coverage) Start(p object.Process) error {
+	th, ok := mainThread(p)
+	if !ok {
+		return errors.New("runtime: unknown process implementation")
+	}
+
+	th.settings.coverage = cov
+
+	atomic.StoreInt32(&cov.running, 1)
+
+	return nil
+}
+
+func (cov *Coverage) Stop() {
+	atomic.StoreInt32(&cov.running, 0)
+}
+
+func (cov *Coverage) isRunning() bool {
+	return atomic.LoadInt32(&cov.running) != 0
+}
+
+func (cov *Coverage) register(p *object.Proto) *covProto {
+	cov.m.Lock()
+	defer cov.m.Unlock()
+
+	if cp, ok := cov.protos[p]; ok {
+		return cp
+	}
+
+	// register nested functions too, so that never called functions are reported.
+	cov.add(p)
+
+	return cov.protos[p]
+}
+
+func (cov *Coverage) add(p *object.Proto) {
+	if _, ok := cov.protos[p]; ok {
+		return
+	}
+
+	cov.protos[p] = &covProto{
+		cov:      cov,
+		counts:   make([]int64, len(p.Code)),
+		branches: make([][2]int64, len(p.Code)),
+	}
+
+	for _, child := range p.Protos {
+		cov.add(child)
+	}
+}
+
+func isConditional(inst opcode.Instruction) bool {
+	switch inst.OpCode() {
+	case opcode.EQ, opcode.LT, opcode.LE, opcode.TEST, opcode.TESTSET:
+		return true
+	}
+	return false
+}
+
+func (th *thread) coverInstruction(cov *Coverage) {
+	ci := th.context.ci
+
+	cp := ci.cproto
+	if cp == nil || cp.cov != cov {
+		cp = cov.register(ci.Proto)
+
+		ci.cproto = cp
+		ci.cbranch = 0
+	}
+
+	// conditional instructions either skip the following JMP or execute it
+	// in place, so the next pc tells the result.
+	if ci.cbranch != 0 {
+		pc := ci.cbranch - 1
+		if ci.pc == pc+2 {
+			atomic.AddInt64(&cp.branches[pc][1], 1)
+		} else {
+			atomic.AddInt64(&cp.branches[pc][0], 1)
+		}
+
+		ci.cbranch = 0
+	}
+
+	atomic.AddInt64(&cp.counts[ci.pc], 1)
+
+	if isConditional(ci.Code[ci.pc]) {
+		ci.cbranch = ci.pc + 1
+	}
+}
+
+// Profile returns a snapshot of collected counts.
+func (cov *Coverage) Profile() *cover.Profile {
+	cov.m.Lock()
+	defer cov.m.Unlock()
+
+	prof := cover.NewProfile()
+
+	for p, cp := range cov.protos {
+		if len(p.LineInfo) != len(p.Code) {
+			continue // stripped
+		}
+
+		f := prof.File(sourceName(p.Source))
+
+		end := len(p.Code)
+
+		// skip implicit return, it is unreachable in most cases.
+		if end > 0 {
+			if last := p.Code[end-1]; last.OpCode() == opcode.RETURN && last.B() == 1 {
+				end--
+			}
+		}
+
+		for pc, line := range p.LineInfo[:end] {
+			count := atomic.LoadInt64(&cp.counts[pc])
+			if c, ok := f.Lines[line]; !ok || count > c {
+				f.Lines[line] = count
+			}
+
+			if isConditional(p.Code[pc]) {
+				b := f.Branch(line, pc)
+				b.Taken[0] += atomic.LoadInt64(&cp.branches[pc][0])
+				b.Taken[1] += atomic.LoadInt64(&cp.branches[pc][1])
+			}
+		}
+	}
+
+	return prof
+}
+
+// sourceName returns a file name of the chunk name if possible.
+func sourceName(source string) string {
+	if strings.HasPrefix(source, "@") {
+		return source[1:]
+	}
+	return util.Shorten(source)
+}
@@ -0,0 +1,72 @@
+package runtime_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib"
+)
+
+var testCoverageCode = `local function f(x)
+  if x > 3 then
+    return "big"
+  end
+  return "small"
+end
+
+local function never()
+  return 1
+end
+
+for i = 1, 5 do f(i) end
+`
+
+func TestCoverage(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	proto, err := c.Compile(strings.NewReader(testCoverageCode), "@cov.lua", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, backend := range backends {
+		p := runtime.NewProcess()
+
+		p.Require("", stdlib.Open)
+
+		runtime.SetBackend(p, backend)
+
+		cov := runtime.NewCoverage()
+
+		if err := cov.Start(p); err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = p.Exec(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cov.Stop()
+
+		f := cov.Profile().Files["cov.lua"]
+		if f == nil {
+			t.Fatal("expected coverage of cov.lua")
+		}
+
+		lines := map[int]int64{2: 5, 3: 2, 5: 3, 9: 0, 12: 6}
+		for line, count := range lines {
+			if got, ok := f.Lines[line]; !ok || got != count {
+				t.Errorf("%v: line %d: expected %d, got %d (%t)", backend, line, count, got, ok)
+			}
+		}
+
+		for _, b := range f.Branches {
+			if b.Line == 2 && b.Taken != [2]int64{3, 2} {
+				t.Errorf("%v: line 2: expected branches [3 2], got %v", backend, b.Taken)
+			}
+		}
+	}
+}
@@ -23,6 +23,21 @@ func newEnvironment() *environment {
 	}
 }
 
+// settings are shared by threads of a process, and copied by Fork,
+// so that they don't affect other processes sharing the environment.
+type settings struct {
+	backend Backend
+	limits  Limits
+	compile CompileOptions
+
+	profiler *Profiler
+	coverage *Coverage
+}
+
+func newSettings() *settings {
+	return &settings{limits: DefaultLimits}
+}
+
 func (env *environment) getMetatable(val object.Value) object.Table {
 	switch val := val.(type) {
 	case object.Table:
@@ -0,0 +1,53 @@
+package runtime
+
+import (
+	"github.com/hirochachacha/plua/internal/profile"
+	"github.com/hirochachacha/plua/object"
+)
+
+// LineCounts returns the samples (ProfileSample) or the executed instructions (ProfileCount)
+// attributed to lines of the function named name, the same as WriteProfile names it.
+func (prof *Profiler) LineCounts(name string) map[int]int64 {
+	prof.m.Lock()
+	defer prof.m.Unlock()
+
+	b := &profBuilder{prof: prof, funcs: make(map[profFunc]*profile.Function)}
+
+	lines := make(map[int]int64)
+
+	switch prof.mode {
+	case ProfileSample:
+		for _, s := range prof.samples {
+			if len(s.frames) > 0 && b.function(s.frames[0].fn).Name == name {
+				lines[s.frames[0].line] += s.count
+			}
+		}
+	case ProfileCount:
+		var walk func(n *profNode)
+
+		walk = func(n *profNode) {
+			if n.parent != nil && n.counts != nil && b.function(n.fn).Name == name {
+				for pc, count := range n.counts {
+					lines[n.fn.proto.LineInfo[pc]] += count
+				}
+			}
+
+			for _, child := range n.children {
+				walk(child)
+			}
+		}
+
+		for _, root := range prof.roots {
+			walk(root)
+		}
+	}
+
+	return lines
+}
+
+// InlineCaches returns the number of inline caches of the main thread of p.
+func InlineCaches(p object.Process) int {
+	th, _ := mainThread(p)
+
+	return th.nicaches
+}
@@ -0,0 +1,153 @@
+package runtime
+
+import (
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// versionedTable is implemented by tables which tell whether they are mutated.
+type versionedTable interface {
+	object.Table
+
+	// Version returns a number which changes whenever the contents or the metatable change.
+	Version() uint64
+}
+
+// maxCacheChain is the maximum number of tables visited by a cached lookup,
+// e.g. an object, its metatable, the class, its metatable and the base class.
+const maxCacheChain = 8
+
+// maxInlineCaches is the maximum number of inline caches of a thread.
+// all caches are dropped if it's exceeded, so that functions loaded repeatedly don't leak.
+const maxInlineCaches = 1 << 16
+
+type cachedTable struct {
+	t       versionedTable
+	version uint64
+}
+
+// inlineCache caches the result of an indexing with a constant string key at an instruction.
+//
+// chain records all tables visited by the lookup, the receiver comes first.
+// the result is valid as long as their versions are unchanged,
+// since a version of a table covers its metatable and its fields including __index.
+type inlineCache struct {
+	chain []cachedTable
+	val   tvalue.Value
+}
+
+func (ic *inlineCache) get(t tvalue.Value) (val tvalue.Value, ok bool) {
+	if len(ic.chain) == 0 || ic.chain[0].t != t.V {
+		return tvalue.Nil, false
+	}
+
+	for _, c := range ic.chain {
+		if c.t.Version() != c.version {
+			return tvalue.Nil, false
+		}
+	}
+
+	return ic.val, true
+}
+
+// fill looks up key the same as arith.CallGettable, and caches the result.
+// if ok is false, the lookup needs metamethod calls or non-table values,
+// and nothing is cached.
+func (ic *inlineCache) fill(t, key tvalue.Value) (val tvalue.Value, ok bool) {
+	ic.chain = ic.chain[:0]
+
+	cur := t.V
+
+	for len(ic.chain) < maxCacheChain-1 {
+		tab, ok := cur.(versionedTable)
+		if !ok {
+			break
+		}
+
+		// load versions before reading, see concurrentTable.Version
+		ic.chain = append(ic.chain, cachedTable{tab, tab.Version()})
+
+		val := tvalue.Of(tab.Get(key.V))
+		if !val.IsNil() {
+			return ic.set(val), true
+		}
+
+		mt, ok := tab.Metatable().(versionedTable)
+		if !ok {
+			if tab.Metatable() == nil {
+				return ic.set(tvalue.Nil), true
+			}
+			break
+		}
+
+		ic.chain = append(ic.chain, cachedTable{mt, mt.Version()})
+
+		tm := mt.Get(object.TM_INDEX)
+		if tm == nil {
+			return ic.set(tvalue.Nil), true
+		}
+
+		cur = tm
+	}
+
+	ic.chain = ic.chain[:0]
+
+	return tvalue.Nil, false
+}
+
+func (ic *inlineCache) set(val tvalue.Value) tvalue.Value {
+	ic.val = val
+	return val
+}
+
+// inlineCache returns the cache of the current instruction.
+func (th *thread) inlineCache() *inlineCache {
+	ci := th.context.ci
+
+	if ci.icache == nil {
+		icache, ok := th.icaches[ci.Proto]
+		if !ok {
+			if th.icaches == nil || th.nicaches+len(ci.Code) > maxInlineCaches {
+				th.icaches = make(map[*object.Proto][]inlineCache)
+				th.nicaches = 0
+			}
+
+			icache = make([]inlineCache, len(ci.Code))
+
+			th.icaches[ci.Proto] = icache
+			th.nicaches += len(icache)
+		}
+
+		ci.icache = icache
+	}
+
+	return &ci.icache[ci.pc-1]
+}
+
+// getfield is the same as gettable, but caches lookups of constant string keys.
+func (th *thread) getfield(a, rk int, t, key tvalue.Value) *object.RuntimeError {
+	if rk&opcode.BitRK == 0 {
+		return th.gettable(a, t, key)
+	}
+
+	if _, ok := key.V.(object.String); !ok {
+		return th.gettable(a, t, key)
+	}
+
+	ic := th.inlineCache()
+
+	if val, ok := ic.get(t); ok {
+		th.setR(a, val)
+
+		return nil
+	}
+
+	if val, ok := ic.fill(t, key); ok {
+		th.setR(a, val)
+
+		return nil
+	}
+
+	return th.gettable(a, t, key)
+}
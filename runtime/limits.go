@@ -0,0 +1,45 @@
+package runtime
+
+import (
+	"errors"
+
+	"github.com/hirochachacha/plua/internal/version"
+	"github.com/hirochachacha/plua/object"
+)
+
+// Limits bound the recursion of threads.
+// exceeding them raises "stack overflow" or "Go stack overflow" errors.
+type Limits struct {
+	// StackSize is the maximum number of slots of the Lua stack of a thread,
+	// which hold registers and arguments of running functions.
+	StackSize int
+
+	// GoDepth is the maximum depth of nested executions of the VM in a thread.
+	// Go functions calling Lua functions, e.g. table.sort with a comparator, nest them,
+	// and so do Go functions calling Go functions by Thread.Call, e.g. functions translated by lua2go.
+	// Lua functions called by Lua functions, pcall, metamethods and iterators of generic for statements don't.
+	GoDepth int
+}
+
+// DefaultLimits are limits of new processes.
+var DefaultLimits = Limits{
+	StackSize: version.MAX_STACK_SIZE,
+	GoDepth:   version.MAX_VM_RECURSION,
+}
+
+// SetLimits sets the limits of p and processes forked from it after that.
+// It must not be called while p is running in another goroutine.
+func SetLimits(p object.Process, l Limits) error {
+	th, ok := mainThread(p)
+	if !ok {
+		return errors.New("runtime: unknown process implementation")
+	}
+
+	if l.StackSize < basicStackSize || l.GoDepth < 1 {
+		return errors.New("runtime: limits are too small")
+	}
+
+	th.settings.limits = l
+
+	return nil
+}
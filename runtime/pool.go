@@ -0,0 +1,390 @@
+package runtime
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+)
+
+var errPoolClosed = errors.New("runtime: pool is closed")
+
+// PoolOptions bound the number of processes of a pool, and their idle time.
+type PoolOptions struct {
+	// MaxIdle is the maximum number of idle processes kept by the pool.
+	// processes returned to a full pool are discarded.
+	// 0 means no limit.
+	MaxIdle int
+
+	// MaxActive is the maximum number of processes handed out at the same time.
+	// Get waits for a process to be returned if the limit is reached.
+	// 0 means no limit.
+	MaxActive int
+
+	// IdleTimeout discards processes which have been idle longer than it.
+	// 0 means no timeout.
+	IdleTimeout time.Duration
+}
+
+// Pool is a pool of processes created from a template, which is safe for use by multiple goroutines.
+//
+// New processes are initialized by the init function, e.g. opening the standard library and preloading modules,
+// and the state after the initialization is the template.
+// Processes returned by Put are reset to the template:
+//
+//	tables reachable from globals, loaded and preloaded modules and metatables
+//	get back their fields and metatables, and so do upvalues of Lua functions reachable from them.
+//	the main thread is replaced, so hooks are cleared, and Exec can be called again.
+//	the backend, the limits, the compile options, the profiler and the coverage are restored.
+//
+// States of Go values such as userdata and Go functions aren't restored.
+type Pool struct {
+	init func(p object.Process) error
+	opts PoolOptions
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	idle   []*pooledProcess // oldest first
+	active int
+	closed bool
+}
+
+type pooledProcess struct {
+	*process
+
+	pool     *Pool
+	snapshot *snapshot
+	idleAt   time.Time
+}
+
+// NewPool returns a new pool of processes initialized by init.
+func NewPool(init func(p object.Process) error, opts PoolOptions) *Pool {
+	pool := &Pool{
+		init: init,
+		opts: opts,
+	}
+
+	pool.cond = sync.NewCond(&pool.mu)
+
+	return pool
+}
+
+// Get returns an idle process, or a new one if there is none.
+func (pool *Pool) Get() (object.Process, error) {
+	pool.mu.Lock()
+
+	for !pool.closed && pool.opts.MaxActive > 0 && pool.active >= pool.opts.MaxActive {
+		pool.cond.Wait()
+	}
+
+	if pool.closed {
+		pool.mu.Unlock()
+
+		return nil, errPoolClosed
+	}
+
+	pool.expire(time.Now())
+
+	var p *pooledProcess
+
+	if n := len(pool.idle); n > 0 {
+		p = pool.idle[n-1]
+		pool.idle[n-1] = nil
+		pool.idle = pool.idle[:n-1]
+	}
+
+	pool.active++
+
+	pool.mu.Unlock()
+
+	if p != nil {
+		return p, nil
+	}
+
+	p, err := pool.newProcess()
+	if err != nil {
+		pool.mu.Lock()
+		pool.active--
+		pool.cond.Signal()
+		pool.mu.Unlock()
+
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Put resets p, and returns it to the pool.
+// p must be a process returned by Get, and mustn't be used after that.
+func (pool *Pool) Put(p object.Process) {
+	pp, ok := p.(*pooledProcess)
+	if !ok || pp.pool != pool {
+		panic("runtime: Put of a process which doesn't belong to the pool")
+	}
+
+	pp.reset()
+
+	now := time.Now()
+
+	pp.idleAt = now
+
+	pool.mu.Lock()
+
+	pool.active--
+	pool.cond.Signal()
+
+	if pool.closed || pool.opts.MaxIdle > 0 && len(pool.idle) >= pool.opts.MaxIdle {
+		pool.mu.Unlock()
+
+		pp.discard()
+
+		return
+	}
+
+	pool.idle = append(pool.idle, pp)
+
+	pool.expire(now)
+
+	pool.mu.Unlock()
+}
+
+// Len returns the number of idle processes.
+func (pool *Pool) Len() int {
+	pool.mu.Lock()
+	n := len(pool.idle)
+	pool.mu.Unlock()
+
+	return n
+}
+
+// Close discards idle processes.
+// Get returns an error after that, and processes in use are discarded when they are returned.
+func (pool *Pool) Close() {
+	pool.mu.Lock()
+
+	idle := pool.idle
+
+	pool.idle = nil
+	pool.closed = true
+	pool.cond.Broadcast()
+
+	pool.mu.Unlock()
+
+	for _, p := range idle {
+		p.discard()
+	}
+}
+
+// expire discards processes which have been idle longer than IdleTimeout.
+// pool.mu must be held.
+func (pool *Pool) expire(now time.Time) {
+	if pool.opts.IdleTimeout <= 0 {
+		return
+	}
+
+	var i int
+	for i < len(pool.idle) && now.Sub(pool.idle[i].idleAt) > pool.opts.IdleTimeout {
+		pool.idle[i].discard()
+		pool.idle[i] = nil
+		i++
+	}
+
+	if i > 0 {
+		pool.idle = append(pool.idle[:0], pool.idle[i:]...)
+	}
+}
+
+func (pool *Pool) newProcess() (*pooledProcess, error) {
+	p := &process{newMainThread(newEnvironment(), newSettings())}
+
+	if pool.init != nil {
+		if err := pool.init(p); err != nil {
+			p.Thread.(*thread).release()
+
+			return nil, err
+		}
+	}
+
+	p.renew()
+
+	return &pooledProcess{
+		process:  p,
+		pool:     pool,
+		snapshot: takeSnapshot(p.Thread.(*thread)),
+	}, nil
+}
+
+func (p *pooledProcess) reset() {
+	p.renew()
+
+	p.snapshot.restore(p.Thread.(*thread))
+}
+
+func (p *pooledProcess) discard() {
+	p.Thread.(*thread).release()
+}
+
+// renew replaces the main thread of p if it's used by Exec.
+func (p *process) renew() {
+	th := p.Thread.(*thread)
+
+	if th.status != object.THREAD_INIT {
+		p.Thread = newMainThread(th.env, th.settings)
+	}
+}
+
+// release stops the goroutine of th, if it's never resumed.
+func (th *thread) release() {
+	if th.status == object.THREAD_INIT {
+		close(th.resume)
+	}
+}
+
+// snapshot is a state of an environment and settings of a process.
+type snapshot struct {
+	tables []tableSnapshot
+	upvals []upvalSnapshot
+
+	metatables [object.MaxType + 1]object.Table
+
+	settings settings
+}
+
+type tableSnapshot struct {
+	t      object.Table
+	mt     object.Table
+	fields object.Table
+}
+
+type upvalSnapshot struct {
+	uv  *upvalue
+	val tvalue.Value
+}
+
+func takeSnapshot(th *thread) *snapshot {
+	env := th.env
+
+	s := &snapshot{
+		metatables: env.metatables,
+		settings:   *th.settings,
+	}
+
+	visited := make(map[object.Value]bool)
+
+	s.visit(env.globals, visited)
+	s.visit(env.loaded, visited)
+	s.visit(env.preload, visited)
+
+	for _, mt := range env.metatables {
+		if mt != nil {
+			s.visit(mt, visited)
+		}
+	}
+
+	return s
+}
+
+func (s *snapshot) visit(val object.Value, visited map[object.Value]bool) {
+	switch val := val.(type) {
+	case object.Table:
+		if visited[val] {
+			return
+		}
+
+		visited[val] = true
+
+		fields := newTableSize(0, 0)
+
+		var key, elem object.Value
+		for {
+			key, elem, _ = val.Next(key)
+			if elem == nil {
+				break
+			}
+
+			fields.Set(key, elem)
+		}
+
+		mt := val.Metatable()
+
+		s.tables = append(s.tables, tableSnapshot{t: val, mt: mt, fields: fields})
+
+		if mt != nil {
+			s.visit(mt, visited)
+		}
+
+		key = nil
+		for {
+			key, elem, _ = fields.Next(key)
+			if elem == nil {
+				break
+			}
+
+			s.visit(key, visited)
+			s.visit(elem, visited)
+		}
+	case *closure:
+		if visited[val] {
+			return
+		}
+
+		visited[val] = true
+
+		for _, uv := range val.upvals {
+			v := uv.get()
+
+			s.upvals = append(s.upvals, upvalSnapshot{uv: uv, val: v})
+
+			s.visit(v.Box(), visited)
+		}
+	}
+}
+
+func (s *snapshot) restore(th *thread) {
+	env := th.env
+
+	for _, ts := range s.tables {
+		t := ts.t
+
+		var dels []object.Value
+
+		var key, elem object.Value
+		for {
+			key, elem, _ = t.Next(key)
+			if elem == nil {
+				break
+			}
+
+			if ts.fields.Get(key) == nil {
+				dels = append(dels, key)
+			}
+		}
+
+		for _, key := range dels {
+			t.Del(key)
+		}
+
+		for {
+			key, elem, _ = ts.fields.Next(key)
+			if elem == nil {
+				break
+			}
+
+			t.Set(key, elem)
+		}
+
+		if t.Metatable() != ts.mt {
+			t.SetMetatable(ts.mt)
+		}
+	}
+
+	for _, us := range s.upvals {
+		us.uv.set(us.val)
+	}
+
+	env.metatables = s.metatables
+
+	*th.settings = s.settings
+}
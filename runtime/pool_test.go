@@ -0,0 +1,207 @@
+package runtime_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib"
+)
+
+var testPoolInit = `
+local n = 0
+package.preload.counter = function()
+	return {next = function() n = n + 1 return n end}
+end
+counter = require("counter")
+config = {name = "template"}
+`
+
+var testPoolCode = `
+local a = counter.next()
+local b = config.name
+config.name = "changed"
+config.extra = {}
+leaked = true
+string.upper = nil
+getmetatable("").__index = nil
+setmetatable(_G, {__index = function() return "meta" end})
+package.loaded.other = {}
+return a, b
+`
+
+func newTestPool(t *testing.T, opts runtime.PoolOptions) *runtime.Pool {
+	init, err := compiler.NewCompiler().Compile(strings.NewReader(testPoolInit), "=init", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return runtime.NewPool(func(p object.Process) error {
+		p.Require("", stdlib.Open)
+
+		_, err := p.Exec(init)
+
+		return err
+	}, opts)
+}
+
+func TestPool(t *testing.T) {
+	pool := newTestPool(t, runtime.PoolOptions{MaxIdle: 1})
+
+	proto, err := compiler.NewCompiler().Compile(strings.NewReader(testPoolCode), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var last object.Process
+
+	for i := 0; i < 3; i++ {
+		p, err := pool.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if i > 0 && p != last {
+			t.Error("expected the idle process")
+		}
+
+		last = p
+
+		rets, err := p.Exec(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		want := []object.Value{object.Integer(1), object.String("template")}
+
+		if len(rets) != len(want) || rets[0] != want[0] || rets[1] != want[1] {
+			t.Errorf("expected %v, got %v", want, rets)
+		}
+
+		pool.Put(p)
+
+		g := p.Globals()
+
+		if g.Get(object.String("leaked")) != nil || g.Metatable() != nil {
+			t.Error("expected restored globals")
+		}
+
+		if p.Loaded().Get(object.String("other")) != nil {
+			t.Error("expected restored loaded modules")
+		}
+
+		str := g.Get(object.String("string")).(object.Table)
+
+		if str.Get(object.String("upper")) == nil || p.GetMetatable(object.String("")).Get(object.String("__index")) != str {
+			t.Error("expected restored string library")
+		}
+	}
+
+	p1, _ := pool.Get()
+	p2, _ := pool.Get()
+
+	pool.Put(p1)
+	pool.Put(p2)
+
+	if n := pool.Len(); n != 1 {
+		t.Errorf("expected 1 idle process, got %d", n)
+	}
+
+	pool.Close()
+
+	if _, err := pool.Get(); err == nil {
+		t.Error("expected err, got nil")
+	}
+}
+
+func TestPoolLimits(t *testing.T) {
+	pool := newTestPool(t, runtime.PoolOptions{MaxActive: 1, IdleTimeout: time.Millisecond})
+	defer pool.Close()
+
+	p1, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(chan object.Process)
+
+	go func() {
+		p, err := pool.Get()
+		if err != nil {
+			t.Error(err)
+		}
+		got <- p
+	}()
+
+	select {
+	case <-got:
+		t.Fatal("expected Get to wait for Put")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	pool.Put(p1)
+
+	p2 := <-got
+
+	if p2 != p1 {
+		t.Error("expected the returned process")
+	}
+
+	pool.Put(p2)
+
+	time.Sleep(10 * time.Millisecond)
+
+	p3, err := pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p3 == p1 {
+		t.Error("expected a new process after the idle timeout")
+	}
+
+	pool.Put(p3)
+}
+
+func TestPoolConcurrent(t *testing.T) {
+	pool := newTestPool(t, runtime.PoolOptions{MaxIdle: 4, MaxActive: 4})
+	defer pool.Close()
+
+	proto, err := compiler.NewCompiler().Compile(strings.NewReader(testPoolCode), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				p, err := pool.Get()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+
+				rets, err := p.Exec(proto)
+				if err != nil {
+					t.Error(err)
+				} else if rets[0] != object.Integer(1) {
+					t.Errorf("expected 1, got %v", rets[0])
+				}
+
+				pool.Put(p)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
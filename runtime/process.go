@@ -9,13 +9,15 @@ type process struct {
 }
 
 func NewProcess() object.Process {
-	return &process{newMainThread()}
+	return &process{newMainThread(newEnvironment(), newSettings())}
 }
 
 func (p *process) Fork() object.Process {
 	th := p.Thread.(*thread)
 
-	th = th.newThreadWith(threadMain, th.env, 0)
+	s := *th.settings
+
+	th = th.newThreadWith(threadMain, th.env, &s, 0)
 
 	return &process{th}
 }
@@ -34,3 +36,15 @@ func (p *process) Exec(proto *object.Proto, args ...object.Value) (rets []object
 
 	return rets, nil
 }
+
+// mainThread returns the main thread of p, if p is implemented by this package.
+func mainThread(p object.Process) (*thread, bool) {
+	switch p := p.(type) {
+	case *process:
+		return p.Thread.(*thread), true
+	case *pooledProcess:
+		return p.Thread.(*thread), true
+	}
+
+	return nil, false
+}
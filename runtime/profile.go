@@ -0,0 +1,572 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	goruntime "runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hirochachacha/plua/internal/profile"
+	"github.com/hirochachacha/plua/internal/util"
+	"github.com/hirochachacha/plua/object"
+)
+
+type ProfileMode int
+
+const (
+	// ProfileSample samples call stacks periodically and attributes time to them.
+	ProfileSample ProfileMode = iota
+
+	// ProfileCount counts every call and every executed instruction exactly.
+	ProfileCount
+)
+
+const defaultProfileRate = 100 // Hz
+
+// Profiler attributes time or instruction counts to Lua functions and lines.
+//
+// A profiler is attached to a process by Start, and must be started before
+// and stopped after the code to profile is executed.
+// Every thread created from the process shares the profiler.
+type Profiler struct {
+	mode   ProfileMode
+	period time.Duration
+
+	th *thread // main thread of the profiled process
+
+	running int32
+	pending int32 // sampling request from the ticker
+
+	ticker *time.Ticker
+	done   chan struct{}
+
+	start    time.Time
+	duration time.Duration
+
+	m       sync.Mutex
+	samples map[string]*profSample // for ProfileSample
+	roots   []*profNode            // for ProfileCount, one per thread
+	funcs   map[profFunc]*profFuncInfo
+}
+
+func NewProfiler(mode ProfileMode) *Profiler {
+	return &Profiler{
+		mode:    mode,
+		period:  time.Second / defaultProfileRate,
+		samples: make(map[string]*profSample),
+		funcs:   make(map[profFunc]*profFuncInfo),
+	}
+}
+
+// SetRate sets the sampling rate in Hz. It must be called before Start.
+func (prof *Profiler) SetRate(hz int) {
+	if hz <= 0 {
+		hz = defaultProfileRate
+	}
+
+	prof.period = time.Second / time.Duration(hz)
+}
+
+func (prof *Profiler) Start(p object.Process) error {
+	th, ok := mainThread(p)
+	if !ok {
+		return errors.New("runtime: unknown process implementation")
+	}
+
+	if !atomic.CompareAndSwapInt32(&prof.running, 0, 1) {
+		return errors.New("runtime: profiler is already running")
+	}
+
+	prof.th = th
+	prof.th.settings.profiler = prof
+	prof.start = time.Now()
+
+	if prof.mode == ProfileSample {
+		ticker := time.NewTicker(prof.period)
+
+		prof.ticker = ticker
+		prof.done = make(chan struct{})
+
+		go func(done chan struct{}) {
+			for {
+				select {
+				case <-ticker.C:
+					atomic.StoreInt32(&prof.pending, 1)
+				case <-done:
+					return
+				}
+			}
+		}(prof.done)
+	}
+
+	return nil
+}
+
+func (prof *Profiler) Stop() {
+	if !atomic.CompareAndSwapInt32(&prof.running, 1, 0) {
+		return
+	}
+
+	prof.duration = time.Since(prof.start)
+
+	if prof.ticker != nil {
+		prof.ticker.Stop()
+		close(prof.done)
+		prof.ticker = nil
+	}
+}
+
+func (prof *Profiler) isRunning() bool {
+	return atomic.LoadInt32(&prof.running) != 0
+}
+
+// profFunc identifies a function, closures sharing the same prototype are merged.
+type profFunc struct {
+	proto *object.Proto
+	pc    uintptr // entry of go function
+}
+
+type profFuncInfo struct {
+	fn   object.Value // one of the instances
+	name string       // name at the first call site
+}
+
+type profFrame struct {
+	fn   profFunc
+	line int
+}
+
+type profSample struct {
+	frames []profFrame // leaf first
+	count  int64
+}
+
+type profNode struct {
+	prof *Profiler
+
+	fn     profFunc
+	line   int // line of the call site in parent
+	parent *profNode
+
+	children map[profFrame]*profNode
+
+	calls  int64
+	counts []int64 // instruction counts by pc
+
+	named bool
+}
+
+func funcOf(fn object.Value) (f profFunc, ok bool) {
+	switch fn := fn.(type) {
+	case *closure:
+		return profFunc{proto: fn.Proto}, true
+	case object.GoFunction:
+		return profFunc{pc: reflect.ValueOf(fn).Pointer()}, true
+	}
+	return f, false
+}
+
+// caller returns the frame which called ctx.ciStack[i].
+func caller(ctx *context, i int) (*context, int) {
+	for {
+		i--
+		for i < 0 {
+			ctx = ctx.prev
+			if ctx == nil {
+				return nil, 0
+			}
+			i += len(ctx.ciStack)
+		}
+
+		// the bottom of a context is a placeholder unless it holds a closure
+		if i != 0 || ctx.ciStack[i].closure != nil {
+			return ctx, i
+		}
+	}
+}
+
+// register records the function of ctx.ciStack[i], and reports whether its name is known.
+func (prof *Profiler) register(th *thread, f profFunc, ctx *context, i int) bool {
+	prof.m.Lock()
+	defer prof.m.Unlock()
+
+	info, ok := prof.funcs[f]
+	if !ok {
+		info = &profFuncInfo{fn: ctx.fn(&ctx.ciStack[i])}
+
+		prof.funcs[f] = info
+	}
+
+	if info.name != "" {
+		return true
+	}
+
+	if ctx.hookState == isHook && i == 0 {
+		info.name = "hook"
+	} else if pctx, j := caller(ctx, i); pctx != nil && !ctx.ciStack[i].isTailCall {
+		prev := &pctx.ciStack[j]
+		if !prev.isGoFunction() && prev.pc > 0 {
+			d := new(object.DebugInfo)
+			setFuncName(d, prev)
+			if d.NameWhat != "metamethod" && d.NameWhat != "for iterator" {
+				info.name = d.Name
+			}
+		}
+	}
+
+	return info.name != ""
+}
+
+func (th *thread) profileInstruction(prof *Profiler) {
+	switch prof.mode {
+	case ProfileSample:
+		if atomic.LoadInt32(&prof.pending) != 0 && atomic.CompareAndSwapInt32(&prof.pending, 1, 0) {
+			th.profileSample(prof)
+		}
+	case ProfileCount:
+		ctx := th.context
+		n := th.profileNode(prof, ctx, len(ctx.ciStack)-1)
+		if n.counts != nil {
+			n.counts[ctx.ci.pc]++
+		}
+	}
+}
+
+func (th *thread) profileCall(prof *Profiler) {
+	if prof.mode == ProfileCount {
+		ctx := th.context
+		th.profileNode(prof, ctx, len(ctx.ciStack)-1)
+	}
+}
+
+func (th *thread) profileSample(prof *Profiler) {
+	var frames []profFrame
+
+	ctx := th.context
+	i := len(ctx.ciStack) - 1
+
+	leaf := true
+
+	for {
+		ci := &ctx.ciStack[i]
+
+		if f, ok := funcOf(ctx.fn(ci)); ok {
+			var line int
+			if !ci.isGoFunction() && len(ci.LineInfo) > 0 {
+				if leaf && ci.pc < len(ci.LineInfo) {
+					line = ci.LineInfo[ci.pc]
+				} else {
+					line = getCurrentLine(ci)
+				}
+			}
+
+			frames = append(frames, profFrame{fn: f, line: line})
+
+			prof.register(th, f, ctx, i)
+		}
+
+		leaf = false
+
+		ctx, i = caller(ctx, i)
+		if ctx == nil {
+			break
+		}
+	}
+
+	key := framesKey(frames)
+
+	prof.m.Lock()
+	s := prof.samples[key]
+	if s == nil {
+		s = &profSample{frames: frames}
+		prof.samples[key] = s
+	}
+	s.count++
+	prof.m.Unlock()
+}
+
+func framesKey(frames []profFrame) string {
+	buf := make([]byte, 0, len(frames)*24)
+	for _, fr := range frames {
+		buf = append(buf, fmt.Sprintf("%p:%x:%d;", fr.fn.proto, fr.fn.pc, fr.line)...)
+	}
+	return string(buf)
+}
+
+func (th *thread) profileRoot(prof *Profiler) *profNode {
+	if th.proot == nil || th.proot.prof != prof {
+		th.proot = &profNode{prof: prof, children: make(map[profFrame]*profNode)}
+
+		prof.m.Lock()
+		prof.roots = append(prof.roots, th.proot)
+		prof.m.Unlock()
+	}
+
+	return th.proot
+}
+
+func (th *thread) profileNode(prof *Profiler, ctx *context, i int) *profNode {
+	ci := &ctx.ciStack[i]
+
+	if n := ci.pnode; n != nil && n.prof == prof {
+		return n
+	}
+
+	var parent *profNode
+	var line int
+
+	if pctx, j := caller(ctx, i); pctx != nil {
+		parent = th.profileNode(prof, pctx, j)
+		if l := getCurrentLine(&pctx.ciStack[j]); l > 0 {
+			line = l
+		}
+	} else {
+		parent = th.profileRoot(prof)
+	}
+
+	f, _ := funcOf(ctx.fn(ci))
+
+	key := profFrame{fn: f, line: line}
+
+	n := parent.children[key]
+	if n == nil {
+		n = &profNode{
+			prof:     prof,
+			fn:       f,
+			line:     line,
+			parent:   parent,
+			children: make(map[profFrame]*profNode),
+		}
+		if f.proto != nil {
+			n.counts = make([]int64, len(f.proto.Code))
+		}
+
+		parent.children[key] = n
+	}
+
+	n.calls++
+
+	ci.pnode = n
+
+	if !n.named {
+		// names may be unknown at the first call, e.g. tail calls.
+		n.named = prof.register(th, f, ctx, i)
+	}
+
+	return n
+}
+
+// WriteProfile writes the collected profile to w in the pprof format.
+// It should be called after the profiler is stopped.
+func (prof *Profiler) WriteProfile(w io.Writer) error {
+	prof.m.Lock()
+	defer prof.m.Unlock()
+
+	b := &profBuilder{
+		prof:  prof,
+		funcs: make(map[profFunc]*profile.Function),
+		locs:  make(map[profFrame]*profile.Location),
+	}
+
+	p := &profile.Profile{
+		TimeNanos:     prof.start.UnixNano(),
+		DurationNanos: int64(prof.duration),
+	}
+
+	switch prof.mode {
+	case ProfileSample:
+		p.SampleType = []profile.ValueType{{Type: "samples", Unit: "count"}, {Type: "cpu", Unit: "nanoseconds"}}
+		p.PeriodType = profile.ValueType{Type: "cpu", Unit: "nanoseconds"}
+		p.Period = int64(prof.period)
+
+		keys := make([]string, 0, len(prof.samples))
+		for key := range prof.samples {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			s := prof.samples[key]
+
+			locs := make([]*profile.Location, len(s.frames))
+			for i, fr := range s.frames {
+				locs[i] = b.location(fr)
+			}
+
+			p.Sample = append(p.Sample, &profile.Sample{
+				Location: locs,
+				Value:    []int64{s.count, s.count * int64(prof.period)},
+			})
+		}
+	case ProfileCount:
+		p.SampleType = []profile.ValueType{{Type: "calls", Unit: "count"}, {Type: "instructions", Unit: "count"}}
+		p.PeriodType = profile.ValueType{Type: "instructions", Unit: "count"}
+		p.Period = 1
+
+		for _, root := range prof.roots {
+			b.walk(p, root, nil)
+		}
+	}
+
+	p.Function = b.funcList
+	p.Location = b.locList
+
+	return p.Write(w)
+}
+
+type profBuilder struct {
+	prof *Profiler
+
+	funcs    map[profFunc]*profile.Function
+	funcList []*profile.Function
+
+	locs    map[profFrame]*profile.Location
+	locList []*profile.Location
+}
+
+func (b *profBuilder) walk(p *profile.Profile, n *profNode, callers []*profile.Location) {
+	if n.parent != nil {
+		if n.calls > 0 {
+			entry := profFrame{fn: n.fn}
+			if n.fn.proto != nil {
+				entry.line = n.fn.proto.LineDefined
+			}
+
+			p.Sample = append(p.Sample, &profile.Sample{
+				Location: append([]*profile.Location{b.location(entry)}, callers...),
+				Value:    []int64{n.calls, 0},
+			})
+		}
+
+		if n.counts != nil {
+			lines := make(map[int]int64)
+			for pc, count := range n.counts {
+				if count == 0 {
+					continue
+				}
+				var line int
+				if pc < len(n.fn.proto.LineInfo) {
+					line = n.fn.proto.LineInfo[pc]
+				}
+				lines[line] += count
+			}
+
+			ls := make([]int, 0, len(lines))
+			for line := range lines {
+				ls = append(ls, line)
+			}
+			sort.Ints(ls)
+
+			for _, line := range ls {
+				p.Sample = append(p.Sample, &profile.Sample{
+					Location: append([]*profile.Location{b.location(profFrame{fn: n.fn, line: line})}, callers...),
+					Value:    []int64{0, lines[line]},
+				})
+			}
+		}
+	}
+
+	children := make([]*profNode, 0, len(n.children))
+	for _, child := range n.children {
+		children = append(children, child)
+	}
+	sort.Sort(byFrame(children))
+
+	for _, child := range children {
+		var cs []*profile.Location
+		if n.parent != nil {
+			cs = append([]*profile.Location{b.location(profFrame{fn: n.fn, line: child.line})}, callers...)
+		}
+		b.walk(p, child, cs)
+	}
+}
+
+type byFrame []*profNode
+
+func (ns byFrame) Len() int      { return len(ns) }
+func (ns byFrame) Swap(i, j int) { ns[i], ns[j] = ns[j], ns[i] }
+func (ns byFrame) Less(i, j int) bool {
+	x, y := ns[i], ns[j]
+	if x.line != y.line {
+		return x.line < y.line
+	}
+	if x.fn.proto != nil && y.fn.proto != nil {
+		return x.fn.proto.LineDefined < y.fn.proto.LineDefined
+	}
+	return x.fn.pc < y.fn.pc
+}
+
+func (b *profBuilder) location(fr profFrame) *profile.Location {
+	if loc, ok := b.locs[fr]; ok {
+		return loc
+	}
+
+	loc := &profile.Location{
+		ID:       uint64(len(b.locList) + 1),
+		Function: b.function(fr.fn),
+		Line:     int64(fr.line),
+	}
+
+	b.locs[fr] = loc
+	b.locList = append(b.locList, loc)
+
+	return loc
+}
+
+func (b *profBuilder) function(f profFunc) *profile.Function {
+	if fn, ok := b.funcs[f]; ok {
+		return fn
+	}
+
+	fn := &profile.Function{ID: uint64(len(b.funcList) + 1)}
+
+	info := b.prof.funcs[f]
+
+	var name string
+	if info != nil {
+		if g := b.prof.th.getFuncName(info.fn); g != "?" {
+			name = g
+		} else {
+			name = info.name
+		}
+	}
+
+	if p := f.proto; p != nil {
+		var sig string
+		if p.LineDefined == 0 {
+			sig = "main chunk"
+		} else {
+			sig = fmt.Sprintf("function <%s:%d>", util.Shorten(p.Source), p.LineDefined)
+		}
+
+		if name == "" {
+			name = sig
+		}
+
+		fn.Name = name
+		fn.SystemName = sig
+		fn.Filename = sourceName(p.Source)
+		fn.StartLine = int64(p.LineDefined)
+	} else {
+		if gofn := goruntime.FuncForPC(f.pc); gofn != nil {
+			fn.SystemName = gofn.Name()
+			fn.Filename, _ = gofn.FileLine(f.pc)
+		}
+
+		if name == "" {
+			name = fn.SystemName
+		}
+
+		fn.Name = name
+	}
+
+	b.funcs[f] = fn
+	b.funcList = append(b.funcList, fn)
+
+	return fn
+}
@@ -0,0 +1,111 @@
+package runtime_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/object"
+	"github.com/hirochachacha/plua/runtime"
+	"github.com/hirochachacha/plua/stdlib"
+)
+
+var testProfileCode = `
+local function fib(n)
+  if n < 2 then return n end
+  return fib(n-1) + fib(n-2)
+end
+repeat fib(15) until done()
+`
+
+func TestProfiler(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	proto, err := c.Compile(strings.NewReader(testProfileCode), "@prof.lua", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, mode := range []runtime.ProfileMode{runtime.ProfileSample, runtime.ProfileCount} {
+		p := runtime.NewProcess()
+
+		p.Require("", stdlib.Open)
+
+		// run long enough to be sampled.
+		start := time.Now()
+
+		p.Globals().Set(object.String("done"), object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+			return []object.Value{object.Boolean(time.Since(start) > 50*time.Millisecond)}, nil
+		}))
+
+		prof := runtime.NewProfiler(mode)
+		prof.SetRate(1000)
+
+		if err := prof.Start(p); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := prof.Start(p); err == nil {
+			t.Error("expected error on double start, got nil")
+		}
+
+		_, err = p.Exec(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		prof.Stop()
+
+		var buf bytes.Buffer
+
+		if err := prof.WriteProfile(&buf); err != nil {
+			t.Fatal(err)
+		}
+
+		zr, err := gzip.NewReader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if mode == runtime.ProfileCount {
+			for _, s := range []string{"fib", "main chunk", "prof.lua", "instructions"} {
+				if !bytes.Contains(data, []byte(s)) {
+					t.Errorf("expected %q in the profile", s)
+				}
+			}
+		} else {
+			if !bytes.Contains(data, []byte("cpu")) {
+				t.Errorf("expected %q in the profile", "cpu")
+			}
+		}
+
+		lines := prof.LineCounts("fib")
+
+		switch mode {
+		case runtime.ProfileSample:
+			var total int64
+			for _, count := range lines {
+				total += count
+			}
+
+			if total == 0 {
+				t.Errorf("expected samples of fib, got %v", lines)
+			}
+		case runtime.ProfileCount:
+			for _, line := range []int{3, 4} {
+				if lines[line] == 0 {
+					t.Errorf("expected instructions of fib at line %d, got %v", line, lines)
+				}
+			}
+		}
+	}
+}
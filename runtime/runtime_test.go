@@ -5,11 +5,14 @@ import (
 	"testing"
 
 	"github.com/hirochachacha/plua/compiler"
+	"github.com/hirochachacha/plua/compiler/cache"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/runtime"
 	"github.com/hirochachacha/plua/stdlib"
 )
 
+var backends = []runtime.Backend{runtime.Interpreter, runtime.ClosureCompiler}
+
 var testExec = []struct {
 	Code string
 	Rets []object.Value
@@ -121,6 +124,79 @@ var testExec = []struct {
 		`,
 		[]object.Value{object.Integer(2)},
 	},
+	{
+		`local d = debug.getinfo(1, "l") return d.currentline, d.currentcolumn`,
+		[]object.Value{object.Integer(1), object.Integer(24)},
+	},
+	{
+		`
+		-- inline caches are invalidated by mutations
+		local s = ""
+		x = 1
+		for i = 1, 4 do
+			s = s .. tostring(x)
+			if i == 2 then x = "a" end
+			if i == 3 then x = nil end
+		end
+		return s
+		`,
+		[]object.Value{object.String("11anil")},
+	},
+	{
+		`
+		local Base = {}
+		Base.__index = Base
+		function Base:name() return "base" end
+		local Class = setmetatable({}, Base)
+		Class.__index = Class
+		local obj = setmetatable({}, Class)
+		local s = ""
+		for i = 1, 7 do
+			s = s .. obj:name() .. " "
+			if i == 1 then function Class:name() return "class" end end
+			if i == 2 then obj.name = function() return "obj" end end
+			if i == 3 then rawset(obj, "name", nil) end
+			if i == 4 then Class.name = nil; Base.__index = {name = function() return "other" end} end
+			if i == 5 then Base.__index = function(t, k) return function() return "func" end end end
+			if i == 6 then setmetatable(obj, nil); obj.name = function() return "plain" end end
+		end
+		return s
+		`,
+		[]object.Value{object.String("base class obj class other func plain ")},
+	},
+	{
+		`
+		local function f(n)
+			if n == 0 then return 0 end
+			local _, v = pcall(f, n - 1)
+			return v + 1
+		end
+		local t = setmetatable({}, {__index = function(t, n)
+			if n == 0 then return 0 end
+			return t[n - 1] + 1
+		end})
+		return f(10000), t[10000], pcall(pcall, error, "x")
+		`,
+		[]object.Value{object.Integer(10000), object.Integer(10000), object.True, object.False, object.String("x")},
+	},
+	{
+		`
+		local mt = {}
+		mt.__lt = function(x, y) return x.v < y.v end
+		mt.__le = nil
+		mt.__concat = function(x, y)
+			if type(x) == "table" then x = x.v end
+			if type(y) == "table" then y = y.v end
+			return x .. y
+		end
+		mt.__eq = function(x, y) return x.v == y.v end
+		local a = setmetatable({v = 1}, mt)
+		local b = setmetatable({v = 2}, mt)
+		local c = setmetatable({v = 1}, mt)
+		return a < b, a <= b, b <= a, a == c, a ~= b, "x" .. a .. b .. "y"
+		`,
+		[]object.Value{object.True, object.True, object.False, object.True, object.True, object.String("x12y")},
+	},
 }
 
 func TestExec(t *testing.T) {
@@ -132,21 +208,25 @@ func TestExec(t *testing.T) {
 			t.Fatalf("%d: %v", i+1, err)
 		}
 
-		p := runtime.NewProcess()
+		for _, backend := range backends {
+			p := runtime.NewProcess()
 
-		p.Require("", stdlib.Open)
+			p.Require("", stdlib.Open)
 
-		rets, err := p.Exec(proto)
-		if err != nil {
-			t.Fatal(err)
-		}
+			runtime.SetBackend(p, backend)
+
+			rets, err := p.Exec(proto)
+			if err != nil {
+				t.Fatalf("%v: %v", backend, err)
+			}
 
-		if len(rets) != len(test.Rets) {
-			t.Errorf("expected %v, got %v", test.Rets, rets)
-		} else {
-			for i := range rets {
-				if !object.Equal(rets[i], test.Rets[i]) {
-					t.Errorf("code: %s, expected %v, got %v", test.Code, test.Rets[i], rets[i])
+			if len(rets) != len(test.Rets) {
+				t.Errorf("%v: expected %v, got %v", backend, test.Rets, rets)
+			} else {
+				for i := range rets {
+					if !object.Equal(rets[i], test.Rets[i]) {
+						t.Errorf("%v: code: %s, expected %v, got %v", backend, test.Code, test.Rets[i], rets[i])
+					}
 				}
 			}
 		}
@@ -192,3 +272,455 @@ func TestExecError(t *testing.T) {
 		}
 	}
 }
+
+var testErrorPosition = []struct {
+	Code string
+
+	Msg string
+}{
+	{"local t = {}\nreturn t.x.y", "test_code:2:11: attempt to index a nil value"},
+	{"local t = {}\nreturn t[1][2]", "test_code:2:12: attempt to index a nil value"},
+	{"local t = {}\nreturn 1 + t", "test_code:2:10: attempt to perform arithmetic on a table value"},
+	{"local t = {}\nreturn -t", "test_code:2:8: attempt to negate a table value"},
+	{"local t = {}\nreturn 'a' .. t", "test_code:2:12: attempt to concatenate a table value"},
+	{"local t = {}\nreturn 1 < t", "test_code:2:10: attempt to compare number with table"},
+	{"local t = {}\nt:m()", "test_code:2:4: attempt to call a nil value"},
+	{"local t = {}\nt.a.b, t.c = 1, 2", "test_code:2:4: attempt to index a nil value"},
+	{"local t = {}\nt.c, t.a.b = 1, 2", "test_code:2:9: attempt to index a nil value"},
+	{"local function f()\n  error('x')\nend\nf()", "test_code:2:8: x"},
+	{"for i = 1, 'x' do end", "test_code:1:1: 'for' limit value must be a number"},
+	{"local t = setmetatable({}, {__index = function() return 1 + {} end})\nreturn t.x", "test_code:1:59: attempt to perform arithmetic on a table value"},
+}
+
+func TestErrorPosition(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	for _, test := range testErrorPosition {
+		proto, err := c.Compile(strings.NewReader(test.Code), "=test_code", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, backend := range backends {
+			p := runtime.NewProcess()
+
+			p.Require("", stdlib.Open)
+
+			runtime.SetBackend(p, backend)
+
+			_, err = p.Exec(proto)
+			if err == nil {
+				t.Errorf("%v: code: %q: expected err, got nil", backend, test.Code)
+
+				continue
+			}
+
+			oerr, ok := err.(*object.RuntimeError)
+			if !ok {
+				t.Fatalf("expected *object.RuntimeError, got %T: %v", err, err)
+			}
+
+			if msg := oerr.Value(); msg != object.String(test.Msg) {
+				t.Errorf("%v: code: %q: expected %q, got %q", backend, test.Code, test.Msg, msg)
+			}
+		}
+	}
+}
+
+var testLimits = []struct {
+	Code   string
+	Limits runtime.Limits
+
+	Msg string
+}{
+	{
+		"local function f(n) return 1 + f(n + 1) end\nreturn f(1)",
+		runtime.Limits{StackSize: 1000, GoDepth: 200},
+		"test_code:1:32: stack overflow",
+	},
+	{
+		"local function f(n) table.sort({1, 2}, function() return f(n + 1) end) end\nf(1)",
+		runtime.Limits{StackSize: 1000000, GoDepth: 10},
+		"Go stack overflow",
+	},
+}
+
+func TestLimits(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	for _, test := range testLimits {
+		proto, err := c.Compile(strings.NewReader(test.Code), "=test_code", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, backend := range backends {
+			p := runtime.NewProcess()
+
+			p.Require("", stdlib.Open)
+
+			runtime.SetBackend(p, backend)
+
+			err = runtime.SetLimits(p, test.Limits)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = p.Exec(proto)
+			if err == nil {
+				t.Errorf("%v: code: %q: expected err, got nil", backend, test.Code)
+
+				continue
+			}
+
+			msg := err.(*object.RuntimeError).Value()
+			if s, _ := object.ToGoString(msg); !strings.HasSuffix(s, test.Msg) {
+				t.Errorf("%v: code: %q: expected %q, got %q", backend, test.Code, test.Msg, msg)
+			}
+		}
+	}
+
+	err := runtime.SetLimits(runtime.NewProcess(), runtime.Limits{StackSize: 1, GoDepth: 1})
+	if err == nil {
+		t.Error("expected err, got nil")
+	}
+}
+
+// TestLimitsFork tests that limits of forked processes don't affect others.
+func TestLimitsFork(t *testing.T) {
+	code := `
+local n = 50
+local mt = {}
+function mt.__tostring()
+  if n > 0 then
+    n = n - 1
+    tostring(setmetatable({}, mt))
+  end
+  return ""
+end
+tostring(setmetatable({}, mt))
+`
+
+	proto, err := compiler.NewCompiler().Compile(strings.NewReader(code), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := runtime.NewProcess()
+
+	p.Require("", stdlib.Open)
+
+	fork := p.Fork()
+
+	err = runtime.SetLimits(fork, runtime.Limits{StackSize: 1000000, GoDepth: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = fork.Exec(proto)
+	if err == nil {
+		t.Error("expected err, got nil")
+	}
+
+	_, err = p.Exec(proto)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, err = p.Fork().Exec(proto)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+// TestCompileOptions tests that load uses the cache of the process.
+func TestCompileOptions(t *testing.T) {
+	proto, err := compiler.NewCompiler().Compile(strings.NewReader(`return load("return 1"), load("return 1")`), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := runtime.NewProcess()
+
+	p.Require("", stdlib.Open)
+
+	err = runtime.SetCompileOptions(p, runtime.CompileOptions{Cache: cache.New("")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fork := p.Fork()
+
+	err = runtime.SetCompileOptions(fork, runtime.CompileOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		p      object.Process
+		shared bool
+	}{
+		{p, true},
+		{fork, false},
+		{p.Fork(), true},
+	} {
+		rets, err := test.p.Exec(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		shared := rets[0].(object.Closure).Prototype() == rets[1].(object.Closure).Prototype()
+		if shared != test.shared {
+			t.Errorf("expected shared %v, got %v", test.shared, shared)
+		}
+	}
+}
+
+var testInlineCacheLoad = `
+local t = {x = 1}
+for i = 1, 20000 do
+  assert(load("local t = ... return t.x + " .. i)(t) == i + 1)
+end
+`
+
+// TestInlineCacheLoad tests that inline caches of loaded functions don't accumulate.
+func TestInlineCacheLoad(t *testing.T) {
+	proto, err := compiler.NewCompiler().Compile(strings.NewReader(testInlineCacheLoad), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, backend := range backends {
+		p := runtime.NewProcess()
+
+		p.Require("", stdlib.Open)
+
+		runtime.SetBackend(p, backend)
+
+		_, err = p.Exec(proto)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n := runtime.InlineCaches(p); n > 1<<16 {
+			t.Errorf("%v: expected bounded inline caches, got %d", backend, n)
+		}
+	}
+}
+
+var testBackendHook = `
+local events = {}
+debug.sethook(function(event, line)
+  events[#events + 1] = event .. ":" .. tostring(line)
+end, "crl", 3)
+local function f(n)
+  local t = {}
+  for i = 1, n do
+    if i % 2 == 0 then
+      t[#t + 1] = i
+    else
+      t[#t + 1] = -i
+    end
+  end
+  return t
+end
+local t = f(5)
+local ok = pcall(function() return t.x.y end)
+debug.sethook()
+return table.concat(events, " "), ok
+`
+
+// TestBackendHook tests that hooks see the same events with each backend.
+func TestBackendHook(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	proto, err := c.Compile(strings.NewReader(testBackendHook), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []object.Value
+
+	for _, backend := range backends {
+		p := runtime.NewProcess()
+
+		p.Require("", stdlib.Open)
+
+		runtime.SetBackend(p, backend)
+
+		rets, err := p.Exec(proto)
+		if err != nil {
+			t.Fatalf("%v: %v", backend, err)
+		}
+
+		if want == nil {
+			if len(rets) != 2 || rets[0] == object.String("") {
+				t.Fatalf("expected hook events, got %v", rets)
+			}
+
+			want = rets
+
+			continue
+		}
+
+		if len(rets) != len(want) || !object.Equal(rets[0], want[0]) || !object.Equal(rets[1], want[1]) {
+			t.Errorf("%v: expected %v, got %v", backend, want, rets)
+		}
+	}
+}
+
+func TestResumeFromGo(t *testing.T) {
+	c := compiler.NewCompiler()
+
+	proto, err := c.Compile(strings.NewReader(`
+	local co = coroutine.wrap(function(a)
+		local b = coroutine.yield(a + 1)
+		return b * 2
+	end)
+	return f(co)
+	`), "=test_code", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := runtime.NewProcess()
+
+	p.Require("", stdlib.Open)
+
+	// the Go function keeps the values yielded by the first call during the second one.
+	p.Globals().Set(object.String("f"), object.GoFunction(func(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
+		rets1, err := th.Call(args[0], object.Integer(1))
+		if err != nil {
+			return nil, err
+		}
+
+		rets2, err := th.Call(args[0], object.Integer(10))
+		if err != nil {
+			return nil, err
+		}
+
+		return append(rets1, rets2...), nil
+	}))
+
+	rets, err := p.Exec(proto)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rets) != 2 || rets[0] != object.Integer(2) || rets[1] != object.Integer(20) {
+		t.Errorf("expected [2 20], got %v", rets)
+	}
+}
+
+var benchExec = []struct {
+	Name string
+	Code string
+}{
+	{"IntegerLoop", `
+	local sum = 0
+	for i = 1, 100000 do
+		sum = sum + i % 7 * 3 - 1
+	end
+	return sum
+	`},
+	{"FloatLoop", `
+	local x, y = 0.5, 0.25
+	for i = 1, 100000 do
+		x, y = x * y + 0.125, y / (x + 1.5)
+	end
+	return x, y
+	`},
+	{"Fib", `
+	local function fib(n)
+		if n < 2 then
+			return n
+		end
+		return fib(n-1) + fib(n-2)
+	end
+	return fib(20)
+	`},
+	{"TableArray", `
+	local t = {}
+	for i = 1, 10000 do
+		t[i] = i * 2
+	end
+	local sum = 0
+	for i = 1, #t do
+		sum = sum + t[i]
+	end
+	return sum
+	`},
+	{"Comparison", `
+	local n = 0
+	for i = 1, 100000 do
+		if i < 50000 == (i % 2 == 0) then
+			n = n + 1
+		end
+	end
+	return n
+	`},
+	{"GlobalAccess", `
+	x, y = 1, 2
+	local n = 0
+	for i = 1, 100000 do
+		n = n + x * y
+	end
+	return n
+	`},
+	{"MethodCall", `
+	local Base = {}
+	Base.__index = Base
+	function Base:get() return self.v end
+	local Class = setmetatable({}, Base)
+	Class.__index = Class
+	local obj = setmetatable({v = 1}, Class)
+	local n = 0
+	for i = 1, 100000 do
+		n = n + obj:get()
+	end
+	return n
+	`},
+	{"Closures", `
+	local n = 0
+	for i = 1, 100000 do
+		local f = function() return i end
+		n = n + f()
+	end
+	return n
+	`},
+}
+
+func BenchmarkExec(b *testing.B) {
+	c := compiler.NewCompiler()
+
+	for _, bench := range benchExec {
+		proto, err := c.Compile(strings.NewReader(bench.Code), "=bench", 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		for _, backend := range []runtime.Backend{runtime.Interpreter, runtime.ClosureCompiler} {
+			backend := backend
+
+			b.Run(bench.Name+"/"+backend.String(), func(b *testing.B) {
+				b.ReportAllocs()
+
+				for i := 0; i < b.N; i++ {
+					b.StopTimer()
+
+					p := runtime.NewProcess()
+
+					p.Require("", stdlib.Open)
+
+					runtime.SetBackend(p, backend)
+
+					b.StartTimer()
+
+					if _, err := p.Exec(proto); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package runtime
 
 import (
+	"github.com/hirochachacha/plua/internal/arith"
 	"github.com/hirochachacha/plua/internal/tables"
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/object"
 )
 
@@ -20,3 +22,70 @@ func newConcurrentTableSize(asize, msize int) object.Table {
 func newLockedTableSize(asize, msize int) object.Table {
 	return tables.NewLockedTableSize(asize, msize)
 }
+
+// valueTable is implemented by tables which can store tagged values without boxing.
+type valueTable interface {
+	object.Table
+
+	GetValue(key tvalue.Value) tvalue.Value
+	SetValue(key, val tvalue.Value)
+	SetListValue(base int, src []tvalue.Value)
+}
+
+// gettable stores t[key] to R(a).
+func (th *thread) gettable(a int, t, key tvalue.Value) *object.RuntimeError {
+	if tab, ok := t.V.(valueTable); ok {
+		val := tab.GetValue(key)
+		if !val.IsNil() || tab.Metatable() == nil {
+			th.setR(a, val)
+
+			return nil
+		}
+	}
+
+	val, tm, self, err := arith.GettableTM(th, t.Box(), key.Box())
+	if err != nil {
+		return err
+	}
+
+	if tm != nil {
+		var done bool
+
+		val, done, err = th.calltm(tm, self, key.Box())
+		if err != nil || !done {
+			return err
+		}
+	}
+
+	th.setR(a, tvalue.Of(val))
+
+	return nil
+}
+
+func (th *thread) settable(t, key, val tvalue.Value) *object.RuntimeError {
+	if tab, ok := t.V.(valueTable); ok && isPlainKey(key) {
+		if tab.Metatable() == nil || !tab.GetValue(key).IsNil() {
+			tab.SetValue(key, val)
+
+			return nil
+		}
+	}
+
+	tm, self, err := arith.SettableTM(th, t.Box(), key.Box(), val.Box())
+	if err != nil || tm == nil {
+		return err
+	}
+
+	_, _, err = th.calltm(tm, self, key.Box(), val.Box())
+
+	return err
+}
+
+// isPlainKey reports whether key can be stored without normalization or errors.
+func isPlainKey(key tvalue.Value) bool {
+	if key.Kind == tvalue.IntKind {
+		return true
+	}
+	_, ok := key.V.(object.String)
+	return ok
+}
@@ -17,8 +17,9 @@ const (
 type thread struct {
 	*context
 
-	env *environment
-	typ threadType
+	env      *environment
+	settings *settings
+	typ      threadType
 
 	resume chan []object.Value
 	yield  chan []object.Value
@@ -29,7 +30,13 @@ type thread struct {
 	hookCount int
 	lastLine  int
 
-	depth int
+	depth     int
+	stackSize int // total length of stacks of contexts, see growStack
+
+	proot *profNode // for ProfileCount
+
+	icaches  map[*object.Proto][]inlineCache // inline caches by function
+	nicaches int                             // total length of icaches, see maxInlineCaches
 }
 
 func (th *thread) Type() object.Type {
@@ -51,7 +58,8 @@ func (th *thread) Yield(args ...object.Value) (rets []object.Value, err *object.
 
 		th.status = object.THREAD_SUSPENDED
 
-		th.yield <- args
+		// args may be on the gostack of th, which is reused after this returns
+		th.yield <- dupValues(args)
 
 		rets = <-th.resume
 
@@ -72,7 +80,8 @@ func (th *thread) Resume(args ...object.Value) (rets []object.Value, err *object
 			return nil, object.NewRuntimeError("cannot resume dead coroutine")
 		}
 
-		th.resume <- args
+		// args may be on the gostack of the caller, which is reused after this returns
+		th.resume <- dupValues(args)
 
 		rets, ok := <-th.yield
 		if !ok {
@@ -90,7 +99,7 @@ func (th *thread) Resume(args ...object.Value) (rets []object.Value, err *object
 			return nil, object.NewRuntimeError("goroutine is already resumed")
 		}
 
-		th.resume <- args
+		th.resume <- dupValues(args)
 	default:
 		panic("unreachable")
 	}
@@ -111,11 +120,11 @@ func (th *thread) Status() object.ThreadStatus {
 }
 
 func (th *thread) NewThread() object.Thread {
-	return th.newThreadWith(threadCo, th.env, 0)
+	return th.newThreadWith(threadCo, th.env, th.settings, 0)
 }
 
 func (th *thread) NewGoThread() object.Thread {
-	return th.newThreadWith(threadGo, th.env, 0)
+	return th.newThreadWith(threadGo, th.env, th.settings, 0)
 }
 
 func (th *thread) NewTableSize(asize, msize int) object.Table {
@@ -236,17 +245,18 @@ func (th *thread) Call(fn object.Value, args ...object.Value) ([]object.Value, *
 	return th.docall(fn, args...)
 }
 
-func (th *thread) newThreadWith(typ threadType, env *environment, stackSize int) *thread {
+func (th *thread) newThreadWith(typ threadType, env *environment, settings *settings, stackSize int) *thread {
 	if stackSize < minStackSize {
 		stackSize = minStackSize
 	}
 
 	newth := &thread{
-		typ:    typ,
-		env:    env,
-		resume: make(chan []object.Value, 0),
-		yield:  make(chan []object.Value, 0),
-		depth:  th.depth,
+		typ:      typ,
+		env:      env,
+		settings: settings,
+		resume:   make(chan []object.Value, 0),
+		yield:    make(chan []object.Value, 0),
+		depth:    th.depth,
 	}
 
 	newth.pushContext(stackSize, false)
@@ -256,12 +266,13 @@ func (th *thread) newThreadWith(typ threadType, env *environment, stackSize int)
 	return newth
 }
 
-func newMainThread() *thread {
+func newMainThread(env *environment, settings *settings) *thread {
 	th := &thread{
-		typ:    threadMain,
-		env:    newEnvironment(),
-		resume: make(chan []object.Value, 0),
-		yield:  make(chan []object.Value, 0),
+		typ:      threadMain,
+		env:      env,
+		settings: settings,
+		resume:   make(chan []object.Value, 0),
+		yield:    make(chan []object.Value, 0),
 	}
 
 	th.pushContext(basicStackSize, false)
@@ -1,7 +1,11 @@
 package runtime
 
 import (
+	"reflect"
+
 	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/internal/pcall"
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/object"
 )
 
@@ -11,7 +15,7 @@ func (th *thread) call(a, nargs, nrets int) (err *object.RuntimeError) {
 
 	f := ctx.ci.base + a
 
-	fn := ctx.stack[f]
+	fn := ctx.stack[f].Box()
 
 	switch fn := fn.(type) {
 	case nil:
@@ -30,13 +34,13 @@ func (th *thread) call(a, nargs, nrets int) (err *object.RuntimeError) {
 
 	ctx.ci.top = f + 2 + nargs
 
-	if !ctx.growStack(ctx.ci.top) {
+	if !th.growStack(ctx.ci.top) {
 		return errors.StackOverflowError()
 	}
 
 	copy(ctx.stack[f+1:], ctx.stack[f:f+1+nargs])
 
-	ctx.stack[f] = tm
+	ctx.stack[f] = tvalue.Of(tm)
 
 	return th.call(a, nargs+1, nrets)
 }
@@ -45,6 +49,12 @@ func (th *thread) call(a, nargs, nrets int) (err *object.RuntimeError) {
 func (th *thread) callGo(fn object.GoFunction, f, nargs, nrets int, isTailCall bool) (err *object.RuntimeError) {
 	ctx := th.context
 
+	if nargs > 0 {
+		if cl, ok := ctx.stack[f+1].V.(*closure); ok && isPcall(fn) {
+			return th.pcallLua(cl, f, nargs, nrets, isTailCall)
+		}
+	}
+
 	ctx.pushFrame(callInfo{
 		nrets:      nrets,
 		isTailCall: isTailCall,
@@ -65,7 +75,13 @@ func (th *thread) callGo(fn object.GoFunction, f, nargs, nrets int, isTailCall b
 		}
 	}
 
-	rets, err := fn(th, ctx.stack[ctx.ci.base:ctx.ci.top]...)
+	mark := len(ctx.gostack)
+
+	rets, err := fn(th, ctx.gopush(ctx.stack[ctx.ci.base:ctx.ci.top])...)
+
+	// rets may share memory with the arguments
+	defer ctx.gopop(mark)
+
 	if err != nil {
 		return err
 	}
@@ -80,15 +96,15 @@ func (th *thread) callGo(fn object.GoFunction, f, nargs, nrets int, isTailCall b
 
 	top := ctx.ci.base - 1 + len(rets)
 
-	if !ctx.growStack(top) {
+	if !th.growStack(top) {
 		return errors.StackOverflowError()
 	}
 
-	copy(ctx.stack[ctx.ci.base-1:], rets)
+	tvalue.Unbox(ctx.stack[ctx.ci.base-1:], rets)
 
 	// clear unused stack
 	for r := ctx.ci.base - 1 + nrets; r >= top; r-- {
-		ctx.stack[r] = nil
+		ctx.stack[r] = tvalue.Nil
 	}
 
 	ctx.popFrame()
@@ -114,7 +130,7 @@ func (th *thread) callLua(c object.Closure, f, nargs, nrets int) (err *object.Ru
 
 	ci := ctx.ci
 
-	if !ctx.growStack(ci.top) {
+	if !th.growStack(ci.top) {
 		return errors.StackOverflowError()
 	}
 
@@ -125,11 +141,11 @@ func (th *thread) callLua(c object.Closure, f, nargs, nrets int) (err *object.Ru
 			ci.varargs = dup(ctx.stack[ci.base+cl.NParams : ci.base+nargs])
 		}
 		for r := ci.base + nargs - 1; r >= ci.base+cl.NParams; r-- {
-			ctx.stack[r] = nil
+			ctx.stack[r] = tvalue.Nil
 		}
 	} else {
 		for r := ci.base + cl.NParams - 1; r >= ci.base+nargs; r-- {
-			ctx.stack[r] = nil
+			ctx.stack[r] = tvalue.Nil
 		}
 	}
 
@@ -140,6 +156,40 @@ func (th *thread) callLua(c object.Closure, f, nargs, nrets int) (err *object.Ru
 	return nil
 }
 
+// call a closure by pcall at f, in the current context.
+// the frame of pcall is protected, errors are caught by recover instead of exiting the VM.
+func (th *thread) pcallLua(cl *closure, f, nargs, nrets int, isTailCall bool) (err *object.RuntimeError) {
+	ctx := th.context
+
+	ctx.pushFrame(callInfo{
+		nrets:       nrets,
+		isTailCall:  isTailCall,
+		isProtected: true,
+		base:        f + 1,
+		top:         f + 1 + nargs,
+
+		// fake infos
+		pc: -1,
+	})
+
+	if isTailCall {
+		if err := th.onTailCall(); err != nil {
+			return err
+		}
+	} else {
+		if err := th.onCall(); err != nil {
+			return err
+		}
+	}
+
+	// true is prepended to the results, see returnLua
+	if nrets > 0 {
+		nrets--
+	}
+
+	return th.callLua(cl, f+1, nargs-1, nrets)
+}
+
 // tail call a callable by stack index.
 func (th *thread) tailcall(a, nargs int) (err *object.RuntimeError) {
 	ctx := th.context
@@ -148,9 +198,9 @@ func (th *thread) tailcall(a, nargs int) (err *object.RuntimeError) {
 
 	f := ctx.ci.base + a
 
-	fn := ctx.stack[f]
+	fn := ctx.stack[f].Box()
 
-	switch fn := ctx.stack[f].(type) {
+	switch fn := fn.(type) {
 	case nil:
 		return errors.CallError(th, fn)
 	case object.GoFunction:
@@ -167,13 +217,13 @@ func (th *thread) tailcall(a, nargs int) (err *object.RuntimeError) {
 
 	ctx.ci.top = f + 2 + nargs
 
-	if !ctx.growStack(ctx.ci.top) {
+	if !th.growStack(ctx.ci.top) {
 		return errors.StackOverflowError()
 	}
 
 	copy(ctx.stack[f+1:], ctx.stack[f:f+1+nargs])
 
-	ctx.stack[f] = tm
+	ctx.stack[f] = tvalue.Of(tm)
 
 	return th.tailcall(a, nargs+1)
 }
@@ -190,15 +240,20 @@ func (th *thread) tailcallLua(c object.Closure, f, nargs int) (err *object.Runti
 	ci.top = ci.base + cl.MaxStackSize
 	ci.closure = cl
 	ci.isTailCall = true
+	ci.icache = nil
+	ci.ccode = nil
+	ci.pnode = nil
+	ci.cproto = nil
+	ci.cbranch = 0
 
-	if !ctx.growStack(ci.top) {
+	if !th.growStack(ci.top) {
 		return errors.StackOverflowError()
 	}
 
 	copy(ctx.stack[ci.base-1:], ctx.stack[f:f+1+nargs])
 
 	for r := f + nargs; r >= ci.base+nargs; r-- {
-		ctx.stack[r] = nil
+		ctx.stack[r] = tvalue.Nil
 	}
 
 	ci.varargs = nil
@@ -208,11 +263,11 @@ func (th *thread) tailcallLua(c object.Closure, f, nargs int) (err *object.Runti
 			ci.varargs = dup(ctx.stack[ci.base+cl.NParams : ci.base+nargs])
 		}
 		for r := ci.base + nargs - 1; r >= ci.base+cl.NParams; r-- {
-			ctx.stack[r] = nil
+			ctx.stack[r] = tvalue.Nil
 		}
 	} else {
 		for r := ci.base + cl.NParams - 1; r >= ci.base+nargs; r-- {
-			ctx.stack[r] = nil
+			ctx.stack[r] = tvalue.Nil
 		}
 	}
 
@@ -229,7 +284,7 @@ func (th *thread) tforcall(a, nrets int) (err *object.RuntimeError) {
 
 	f := ctx.ci.base + a
 
-	fn := ctx.stack[f]
+	fn := ctx.stack[f].Box()
 
 	switch fn := fn.(type) {
 	case nil:
@@ -239,31 +294,9 @@ func (th *thread) tforcall(a, nrets int) (err *object.RuntimeError) {
 
 		return th.callGo(fn, f+3, 2, nrets, false)
 	case object.Closure:
-		args := ctx.stack[f+1 : f+3]
-
-		rets, err := th.docallLua(fn, args...)
-		if err != nil {
-			return err
-		}
-
-		if nrets != -1 && nrets < len(rets) {
-			rets = rets[:nrets]
-		}
-
-		ctx.ci.top = f + 3 + nrets
-
-		if !ctx.growStack(ctx.ci.top) {
-			return errors.StackOverflowError()
-		}
-
-		copy(ctx.stack[f+3:], rets)
-
-		// clear unused stack
-		for r := f + 3 + nrets; r >= f+3+len(rets); r-- {
-			ctx.stack[r] = nil
-		}
+		copy(ctx.stack[f+3:], ctx.stack[f:f+3])
 
-		return nil
+		return th.callLua(fn, f+3, 2, nrets)
 	}
 
 	tm := th.gettmbyobj(fn, object.TM_CALL)
@@ -274,19 +307,21 @@ func (th *thread) tforcall(a, nrets int) (err *object.RuntimeError) {
 
 	ctx.ci.top = f + 4
 
-	if !ctx.growStack(ctx.ci.top) {
+	if !th.growStack(ctx.ci.top) {
 		return errors.StackOverflowError()
 	}
 
 	copy(ctx.stack[f+1:], ctx.stack[f:f+3])
 
-	ctx.stack[f] = tm
+	ctx.stack[f] = tvalue.Of(tm)
 
 	return th.tforcall(a, nrets)
 }
 
-func (th *thread) returnLua(a, nrets int) (rets []object.Value, exit bool) {
+func (th *thread) returnLua(a, nrets int) (rets []tvalue.Value, exit bool) {
 	if err := th.onReturn(); err != nil {
+		th.error(err)
+
 		return nil, true
 	}
 
@@ -313,14 +348,38 @@ func (th *thread) returnLua(a, nrets int) (rets []object.Value, exit bool) {
 
 	// clear unused stack
 	for r := ctx.ci.base - 1 + ctx.ci.nrets; r >= top; r-- {
-		ctx.stack[r] = nil
+		ctx.stack[r] = tvalue.Nil
 	}
 
+	f := ctx.ci.base - 1
+	isMeta, negate, concatAt := ctx.ci.isMeta, ctx.ci.negate, ctx.ci.concatAt
+
 	ctx.popFrame()
 
 	// adjust top
 	ctx.ci.top = top
 
+	switch {
+	case ctx.ci.isProtected:
+		if err := th.onReturn(); err != nil {
+			th.error(err)
+
+			return nil, true
+		}
+
+		ctx.stack[f-1] = tvalue.Boolean(true)
+
+		ctx.popFrame()
+
+		ctx.ci.top = top
+	case isMeta:
+		if err := th.finishOp(ctx.stack[f], negate, concatAt); err != nil {
+			th.error(err)
+
+			return nil, true
+		}
+	}
+
 	return nil, false
 }
 
@@ -334,10 +393,25 @@ func (th *thread) docall(fn object.Value, args ...object.Value) (rets []object.V
 
 		return nil, err
 	case object.GoFunction:
+		// see Limits.GoDepth
+		th.depth++
+
+		if th.depth > th.settings.limits.GoDepth {
+			th.depth--
+
+			err := errors.GoStackOverflowError()
+
+			th.trackError(err)
+
+			return nil, err
+		}
+
 		old := th.stack[1]
 
 		rets, err := th.docallGo(fn, args...)
 
+		th.depth--
+
 		if err != nil {
 			th.trackErrorOnce(err)
 
@@ -376,7 +450,7 @@ func (th *thread) docallGo(fn object.GoFunction, args ...object.Value) (rets []o
 		pc:   -1,
 	})
 
-	ctx.stack[1] = fn
+	ctx.stack[1] = tvalue.Of(fn)
 
 	if err := th.onCall(); err != nil {
 		return nil, err
@@ -398,7 +472,11 @@ func (th *thread) docallGo(fn object.GoFunction, args ...object.Value) (rets []o
 
 // call a closure by values, return values immediately.
 func (th *thread) docallLua(c object.Closure, args ...object.Value) (rets []object.Value, err *object.RuntimeError) {
-	return th.doExecute(c, args, false)
+	trets, err := th.doExecute(c, tvalue.Unbox(make([]tvalue.Value, len(args)), args), false)
+	if err != nil {
+		return nil, err
+	}
+	return box(trets), nil
 }
 
 func (th *thread) gettmbyobj(val object.Value, tag object.Value) object.Value {
@@ -409,3 +487,49 @@ func (th *thread) gettmbyobj(val object.Value, tag object.Value) object.Value {
 
 	return mt.Get(tag)
 }
+
+// call a metamethod for the current instruction, return the first result immediately if done.
+// Lua functions are called in new frames of the current context instead of nesting the VM,
+// then done is false, and finishOp completes the instruction when they return.
+func (th *thread) calltm(tm object.Value, args ...object.Value) (val object.Value, done bool, err *object.RuntimeError) {
+	cl, ok := tm.(*closure)
+	if !ok {
+		rets, err := th.Call(tm, args...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if len(rets) > 0 {
+			val = rets[0]
+		}
+
+		return val, true, nil
+	}
+
+	ctx := th.context
+
+	// above registers of the current function
+	f := ctx.ci.base + ctx.ci.MaxStackSize
+
+	if !th.growStack(f + 1 + len(args)) {
+		return nil, false, errors.StackOverflowError()
+	}
+
+	ctx.stack[f] = tvalue.Of(cl)
+
+	tvalue.Unbox(ctx.stack[f+1:], args)
+
+	if err := th.callLua(cl, f, len(args), 1); err != nil {
+		return nil, false, err
+	}
+
+	ctx.ci.isMeta = true
+
+	return nil, false, nil
+}
+
+var pcallPtr = reflect.ValueOf(pcall.Call).Pointer()
+
+func isPcall(fn object.GoFunction) bool {
+	return reflect.ValueOf(fn).Pointer() == pcallPtr
+}
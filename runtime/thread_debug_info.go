@@ -1,6 +1,7 @@
 package runtime
 
 import (
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/internal/util"
 	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/object"
@@ -35,6 +36,7 @@ func (th *thread) getInfo(level int, what string) *object.DebugInfo {
 			setFuncInfo(d, cl)
 		case 'l':
 			d.CurrentLine = getCurrentLine(ci)
+			d.CurrentColumn = getCurrentColumn(ci)
 		case 'u':
 			setUpInfo(d, cl)
 		case 't':
@@ -95,6 +97,7 @@ func (th *thread) getInfoByFunc(fn object.Value, what string) *object.DebugInfo
 			setFuncInfo(d, cl)
 		case 'l':
 			d.CurrentLine = -1
+			d.CurrentColumn = -1
 		case 'u':
 			setUpInfo(d, cl)
 		case 'L':
@@ -137,7 +140,7 @@ func (th *thread) setLocal(level, n int, val object.Value) (name string) {
 		if n < 0 {
 			if -n <= len(ci.varargs) {
 				name = "(*vararg)"
-				ci.varargs[-n-1] = val
+				ci.varargs[-n-1] = tvalue.Of(val)
 			}
 
 			return
@@ -148,14 +151,14 @@ func (th *thread) setLocal(level, n int, val object.Value) (name string) {
 		if i+1 < len(ctx.ciStack) {
 			next := &ctx.ciStack[i+1]
 			if ci.base-1+n <= next.base-1 {
-				ctx.stack[ci.base-1+n] = val
+				ctx.stack[ci.base-1+n] = tvalue.Of(val)
 				if name == "" {
 					name = "(*temporary)"
 				}
 			}
 		} else {
 			if ci.base-1+n <= ci.top {
-				ctx.stack[ci.base-1+n] = val
+				ctx.stack[ci.base-1+n] = tvalue.Of(val)
 				if name == "" {
 					name = "(*temporary)"
 				}
@@ -191,7 +194,7 @@ func (th *thread) getLocal(level, n int) (name string, val object.Value) {
 	if !ci.isGoFunction() {
 		if n < 0 {
 			if -n <= len(ci.varargs) {
-				name, val = "(*vararg)", ci.varargs[-n-1]
+				name, val = "(*vararg)", ci.varargs[-n-1].Box()
 			}
 
 			return
@@ -202,14 +205,14 @@ func (th *thread) getLocal(level, n int) (name string, val object.Value) {
 		if i+1 < len(ctx.ciStack) {
 			next := &ctx.ciStack[i+1]
 			if ci.base-1+n <= next.base-1 {
-				val = ctx.stack[ci.base-1+n]
+				val = ctx.stack[ci.base-1+n].Box()
 				if name == "" {
 					name = "(*temporary)"
 				}
 			}
 		} else {
 			if ci.base-1+n <= ci.top {
-				val = ctx.stack[ci.base-1+n]
+				val = ctx.stack[ci.base-1+n].Box()
 				if name == "" {
 					name = "(*temporary)"
 				}
@@ -299,6 +302,19 @@ func getCurrentLine(ci *callInfo) int {
 	return ci.LineInfo[ci.pc-1]
 }
 
+func getCurrentColumn(ci *callInfo) int {
+	if ci == nil || ci.isGoFunction() {
+		return -1
+	}
+	if len(ci.ColumnInfo) == 0 {
+		return -1
+	}
+	if ci.pc == 0 {
+		return ci.ColumnInfo[0]
+	}
+	return ci.ColumnInfo[ci.pc-1]
+}
+
 func getObjectName(p *object.Proto, pc, reg int) (name, nameWhat string) {
 	name = getLocalName(p, pc, reg+1)
 	if len(name) != 0 {
@@ -25,6 +25,13 @@ func (th *thread) trackError(err *object.RuntimeError) {
 func (th *thread) error(err *object.RuntimeError) {
 	if th.status != object.THREAD_ERROR {
 		th.trackError(err)
+
+		// errors raised by instructions are located at the running function, not its caller.
+		if msg, ok := err.RawValue.(object.String); ok && err.Level == 1 && !th.ci.isGoFunction() && len(err.Traceback) > 0 {
+			err.RawValue = object.String(err.Traceback[0].Where()) + msg
+			err.Level = 0
+		}
+
 		th.status = object.THREAD_ERROR
 		th.err = err
 	}
@@ -5,28 +5,29 @@ import (
 
 	"github.com/hirochachacha/plua/internal/arith"
 	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/internal/tvalue"
 	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/opcode"
 )
 
-func (th *thread) initExecute(args []object.Value) (rets []object.Value, done bool) {
+func (th *thread) initExecute(args []tvalue.Value) (rets []tvalue.Value, done bool) {
 	ctx := th.context
 
-	switch fn := ctx.stack[ctx.ci.base-1].(type) {
+	switch fn := ctx.stack[ctx.ci.base-1].V.(type) {
 	case nil:
 		panic("main function isn't loaded yet")
 	case object.GoFunction:
-		var err *object.RuntimeError
-
 		old := th.stack[1]
 
-		rets, err = th.docallGo(fn, args...)
+		grets, err := th.docallGo(fn, box(args)...)
 
 		if err != nil {
 			th.error(err)
 		} else {
 			ctx.status = object.THREAD_RETURN
+
+			rets = tvalue.Unbox(make([]tvalue.Value, len(grets)), grets)
 		}
 
 		th.stack[1] = old
@@ -37,25 +38,27 @@ func (th *thread) initExecute(args []object.Value) (rets []object.Value, done bo
 
 		ci := &ctx.ciStack[0]
 		ci.closure = cl
+		ci.icache = nil
+		ci.ccode = nil
 		ci.top = ci.base + cl.MaxStackSize
 
-		if !ctx.growStack(ci.top) {
+		if !th.growStack(ci.top) {
 			th.error(errors.StackOverflowError())
 		}
 
 		if len(args) > cl.NParams {
 			if cl.IsVararg {
-				ci.varargs = args[cl.NParams:]
+				ci.varargs = dup(args[cl.NParams:])
 			} else {
 				ci.varargs = nil
 			}
 			for r := ci.base - 1 + len(args); r > ci.base-1+cl.NParams; r-- {
-				ctx.stack[r] = nil
+				ctx.stack[r] = tvalue.Nil
 			}
 			args = args[:cl.NParams]
 		} else {
 			for r := ci.base - 1 + cl.NParams; r > ci.base-1+len(args); r-- {
-				ctx.stack[r] = nil
+				ctx.stack[r] = tvalue.Nil
 			}
 		}
 		copy(ctx.stack[ci.base:], args)
@@ -67,12 +70,17 @@ func (th *thread) initExecute(args []object.Value) (rets []object.Value, done bo
 }
 
 func (th *thread) execute() {
+	args, ok := <-th.resume
+	if !ok { // see release
+		close(th.yield)
+
+		return
+	}
+
 	defer close(th.resume)
 	defer close(th.yield)
 
-	args := <-th.resume
-
-	rets, done := th.initExecute(args)
+	rets, done := th.initExecute(tvalue.Unbox(make([]tvalue.Value, len(args)), args))
 	if !done {
 		rets = th.execute0()
 	}
@@ -83,14 +91,14 @@ func (th *thread) execute() {
 
 	switch th.status {
 	case object.THREAD_RETURN:
-		th.yield <- rets
+		th.yield <- box(rets)
 	case object.THREAD_ERROR:
 	default:
 		panic("unexpected")
 	}
 }
 
-func (th *thread) doExecute(fn object.Value, args []object.Value, isHook bool) (rets []object.Value, err *object.RuntimeError) {
+func (th *thread) doExecute(fn object.Value, args []tvalue.Value, isHook bool) (rets []tvalue.Value, err *object.RuntimeError) {
 	th.pushContext(basicStackSize, isHook)
 
 	th.loadfn(fn)
@@ -117,9 +125,9 @@ func (th *thread) doExecute(fn object.Value, args []object.Value, isHook bool) (
 }
 
 // execute with current context
-func (th *thread) execute0() (rets []object.Value) {
-	if th.depth >= version.MAX_VM_RECURSION {
-		th.error(errors.StackOverflowError())
+func (th *thread) execute0() (rets []tvalue.Value) {
+	if th.depth > th.settings.limits.GoDepth {
+		th.error(errors.GoStackOverflowError())
 
 		return nil
 	}
@@ -128,11 +136,74 @@ func (th *thread) execute0() (rets []object.Value) {
 
 	ctx.status = object.THREAD_RUNNING
 
+	backend := th.settings.backend
+
+	for {
+		if backend == ClosureCompiler {
+			rets = th.executeCompiled()
+		} else {
+			rets = th.interpret()
+		}
+
+		if ctx.status != object.THREAD_ERROR || !th.recover() {
+			return rets
+		}
+	}
+}
+
+// recover catches the error of the current context by the innermost protected frame,
+// as if pcall returned.
+func (th *thread) recover() bool {
+	ctx := th.context
+
+	for i := len(ctx.ciStack) - 1; i > 0; i-- {
+		ci := &ctx.ciStack[i]
+
+		if !ci.isProtected {
+			continue
+		}
+
+		th.closeUpvals(ci.base) // closing upvalues
+
+		ctx.ciStack = ctx.ciStack[:i+1]
+		ctx.ci = ci
+
+		f := ci.base - 1
+
+		ctx.stack[f] = tvalue.Boolean(false)
+		ctx.stack[f+1] = tvalue.Of(ctx.err.Value())
+
+		// clear unused stack
+		for r := f + ci.nrets; r >= f+2; r-- {
+			ctx.stack[r] = tvalue.Nil
+		}
+
+		ctx.popFrame()
+
+		// adjust top
+		ctx.ci.top = f + 2
+
+		ctx.status = object.THREAD_RUNNING
+		ctx.err = nil
+
+		return true
+	}
+
+	return false
+}
+
+// execute instructions of the current context.
+func (th *thread) interpret() (rets []tvalue.Value) {
+	ctx := th.context
+
 	var inst opcode.Instruction
 
-	ci := ctx.ci
+	var ci *callInfo
 
 	for {
+		// instructions may push frames, see calltm
+		ci = ctx.ci
+
 		inst = ci.Code[ci.pc]
 
 		if err := th.onInstruction(); err != nil {
@@ -143,7 +214,7 @@ func (th *thread) execute0() (rets []object.Value) {
 
 		ci.pc++
 
-		switch inst.OpCode() {
+		switch op := inst.OpCode(); op {
 		case opcode.MOVE:
 			ctx.setRA(inst, ctx.getRB(inst))
 		case opcode.LOADK:
@@ -160,14 +231,14 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			ci.pc++
 		case opcode.LOADBOOL:
-			ctx.setRA(inst, object.Boolean(inst.B() != 0))
+			ctx.setRA(inst, tvalue.Boolean(inst.B() != 0))
 			if inst.C() != 0 {
 				ci.pc++
 			}
 		case opcode.LOADNIL:
 			a := inst.A()
 			for i := 0; i <= inst.B(); i++ {
-				ctx.setR(a+i, nil)
+				ctx.setR(a+i, tvalue.Nil)
 			}
 		case opcode.GETUPVAL:
 			ctx.setRA(inst, ctx.getUB(inst))
@@ -175,32 +246,26 @@ func (th *thread) execute0() (rets []object.Value) {
 			t := ctx.getUB(inst)
 			key := ctx.getRKC(inst)
 
-			val, err := arith.CallGettable(th, t, key)
-			if err != nil {
+			if err := th.getfield(inst.A(), inst.C(), t, key); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, val)
 		case opcode.GETTABLE:
 			t := ctx.getRB(inst)
 			key := ctx.getRKC(inst)
 
-			val, err := arith.CallGettable(th, t, key)
-			if err != nil {
+			if err := th.getfield(inst.A(), inst.C(), t, key); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, val)
 		case opcode.SETTABUP:
 			t := ctx.getUA(inst)
 			key := ctx.getRKB(inst)
 			val := ctx.getRKC(inst)
 
-			err := arith.CallSettable(th, t, key, val)
+			err := th.settable(t, key, val)
 			if err != nil {
 				th.error(err)
 
@@ -213,7 +278,7 @@ func (th *thread) execute0() (rets []object.Value) {
 			key := ctx.getRKB(inst)
 			val := ctx.getRKC(inst)
 
-			err := arith.CallSettable(th, t, key, val)
+			err := th.settable(t, key, val)
 			if err != nil {
 				th.error(err)
 
@@ -225,203 +290,71 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			t := newTableSize(asize, msize)
 
-			ctx.setRA(inst, t)
+			ctx.setRA(inst, tvalue.Of(t))
 		case opcode.SELF:
 			a := inst.A()
 
 			t := ctx.getRB(inst)
 			key := ctx.getRKC(inst)
 
-			val, err := arith.CallGettable(th, t, key)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
 			ctx.setR(a+1, t)
-			ctx.setR(a, val)
-		case opcode.ADD:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			sum, err := arith.CallAdd(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, sum)
-		case opcode.SUB:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			diff, err := arith.CallSub(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, diff)
-		case opcode.MUL:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			prod, err := arith.CallMul(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, prod)
-		case opcode.DIV:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			quo, err := arith.CallDiv(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, quo)
-		case opcode.IDIV:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			quo, err := arith.CallIdiv(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, quo)
-		case opcode.BAND:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			band, err := arith.CallBand(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, band)
-		case opcode.BOR:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			bor, err := arith.CallBor(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, bor)
-		case opcode.BXOR:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
 
-			bxor, err := arith.CallBxor(th, rb, rc)
-			if err != nil {
+			if err := th.getfield(a, inst.C(), t, key); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, bxor)
-		case opcode.SHL:
+		case opcode.ADD, opcode.SUB, opcode.MUL, opcode.MOD, opcode.POW, opcode.DIV, opcode.IDIV,
+			opcode.BAND, opcode.BOR, opcode.BXOR, opcode.SHL, opcode.SHR:
 			rb := ctx.getRKB(inst)
 			rc := ctx.getRKC(inst)
 
-			shl, err := arith.CallShl(th, rb, rc)
-			if err != nil {
+			if err := th.arith(op, inst.A(), rb, rc); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, shl)
-		case opcode.SHR:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			shr, err := arith.CallShr(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, shr)
-		case opcode.MOD:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			rem, err := arith.CallMod(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, rem)
-		case opcode.POW:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			prod, err := arith.CallPow(th, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			ctx.setRA(inst, prod)
 		case opcode.UNM:
 			rb := ctx.getRB(inst)
 
-			unm, err := arith.CallUnm(th, rb)
-			if err != nil {
-				th.error(err)
+			switch rb.Kind {
+			case tvalue.IntKind:
+				ctx.setRA(inst, tvalue.Integer(-rb.Integer()))
+			case tvalue.FloatKind:
+				ctx.setRA(inst, tvalue.Number(-rb.Number()))
+			default:
+				if err := th.unary(object.TM_UNM, inst.A(), rb); err != nil {
+					th.error(err)
 
-				return nil
+					return nil
+				}
 			}
-
-			ctx.setRA(inst, unm)
 		case opcode.BNOT:
 			rb := ctx.getRB(inst)
 
-			bnot, err := arith.CallBnot(th, rb)
-			if err != nil {
+			if rb.Kind == tvalue.IntKind {
+				ctx.setRA(inst, tvalue.Integer(^rb.Integer()))
+
+				break
+			}
+
+			if err := th.unary(object.TM_BNOT, inst.A(), rb); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, bnot)
 		case opcode.NOT:
 			rb := ctx.getRB(inst)
 
-			ctx.setRA(inst, arith.Not(rb))
+			ctx.setRA(inst, tvalue.Boolean(!rb.ToBoolean()))
 		case opcode.LEN:
 			rb := ctx.getRB(inst)
 
-			len, err := arith.CallLen(th, rb)
-			if err != nil {
+			if err := th.unary(object.TM_LEN, inst.A(), rb); err != nil {
 				th.error(err)
 
 				return nil
 			}
-
-			ctx.setRA(inst, len)
 		case opcode.CONCAT:
 			if err := th.concat(inst.A(), inst.B(), inst.C()); err != nil {
 				th.error(err)
@@ -430,67 +363,39 @@ func (th *thread) execute0() (rets []object.Value) {
 			}
 		case opcode.JMP:
 			th.dojmp(inst)
-		case opcode.EQ:
+		case opcode.EQ, opcode.LT, opcode.LE:
 			rb := ctx.getRKB(inst)
 			rc := ctx.getRKC(inst)
 
-			b, err := arith.CallEqual(th, inst.A() != 0, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
-			}
-
-			if b {
-				ci.pc++
-			} else {
-				jmp := ci.Code[ci.pc]
+			not := inst.A() != 0
 
-				if jmp.OpCode() != opcode.JMP {
-					th.error(errors.InvalidByteCodeError())
+			var b bool
+			var err *object.RuntimeError
 
-					return nil
+			switch op {
+			case opcode.EQ:
+				// numbers and booleans have no metamethods
+				if rb.Kind != tvalue.BoxedKind || rc.Kind != tvalue.BoxedKind {
+					b = tvalue.Equal(rb, rc) != not
+				} else {
+					b, err = th.compare(op, not, rb.V, rc.V)
+				}
+			case opcode.LT, opcode.LE:
+				if lt, ok := fastLessThan(op, rb, rc); ok {
+					b = lt != not
+				} else {
+					b, err = th.compare(op, not, rb.Box(), rc.Box())
 				}
-
-				ci.pc++
-
-				th.dojmp(jmp)
 			}
-		case opcode.LT:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
 
-			b, err := arith.CallLessThan(th, inst.A() != 0, rb, rc)
 			if err != nil {
 				th.error(err)
 
 				return nil
 			}
 
-			if b {
-				ci.pc++
-			} else {
-				jmp := ci.Code[ci.pc]
-
-				if jmp.OpCode() != opcode.JMP {
-					th.error(errors.InvalidByteCodeError())
-
-					return nil
-				}
-
-				ci.pc++
-
-				th.dojmp(jmp)
-			}
-		case opcode.LE:
-			rb := ctx.getRKB(inst)
-			rc := ctx.getRKC(inst)
-
-			b, err := arith.CallLessThanOrEqualTo(th, inst.A() != 0, rb, rc)
-			if err != nil {
-				th.error(err)
-
-				return nil
+			if ctx.ci != ci {
+				break
 			}
 
 			if b {
@@ -511,7 +416,7 @@ func (th *thread) execute0() (rets []object.Value) {
 		case opcode.TEST:
 			ra := ctx.getRA(inst)
 
-			if object.ToGoBool(ra) != (inst.C() != 0) {
+			if ra.ToBoolean() != (inst.C() != 0) {
 				ci.pc++
 			} else {
 				jmp := ci.Code[ci.pc]
@@ -529,7 +434,7 @@ func (th *thread) execute0() (rets []object.Value) {
 		case opcode.TESTSET:
 			rb := ctx.getRB(inst)
 
-			if object.ToGoBool(rb) != (inst.C() != 0) {
+			if rb.ToBoolean() != (inst.C() != 0) {
 				ci.pc++
 			} else {
 				ctx.setRA(inst, rb)
@@ -554,6 +459,11 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			if nargs == -1 {
 				nargs = ci.top - ci.base - a - 1
+				if nargs < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
 			}
 
 			if err := th.call(a, nargs, nrets); err != nil {
@@ -561,7 +471,6 @@ func (th *thread) execute0() (rets []object.Value) {
 
 				return nil
 			}
-			ci = ctx.ci
 		case opcode.TAILCALL:
 			a := inst.A()
 
@@ -569,6 +478,11 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			if nargs == -1 {
 				nargs = ci.top - ci.base - a - 1
+				if nargs < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
 			}
 
 			if err := th.tailcall(a, nargs); err != nil {
@@ -576,7 +490,6 @@ func (th *thread) execute0() (rets []object.Value) {
 
 				return nil
 			}
-			ci = ctx.ci
 		case opcode.RETURN:
 			a := inst.A()
 
@@ -584,13 +497,16 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			if nrets == -1 {
 				nrets = ci.top - ci.base - a
+				if nrets < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
 			}
 
 			if rets, exit := th.returnLua(a, nrets); exit {
 				return rets
 			}
-
-			ci = ctx.ci
 		case opcode.FORLOOP:
 			a := inst.A()
 			ra := ctx.getR(a)
@@ -598,141 +514,70 @@ func (th *thread) execute0() (rets []object.Value) {
 			ra2 := ctx.getR(a + 2)
 
 			// forprep already convert val to integer or number.
-			// so there are no need to check types.
-			if idx, ok := ra.(object.Integer); ok {
-				limit := ra1.(object.Integer)
-				step := ra2.(object.Integer)
+			// types are checked only for malformed byte code.
+			if ra.Kind == tvalue.IntKind {
+				if ra1.Kind != tvalue.IntKind || ra2.Kind != tvalue.IntKind {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
+				idx, limit, step := ra.Integer(), ra1.Integer(), ra2.Integer()
 				idx += step
 				if 0 < step {
 					if idx <= limit {
 						ci.pc += inst.SBx()
-						ctx.setR(a, idx)
-						ctx.setR(a+3, idx)
+						ctx.setR(a, tvalue.Integer(idx))
+						ctx.setR(a+3, tvalue.Integer(idx))
 
 						break
 					}
 				} else {
 					if idx >= limit {
 						ci.pc += inst.SBx()
-						ctx.setR(a, idx)
-						ctx.setR(a+3, idx)
+						ctx.setR(a, tvalue.Integer(idx))
+						ctx.setR(a+3, tvalue.Integer(idx))
 
 						break
 					}
 				}
 			} else {
-				idx := ra.(object.Number)
-				limit := ra1.(object.Number)
-				step := ra2.(object.Number)
+				if ra.Kind != tvalue.FloatKind || ra1.Kind != tvalue.FloatKind || ra2.Kind != tvalue.FloatKind {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
+				idx, limit, step := ra.Number(), ra1.Number(), ra2.Number()
 				idx += step
 				if 0 < step {
 					if idx <= limit {
 						ci.pc += inst.SBx()
-						ctx.setR(a, idx)
-						ctx.setR(a+3, idx)
+						ctx.setR(a, tvalue.Number(idx))
+						ctx.setR(a+3, tvalue.Number(idx))
 
 						break
 					}
 				} else {
 					if idx >= limit {
 						ci.pc += inst.SBx()
-						ctx.setR(a, idx)
-						ctx.setR(a+3, idx)
+						ctx.setR(a, tvalue.Number(idx))
+						ctx.setR(a+3, tvalue.Number(idx))
 
 						break
 					}
 				}
 			}
 		case opcode.FORPREP:
-			a := inst.A()
-			ra := ctx.getR(a)
-			ra1 := ctx.getR(a + 1)
-			ra2 := ctx.getR(a + 2)
-
-			if init, ok := ra.(object.Integer); ok {
-				if step, ok := ra2.(object.Integer); ok {
-					ilimit, ok := object.ToInteger(ra1)
-					if !ok {
-						nlimit, ok := object.ToNumber(ra1)
-						if !ok {
-							th.error(errors.ForLoopError("limit"))
-
-							return nil
-						}
-
-						switch {
-						case nlimit < object.Number(object.MinInteger):
-							ilimit = object.MinInteger
-							if step >= 0 {
-								init = 0
-							}
-						case nlimit > object.Number(object.MaxInteger):
-							ilimit = object.MaxInteger
-							if step < 0 {
-								init = 0
-							}
-						default:
-							if step < 0 {
-								if nlimit < 0 {
-									ilimit = object.Integer(nlimit)
-								} else {
-									ilimit = object.Integer(nlimit + 1)
-								}
-							} else {
-								if nlimit < 0 {
-									ilimit = object.Integer(nlimit - 1)
-								} else {
-									ilimit = object.Integer(nlimit)
-								}
-							}
-						}
-					}
-
-					ctx.setR(a, init-step)
-					ctx.setR(a+1, ilimit)
-
-					ci.pc += inst.SBx()
-
-					break
-				}
-			}
-
-			init, ok := object.ToNumber(ra)
-			if !ok {
-				th.error(errors.ForLoopError("initial"))
-
-				return nil
-			}
-
-			limit, ok := object.ToNumber(ra1)
-			if !ok {
-				th.error(errors.ForLoopError("limit"))
-
-				return nil
-			}
-
-			step, ok := object.ToNumber(ra2)
-			if !ok {
-				th.error(errors.ForLoopError("step"))
+			if err := th.forprep(inst.A()); err != nil {
+				th.error(err)
 
 				return nil
 			}
 
-			ctx.setR(a, init-step)
-			ctx.setR(a+1, limit)
-			ctx.setR(a+2, step)
-
 			ci.pc += inst.SBx()
 		case opcode.TFORCALL:
 			a := inst.A()
 			nrets := inst.C()
 
-			if err := th.tforcall(a, nrets); err != nil {
-				th.error(err)
-
-				return nil
-			}
-
 			tloop := ci.Code[ci.pc]
 
 			if tloop.OpCode() != opcode.TFORLOOP {
@@ -740,11 +585,17 @@ func (th *thread) execute0() (rets []object.Value) {
 
 				return nil
 			}
+
+			if err := th.tforcall(a, nrets); err != nil {
+				th.error(err)
+
+				return nil
+			}
 		case opcode.TFORLOOP:
 			a := inst.A()
 			raplus := ctx.getR(a + 1)
 
-			if raplus != nil {
+			if !raplus.IsNil() {
 				ctx.setR(a, raplus)
 
 				ci.pc += inst.SBx()
@@ -752,12 +603,6 @@ func (th *thread) execute0() (rets []object.Value) {
 				break
 			}
 		case opcode.SETLIST:
-			a := inst.A()
-			length := inst.B()
-			if length == 0 {
-				length = ci.top - ci.base - a - 1
-			}
-
 			c := inst.C()
 			if c == 0 {
 				extra := ci.Code[ci.pc]
@@ -772,11 +617,11 @@ func (th *thread) execute0() (rets []object.Value) {
 				c = extra.Ax()
 			}
 
-			base := (c - 1) * version.LUA_FPF
-
-			t := ctx.getR(a).(object.Table)
+			if err := th.setlist(inst.A(), inst.B(), c); err != nil {
+				th.error(err)
 
-			t.SetList(base, ctx.stack[ci.base+a+1:ci.base+a+1+length])
+				return nil
+			}
 		case opcode.CLOSURE:
 			bx := inst.Bx()
 
@@ -788,29 +633,13 @@ func (th *thread) execute0() (rets []object.Value) {
 
 			cl := th.makeClosure(bx)
 
-			ctx.setRA(inst, cl)
+			ctx.setRA(inst, tvalue.Of(cl))
 		case opcode.VARARG:
-			a := inst.A()
-			nrets := inst.B() - 1
-
-			varargs := ci.varargs
-			if nrets != -1 && nrets < len(varargs) {
-				varargs = varargs[:nrets]
-			}
-
-			top := ci.base + a + len(varargs)
-
-			if !ctx.growStack(top) {
-				th.error(errors.StackOverflowError())
-			}
-
-			copy(ctx.stack[ci.base+a:], varargs)
+			if err := th.vararg(inst.A(), inst.B()); err != nil {
+				th.error(err)
 
-			for r := ci.base + a + nrets; r >= top; r-- {
-				ctx.stack[r] = nil
+				return nil
 			}
-
-			ctx.ci.top = top
 		case opcode.EXTRAARG:
 			th.error(errors.InvalidByteCodeError())
 
@@ -832,21 +661,208 @@ func (th *thread) dojmp(inst opcode.Instruction) {
 	th.ci.pc += sbx
 }
 
+// concat stores "R(b) .. ... .. R(c)" to R(a).
+// values are concatenated from the right,
+// a metamethod called in a new frame leaves the rest to finishOp.
 func (th *thread) concat(a, b, c int) (err *object.RuntimeError) {
 	ctx := th.context
 	ci := ctx.ci
 
-	rc := ctx.stack[ci.base+c]
+	rc := ctx.stack[ci.base+c].Box()
 	for r := c - 1; r >= b; r-- {
-		rb := ctx.stack[ci.base+r]
+		rb := ctx.stack[ci.base+r].Box()
 
-		rc, err = arith.CallConcat(th, rb, rc)
+		con, tm, err := arith.ConcatTM(th, rb, rc)
 		if err != nil {
 			return err
 		}
+
+		if tm != nil {
+			var done bool
+
+			con, done, err = th.calltm(tm, rb, rc)
+			if err != nil {
+				return err
+			}
+
+			if !done {
+				ctx.ci.concatAt = r
+
+				return nil
+			}
+		}
+
+		rc = con
+	}
+
+	ctx.setR(a, tvalue.Of(rc))
+
+	return nil
+}
+
+// finishOp completes the instruction of the current frame,
+// which called a metamethod returning val in a new frame, see calltm.
+func (th *thread) finishOp(val tvalue.Value, negate bool, concatAt int) *object.RuntimeError {
+	ctx := th.context
+	ci := ctx.ci
+
+	inst := ci.Code[ci.pc-1]
+
+	switch op := inst.OpCode(); op {
+	case opcode.SETTABUP, opcode.SETTABLE:
+	case opcode.EQ, opcode.LT, opcode.LE:
+		if b := val.ToBoolean() != negate; b != (inst.A() != 0) {
+			ci.pc++
+
+			break
+		}
+
+		// the following jump is executed as usual
+		if ci.Code[ci.pc].OpCode() != opcode.JMP {
+			return errors.InvalidByteCodeError()
+		}
+	case opcode.CONCAT:
+		ctx.setR(concatAt, val)
+
+		return th.concat(inst.A(), inst.B(), concatAt)
+	default:
+		ctx.setRA(inst, val)
+	}
+
+	return nil
+}
+
+func (th *thread) forprep(a int) (err *object.RuntimeError) {
+	ctx := th.context
+
+	ra := ctx.getR(a)
+	ra1 := ctx.getR(a + 1)
+	ra2 := ctx.getR(a + 2)
+
+	if ra.Kind == tvalue.IntKind && ra2.Kind == tvalue.IntKind {
+		init, step := ra.Integer(), ra2.Integer()
+
+		var ilimit object.Integer
+		var ok bool
+		if ra1.Kind == tvalue.IntKind {
+			ilimit, ok = ra1.Integer(), true
+		} else {
+			ilimit, ok = object.ToInteger(ra1.Box())
+		}
+		if !ok {
+			nlimit, ok := object.ToNumber(ra1.Box())
+			if !ok {
+				return errors.ForLoopError("limit")
+			}
+
+			switch {
+			case nlimit < object.Number(object.MinInteger):
+				ilimit = object.MinInteger
+				if step >= 0 {
+					init = 0
+				}
+			case nlimit > object.Number(object.MaxInteger):
+				ilimit = object.MaxInteger
+				if step < 0 {
+					init = 0
+				}
+			default:
+				if step < 0 {
+					if nlimit < 0 {
+						ilimit = object.Integer(nlimit)
+					} else {
+						ilimit = object.Integer(nlimit + 1)
+					}
+				} else {
+					if nlimit < 0 {
+						ilimit = object.Integer(nlimit - 1)
+					} else {
+						ilimit = object.Integer(nlimit)
+					}
+				}
+			}
+		}
+
+		ctx.setR(a, tvalue.Integer(init-step))
+		ctx.setR(a+1, tvalue.Integer(ilimit))
+
+		return nil
+	}
+
+	init, ok := object.ToNumber(ra.Box())
+	if !ok {
+		return errors.ForLoopError("initial")
+	}
+
+	limit, ok := object.ToNumber(ra1.Box())
+	if !ok {
+		return errors.ForLoopError("limit")
+	}
+
+	step, ok := object.ToNumber(ra2.Box())
+	if !ok {
+		return errors.ForLoopError("step")
+	}
+
+	ctx.setR(a, tvalue.Number(init-step))
+	ctx.setR(a+1, tvalue.Number(limit))
+	ctx.setR(a+2, tvalue.Number(step))
+
+	return nil
+}
+
+// setlist stores length values after R(a) into the table R(a), from the block c.
+func (th *thread) setlist(a, length, c int) (err *object.RuntimeError) {
+	ctx := th.context
+	ci := ctx.ci
+
+	if length == 0 {
+		length = ci.top - ci.base - a - 1
+		if length < 0 {
+			return errors.InvalidByteCodeError()
+		}
+	}
+
+	base := (c - 1) * version.LUA_FPF
+
+	list := ctx.stack[ci.base+a+1 : ci.base+a+1+length]
+
+	switch t := ctx.getR(a).V.(type) {
+	case valueTable:
+		t.SetListValue(base, list)
+	case object.Table:
+		t.SetList(base, box(list))
+	default:
+		return errors.InvalidByteCodeError()
 	}
 
-	ctx.setR(a, rc)
+	return nil
+}
+
+func (th *thread) vararg(a, b int) (err *object.RuntimeError) {
+	ctx := th.context
+	ci := ctx.ci
+
+	nrets := b - 1
+
+	varargs := ci.varargs
+	if nrets != -1 && nrets < len(varargs) {
+		varargs = varargs[:nrets]
+	}
+
+	top := ci.base + a + len(varargs)
+
+	if !th.growStack(top) {
+		return errors.StackOverflowError()
+	}
+
+	copy(ctx.stack[ci.base+a:], varargs)
+
+	for r := ci.base + a + nrets; r >= top; r-- {
+		ctx.stack[r] = tvalue.Nil
+	}
+
+	ci.top = top
 
 	return nil
 }
@@ -868,19 +884,19 @@ func mustFunctionOrNil(val object.Value) {
 	}
 }
 
-func (ctx *context) getR(r int) object.Value {
+func (ctx *context) getR(r int) tvalue.Value {
 	return ctx.stack[ctx.ci.base+r]
 }
 
-func (ctx *context) setR(r int, val object.Value) {
+func (ctx *context) setR(r int, val tvalue.Value) {
 	ctx.stack[ctx.ci.base+r] = val
 }
 
-func (ctx *context) getK(k int) object.Value {
-	return ctx.ci.Constants[k]
+func (ctx *context) getK(k int) tvalue.Value {
+	return tvalue.Of(ctx.ci.Constants[k])
 }
 
-func (ctx *context) getRK(rk int) object.Value {
+func (ctx *context) getRK(rk int) tvalue.Value {
 	if rk&opcode.BitRK != 0 {
 		return ctx.getK(rk & ^opcode.BitRK)
 	}
@@ -888,54 +904,62 @@ func (ctx *context) getRK(rk int) object.Value {
 	return ctx.getR(rk)
 }
 
-func (ctx *context) getU(r int) object.Value {
-	return ctx.ci.GetUpvalue(r)
+func (ctx *context) getU(r int) tvalue.Value {
+	if r < 0 || r >= len(ctx.ci.upvals) {
+		return tvalue.Nil
+	}
+
+	return ctx.ci.upvals[r].get()
 }
 
-func (ctx *context) setU(r int, val object.Value) {
-	ctx.ci.SetUpvalue(r, val)
+func (ctx *context) setU(r int, val tvalue.Value) {
+	if r < 0 || r >= len(ctx.ci.upvals) {
+		return
+	}
+
+	ctx.ci.upvals[r].set(val)
 }
 
-func (ctx *context) getRA(inst opcode.Instruction) object.Value {
+func (ctx *context) getRA(inst opcode.Instruction) tvalue.Value {
 	return ctx.getR(inst.A())
 }
 
-func (ctx *context) getRB(inst opcode.Instruction) object.Value {
+func (ctx *context) getRB(inst opcode.Instruction) tvalue.Value {
 	return ctx.getR(inst.B())
 }
 
-func (ctx *context) getRC(inst opcode.Instruction) object.Value {
+func (ctx *context) getRC(inst opcode.Instruction) tvalue.Value {
 	return ctx.getR(inst.C())
 }
 
-func (ctx *context) setRA(inst opcode.Instruction, val object.Value) {
+func (ctx *context) setRA(inst opcode.Instruction, val tvalue.Value) {
 	ctx.setR(inst.A(), val)
 }
 
-func (ctx *context) setUB(inst opcode.Instruction, val object.Value) {
+func (ctx *context) setUB(inst opcode.Instruction, val tvalue.Value) {
 	ctx.setU(inst.B(), val)
 }
 
-func (ctx *context) getKBx(inst opcode.Instruction) object.Value {
+func (ctx *context) getKBx(inst opcode.Instruction) tvalue.Value {
 	return ctx.getK(inst.Bx())
 }
 
-func (ctx *context) getKAx(inst opcode.Instruction) object.Value {
+func (ctx *context) getKAx(inst opcode.Instruction) tvalue.Value {
 	return ctx.getK(inst.Ax())
 }
 
-func (ctx *context) getRKB(inst opcode.Instruction) object.Value {
+func (ctx *context) getRKB(inst opcode.Instruction) tvalue.Value {
 	return ctx.getRK(inst.B())
 }
 
-func (ctx *context) getRKC(inst opcode.Instruction) object.Value {
+func (ctx *context) getRKC(inst opcode.Instruction) tvalue.Value {
 	return ctx.getRK(inst.C())
 }
 
-func (ctx *context) getUA(inst opcode.Instruction) object.Value {
+func (ctx *context) getUA(inst opcode.Instruction) tvalue.Value {
 	return ctx.getU(inst.A())
 }
 
-func (ctx *context) getUB(inst opcode.Instruction) object.Value {
+func (ctx *context) getUB(inst opcode.Instruction) tvalue.Value {
 	return ctx.getU(inst.B())
 }
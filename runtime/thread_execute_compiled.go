@@ -0,0 +1,109 @@
+package runtime
+
+import (
+	"github.com/hirochachacha/plua/internal/errors"
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/opcode"
+)
+
+// executeCompiled is the same as interpret, but runs compiled codes.
+func (th *thread) executeCompiled() (rets []tvalue.Value) {
+	ctx := th.context
+
+	ci := ctx.ci
+	code := th.compiledCode()
+
+	for {
+		if th.hookFunc != nil || th.settings.profiler != nil || th.settings.coverage != nil {
+			if err := th.onInstruction(); err != nil {
+				th.error(err)
+
+				return nil
+			}
+		}
+
+		inst := &code[ci.pc]
+
+		ci.pc++
+
+		switch inst.op {
+		case opcode.CALL:
+			a := inst.a
+
+			nargs := inst.b - 1
+			nrets := inst.c - 1
+
+			if nargs == -1 {
+				nargs = ci.top - ci.base - a - 1
+				if nargs < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
+			}
+
+			if err := th.call(a, nargs, nrets); err != nil {
+				th.error(err)
+
+				return nil
+			}
+
+			ci = ctx.ci
+			code = th.compiledCode()
+		case opcode.TAILCALL:
+			a := inst.a
+
+			nargs := inst.b - 1
+
+			if nargs == -1 {
+				nargs = ci.top - ci.base - a - 1
+				if nargs < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
+			}
+
+			if err := th.tailcall(a, nargs); err != nil {
+				th.error(err)
+
+				return nil
+			}
+
+			ci = ctx.ci
+			code = th.compiledCode()
+		case opcode.RETURN:
+			a := inst.a
+
+			nrets := inst.b - 1
+
+			if nrets == -1 {
+				nrets = ci.top - ci.base - a
+				if nrets < 0 {
+					th.error(errors.InvalidByteCodeError())
+
+					return nil
+				}
+			}
+
+			if rets, exit := th.returnLua(a, nrets); exit {
+				return rets
+			}
+
+			ci = ctx.ci
+			code = th.compiledCode()
+		default:
+			if err := inst.fn(th, ci); err != nil {
+				th.error(err)
+
+				return nil
+			}
+
+			// instructions may push frames, see calltm
+			if ctx.ci != ci {
+				ci = ctx.ci
+				code = th.compiledCode()
+			}
+		}
+	}
+}
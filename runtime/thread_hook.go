@@ -1,6 +1,9 @@
 package runtime
 
-import "github.com/hirochachacha/plua/object"
+import (
+	"github.com/hirochachacha/plua/internal/tvalue"
+	"github.com/hirochachacha/plua/object"
+)
 
 type hookType uint
 
@@ -34,6 +37,14 @@ var hookNames = [...]string{
 }
 
 func (th *thread) onInstruction() *object.RuntimeError {
+	if prof := th.settings.profiler; prof != nil && prof.isRunning() {
+		th.profileInstruction(prof)
+	}
+
+	if cov := th.settings.coverage; cov != nil && cov.isRunning() {
+		th.coverInstruction(cov)
+	}
+
 	if th.context.hookState != noHook {
 		return nil
 	}
@@ -83,6 +94,10 @@ func (th *thread) onReturn() *object.RuntimeError {
 }
 
 func (th *thread) onCall() *object.RuntimeError {
+	if prof := th.settings.profiler; prof != nil && prof.isRunning() {
+		th.profileCall(prof)
+	}
+
 	if th.context.hookState != noHook {
 		return nil
 	}
@@ -99,6 +114,10 @@ func (th *thread) onCall() *object.RuntimeError {
 }
 
 func (th *thread) onTailCall() *object.RuntimeError {
+	if prof := th.settings.profiler; prof != nil && prof.isRunning() {
+		th.profileCall(prof)
+	}
+
 	if th.context.hookState != noHook {
 		return nil
 	}
@@ -117,7 +136,7 @@ func (th *thread) onTailCall() *object.RuntimeError {
 func (th *thread) callHook(typ hookType, arg object.Value) (err *object.RuntimeError) {
 	event := object.String(typ.String())
 
-	_, err = th.doExecute(th.hookFunc, []object.Value{event, arg}, true)
+	_, err = th.doExecute(th.hookFunc, []tvalue.Value{tvalue.Of(event), tvalue.Of(arg)}, true)
 
 	return err
 }
@@ -36,6 +36,7 @@ func (th *thread) stackTrace(d *object.DebugInfo) *object.StackTrace {
 	st := new(object.StackTrace)
 	st.Source = d.ShortSource
 	st.Line = d.CurrentLine
+	st.Column = d.CurrentColumn
 	st.IsTailCall = d.IsTailCall
 
 	if g := th.getFuncName(d.Func); g != "?" {
@@ -10,6 +10,7 @@ import (
 	"github.com/hirochachacha/plua/internal/compiler_pool"
 	"github.com/hirochachacha/plua/internal/errors"
 	"github.com/hirochachacha/plua/internal/limits"
+	"github.com/hirochachacha/plua/internal/pcall"
 	"github.com/hirochachacha/plua/internal/version"
 	"github.com/hirochachacha/plua/object"
 	"github.com/hirochachacha/plua/object/fnutil"
@@ -87,7 +88,7 @@ func dofile(th object.Thread, args ...object.Value) (rets []object.Value, err *o
 		}
 	}
 
-	p, err := compiler_pool.CompileFile(fname, 0)
+	p, err := compiler_pool.CompileFile(th, fname, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -289,11 +290,11 @@ func loadfile(th object.Thread, args ...object.Value) ([]object.Value, *object.R
 
 	switch mode {
 	case "b":
-		p, err = compiler_pool.CompileFile(fname, compiler.Binary)
+		p, err = compiler_pool.CompileFile(th, fname, compiler.Binary)
 	case "t":
-		p, err = compiler_pool.CompileFile(fname, compiler.Text)
+		p, err = compiler_pool.CompileFile(th, fname, compiler.Text)
 	case "bt":
-		p, err = compiler_pool.CompileFile(fname, 0)
+		p, err = compiler_pool.CompileFile(th, fname, 0)
 	default:
 		return nil, ap.OptionError(1, mode)
 	}
@@ -364,11 +365,11 @@ func load(th object.Thread, args ...object.Value) ([]object.Value, *object.Runti
 	var p *object.Proto
 	switch mode {
 	case "b":
-		p, err = compiler_pool.CompileString(chunk, chunkname, compiler.Binary)
+		p, err = compiler_pool.CompileString(th, chunk, chunkname, compiler.Binary)
 	case "t":
-		p, err = compiler_pool.CompileString(chunk, chunkname, compiler.Text)
+		p, err = compiler_pool.CompileString(th, chunk, chunkname, compiler.Text)
 	case "bt":
-		p, err = compiler_pool.CompileString(chunk, chunkname, 0)
+		p, err = compiler_pool.CompileString(th, chunk, chunkname, 0)
 	default:
 		return nil, ap.OptionError(2, mode)
 	}
@@ -433,22 +434,6 @@ func _print(th object.Thread, args ...object.Value) ([]object.Value, *object.Run
 	return nil, nil
 }
 
-func pcall(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
-	ap := fnutil.NewArgParser(th, args)
-
-	fn, err := ap.ToFunctionOrNil(0)
-	if err != nil {
-		return nil, err
-	}
-
-	rets, err := th.Call(fn, args[1:]...)
-	if err != nil {
-		return []object.Value{object.False, err.Value()}, nil
-	}
-
-	return append([]object.Value{object.True}, rets...), nil
-}
-
 func rawequal(th object.Thread, args ...object.Value) ([]object.Value, *object.RuntimeError) {
 	ap := fnutil.NewArgParser(th, args)
 
@@ -708,7 +693,7 @@ func Open(th object.Thread, args ...object.Value) ([]object.Value, *object.Runti
 	g.Set(object.String("load"), object.GoFunction(load))
 	g.Set(object.String("next"), object.GoFunction(next))
 	g.Set(object.String("pairs"), object.GoFunction(pairs))
-	g.Set(object.String("pcall"), object.GoFunction(pcall))
+	g.Set(object.String("pcall"), object.GoFunction(pcall.Call))
 	g.Set(object.String("print"), object.GoFunction(_print))
 	g.Set(object.String("rawequal"), object.GoFunction(rawequal))
 	g.Set(object.String("rawlen"), object.GoFunction(rawlen))
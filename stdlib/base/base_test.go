@@ -89,7 +89,7 @@ var testErrors = []execCase{
 		error("test_message")
 		`,
 		nil,
-		`runtime: test_error:3: test_message`,
+		`runtime: test_error:3:8: test_message`,
 	},
 	{
 		`
@@ -100,7 +100,7 @@ var testErrors = []execCase{
 		x()
 		`,
 		nil,
-		`runtime: test_error:3: test_message`,
+		`runtime: test_error:3:10: test_message`,
 	},
 }
 
@@ -1,7 +1,7 @@
 ok, msg = pcall(error, "test", 1)
 assert(not ok and msg == "test")
 ok, msg = pcall(error, "test", 2)
-assert(not ok and (msg == "testdata/error.lua:3: test" or msg == "testdata\\error.lua:3: test"))
+assert(not ok and (msg == "testdata/error.lua:3:16: test" or msg == "testdata\\error.lua:3:16: test"))
 ok, msg = pcall(error, "test", 3)
 assert(not ok and msg == "test")
 
@@ -18,4 +18,4 @@ local function f()
 end
 
 ok, msg = pcall(f)
-assert(not ok and (msg == "testdata/error.lua:17: test" or msg == "testdata\\error.lua:17: test"))
+assert(not ok and (msg == "testdata/error.lua:17:3: test" or msg == "testdata\\error.lua:17:3: test"))
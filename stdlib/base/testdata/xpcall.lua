@@ -1,11 +1,11 @@
 function f() error("error") end
 function g(x) return x end
 ok, err = xpcall(f, g)
-assert(not ok and (err == "testdata/xpcall.lua:1: error" or err == "testdata\\xpcall.lua:1: error"))
+assert(not ok and (err == "testdata/xpcall.lua:1:19: error" or err == "testdata\\xpcall.lua:1:19: error"))
 
 function g2(x) error(x) end
 ok, err = xpcall(f, g2)
-assert(not ok and (err == "testdata/xpcall.lua:6: error in error handling" or err == "testdata\\xpcall.lua:6: error in error handling"))
+assert(not ok and (err == "testdata/xpcall.lua:6:21: error in error handling" or err == "testdata\\xpcall.lua:6:21: error in error handling"))
 
 function f() return 1, 2 end
 
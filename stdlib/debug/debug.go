@@ -33,7 +33,7 @@ func debug(th object.Thread, args ...object.Value) ([]object.Value, *object.Runt
 			return nil, nil
 		}
 
-		p, err := compiler_pool.CompileString(line, "=(debug command)", 0)
+		p, err := compiler_pool.CompileString(th, line, "=(debug command)", 0)
 		if err != nil {
 			return nil, err
 		}
@@ -142,6 +142,7 @@ func getinfo(th object.Thread, args ...object.Value) ([]object.Value, *object.Ru
 
 	if opts['l'] {
 		t.Set(object.String("currentline"), object.Integer(d.CurrentLine))
+		t.Set(object.String("currentcolumn"), object.Integer(d.CurrentColumn))
 	}
 
 	if opts['u'] {
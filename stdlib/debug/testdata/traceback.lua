@@ -16,4 +16,4 @@ iter = string.gmatch(tb, ".-\n")
 assert(iter() == "\n")
 assert(iter() == "stack traceback:\n")
 x = iter()
-assert(x == "	testdata/traceback.lua:10: in local 'f'\n" or "	testdata\\traceback.lua:10: in local 'f'\n")
+assert(x == "	testdata/traceback.lua:10:3: in local 'f'\n" or "	testdata\\traceback.lua:10:3: in local 'f'\n")
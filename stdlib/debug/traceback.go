@@ -21,6 +21,10 @@ func writeStackTrace(buf *bytes.Buffer, st *object.StackTrace) {
 	if st.Line > 0 {
 		buf.WriteString(strconv.Itoa(st.Line))
 		buf.WriteByte(':')
+		if st.Column > 0 {
+			buf.WriteString(strconv.Itoa(st.Column))
+			buf.WriteByte(':')
+		}
 		write = true
 	}
 
@@ -105,7 +105,7 @@ func makeSearchers(m object.Table) []object.Value {
 		case 1:
 			fpath := string(rets[0].(object.String))
 
-			p, err := compiler_pool.CompileFile(fpath, 0)
+			p, err := compiler_pool.CompileFile(th, fpath, 0)
 			if err != nil {
 				return nil, err
 			}